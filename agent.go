@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/mooship/vivteno/pkg/monitor"
+)
+
+// Agent mode settings
+const (
+	DefaultAgentInterval = 15 * time.Second
+	// AgentStaleAfter is how long the central instance shows the results of
+	// a location before marking them stale.
+	AgentStaleAfter = 5 * time.Minute
+	// AgentMaxPayload limits the size of a push the central instance reads.
+	AgentMaxPayload = 4 << 20
+	agentTimeout    = 10 * time.Second
+	// agentResultsPath is where the central instance receives pushes.
+	agentResultsPath = "/agent/results"
+)
+
+// agentPush is the body of a push from an agent: the latest result of every
+// website checked since the previous push.
+type agentPush struct {
+	Location string        `json:"location"`
+	Results  []agentResult `json:"results"`
+}
+
+// agentResult is the outcome of one check cycle of an agent, in the format
+// of --once results.
+type agentResult struct {
+	onceResult
+	Time time.Time `json:"time"`
+}
+
+// agent runs the checks of a location and pushes their results to a central
+// vivteno instance.
+type agent struct {
+	url      string
+	token    string
+	location string
+	interval time.Duration
+	client   *http.Client
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	pending map[string]agentResult
+}
+
+// loadAgent reads the AGENT_* settings of agent mode.
+func loadAgent(logger *slog.Logger) (*agent, error) {
+	central := os.Getenv("AGENT_CENTRAL_URL")
+	if central == "" {
+		return nil, fmt.Errorf("AGENT_CENTRAL_URL is required in agent mode, e.g. https://vivteno.example.com")
+	}
+	if u, err := url.Parse(central); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid AGENT_CENTRAL_URL: %q", central)
+	}
+	a := &agent{
+		url:      strings.TrimSuffix(central, "/") + agentResultsPath,
+		token:    os.Getenv("AGENT_TOKEN"),
+		location: os.Getenv("AGENT_LOCATION"),
+		interval: DefaultAgentInterval,
+		client:   &http.Client{Timeout: agentTimeout},
+		logger:   logger,
+		pending:  make(map[string]agentResult),
+	}
+	if a.token == "" {
+		return nil, fmt.Errorf("AGENT_TOKEN is required in agent mode and must match that of the central instance")
+	}
+	if a.location == "" {
+		host, err := os.Hostname()
+		if err != nil || host == "" {
+			return nil, fmt.Errorf("AGENT_LOCATION is required when the hostname is unknown")
+		}
+		a.location = host
+	}
+	if v := os.Getenv("AGENT_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid AGENT_INTERVAL: %q", v)
+		}
+		a.interval = d
+	}
+	return a, nil
+}
+
+// record keeps the result of a check cycle until the next push, replacing
+// an earlier one of the same website.
+func (a *agent) record(r monitor.Report) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending[r.Target.Name] = agentResult{onceResult: newOnceResult(r), Time: r.Time}
+}
+
+// run pushes every interval until ctx is cancelled, then once more so the
+// last checks are not lost.
+func (a *agent) run(ctx context.Context) {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			final, cancel := context.WithTimeout(context.Background(), agentTimeout)
+			a.push(final)
+			cancel()
+			return
+		case <-ticker.C:
+			a.push(ctx)
+		}
+	}
+}
+
+// push sends the pending results. Results that could not be sent are kept
+// unless a newer result of the same website arrived meanwhile.
+func (a *agent) push(ctx context.Context) {
+	a.mu.Lock()
+	pending := a.pending
+	a.pending = make(map[string]agentResult)
+	a.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+	results := slices.SortedFunc(maps.Values(pending), func(x, y agentResult) int { return strings.Compare(x.Website, y.Website) })
+	err := a.post(ctx, agentPush{Location: a.location, Results: results})
+	if err == nil {
+		a.logger.Debug("results pushed", "results", len(results))
+		return
+	}
+	a.logger.Error("pushing results failed", "error", err.Error())
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for website, r := range pending {
+		if _, newer := a.pending[website]; !newer {
+			a.pending[website] = r
+		}
+	}
+}
+
+func (a *agent) post(ctx context.Context, push agentPush) error {
+	payload, err := json.Marshal(push)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// runAgent implements the agent subcommand: it checks the configured
+// websites without the terminal UI and pushes the results to the central
+// instance at AGENT_CENTRAL_URL until interrupted. It returns the process
+// exit code.
+func runAgent(args []string) int {
+	fs := flag.NewFlagSet("agent", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	loadEnv()
+	logger, logFile, err := loadLogger()
+	if err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		return 1
+	}
+	defer logFile.Close()
+	// An agent has no terminal UI to show its errors in
+	if os.Getenv("LOG_FILE") == "" {
+		logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+	}
+	a, err := loadAgent(logger)
+	if err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		return 1
+	}
+	_, discovered, err := startDiscovery()
+	if err != nil {
+		fmt.Printf("Discovery failed: %v\n", err)
+		return 1
+	}
+	cfg, err := loadConfig(discovered)
+	if err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		return 1
+	}
+	if i := slices.Index(cfg.checkTypes, monitor.CheckHeartbeat); i >= 0 {
+		fmt.Printf("Configuration error: %s uses the heartbeat check, which agents cannot receive\n", cfg.websites[i])
+		return 1
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	mon := cfg.newMonitor()
+	go mon.Run(ctx)
+	wait := runExporters(ctx, []exporter{a})
+	logger.Info("agent started", "location", a.location, "central", a.url, "targets", len(cfg.websites))
+	for {
+		select {
+		case r := <-mon.Reports():
+			a.record(r)
+		case <-ctx.Done():
+			wait()
+			logger.Info("agent stopped", "location", a.location)
+			return 0
+		}
+	}
+}
+
+// locationResult is the latest result a location reported for a website.
+type locationResult struct {
+	agentResult
+	// received is when the central instance received the result, which
+	// decides when it is stale regardless of the agent's clock.
+	received time.Time
+}
+
+// status returns the state of the website at the location.
+func (r locationResult) status(now time.Time) status {
+	switch {
+	case now.Sub(r.received) > AgentStaleAfter:
+		return statusUnknown
+	case !r.Up && r.Maintenance:
+		return statusMaintenance
+	case !r.Up:
+		return statusDown
+	case len(r.Violations) > 0:
+		return statusDegraded
+	}
+	return statusUp
+}
+
+// agentMsg delivers the results pushed by the agent of a location.
+type agentMsg struct {
+	agentPush
+	received time.Time
+}
+
+// handleAgents registers the URL agents push their results to, if token is
+// set. Pushes must authenticate with token as a bearer token and are handed
+// to the program with send.
+func handleAgents(mux *http.ServeMux, token string, send func(tea.Msg)) {
+	if token == "" {
+		return
+	}
+	mux.HandleFunc("POST "+agentResultsPath, func(w http.ResponseWriter, r *http.Request) {
+		bearer, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			http.Error(w, "invalid agent token", http.StatusUnauthorized)
+			return
+		}
+		var push agentPush
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, AgentMaxPayload)).Decode(&push); err != nil {
+			http.Error(w, "invalid results: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if push.Location = strings.TrimSpace(push.Location); push.Location == "" {
+			http.Error(w, "missing location", http.StatusBadRequest)
+			return
+		}
+		send(agentMsg{agentPush: push, received: time.Now()})
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// applyAgent records the results of a location for the websites they
+// name. Results of websites that are not configured here are ignored.
+func (m model) applyAgent(msg agentMsg) {
+	for _, r := range msg.Results {
+		t := m.target(r.Website)
+		if t == nil {
+			continue
+		}
+		if t.locations == nil {
+			t.locations = make(map[string]locationResult)
+		}
+		t.locations[msg.Location] = locationResult{agentResult: r, received: msg.received}
+	}
+}
+
+// renderLocations shows the latest result of each location that reported
+// on a website, in alphabetical order.
+func renderLocations(t *target, now time.Time) string {
+	var b strings.Builder
+	for i, name := range slices.Sorted(maps.Keys(t.locations)) {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		r := t.locations[name]
+		errLine, _, _ := strings.Cut(r.Error, "\n")
+		title := sectionTitle.Render("Location " + name + ":")
+		switch st := r.status(now); st {
+		case statusUnknown:
+			b.WriteString(title + " " + unknownStyle.Render(glyphUnknown+" no results for "+formatDuration(now.Sub(r.received))))
+		case statusUp:
+			b.WriteString(title + " " + infoStyle.Render(fmt.Sprintf("%s %d ms", glyphUp, r.LatencyMs)))
+		case statusDegraded:
+			b.WriteString(title + " " + degradedStyle.Render(glyphDegraded+" assertion failed: "+strings.Join(r.Violations, "; ")))
+		case statusMaintenance:
+			b.WriteString(title + " " + maintenanceStyle.Render(glyphMaint+" "+errLine))
+		default:
+			b.WriteString(title + " " + downStyle.Render(glyphDown+" "+errLine))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Latency baseline of anomaly detection
+const (
+	// AnomalyBaselineSamples is the number of recent successful latencies
+	// the baseline of a website is computed from.
+	AnomalyBaselineSamples = 100
+	// anomalyMinSamples is the number of latencies needed before checks are
+	// compared with the baseline.
+	anomalyMinSamples = 20
+	// anomalyMinSpread is the smallest standard deviation assumed, as a
+	// fraction of the mean, so the jitter of a very steady website is not
+	// taken for an anomaly.
+	anomalyMinSpread = 0.1
+)
+
+// detectAnomaly compares a successful check's latency with the baseline of
+// the website's recent latencies, then adds it to the baseline. It returns
+// a description of the anomaly if the latency is more than the configured
+// number of standard deviations above the mean, or "".
+func (t *target) detectAnomaly(d time.Duration) string {
+	defer func() {
+		t.baseline = append(t.baseline, d)
+		if len(t.baseline) > AnomalyBaselineSamples {
+			t.baseline = t.baseline[len(t.baseline)-AnomalyBaselineSamples:]
+		}
+	}()
+	if t.latency.anomaly == 0 || len(t.baseline) < anomalyMinSamples {
+		return ""
+	}
+	var sum float64
+	for _, s := range t.baseline {
+		sum += float64(s)
+	}
+	mean := sum / float64(len(t.baseline))
+	var variance float64
+	for _, s := range t.baseline {
+		variance += (float64(s) - mean) * (float64(s) - mean)
+	}
+	stddev := max(math.Sqrt(variance/float64(len(t.baseline))), mean*anomalyMinSpread)
+	deviations := (float64(d) - mean) / stddev
+	if deviations < t.latency.anomaly {
+		return ""
+	}
+	return fmt.Sprintf("latency %d ms is %.1f standard deviations above the baseline of %d ± %d ms",
+		d.Milliseconds(), deviations, time.Duration(mean).Milliseconds(), time.Duration(stddev).Milliseconds())
+}
+
+// parseDeviations is the parse function for per-website anomaly thresholds
+// in standard deviations. An empty value or 0 disables anomaly detection.
+func parseDeviations(s string) (float64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("expected a number of standard deviations such as 3, got %q", s)
+	}
+	return v, nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// BellFlashDuration is how long the header flashes after a website went
+// down.
+const BellFlashDuration = 5 * time.Second
+
+// bellMsg reports that a website went down, so the header starts flashing.
+type bellMsg struct{}
+
+// bellCmd returns a command ringing the terminal bell and flashing the header
+// for a website that went down, as far as BELL and BELL_FLASH enable them,
+// or nil.
+func (m model) bellCmd() tea.Cmd {
+	if !m.bell && !m.bellFlash {
+		return nil
+	}
+	bell, flash := m.bell, m.bellFlash
+	return func() tea.Msg {
+		if bell {
+			// A lone BEL cannot break the escape sequences of a frame, as the
+			// renderer writes each frame at once
+			_, _ = os.Stdout.WriteString("\a")
+		}
+		if flash {
+			return bellMsg{}
+		}
+		return nil
+	}
+}
+
+// flashing reports whether the header is shown flashed at now: every other
+// second for BellFlashDuration after a website went down.
+func (m model) flashing(now time.Time) bool {
+	elapsed := now.Sub(m.flashStart)
+	return !m.flashStart.IsZero() && elapsed < BellFlashDuration && (elapsed/time.Second)%2 == 0
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/mooship/vivteno/pkg/monitor"
+)
+
+// CheckFormatPretty is the default output format of the check subcommand.
+const CheckFormatPretty = "pretty"
+
+// runCheck implements the check subcommand: it checks the given websites, or
+// all configured ones, once and prints the outcome. Websites configured in
+// PING_WEBSITE are checked with their own settings; others with the
+// settings given as a single value. It returns the process exit code, as
+// --once does.
+func runCheck(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	format := fs.String("format", CheckFormatPretty, "output format: pretty, table or json")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	switch *format {
+	case CheckFormatPretty, OnceFormatTable, OnceFormatJSON:
+	default:
+		fmt.Printf("Invalid --format: %q (expected %q, %q or %q)\n", *format, CheckFormatPretty, OnceFormatTable, OnceFormatJSON)
+		return 1
+	}
+	loadEnv()
+	websites := fs.Args()
+	var discovered []discoveredSite
+	for _, w := range websites {
+		discovered = append(discovered, discoveredSite{website: w})
+	}
+	if len(websites) == 0 {
+		var err error
+		if _, discovered, err = startDiscovery(); err != nil {
+			fmt.Printf("Discovery failed: %v\n", err)
+			return 1
+		}
+	}
+	cfg, err := loadConfig(discovered)
+	if err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		return 1
+	}
+	// Only check the websites asked for, in the order given
+	indices := make([]int, len(cfg.websites))
+	for i := range indices {
+		indices[i] = i
+	}
+	if len(websites) > 0 {
+		indices = indices[:0]
+		for _, w := range websites {
+			if i := slices.Index(cfg.websites, w); i >= 0 && !slices.Contains(indices, i) {
+				indices = append(indices, i)
+			}
+		}
+		targets := make([]monitor.Target, len(indices))
+		for j, i := range indices {
+			targets[j] = cfg.targets[i]
+		}
+		cfg.targets = targets
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	reports := cfg.newMonitor().CheckAll(ctx)
+	results := make([]onceResult, len(reports))
+	for i, r := range reports {
+		results[i] = newOnceResult(r)
+	}
+	switch *format {
+	case OnceFormatJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(results)
+	case OnceFormatTable:
+		writeOnceTable(os.Stdout, results)
+	default:
+		cfg.theme.apply()
+		for _, r := range reports {
+			writeCheckReport(os.Stdout, r, cfg.healthFields[indices[r.Index]], cfg)
+		}
+	}
+	return onceExitCode(results)
+}
+
+// checkStatus returns the state a single check puts a website in.
+func checkStatus(r monitor.Report) status {
+	switch {
+	case r.Maintenance && !r.OK():
+		return statusMaintenance
+	case !r.OK():
+		return statusDown
+	case len(r.Violations) > 0:
+		return statusDegraded
+	}
+	return statusUp
+}
+
+// writeCheckReport writes the outcome of a check in the style of the cards
+// of the terminal UI.
+func writeCheckReport(w io.Writer, r monitor.Report, fields []monitor.Path, cfg config) {
+	st := checkStatus(r)
+	heading := statusGlyph(st) + " " + sectionTitle.Render(r.Target.Name) +
+		healthKeyStyle.Render(r.Target.Check) + " " + statusStyle(st).Render(strings.ToUpper(st.String()))
+	if r.Ping.Err == nil {
+		heading += " " + infoStyle.Render(fmt.Sprintf("%d ms", r.Ping.Latency.Milliseconds()))
+	}
+	lines := []string{heading}
+	if r.Ping.Err == nil {
+		for _, line := range strings.Split(r.Ping.Detail, "\n") {
+			if line != "" {
+				lines = append(lines, "  "+infoStyle.Render(line))
+			}
+		}
+		if r.Ping.Timing != nil {
+			lines = append(lines, "  "+infoStyle.Render(r.Ping.Timing.String()))
+		}
+		if r.Ping.Attempts > 1 {
+			lines = append(lines, "  "+infoStyle.Render(fmt.Sprintf("Succeeded after %d attempts", r.Ping.Attempts)))
+		}
+	}
+	if len(r.Families) > 0 {
+		lines = append(lines, indent(renderFamilies(r.Families)))
+	}
+	if len(r.Ports) > 0 {
+		lines = append(lines, indent(renderPorts(r.Ports)))
+	}
+	if r.Health != nil && r.Health.Err == nil {
+		if len(fields) > 0 {
+			lines = append(lines, indent(renderHealthFields(r.Health.Data, fields, cfg.healthOrder, cfg.timezone, nil)))
+		} else {
+			lines = append(lines, indent(renderHealthSection(r.Health.Data, cfg.healthOrder, cfg.timezone, nil)))
+		}
+		if r.Health.Timing != nil {
+			lines = append(lines, "    "+healthKeyStyle.Render("timing:")+" "+healthValueStyle.Render(r.Health.Timing.String()))
+		}
+	}
+	if !r.OK() {
+		msg := reportError(r)
+		if st == statusMaintenance {
+			lines = append(lines, "  "+maintenanceStyle.Render("MAINTENANCE: "+msg))
+		} else {
+			lines = append(lines, "  "+downStyle.Render("FAILED: "+msg))
+		}
+	}
+	for _, v := range r.Violations {
+		lines = append(lines, "  "+degradedStyle.Render("ASSERTION FAILED: "+v.Error()))
+	}
+	fmt.Fprintln(w, strings.Join(lines, "\n")+"\n")
+}
+
+// indent indents every line of s by two spaces.
+func indent(s string) string {
+	return "  " + strings.ReplaceAll(s, "\n", "\n  ")
+}
@@ -0,0 +1,1497 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mooship/vivteno/pkg/monitor"
+	"github.com/mooship/vivteno/pkg/notify"
+)
+
+// backendChecks are the check types BACKEND_PORT applies to.
+var backendChecks = []string{monitor.CheckRedis, monitor.CheckMemcached, monitor.CheckPostgres, monitor.CheckMySQL, monitor.CheckMQTT}
+
+// config is the parsed environment configuration.
+type config struct {
+	websites        []string
+	targets         []monitor.Target
+	schedule        string
+	interval        time.Duration
+	downInterval    time.Duration
+	stagger         bool
+	jitter          time.Duration
+	timezone        *time.Location
+	threshold       int
+	flap            flapPolicy
+	muteDuration    time.Duration
+	quiet           quietHours
+	retry           monitor.RetryPolicy
+	maxConcurrent   int
+	historySize     int
+	healthEndpoints []string
+	checkTypes      []string
+	healthFields    [][]monitor.Path
+	healthWatch     [][]monitor.Path
+	watchAlerts     []bool
+	captureHeaders  [][]string
+	healthOrder     []string
+	tags            [][]string
+	groupBy         string
+	maintenance     [][]monitor.Window
+	latency         []latencyThresholds
+	domainExpiry    []int
+	domains         []string
+	rdapServer      string
+	domainRefresh   time.Duration
+	notifiers       []notify.Notifier
+	pushURLs        []string
+	slos            []float64
+	traceroutes     []string
+	descriptions    []string
+	owners          []string
+	severities      []string
+	runbooks        []string
+	slo             sloPolicy
+	alerts          alertPolicy
+	shutdownTimeout time.Duration
+	webListen       string
+	controlListen   string
+	agentToken      string
+	heartbeatTokens []string
+	historyFile     string
+	configWatch     bool
+	exportDir       string
+	exportFormat    string
+	theme           theme
+	bell            bool
+	bellFlash       bool
+}
+
+// loadConfig reads and validates the configuration from the environment.
+// Discovered websites are monitored after those in PING_WEBSITE, with the
+// tags they were discovered with.
+func loadConfig(discovered []discoveredSite) (config, error) {
+	var cfg config
+	if websiteEnv := os.Getenv("PING_WEBSITE"); websiteEnv != "" || len(discovered) == 0 {
+		if err := json.Unmarshal([]byte(websiteEnv), &cfg.websites); err != nil || len(cfg.websites) == 0 {
+			return cfg, fmt.Errorf("PING_WEBSITE must be a JSON array of at least one website, e.g. [\"example.com\"]")
+		}
+	}
+	n := siteCount{listed: len(cfg.websites)}
+	var discoveredTags [][]string
+	for _, d := range discovered {
+		if !slices.Contains(cfg.websites, d.website) {
+			cfg.websites = append(cfg.websites, d.website)
+			discoveredTags = append(discoveredTags, d.tags)
+			n.discovered++
+		}
+	}
+	cfg.targets = make([]monitor.Target, n.total())
+	for i, w := range cfg.websites {
+		t, err := monitor.ParseTarget(w)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid website in PING_WEBSITE: %q (%v)", w, err)
+		}
+		cfg.targets[i] = t
+	}
+
+	cfg.schedule = os.Getenv("PING_SCHEDULE")
+	if cfg.schedule == "" {
+		cfg.schedule = DefaultSchedule
+	}
+	interval, err := time.ParseDuration(cfg.schedule)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid PING_SCHEDULE: %q", cfg.schedule)
+	}
+	cfg.interval = interval
+
+	cfg.downInterval = min(DefaultDownSchedule, interval)
+	if v := os.Getenv("PING_SCHEDULE_DOWN"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return cfg, fmt.Errorf("invalid PING_SCHEDULE_DOWN: %q", v)
+		}
+		cfg.downInterval = d
+	}
+
+	cfg.stagger = true
+	if v := os.Getenv("PING_STAGGER"); v != "" {
+		if cfg.stagger, err = parseBool(v); err != nil {
+			return cfg, fmt.Errorf("invalid PING_STAGGER: %w", err)
+		}
+	}
+	if v := os.Getenv("PING_JITTER"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return cfg, fmt.Errorf("invalid PING_JITTER: %q", v)
+		}
+		cfg.jitter = d
+	}
+
+	cfg.threshold = DefaultFailureThreshold
+	if v := os.Getenv("FAILURE_THRESHOLD"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid FAILURE_THRESHOLD: %q (must be a positive integer)", v)
+		}
+		cfg.threshold = n
+	}
+
+	cfg.flap.window = DefaultFlapWindow
+	if v := os.Getenv("FLAP_THRESHOLD"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("invalid FLAP_THRESHOLD: %q (must be a non-negative integer)", v)
+		}
+		cfg.flap.threshold = n
+	}
+	if v := os.Getenv("FLAP_WINDOW"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("invalid FLAP_WINDOW: %q", v)
+		}
+		cfg.flap.window = d
+	}
+
+	cfg.muteDuration = DefaultMuteDuration
+	if v := os.Getenv("MUTE_DURATION"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return cfg, fmt.Errorf("invalid MUTE_DURATION: %q", v)
+		}
+		cfg.muteDuration = d
+	}
+
+	cfg.retry = monitor.DefaultRetryPolicy()
+	if v := os.Getenv("RETRY_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid RETRY_ATTEMPTS: %q (must be a positive integer)", v)
+		}
+		cfg.retry.MaxAttempts = n
+	}
+	if v := os.Getenv("RETRY_DELAY"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return cfg, fmt.Errorf("invalid RETRY_DELAY: %q", v)
+		}
+		cfg.retry.BaseDelay = d
+	}
+
+	cfg.maxConcurrent = monitor.DefaultMaxConcurrent
+	if v := os.Getenv("MAX_CONCURRENT_CHECKS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("invalid MAX_CONCURRENT_CHECKS: %q (must be a non-negative integer)", v)
+		}
+		cfg.maxConcurrent = n
+	}
+
+	cfg.historySize = DefaultHistorySize
+	if v := os.Getenv("HISTORY_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid HISTORY_SIZE: %q (must be a positive integer)", v)
+		}
+		cfg.historySize = n
+	}
+
+	cfg.timezone = time.Local
+	if v := os.Getenv("TIMEZONE"); v != "" {
+		if cfg.timezone, err = time.LoadLocation(v); err != nil {
+			return cfg, fmt.Errorf("invalid TIMEZONE: %q", v)
+		}
+	}
+	if v := os.Getenv("QUIET_HOURS"); v != "" {
+		if cfg.quiet, err = parseQuietHours(v, cfg.timezone); err != nil {
+			return cfg, fmt.Errorf("invalid QUIET_HOURS: %w", err)
+		}
+		if cfg.quiet.critical, err = parseTags(os.Getenv("QUIET_HOURS_CRITICAL")); err != nil {
+			return cfg, fmt.Errorf("invalid QUIET_HOURS_CRITICAL: %w", err)
+		}
+	}
+
+	// Parse HEALTH_ENDPOINT as array or fallback to single value for all
+	cfg.healthEndpoints, err = parsePerTarget(os.Getenv("HEALTH_ENDPOINT"), n, "", parseString)
+	if err != nil {
+		return cfg, fmt.Errorf("HEALTH_ENDPOINT must be a JSON array with the same length as PING_WEBSITE, or a single string")
+	}
+	healthBaseURLs, err := parsePerTarget(os.Getenv("HEALTH_BASE_URL"), n, "", parseHealthBaseURL)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid HEALTH_BASE_URL: %w", err)
+	}
+	healthMaxBodies, err := parsePerTarget(os.Getenv("HEALTH_MAX_BODY"), n, 0, parseMaxBodySize)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid HEALTH_MAX_BODY: %w", err)
+	}
+	if cfg.checkTypes, err = parsePerTarget(os.Getenv("CHECK_TYPE"), n, monitor.CheckTCP, parseCheckType); err != nil {
+		return cfg, fmt.Errorf("invalid CHECK_TYPE: %w", err)
+	}
+	if cfg.healthFields, err = parsePerTarget(os.Getenv("HEALTH_FIELDS"), n, nil, parsePaths); err != nil {
+		return cfg, fmt.Errorf("invalid HEALTH_FIELDS: %w", err)
+	}
+	healthOrder := os.Getenv("HEALTH_FIELD_ORDER")
+	if healthOrder == "" {
+		healthOrder = DefaultHealthFieldOrder
+	}
+	cfg.healthOrder = parseList(healthOrder)
+	if cfg.tags, err = parsePerTarget(os.Getenv("TAGS"), n, nil, parseTags); err != nil {
+		return cfg, fmt.Errorf("invalid TAGS: %w", err)
+	}
+	for i, tags := range discoveredTags {
+		cfg.tags[n.listed+i] = append(slices.Clone(cfg.tags[n.listed+i]), tags...)
+	}
+	if cfg.groupBy = os.Getenv("GROUP_BY"); cfg.groupBy != "" && !tagKeyPattern.MatchString(cfg.groupBy) {
+		return cfg, fmt.Errorf("invalid GROUP_BY: %q is not a tag name", cfg.groupBy)
+	}
+
+	if cfg.slos, err = parsePerTarget(os.Getenv("SLO_TARGET"), n, 0, parseSLO); err != nil {
+		return cfg, fmt.Errorf("invalid SLO_TARGET: %w", err)
+	}
+	cfg.slo = sloPolicy{window: DefaultSLOWindow, burnRate: DefaultSLOBurnRate}
+	if v := os.Getenv("SLO_WINDOW"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < SLOBurnWindow {
+			return cfg, fmt.Errorf("invalid SLO_WINDOW: %q (must be a duration of at least %s)", v, SLOBurnWindow)
+		}
+		cfg.slo.window = d
+	}
+	if v := os.Getenv("SLO_BURN_RATE"); v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || rate < 0 {
+			return cfg, fmt.Errorf("invalid SLO_BURN_RATE: %q (must be a non-negative number)", v)
+		}
+		cfg.slo.burnRate = rate
+	}
+	if cfg.pushURLs, err = parsePerTarget(os.Getenv("PUSH_URL"), n, "", parsePushURL); err != nil {
+		return cfg, fmt.Errorf("invalid PUSH_URL: %w", err)
+	}
+	if cfg.traceroutes, err = parsePerTarget(os.Getenv("TRACEROUTE"), n, "", monitor.ParseTraceroute); err != nil {
+		return cfg, fmt.Errorf("invalid TRACEROUTE: %w", err)
+	}
+	if cfg.descriptions, err = parsePerTarget(os.Getenv("DESCRIPTION"), n, "", parseString); err != nil {
+		return cfg, fmt.Errorf("invalid DESCRIPTION: %w", err)
+	}
+	if cfg.owners, err = parsePerTarget(os.Getenv("OWNER"), n, "", parseString); err != nil {
+		return cfg, fmt.Errorf("invalid OWNER: %w", err)
+	}
+	if cfg.runbooks, err = parsePerTarget(os.Getenv("RUNBOOK_URL"), n, "", parseRunbookURL); err != nil {
+		return cfg, fmt.Errorf("invalid RUNBOOK_URL: %w", err)
+	}
+	if cfg.severities, err = parsePerTarget(os.Getenv("SEVERITY"), n, "", parseSeverity); err != nil {
+		return cfg, fmt.Errorf("invalid SEVERITY: %w", err)
+	}
+	if cfg.notifiers, err = loadNotifiers(cfg.websites, n); err != nil {
+		return cfg, err
+	}
+	if cfg.alerts.cooldown, err = parseNotifierDurations(os.Getenv("NOTIFY_COOLDOWN"), DefaultNotifyCooldown); err != nil {
+		return cfg, fmt.Errorf("invalid NOTIFY_COOLDOWN: %w", err)
+	}
+	if cfg.alerts.repeat, err = parseNotifierDurations(os.Getenv("NOTIFY_REPEAT"), 0); err != nil {
+		return cfg, fmt.Errorf("invalid NOTIFY_REPEAT: %w", err)
+	}
+
+	cfg.maintenance, err = parsePerTarget(os.Getenv("MAINTENANCE_WINDOW"), n, nil, func(s string) ([]monitor.Window, error) {
+		return parseWindows(s, cfg.timezone)
+	})
+	if err != nil {
+		return cfg, fmt.Errorf("invalid MAINTENANCE_WINDOW: %w", err)
+	}
+	connectTimeouts, err := parsePerTarget(os.Getenv("CONNECT_TIMEOUT"), n, 0, parseTimeout)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid CONNECT_TIMEOUT: %w", err)
+	}
+	healthTimeouts, err := parsePerTarget(os.Getenv("HEALTH_TIMEOUT"), n, 0, parseTimeout)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid HEALTH_TIMEOUT: %w", err)
+	}
+	checkTimeouts, err := parsePerTarget(os.Getenv("CHECK_TIMEOUT"), n, 0, parseTimeout)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid CHECK_TIMEOUT: %w", err)
+	}
+	expectBody, err := parsePerTarget(os.Getenv("EXPECT_BODY"), n, nil, parseBodyMatch)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid EXPECT_BODY: %w", err)
+	}
+	expectRedirects, err := parsePerTarget(os.Getenv("HTTP_EXPECT_REDIRECT"), n, nil, parseRedirect)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid HTTP_EXPECT_REDIRECT: %w", err)
+	}
+	maxRedirects, err := parsePerTarget(os.Getenv("HTTP_MAX_REDIRECTS"), n, monitor.DefaultMaxRedirects, parseMaxRedirects)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid HTTP_MAX_REDIRECTS: %w", err)
+	}
+	if cfg.captureHeaders, err = parsePerTarget(os.Getenv("HTTP_CAPTURE_HEADERS"), n, nil, monitor.ParseHeaderNames); err != nil {
+		return cfg, fmt.Errorf("invalid HTTP_CAPTURE_HEADERS: %w", err)
+	}
+	proxies, err := parsePerTarget(os.Getenv("PROXY_URL"), n, nil, parseProxy)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid PROXY_URL: %w", err)
+	}
+	families, err := parsePerTarget(os.Getenv("IP_FAMILY"), n, monitor.FamilyAny, monitor.ParseFamily)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid IP_FAMILY: %w", err)
+	}
+	dnsServers, err := parsePerTarget(os.Getenv("DNS_SERVER"), n, "", parseDNSServer)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid DNS_SERVER: %w", err)
+	}
+	sources, err := parsePerTarget(os.Getenv("SOURCE_ADDRESS"), n, "", parseSource)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid SOURCE_ADDRESS: %w", err)
+	}
+	probes, err := parsePerTarget(os.Getenv("PROBE_COUNT"), n, 1, parseProbeCount)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid PROBE_COUNT: %w", err)
+	}
+	assertions, err := parsePerTarget(os.Getenv("HEALTH_ASSERT"), n, nil, parseAssertions)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid HEALTH_ASSERT: %w", err)
+	}
+	schemas, err := parsePerTarget(os.Getenv("HEALTH_SCHEMA"), n, nil, parseSchema)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid HEALTH_SCHEMA: %w", err)
+	}
+	if cfg.healthWatch, err = parsePerTarget(os.Getenv("HEALTH_WATCH"), n, nil, parsePaths); err != nil {
+		return cfg, fmt.Errorf("invalid HEALTH_WATCH: %w", err)
+	}
+	if cfg.watchAlerts, err = parsePerTarget(os.Getenv("HEALTH_WATCH_ALERT"), n, false, parseBool); err != nil {
+		return cfg, fmt.Errorf("invalid HEALTH_WATCH_ALERT: %w", err)
+	}
+	methods, err := parsePerTarget(os.Getenv("HTTP_METHOD"), n, "", parseMethod)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid HTTP_METHOD: %w", err)
+	}
+	bodies, err := parseBodies(os.Getenv("HTTP_BODY"), n)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid HTTP_BODY: %w", err)
+	}
+	contentTypes, err := parsePerTarget(os.Getenv("HTTP_CONTENT_TYPE"), n, "", parseString)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid HTTP_CONTENT_TYPE: %w", err)
+	}
+	grpcMethods, err := parsePerTarget(os.Getenv("GRPC_METHOD"), n, "", parseGRPCMethod)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid GRPC_METHOD: %w", err)
+	}
+	grpcRequests, err := parsePerTarget(os.Getenv("GRPC_REQUEST"), n, "", parseJSONObject)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid GRPC_REQUEST: %w", err)
+	}
+	sshPorts, err := parsePerTarget(os.Getenv("SSH_PORT"), n, "", parsePort)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid SSH_PORT: %w", err)
+	}
+	fingerprints, err := parsePerTarget(os.Getenv("SSH_FINGERPRINT"), n, "", parseFingerprint)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid SSH_FINGERPRINT: %w", err)
+	}
+	backendPorts, err := parsePerTarget(os.Getenv("BACKEND_PORT"), n, "", parsePort)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid BACKEND_PORT: %w", err)
+	}
+	ports, err := parsePerTarget(os.Getenv("PING_PORTS"), n, nil, monitor.ParsePorts)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid PING_PORTS: %w", err)
+	}
+	wsPaths, err := parsePerTarget(os.Getenv("WS_PATH"), n, "", parseWebSocketPath)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid WS_PATH: %w", err)
+	}
+	wsPings, err := parsePerTarget(os.Getenv("WS_PING"), n, false, parseBool)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid WS_PING: %w", err)
+	}
+	h3Compares, err := parsePerTarget(os.Getenv("H3_COMPARE"), n, false, parseBool)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid H3_COMPARE: %w", err)
+	}
+	ntpMaxOffsets, err := parsePerTarget(os.Getenv("NTP_MAX_OFFSET"), n, 0, parseNTPMaxOffset)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid NTP_MAX_OFFSET: %w", err)
+	}
+	snmpOIDs, err := parsePerTarget(os.Getenv("SNMP_OIDS"), n, nil, monitor.ParseSNMPOIDs)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid SNMP_OIDS: %w", err)
+	}
+	snmpVersions, err := parsePerTarget(os.Getenv("SNMP_VERSION"), n, "", parseSNMPVersion)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid SNMP_VERSION: %w", err)
+	}
+	snmpUsers, err := parsePerTarget(os.Getenv("SNMP_USER"), n, "", parseString)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid SNMP_USER: %w", err)
+	}
+	snmpAuthProtocols, err := parsePerTarget(os.Getenv("SNMP_AUTH_PROTOCOL"), n, "", parseSNMPAuthProtocol)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid SNMP_AUTH_PROTOCOL: %w", err)
+	}
+	snmpPrivProtocols, err := parsePerTarget(os.Getenv("SNMP_PRIV_PROTOCOL"), n, "", parseSNMPPrivProtocol)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid SNMP_PRIV_PROTOCOL: %w", err)
+	}
+	snmpCommunities, err := loadSecrets(n, "SNMP_COMMUNITY")
+	if err != nil {
+		return cfg, err
+	}
+	snmpAuthPasswords, err := loadSecrets(n, "SNMP_AUTH_PASSWORD")
+	if err != nil {
+		return cfg, err
+	}
+	snmpPrivPasswords, err := loadSecrets(n, "SNMP_PRIV_PASSWORD")
+	if err != nil {
+		return cfg, err
+	}
+	heartbeatWindows, err := parsePerTarget(os.Getenv("HEARTBEAT_WINDOW"), n, 0, parseHeartbeatWindow)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid HEARTBEAT_WINDOW: %w", err)
+	}
+	if cfg.heartbeatTokens, err = loadSecrets(n, "HEARTBEAT_TOKEN"); err != nil {
+		return cfg, err
+	}
+	usernames, err := loadSecrets(n, "HEALTH_USERNAME")
+	if err != nil {
+		return cfg, err
+	}
+	passwords, err := loadSecrets(n, "HEALTH_PASSWORD")
+	if err != nil {
+		return cfg, err
+	}
+	tlsConfigs, err := loadTLSConfigs(n, os.Getenv("HEALTH_TLS_CERT"), os.Getenv("HEALTH_TLS_KEY"), os.Getenv("HEALTH_TLS_CA"), os.Getenv("HEALTH_TLS_INSECURE"))
+	if err != nil {
+		return cfg, fmt.Errorf("invalid HEALTH_TLS_* settings: %w", err)
+	}
+	for i := range cfg.targets {
+		cfg.targets[i].Check = cfg.checkTypes[i]
+		cfg.targets[i].ConnectTimeout = connectTimeouts[i]
+		cfg.targets[i].HealthTimeout = healthTimeouts[i]
+		cfg.targets[i].CheckTimeout = checkTimeouts[i]
+		cfg.targets[i].TLSConfig = tlsConfigs[i]
+		cfg.targets[i].ExpectBody = expectBody[i]
+		cfg.targets[i].Proxy = proxies[i]
+		cfg.targets[i].Family = families[i]
+		if families[i] != monitor.FamilyAny && proxies[i] != nil {
+			return cfg, fmt.Errorf("invalid IP_FAMILY: %s uses PROXY_URL, which picks the address family itself", cfg.websites[i])
+		}
+		cfg.targets[i].DNSServer = dnsServers[i]
+		cfg.targets[i].Source = sources[i]
+		cfg.targets[i].Probes = probes[i]
+		cfg.targets[i].HealthUsername = usernames[i]
+		cfg.targets[i].HealthPassword = passwords[i]
+		if (usernames[i] != "" || passwords[i] != "") && cfg.healthEndpoints[i] == "" {
+			return cfg, fmt.Errorf("invalid HEALTH_USERNAME: %s has no HEALTH_ENDPOINT to authenticate to", cfg.websites[i])
+		}
+		cfg.targets[i].Assertions = assertions[i]
+		cfg.targets[i].Schema = schemas[i]
+		cfg.targets[i].Method = methods[i]
+		cfg.targets[i].Body = bodies[i]
+		cfg.targets[i].ContentType = contentTypes[i]
+		if bodies[i] != "" && (methods[i] == "" || methods[i] == http.MethodGet || methods[i] == http.MethodHead) {
+			return cfg, fmt.Errorf("invalid HTTP_BODY: %s sends a body, set HTTP_METHOD to POST, PUT or similar", cfg.websites[i])
+		}
+		cfg.targets[i].GRPCMethod = grpcMethods[i]
+		cfg.targets[i].GRPCRequest = grpcRequests[i]
+		if grpcMethods[i] != "" && cfg.checkTypes[i] != monitor.CheckGRPC {
+			return cfg, fmt.Errorf("invalid GRPC_METHOD: %s uses the %s check, but GRPC_METHOD requires CHECK_TYPE=grpc", cfg.websites[i], cfg.checkTypes[i])
+		}
+		if grpcRequests[i] != "" && grpcMethods[i] == "" {
+			return cfg, fmt.Errorf("invalid GRPC_REQUEST: %s has no GRPC_METHOD to call", cfg.websites[i])
+		}
+		cfg.targets[i].SSHPort = sshPorts[i]
+		cfg.targets[i].SSHFingerprint = fingerprints[i]
+		if (sshPorts[i] != "" || fingerprints[i] != "") && cfg.checkTypes[i] != monitor.CheckSSH {
+			return cfg, fmt.Errorf("invalid SSH_PORT or SSH_FINGERPRINT: %s uses the %s check, but they require CHECK_TYPE=ssh", cfg.websites[i], cfg.checkTypes[i])
+		}
+		cfg.targets[i].Ports = ports[i]
+		if len(ports[i]) > 0 && cfg.checkTypes[i] != monitor.CheckTCP && cfg.checkTypes[i] != monitor.CheckTLS {
+			return cfg, fmt.Errorf("invalid PING_PORTS: %s uses the %s check, but PING_PORTS requires CHECK_TYPE=tcp or tls", cfg.websites[i], cfg.checkTypes[i])
+		}
+		if len(ports[i]) > 0 && families[i] == monitor.FamilyBoth {
+			return cfg, fmt.Errorf("invalid PING_PORTS: %s uses IP_FAMILY=both, which cannot be combined with several ports", cfg.websites[i])
+		}
+		cfg.targets[i].WebSocketPath = wsPaths[i]
+		cfg.targets[i].WebSocketPing = wsPings[i]
+		if (wsPaths[i] != "" || wsPings[i]) && cfg.checkTypes[i] != monitor.CheckWebSocket {
+			return cfg, fmt.Errorf("invalid WS_PATH or WS_PING: %s uses the %s check, but they require CHECK_TYPE=websocket", cfg.websites[i], cfg.checkTypes[i])
+		}
+		cfg.targets[i].H3Compare = h3Compares[i]
+		if h3Compares[i] && cfg.checkTypes[i] != monitor.CheckH3 {
+			return cfg, fmt.Errorf("invalid H3_COMPARE: %s uses the %s check, but H3_COMPARE requires CHECK_TYPE=h3", cfg.websites[i], cfg.checkTypes[i])
+		}
+		if cfg.checkTypes[i] == monitor.CheckH3 && cfg.targets[i].Scheme == monitor.SchemeHTTP {
+			return cfg, fmt.Errorf("invalid CHECK_TYPE: %s is an http:// site, but HTTP/3 always uses TLS", cfg.websites[i])
+		}
+		if cfg.checkTypes[i] == monitor.CheckH3 && proxies[i] != nil {
+			return cfg, fmt.Errorf("invalid PROXY_URL: %s uses the h3 check, which cannot go through a proxy", cfg.websites[i])
+		}
+		cfg.targets[i].NTPMaxOffset = ntpMaxOffsets[i]
+		if ntpMaxOffsets[i] != 0 && cfg.checkTypes[i] != monitor.CheckNTP {
+			return cfg, fmt.Errorf("invalid NTP_MAX_OFFSET: %s uses the %s check, but NTP_MAX_OFFSET requires CHECK_TYPE=ntp", cfg.websites[i], cfg.checkTypes[i])
+		}
+		if cfg.checkTypes[i] == monitor.CheckSNMP {
+			cfg.targets[i].SNMP = &monitor.SNMP{
+				Version:      snmpVersions[i],
+				Community:    snmpCommunities[i],
+				User:         snmpUsers[i],
+				AuthProtocol: snmpAuthProtocols[i],
+				AuthPassword: snmpAuthPasswords[i],
+				PrivProtocol: snmpPrivProtocols[i],
+				PrivPassword: snmpPrivPasswords[i],
+				OIDs:         snmpOIDs[i],
+			}
+			if err := validateSNMP(cfg.targets[i].SNMP); err != nil {
+				return cfg, fmt.Errorf("invalid SNMP settings: %s: %w", cfg.websites[i], err)
+			}
+		} else if len(snmpOIDs[i]) > 0 || snmpVersions[i] != "" || snmpCommunities[i] != "" || snmpUsers[i] != "" || snmpAuthPasswords[i] != "" || snmpPrivPasswords[i] != "" || snmpAuthProtocols[i] != "" || snmpPrivProtocols[i] != "" {
+			return cfg, fmt.Errorf("invalid SNMP settings: %s uses the %s check, but the SNMP_* settings require CHECK_TYPE=snmp", cfg.websites[i], cfg.checkTypes[i])
+		}
+		cfg.targets[i].HeartbeatWindow = heartbeatWindows[i]
+		if heartbeatWindows[i] != 0 && cfg.checkTypes[i] != monitor.CheckHeartbeat {
+			return cfg, fmt.Errorf("invalid HEARTBEAT_WINDOW: %s uses the %s check, but HEARTBEAT_WINDOW requires CHECK_TYPE=heartbeat", cfg.websites[i], cfg.checkTypes[i])
+		}
+		if cfg.checkTypes[i] == monitor.CheckHeartbeat && cfg.heartbeatTokens[i] == "" {
+			return cfg, fmt.Errorf("invalid HEARTBEAT_TOKEN: %s uses the heartbeat check, which requires HEARTBEAT_TOKEN to authenticate its heartbeats", cfg.websites[i])
+		}
+		if cfg.checkTypes[i] == monitor.CheckHeartbeat && strings.Contains(cfg.websites[i], "://") {
+			return cfg, fmt.Errorf("invalid PING_WEBSITE: %s uses the heartbeat check, which requires a plain name such as \"nightly-backup\"", cfg.websites[i])
+		}
+		if cfg.traceroutes[i] != "" && cfg.checkTypes[i] == monitor.CheckHeartbeat {
+			return cfg, fmt.Errorf("invalid TRACEROUTE: %s uses the heartbeat check, which has no host to trace", cfg.websites[i])
+		}
+		cfg.targets[i].BackendPort = backendPorts[i]
+		if backendPorts[i] != "" && !slices.Contains(backendChecks, cfg.checkTypes[i]) {
+			return cfg, fmt.Errorf("invalid BACKEND_PORT: %s uses the %s check, but BACKEND_PORT requires CHECK_TYPE=redis, memcached, postgres, mysql or mqtt", cfg.websites[i], cfg.checkTypes[i])
+		}
+		if len(assertions[i]) > 0 && cfg.healthEndpoints[i] == "" && grpcMethods[i] == "" {
+			return cfg, fmt.Errorf("invalid HEALTH_ASSERT: %s has no HEALTH_ENDPOINT or GRPC_METHOD to check", cfg.websites[i])
+		}
+		if schemas[i] != nil && cfg.healthEndpoints[i] == "" && grpcMethods[i] == "" {
+			return cfg, fmt.Errorf("invalid HEALTH_SCHEMA: %s has no HEALTH_ENDPOINT or GRPC_METHOD to check", cfg.websites[i])
+		}
+		if len(cfg.healthWatch[i]) > 0 && cfg.healthEndpoints[i] == "" && grpcMethods[i] == "" {
+			return cfg, fmt.Errorf("invalid HEALTH_WATCH: %s has no HEALTH_ENDPOINT or GRPC_METHOD to watch", cfg.websites[i])
+		}
+		if cfg.watchAlerts[i] && len(cfg.healthWatch[i]) == 0 {
+			return cfg, fmt.Errorf("invalid HEALTH_WATCH_ALERT: %s has no HEALTH_WATCH fields to alert on", cfg.websites[i])
+		}
+		if sources[i] != "" && cfg.checkTypes[i] == monitor.CheckHeartbeat {
+			return cfg, fmt.Errorf("invalid SOURCE_ADDRESS: %s uses the heartbeat check, which makes no connections", cfg.websites[i])
+		}
+		if family := monitor.SourceFamily(sources[i]); family != monitor.FamilyAny && families[i] != monitor.FamilyAny && families[i] != family {
+			return cfg, fmt.Errorf("invalid SOURCE_ADDRESS: %s is an %s address, but %s uses IP_FAMILY=%s", sources[i], monitor.FamilyLabel(family), cfg.websites[i], families[i])
+		}
+		if dnsServers[i] != "" && proxies[i] != nil {
+			return cfg, fmt.Errorf("invalid DNS_SERVER: %s uses PROXY_URL, which resolves hostnames itself", cfg.websites[i])
+		}
+		if expectBody[i] != nil && cfg.checkTypes[i] != monitor.CheckHTTP {
+			return cfg, fmt.Errorf("invalid EXPECT_BODY: %s uses the %s check, but EXPECT_BODY requires CHECK_TYPE=http", cfg.websites[i], cfg.checkTypes[i])
+		}
+		cfg.targets[i].ExpectRedirect = expectRedirects[i]
+		if expectRedirects[i] != nil && cfg.checkTypes[i] != monitor.CheckHTTP {
+			return cfg, fmt.Errorf("invalid HTTP_EXPECT_REDIRECT: %s uses the %s check, but HTTP_EXPECT_REDIRECT requires CHECK_TYPE=http", cfg.websites[i], cfg.checkTypes[i])
+		}
+		// Zero in the target means the default, so no redirects is negative
+		cfg.targets[i].MaxRedirects = maxRedirects[i]
+		if maxRedirects[i] == 0 {
+			cfg.targets[i].MaxRedirects = -1
+		}
+		cfg.targets[i].HealthEndpoint = cfg.healthEndpoints[i]
+		cfg.targets[i].CaptureHeaders = cfg.captureHeaders[i]
+		if len(cfg.captureHeaders[i]) > 0 && cfg.checkTypes[i] != monitor.CheckHTTP && cfg.healthEndpoints[i] == "" {
+			return cfg, fmt.Errorf("invalid HTTP_CAPTURE_HEADERS: %s uses the %s check and has no HEALTH_ENDPOINT, but HTTP_CAPTURE_HEADERS requires CHECK_TYPE=http or a HEALTH_ENDPOINT", cfg.websites[i], cfg.checkTypes[i])
+		}
+		cfg.targets[i].HealthBaseURL = healthBaseURLs[i]
+		if healthBaseURLs[i] != "" && cfg.healthEndpoints[i] == "" {
+			return cfg, fmt.Errorf("invalid HEALTH_BASE_URL: %s has no HEALTH_ENDPOINT to fetch", cfg.websites[i])
+		}
+		cfg.targets[i].MaxBodySize = healthMaxBodies[i]
+		if healthMaxBodies[i] != 0 && cfg.healthEndpoints[i] == "" {
+			return cfg, fmt.Errorf("invalid HEALTH_MAX_BODY: %s has no HEALTH_ENDPOINT to fetch", cfg.websites[i])
+		}
+		cfg.targets[i].Maintenance = cfg.maintenance[i]
+	}
+
+	latencyWarning, err := parsePerTarget(os.Getenv("LATENCY_WARNING"), n, 0, parseThreshold)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid LATENCY_WARNING: %w", err)
+	}
+	latencyCritical, err := parsePerTarget(os.Getenv("LATENCY_CRITICAL"), n, 0, parseThreshold)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid LATENCY_CRITICAL: %w", err)
+	}
+	latencyAlert, err := parsePerTarget(os.Getenv("LATENCY_ALERT"), n, false, parseBool)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid LATENCY_ALERT: %w", err)
+	}
+	anomalies, err := parsePerTarget(os.Getenv("LATENCY_ANOMALY"), n, 0, parseDeviations)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid LATENCY_ANOMALY: %w", err)
+	}
+	anomalyAlert, err := parsePerTarget(os.Getenv("LATENCY_ANOMALY_ALERT"), n, false, parseBool)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid LATENCY_ANOMALY_ALERT: %w", err)
+	}
+	cfg.latency = make([]latencyThresholds, n.total())
+	for i := range cfg.latency {
+		l := latencyThresholds{warning: latencyWarning[i], critical: latencyCritical[i], alert: latencyAlert[i],
+			anomaly: anomalies[i], anomalyAlert: anomalyAlert[i]}
+		if l.warning > 0 && l.critical > 0 && l.warning >= l.critical {
+			return cfg, fmt.Errorf("invalid LATENCY_WARNING: %s for %s must be below LATENCY_CRITICAL (%s)", l.warning, cfg.websites[i], l.critical)
+		}
+		if l.alert && l.critical == 0 {
+			return cfg, fmt.Errorf("invalid LATENCY_ALERT: %s has no LATENCY_CRITICAL to alert on", cfg.websites[i])
+		}
+		if l.anomalyAlert && l.anomaly == 0 {
+			return cfg, fmt.Errorf("invalid LATENCY_ANOMALY_ALERT: %s has no LATENCY_ANOMALY to alert on", cfg.websites[i])
+		}
+		cfg.latency[i] = l
+	}
+
+	if cfg.domainExpiry, err = parsePerTarget(os.Getenv("DOMAIN_EXPIRY_DAYS"), n, 0, parseDays); err != nil {
+		return cfg, fmt.Errorf("invalid DOMAIN_EXPIRY_DAYS: %w", err)
+	}
+	cfg.domains = make([]string, n.total())
+	for i, days := range cfg.domainExpiry {
+		// IP addresses and internal names are silently left out, so a
+		// single value can enable the lookup for every website
+		if days > 0 {
+			cfg.domains[i], _ = monitor.RegistrableDomain(cfg.targets[i].Host)
+		}
+	}
+	cfg.rdapServer = monitor.DefaultRDAPServer
+	if v := os.Getenv("RDAP_SERVER"); v != "" {
+		if u, err := url.Parse(v); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return cfg, fmt.Errorf("invalid RDAP_SERVER: %q", v)
+		}
+		cfg.rdapServer = v
+	}
+	cfg.domainRefresh = DefaultDomainRefresh
+	if v := os.Getenv("DOMAIN_EXPIRY_REFRESH"); v != "" {
+		if cfg.domainRefresh, err = time.ParseDuration(v); err != nil || cfg.domainRefresh <= 0 {
+			return cfg, fmt.Errorf("invalid DOMAIN_EXPIRY_REFRESH: %q", v)
+		}
+	}
+
+	cfg.webListen = os.Getenv("WEB_LISTEN")
+	if i := slices.Index(cfg.checkTypes, monitor.CheckHeartbeat); i >= 0 && cfg.webListen == "" {
+		return cfg, fmt.Errorf("invalid CHECK_TYPE: %s uses the heartbeat check, which requires WEB_LISTEN to receive heartbeats", cfg.websites[i])
+	}
+	if cfg.controlListen = os.Getenv("CONTROL_LISTEN"); cfg.controlListen != "" {
+		if err := parseControlListen(cfg.controlListen); err != nil {
+			return cfg, fmt.Errorf("invalid CONTROL_LISTEN: %w", err)
+		}
+	}
+	cfg.agentToken = os.Getenv("AGENT_TOKEN")
+	cfg.historyFile = os.Getenv("HISTORY_FILE")
+	cfg.exportDir = os.Getenv("EXPORT_DIR")
+	if cfg.exportDir == "" {
+		cfg.exportDir = "."
+	}
+	cfg.exportFormat = strings.ToLower(os.Getenv("EXPORT_FORMAT"))
+	switch cfg.exportFormat {
+	case "":
+		cfg.exportFormat = ExportFormatCSV
+	case ExportFormatCSV, ExportFormatJSON:
+	default:
+		return cfg, fmt.Errorf("invalid EXPORT_FORMAT: %q (expected csv or json)", cfg.exportFormat)
+	}
+	if v := os.Getenv("CONFIG_WATCH"); v != "" {
+		if cfg.configWatch, err = parseBool(v); err != nil {
+			return cfg, fmt.Errorf("invalid CONFIG_WATCH: %w", err)
+		}
+	}
+	cfg.shutdownTimeout = DefaultShutdownTimeout
+	if v := os.Getenv("SHUTDOWN_TIMEOUT"); v != "" {
+		if cfg.shutdownTimeout, err = time.ParseDuration(v); err != nil || cfg.shutdownTimeout < 0 {
+			return cfg, fmt.Errorf("invalid SHUTDOWN_TIMEOUT: %q", v)
+		}
+	}
+	if cfg.theme, err = loadTheme(); err != nil {
+		return cfg, err
+	}
+	if v := os.Getenv("BELL"); v != "" {
+		if cfg.bell, err = parseBool(v); err != nil {
+			return cfg, fmt.Errorf("invalid BELL: %w", err)
+		}
+	}
+	if v := os.Getenv("BELL_FLASH"); v != "" {
+		if cfg.bellFlash, err = parseBool(v); err != nil {
+			return cfg, fmt.Errorf("invalid BELL_FLASH: %w", err)
+		}
+	}
+	return cfg, nil
+}
+
+// newMonitor creates a monitor for the configured targets.
+func (c config) newMonitor() *monitor.Monitor {
+	mon := monitor.New(c.targets, c.interval)
+	mon.DownInterval = c.downInterval
+	mon.Retry = c.retry
+	mon.MaxConcurrent = c.maxConcurrent
+	mon.Stagger = c.stagger
+	mon.Jitter = c.jitter
+	return mon
+}
+
+// describeSchedule returns PING_SCHEDULE for display, noting the interval
+// used while a website is failing when it differs.
+func (c config) describeSchedule() string {
+	if c.downInterval <= 0 || c.downInterval == c.interval {
+		return c.schedule
+	}
+	return fmt.Sprintf("%s (%s while failing)", c.schedule, c.downInterval)
+}
+
+// loadNotifiers builds the enabled notifiers.
+func loadNotifiers(websites []string, n siteCount) ([]notify.Notifier, error) {
+	desktopNotify, err := parsePerTarget(os.Getenv("DESKTOP_NOTIFY"), n, false, parseBool)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DESKTOP_NOTIFY: %w", err)
+	}
+	var notifiers []notify.Notifier
+	var desktopTargets []string
+	for i, enabled := range desktopNotify {
+		if enabled {
+			desktopTargets = append(desktopTargets, websites[i])
+		}
+	}
+	if len(desktopTargets) > 0 {
+		notifiers = append(notifiers, notify.ForTargets(notify.Desktop{}, desktopTargets))
+	}
+
+	telegramToken := os.Getenv("TELEGRAM_BOT_TOKEN")
+	telegramChatID := os.Getenv("TELEGRAM_CHAT_ID")
+	if (telegramToken == "") != (telegramChatID == "") {
+		return nil, fmt.Errorf("TELEGRAM_BOT_TOKEN and TELEGRAM_CHAT_ID must be set together")
+	}
+	if telegramToken != "" {
+		notifiers = append(notifiers, notify.NewTelegram(telegramToken, telegramChatID))
+	}
+
+	matrixHomeserver := os.Getenv("MATRIX_HOMESERVER")
+	matrixToken := os.Getenv("MATRIX_ACCESS_TOKEN")
+	matrixRoom := os.Getenv("MATRIX_ROOM_ID")
+	if matrixHomeserver != "" || matrixToken != "" || matrixRoom != "" {
+		if matrixHomeserver == "" || matrixToken == "" || matrixRoom == "" {
+			return nil, fmt.Errorf("MATRIX_HOMESERVER, MATRIX_ACCESS_TOKEN and MATRIX_ROOM_ID must be set together")
+		}
+		if u, err := url.Parse(matrixHomeserver); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid MATRIX_HOMESERVER: %q", matrixHomeserver)
+		}
+		if _, err := notify.ParseMatrixRoom(matrixRoom); err != nil {
+			return nil, fmt.Errorf("invalid MATRIX_ROOM_ID: %w", err)
+		}
+		notifiers = append(notifiers, notify.NewMatrix(matrixHomeserver, matrixToken, matrixRoom))
+	}
+
+	if topic := os.Getenv("NTFY_TOPIC"); topic != "" {
+		n := notify.NewNtfy(os.Getenv("NTFY_SERVER"), topic)
+		n.Token = os.Getenv("NTFY_TOKEN")
+		if v := os.Getenv("NTFY_PRIORITY"); v != "" {
+			if n.Priority, err = notify.ParseNtfyPriority(v); err != nil {
+				return nil, fmt.Errorf("invalid NTFY_PRIORITY: %w", err)
+			}
+		}
+		n.Tags = parseList(os.Getenv("NTFY_TAGS"))
+		notifiers = append(notifiers, n)
+	}
+
+	gotifyURL := os.Getenv("GOTIFY_URL")
+	gotifyToken := os.Getenv("GOTIFY_TOKEN")
+	if (gotifyURL == "") != (gotifyToken == "") {
+		return nil, fmt.Errorf("GOTIFY_URL and GOTIFY_TOKEN must be set together")
+	}
+	if gotifyURL != "" {
+		if u, err := url.Parse(gotifyURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid GOTIFY_URL: %q", gotifyURL)
+		}
+		g := notify.NewGotify(gotifyURL, gotifyToken)
+		if v := os.Getenv("GOTIFY_PRIORITY"); v != "" {
+			if g.Priorities, err = notify.ParseGotifyPriorities(v); err != nil {
+				return nil, fmt.Errorf("invalid GOTIFY_PRIORITY: %w", err)
+			}
+		}
+		notifiers = append(notifiers, g)
+	}
+
+	if key := os.Getenv("PAGERDUTY_ROUTING_KEY"); key != "" {
+		p := notify.NewPagerDuty(key)
+		if v := os.Getenv("PAGERDUTY_SEVERITY"); v != "" {
+			if p.Severity, err = notify.ParsePagerDutySeverity(v); err != nil {
+				return nil, fmt.Errorf("invalid PAGERDUTY_SEVERITY: %w", err)
+			}
+		}
+		notifiers = append(notifiers, p)
+	}
+
+	if key := os.Getenv("OPSGENIE_API_KEY"); key != "" {
+		o := notify.NewOpsgenie(key)
+		if v := os.Getenv("OPSGENIE_API_URL"); v != "" {
+			if u, err := url.Parse(v); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, fmt.Errorf("invalid OPSGENIE_API_URL: %q", v)
+			}
+			o.API = v
+		}
+		if v := os.Getenv("OPSGENIE_PRIORITY"); v != "" {
+			if o.Priorities, err = notify.ParseOpsgeniePriorities(v); err != nil {
+				return nil, fmt.Errorf("invalid OPSGENIE_PRIORITY: %w", err)
+			}
+		}
+		notifiers = append(notifiers, o)
+	}
+
+	webhooks, err := parsePerTarget(os.Getenv("TEAMS_WEBHOOK_URL"), n, "", parseTeamsWebhook)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TEAMS_WEBHOOK_URL: %w", err)
+	}
+	// One notifier per distinct webhook, for the websites that post to it
+	order, webhookTargets := groupTargets(webhooks, websites)
+	for _, webhook := range order {
+		notifiers = append(notifiers, notify.ForTargets(notify.NewTeams(webhook), webhookTargets[webhook]))
+	}
+
+	commands, err := parsePerTarget(os.Getenv("EXEC_COMMAND"), n, "", parseString)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EXEC_COMMAND: %w", err)
+	}
+	// One notifier per distinct command, for the websites that run it
+	order, execTargets := groupTargets(commands, websites)
+	for _, command := range order {
+		notifiers = append(notifiers, notify.ForTargets(notify.Exec{Command: command}, execTargets[command]))
+	}
+
+	smtpNotifier, err := loadSMTP(websites, n)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP settings: %w", err)
+	}
+	if smtpNotifier != nil {
+		notifiers = append(notifiers, smtpNotifier)
+	}
+	if notifiers, err = routeByTags(notifiers, os.Getenv("NOTIFY_TAGS")); err != nil {
+		return nil, fmt.Errorf("invalid NOTIFY_TAGS: %w", err)
+	}
+	if notifiers, err = routeByRules(notifiers, os.Getenv("NOTIFY_ROUTES")); err != nil {
+		return nil, fmt.Errorf("invalid NOTIFY_ROUTES: %w", err)
+	}
+	return notifiers, nil
+}
+
+// siteCount is the number of websites a per-website setting covers: those
+// listed in PING_WEBSITE followed by those found through discovery.
+type siteCount struct{ listed, discovered int }
+
+func (n siteCount) total() int { return n.listed + n.discovered }
+
+// parsePerTarget parses a per-website setting. The value may be a JSON array
+// with one entry per website in PING_WEBSITE, or a single value that applies
+// to all websites. Discovered websites are not in the array and get def.
+// An empty value yields def for every website.
+func parsePerTarget[T any](value string, n siteCount, def T, parse func(string) (T, error)) ([]T, error) {
+	out := make([]T, n.total())
+	for i := range out {
+		out[i] = def
+	}
+	if value == "" {
+		return out, nil
+	}
+	var arr []json.RawMessage
+	if err := json.Unmarshal([]byte(value), &arr); err == nil {
+		if len(arr) != n.listed {
+			return nil, fmt.Errorf("must be a JSON array with the same length as PING_WEBSITE, or a single value")
+		}
+		for i, raw := range arr {
+			// Array entries may be JSON strings ("5s") or bare literals (true, 3)
+			s := string(raw)
+			var str string
+			if err := json.Unmarshal(raw, &str); err == nil {
+				s = str
+			}
+			v, err := parse(s)
+			if err != nil {
+				return nil, fmt.Errorf("entry %d: %w", i, err)
+			}
+			out[i] = v
+		}
+		return out, nil
+	}
+	v, err := parse(value)
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		out[i] = v
+	}
+	return out, nil
+}
+
+// parseBodies parses HTTP_BODY. Only a JSON array of strings has a body per
+// website; any other value, such as a JSON array of objects, is sent as is
+// to every website.
+func parseBodies(value string, n siteCount) ([]string, error) {
+	var arr []string
+	if err := json.Unmarshal([]byte(value), &arr); err == nil && arr != nil {
+		return parsePerTarget(value, n, "", parseString)
+	}
+	out := make([]string, n.total())
+	for i := range out {
+		out[i] = value
+	}
+	return out, nil
+}
+
+// parseString is the parse function for per-website string settings.
+func parseString(s string) (string, error) {
+	return s, nil
+}
+
+// parseBool is the parse function for per-website boolean settings.
+func parseBool(s string) (bool, error) {
+	return strconv.ParseBool(s)
+}
+
+// parseTimeout is the parse function for per-website timeouts, which must be
+// positive durations.
+func parseTimeout(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("timeout must be positive, got %s", s)
+	}
+	return d, nil
+}
+
+// parseHeartbeatWindow is the parse function for per-website heartbeat
+// windows, which must be positive durations. An empty value uses the
+// default window.
+func parseHeartbeatWindow(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("window must be positive, got %s", s)
+	}
+	return d, nil
+}
+
+// parseWebSocketPath is the parse function for per-website WebSocket
+// paths, which must start with a slash. An empty value uses the site's own
+// path.
+func parseWebSocketPath(s string) (string, error) {
+	if s != "" && !strings.HasPrefix(s, "/") {
+		return "", fmt.Errorf("path must start with /, got %q", s)
+	}
+	return s, nil
+}
+
+// parseNTPMaxOffset is the parse function for per-website NTP offset
+// thresholds, which must be positive durations. An empty value uses the
+// default threshold.
+func parseNTPMaxOffset(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("offset must be positive, got %s", s)
+	}
+	return d, nil
+}
+
+// parseMaxBodySize is the parse function for per-website body size limits
+// (see monitor.ParseByteSize). An empty value uses the default limit.
+func parseMaxBodySize(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return monitor.ParseByteSize(s)
+}
+
+// parseProbeCount is the parse function for per-website probe counts, which
+// must be positive integers.
+func parseProbeCount(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("probe count must be a positive integer, got %q", s)
+	}
+	return n, nil
+}
+
+// parsePort is the parse function for per-website ports, which may be
+// empty.
+func parsePort(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	if n, err := strconv.Atoi(s); err != nil || n < 1 || n > 65535 {
+		return "", fmt.Errorf("invalid port %q", s)
+	}
+	return s, nil
+}
+
+// parseFingerprint is the parse function for per-website SSH host key
+// fingerprints, which may be empty.
+func parseFingerprint(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	return monitor.ParseFingerprint(s)
+}
+
+// parseSNMPVersion is the parse function for per-website SNMP versions
+// (see monitor.ParseSNMPVersion). An empty value means SNMPv2c.
+func parseSNMPVersion(s string) (string, error) {
+	if strings.TrimSpace(s) == "" {
+		return "", nil
+	}
+	return monitor.ParseSNMPVersion(s)
+}
+
+// parseSNMPAuthProtocol is the parse function for per-website SNMPv3
+// authentication protocols (see monitor.ParseSNMPAuthProtocol). An empty
+// value means SHA.
+func parseSNMPAuthProtocol(s string) (string, error) {
+	if strings.TrimSpace(s) == "" {
+		return "", nil
+	}
+	return monitor.ParseSNMPAuthProtocol(s)
+}
+
+// parseSNMPPrivProtocol is the parse function for per-website SNMPv3
+// privacy protocols (see monitor.ParseSNMPPrivProtocol). An empty value
+// means AES.
+func parseSNMPPrivProtocol(s string) (string, error) {
+	if strings.TrimSpace(s) == "" {
+		return "", nil
+	}
+	return monitor.ParseSNMPPrivProtocol(s)
+}
+
+// validateSNMP checks that the SNMP settings of a website fit its version:
+// a community for SNMPv2c, and a user, optionally with the passwords of
+// its security level, for SNMPv3.
+func validateSNMP(s *monitor.SNMP) error {
+	if s.Version != monitor.SNMPv3 {
+		if s.User != "" || s.AuthProtocol != "" || s.AuthPassword != "" || s.PrivProtocol != "" || s.PrivPassword != "" {
+			return fmt.Errorf("SNMP_USER, SNMP_AUTH_* and SNMP_PRIV_* require SNMP_VERSION=3")
+		}
+		return nil
+	}
+	switch {
+	case s.Community != "":
+		return fmt.Errorf("SNMP_COMMUNITY only applies to SNMPv2c, SNMPv3 authenticates with SNMP_USER")
+	case s.User == "":
+		return fmt.Errorf("SNMP_VERSION=3 requires SNMP_USER")
+	case s.AuthProtocol != "" && s.AuthPassword == "":
+		return fmt.Errorf("SNMP_AUTH_PROTOCOL requires SNMP_AUTH_PASSWORD")
+	case s.PrivProtocol != "" && s.PrivPassword == "":
+		return fmt.Errorf("SNMP_PRIV_PROTOCOL requires SNMP_PRIV_PASSWORD")
+	case s.PrivPassword != "" && s.AuthPassword == "":
+		return fmt.Errorf("SNMP_PRIV_PASSWORD requires SNMP_AUTH_PASSWORD, as SNMPv3 only encrypts authenticated requests")
+	}
+	for _, password := range []string{s.AuthPassword, s.PrivPassword} {
+		if password != "" && len(password) < monitor.SNMPMinPassword {
+			return fmt.Errorf("SNMPv3 passwords must have at least %d characters", monitor.SNMPMinPassword)
+		}
+	}
+	return nil
+}
+
+// parsePushURL is the parse function for per-website push monitor URLs,
+// which may be empty.
+func parsePushURL(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	return notify.ParsePushURL(s)
+}
+
+// parseRunbookURL validates the link to a runbook, an http:// or https://
+// URL. An empty value links no runbook.
+func parseRunbookURL(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	if u, err := url.Parse(s); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("%q is not an http:// or https:// URL", s)
+	}
+	return s, nil
+}
+
+// parseMethod is the parse function for per-website HTTP methods, which are
+// upper-cased.
+func parseMethod(s string) (string, error) {
+	m := strings.ToUpper(strings.TrimSpace(s))
+	if m == "" {
+		return "", nil
+	}
+	for _, r := range m {
+		if r < 'A' || r > 'Z' {
+			return "", fmt.Errorf("invalid HTTP method %q", s)
+		}
+	}
+	return m, nil
+}
+
+// parseDays is the parse function for per-website day counts, which must be
+// non-negative integers.
+func parseDays(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("days must be a non-negative integer, got %q", s)
+	}
+	return n, nil
+}
+
+// parseThreshold is the parse function for per-website latency thresholds.
+// An empty or zero value disables the threshold.
+func parseThreshold(s string) (time.Duration, error) {
+	if s == "" || s == "0" {
+		return 0, nil
+	}
+	return parseTimeout(s)
+}
+
+// parsePaths is the parse function for per-website JSONPath lists. Several
+// expressions are separated by commas, e.g. "$.status,$.dependencies.db".
+func parsePaths(s string) ([]monitor.Path, error) {
+	var paths []monitor.Path
+	for _, expr := range strings.Split(s, ",") {
+		if strings.TrimSpace(expr) == "" {
+			continue
+		}
+		p, err := monitor.ParsePath(expr)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
+
+// parseSchema parses a JSON Schema given inline or as a file path; empty
+// means none.
+func parseSchema(s string) (*monitor.Schema, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	return monitor.ParseSchema(s)
+}
+
+// parseAssertions parses health assertions separated by semicolons.
+func parseAssertions(s string) ([]monitor.Assertion, error) {
+	var assertions []monitor.Assertion
+	for _, part := range strings.Split(s, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		a, err := monitor.ParseAssertion(part)
+		if err != nil {
+			return nil, err
+		}
+		assertions = append(assertions, a)
+	}
+	return assertions, nil
+}
+
+// parseGRPCMethod is the parse function for per-website gRPC methods, which
+// may be empty.
+func parseGRPCMethod(s string) (string, error) {
+	if strings.TrimSpace(s) == "" {
+		return "", nil
+	}
+	return monitor.ParseGRPCMethod(s)
+}
+
+// parseJSONObject is the parse function for per-website JSON objects, which
+// may be empty.
+func parseJSONObject(s string) (string, error) {
+	if strings.TrimSpace(s) == "" {
+		return "", nil
+	}
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(s), &obj); err != nil {
+		return "", fmt.Errorf("%q is not a JSON object", s)
+	}
+	return s, nil
+}
+
+// parseCheckType is the parse function for per-website check types. The name
+// must be registered in the monitor package.
+func parseCheckType(s string) (string, error) {
+	if _, err := monitor.NewChecker(s); err != nil {
+		return "", err
+	}
+	return s, nil
+}
+
+// parseWindows is the parse function for per-website maintenance windows.
+// Several windows are separated by semicolons.
+func parseWindows(s string, loc *time.Location) ([]monitor.Window, error) {
+	var windows []monitor.Window
+	for _, part := range strings.Split(s, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		w, err := monitor.ParseWindow(part, loc)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+// loadSecrets reads a per-website secret from the variable name or, to keep
+// it out of the environment, from the files listed in name_FILE (e.g. Docker
+// secrets). Setting both for the same website is an error.
+func loadSecrets(n siteCount, name string) ([]string, error) {
+	values, err := parsePerTarget(os.Getenv(name), n, "", parseString)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	files, err := parsePerTarget(os.Getenv(name+"_FILE"), n, "", parseString)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s_FILE: %w", name, err)
+	}
+	for i, path := range files {
+		if path == "" {
+			continue
+		}
+		if values[i] != "" {
+			return nil, fmt.Errorf("invalid %s_FILE: website %d also sets %s", name, i+1, name)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s_FILE: %w", name, err)
+		}
+		values[i] = strings.TrimRight(string(data), "\r\n")
+	}
+	return values, nil
+}
+
+// loadTLSConfigs builds the per-website TLS configuration from the
+// HEALTH_TLS_* settings. Websites without any TLS option get nil.
+func loadTLSConfigs(n siteCount, certEnv, keyEnv, caEnv, insecureEnv string) ([]*tls.Config, error) {
+	certs, err := parsePerTarget(certEnv, n, "", parseString)
+	if err != nil {
+		return nil, fmt.Errorf("HEALTH_TLS_CERT: %w", err)
+	}
+	keys, err := parsePerTarget(keyEnv, n, "", parseString)
+	if err != nil {
+		return nil, fmt.Errorf("HEALTH_TLS_KEY: %w", err)
+	}
+	cas, err := parsePerTarget(caEnv, n, "", parseString)
+	if err != nil {
+		return nil, fmt.Errorf("HEALTH_TLS_CA: %w", err)
+	}
+	insecure, err := parsePerTarget(insecureEnv, n, false, parseBool)
+	if err != nil {
+		return nil, fmt.Errorf("HEALTH_TLS_INSECURE: %w", err)
+	}
+	configs := make([]*tls.Config, n.total())
+	for i := range configs {
+		if configs[i], err = monitor.LoadTLSConfig(certs[i], keys[i], cas[i], insecure[i]); err != nil {
+			return nil, fmt.Errorf("website %d: %w", i+1, err)
+		}
+	}
+	return configs, nil
+}
+
+// parseBodyMatch parses an expected response body. An empty value disables
+// the assertion for that website.
+func parseBodyMatch(s string) (*monitor.BodyMatch, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return monitor.ParseBodyMatch(s)
+}
+
+// groupTargets groups websites by their per-website setting, skipping empty
+// values. It returns the distinct values in order of first use and the
+// websites using each.
+func groupTargets(values, websites []string) ([]string, map[string][]string) {
+	var order []string
+	groups := make(map[string][]string)
+	for i, v := range values {
+		if v == "" {
+			continue
+		}
+		if _, ok := groups[v]; !ok {
+			order = append(order, v)
+		}
+		groups[v] = append(groups[v], websites[i])
+	}
+	return order, groups
+}
+
+// parseTeamsWebhook validates a Teams webhook URL. An empty value sends no
+// Teams messages for that website.
+func parseTeamsWebhook(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	return notify.ParseTeamsWebhook(s)
+}
+
+// parseHealthBaseURL validates a health base URL. An empty value fetches the
+// health endpoint from the website itself.
+func parseHealthBaseURL(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	return monitor.ParseHealthBaseURL(s)
+}
+
+// parseRedirect parses an expected redirect. An empty value disables the
+// assertion for that website.
+func parseRedirect(s string) (*monitor.Redirect, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return monitor.ParseRedirect(s)
+}
+
+// parseMaxRedirects is the parse function for per-website redirect limits.
+func parseMaxRedirects(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("redirect limit must be a non-negative integer, got %q", s)
+	}
+	return n, nil
+}
+
+// parseProxy parses a proxy URL. An empty value connects directly.
+func parseProxy(s string) (*url.URL, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return monitor.ParseProxy(s)
+}
+
+// parseDNSServer parses a DNS server address. An empty value uses the system
+// resolver.
+func parseDNSServer(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	return monitor.ParseDNSServer(s)
+}
+
+// parseSource parses a source address or interface. An empty value lets the
+// system pick.
+func parseSource(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	return monitor.ParseSource(s)
+}
+
+// parseList splits a comma-separated list, dropping empty entries.
+func parseList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// parseRecipients parses a comma-separated list of email addresses.
+func parseRecipients(s string) ([]string, error) {
+	var out []string
+	for _, part := range parseList(s) {
+		addr, err := mail.ParseAddress(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q", part)
+		}
+		out = append(out, addr.Address)
+	}
+	return out, nil
+}
+
+// loadSMTP builds the email notifier from the SMTP_* settings. It returns nil
+// when SMTP_HOST is unset.
+func loadSMTP(websites []string, n siteCount) (*notify.SMTP, error) {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return nil, nil
+	}
+	s := &notify.SMTP{
+		Host:     host,
+		Port:     os.Getenv("SMTP_PORT"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+		Security: strings.ToLower(os.Getenv("SMTP_SECURITY")),
+	}
+	switch s.Security {
+	case "":
+		s.Security = notify.SMTPStartTLS
+	case notify.SMTPStartTLS, notify.SMTPTLS, notify.SMTPPlain:
+	default:
+		return nil, fmt.Errorf("SMTP_SECURITY must be starttls, tls or none")
+	}
+	if s.Port == "" {
+		s.Port = "587"
+		if s.Security == notify.SMTPTLS {
+			s.Port = "465"
+		}
+	}
+	if _, err := strconv.ParseUint(s.Port, 10, 16); err != nil {
+		return nil, fmt.Errorf("invalid SMTP_PORT %q", s.Port)
+	}
+	from, err := mail.ParseAddress(s.From)
+	if err != nil {
+		return nil, fmt.Errorf("SMTP_FROM must be an email address")
+	}
+	s.From = from.Address
+
+	to, err := parsePerTarget(os.Getenv("SMTP_TO"), n, nil, parseRecipients)
+	if err != nil {
+		return nil, fmt.Errorf("SMTP_TO: %w", err)
+	}
+	s.Recipients = make(map[string][]string)
+	for i, addrs := range to {
+		if len(addrs) > 0 {
+			s.Recipients[websites[i]] = addrs
+		}
+	}
+	if len(s.Recipients) == 0 {
+		return nil, fmt.Errorf("SMTP_TO must list at least one recipient")
+	}
+
+	subject := os.Getenv("SMTP_SUBJECT")
+	if subject == "" {
+		subject = notify.DefaultSMTPSubject
+	}
+	if s.Subject, err = notify.ParseTemplate("subject", subject); err != nil {
+		return nil, fmt.Errorf("SMTP_SUBJECT: %w", err)
+	}
+	body := os.Getenv("SMTP_BODY")
+	if body == "" {
+		body = notify.DefaultSMTPBody
+	}
+	if s.Body, err = notify.ParseTemplate("body", body); err != nil {
+		return nil, fmt.Errorf("SMTP_BODY: %w", err)
+	}
+	return s, nil
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Actions of the control API
+const (
+	controlCheck  = "check"
+	controlPause  = "pause"
+	controlResume = "resume"
+)
+
+// controlUnixPrefix marks a CONTROL_LISTEN address as a unix socket path.
+const controlUnixPrefix = "unix:"
+
+// controlClientTimeout bounds requests of the status subcommand.
+const controlClientTimeout = 5 * time.Second
+
+// controlMsg asks the program to check, pause or resume a website, or all
+// websites when website is empty.
+type controlMsg struct {
+	action  string
+	website string
+}
+
+// parseControlListen validates a control API address: a unix socket as
+// "unix:/path/to/socket", or a TCP address on the loopback interface, as the
+// API has no authentication.
+func parseControlListen(addr string) error {
+	if path, ok := strings.CutPrefix(addr, controlUnixPrefix); ok {
+		if path == "" {
+			return fmt.Errorf("missing socket path in %q", addr)
+		}
+		return nil
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("%q is not a loopback address, use e.g. 127.0.0.1:7070 or unix:/run/vivteno.sock", addr)
+	}
+	return nil
+}
+
+// listenControl opens the listener of the control API. A socket left behind
+// by an earlier run is replaced, and a new socket is only accessible to the
+// current user.
+func listenControl(addr string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, controlUnixPrefix)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	if fi, err := os.Stat(path); err == nil && fi.Mode()&fs.ModeSocket != 0 {
+		_ = os.Remove(path)
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// newControlServer returns the control API server:
+//
+//	GET  /status           the snapshots of all websites, as /status.json of the dashboard
+//	POST /check[/{site}]   check a website, or all, right away
+//	POST /pause[/{site}]   pause the checks of a website, or all
+//	POST /resume[/{site}]  resume the checks of a website, or all
+//	GET  /healthz, /readyz the health of vivteno itself (see handleSelfHealth)
+//
+// Websites are named as in PING_WEBSITE, URL-escaped if they contain
+// slashes. Requests are handed to the program with send and answered with
+// 202 Accepted. Requests from web pages are refused (see controlLocalOnly).
+func newControlServer(store *statusStore, send func(tea.Msg)) *http.Server {
+	mux := http.NewServeMux()
+	handleSelfHealth(mux, store)
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		targets, updated := store.get()
+		writeControlJSON(w, http.StatusOK, map[string]any{
+			"updated": updated,
+			"targets": targets,
+		})
+	})
+	for _, action := range []string{controlCheck, controlPause, controlResume} {
+		handle := func(w http.ResponseWriter, r *http.Request) {
+			website := r.PathValue("site")
+			if website != "" {
+				targets, _ := store.get()
+				if !slices.ContainsFunc(targets, func(t targetSnapshot) bool { return t.Website == website }) {
+					writeControlJSON(w, http.StatusNotFound, map[string]any{"error": fmt.Sprintf("unknown website %q", website)})
+					return
+				}
+			}
+			send(controlMsg{action: action, website: website})
+			writeControlJSON(w, http.StatusAccepted, map[string]any{"action": action, "website": website})
+		}
+		mux.HandleFunc("POST /"+action, handle)
+		mux.HandleFunc("POST /"+action+"/{site}", handle)
+	}
+	return &http.Server{
+		Handler:           controlLocalOnly(mux),
+		ReadHeaderTimeout: WebReadHeaderTimeout,
+	}
+}
+
+// controlLocalOnly refuses requests a web page could make to the control API
+// through the browser of the user: those carrying an Origin header, which
+// browsers add to cross-origin requests and to every POST, and, on a TCP
+// address, those for a host name other than localhost or a loopback address,
+// which a page could otherwise reach by resolving its own domain to 127.0.0.1.
+func controlLocalOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			writeControlJSON(w, http.StatusForbidden, map[string]any{"error": "requests from web pages are not allowed"})
+			return
+		}
+		if local, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok && local.Network() == "tcp" {
+			host, _, err := net.SplitHostPort(r.Host)
+			if err != nil {
+				host = strings.Trim(r.Host, "[]")
+			}
+			if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+				writeControlJSON(w, http.StatusForbidden, map[string]any{"error": fmt.Sprintf("host %q is not a loopback address", r.Host)})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeControlJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// control applies a control API request.
+func (m model) control(msg controlMsg) {
+	for i, t := range m.targets {
+		if msg.website != "" && t.website != msg.website {
+			continue
+		}
+		switch msg.action {
+		case controlCheck:
+			m.mon.Trigger(i)
+		case controlPause:
+			m.setPaused(i, true)
+		case controlResume:
+			m.setPaused(i, false)
+		}
+	}
+	website := msg.website
+	if website == "" {
+		website = "all"
+	}
+	m.logger.Info("control request", "action", msg.action, "target", website)
+	m.publish()
+}
+
+// controlStatus is the response of GET /status.
+type controlStatus struct {
+	Updated time.Time        `json:"updated"`
+	Targets []targetSnapshot `json:"targets"`
+}
+
+// runStatus implements the status subcommand: it asks the instance serving
+// the control API at CONTROL_LISTEN, or --control, for the state of every
+// website and prints it. It returns the process exit code, as --once does.
+func runStatus(args []string) int {
+	flags := flag.NewFlagSet("status", flag.ContinueOnError)
+	addr := flags.String("control", "", "control API address of the running instance (default CONTROL_LISTEN)")
+	format := flags.String("format", OnceFormatTable, "output format: table or json")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+	if *format != OnceFormatTable && *format != OnceFormatJSON {
+		fmt.Printf("Invalid --format: %q (expected %q or %q)\n", *format, OnceFormatTable, OnceFormatJSON)
+		return 1
+	}
+	if *addr == "" {
+		loadEnv()
+		*addr = os.Getenv("CONTROL_LISTEN")
+	}
+	if *addr == "" {
+		fmt.Println("No control API address: set CONTROL_LISTEN for the running instance or pass --control")
+		return 1
+	}
+	if err := parseControlListen(*addr); err != nil {
+		fmt.Printf("Invalid --control: %v\n", err)
+		return 1
+	}
+	st, err := fetchStatus(*addr)
+	if err != nil {
+		fmt.Printf("Could not query vivteno at %s: %v\n", *addr, err)
+		return 1
+	}
+
+	if *format == OnceFormatJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(st)
+	} else {
+		writeStatusTable(os.Stdout, st)
+	}
+	code := ExitAllUp
+	for _, t := range st.Targets {
+		switch t.Status {
+		case statusDown.String():
+			return ExitTargetDown
+		case statusDegraded.String():
+			code = ExitTargetDegraded
+		}
+	}
+	return code
+}
+
+// fetchStatus requests GET /status from the control API at addr.
+func fetchStatus(addr string) (controlStatus, error) {
+	var st controlStatus
+	transport := &http.Transport{}
+	base := "http://" + addr
+	if path, ok := strings.CutPrefix(addr, controlUnixPrefix); ok {
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		}
+		base = "http://vivteno"
+	}
+	client := &http.Client{Transport: transport, Timeout: controlClientTimeout}
+	resp, err := client.Get(base + "/status")
+	if err != nil {
+		return st, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return st, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 16<<20)).Decode(&st); err != nil {
+		return st, fmt.Errorf("decoding response: %w", err)
+	}
+	return st, nil
+}
+
+func writeStatusTable(w io.Writer, st controlStatus) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "WEBSITE\tCHECK\tSTATUS\tLATENCY\tUPTIME\tLAST CHECKED\tERROR")
+	for _, t := range st.Targets {
+		row := newDashboardRow(t, nil, time.Local)
+		status := strings.ToUpper(row.Status)
+		if row.Paused {
+			status += " (paused)"
+		}
+		if row.Flapping {
+			status += " (flapping)"
+		}
+		errText := row.LastError
+		if errText == "" && len(row.Violations) > 0 {
+			errText = "assertion failed: " + strings.Join(row.Violations, "; ")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", row.Website, row.Check, status, row.Latency, row.Uptime, row.LastChecked, strings.ReplaceAll(errText, "\n", " "))
+	}
+	_ = tw.Flush()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// clockTickMsg redraws the view every second, so the countdowns to the next
+// checks stay current.
+type clockTickMsg struct{}
+
+// clockTick schedules the next redraw of the countdowns.
+func clockTick() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg { return clockTickMsg{} })
+}
+
+// countdown returns the time left until the website at i is checked next,
+// e.g. "7s", "now" while it is being checked, or "-" while its checks are
+// paused.
+func (m model) countdown(i int, t *target) string {
+	if t.paused {
+		return "-"
+	}
+	var at time.Time
+	if m.mon != nil {
+		at = m.mon.NextCheck(i)
+	}
+	left := time.Until(at)
+	if at.IsZero() || left < time.Second {
+		return "now"
+	}
+	// Rounded up, so the countdown reaches 1s rather than 0s before the check
+	return formatDuration(left.Truncate(time.Second) + time.Second)
+}
+
+// nextCheck describes when the website at i is checked next, e.g. "next
+// check in 7s".
+func (m model) nextCheck(i int, t *target) string {
+	if t.paused {
+		return "checks paused"
+	}
+	if left := m.countdown(i, t); left != "now" {
+		return "next check in " + left
+	}
+	return "checking now"
+}
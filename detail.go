@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Number of distinct recent errors listed in the detail view
+const detailErrorCount = 5
+
+// renderDetail renders the focused view of the selected website: its
+// configuration, the full health response, recent results and errors.
+func renderDetail(m model) string {
+	t := m.targets[m.selected]
+	var b strings.Builder
+
+	b.WriteString(renderSection("Website:", t.website) + pausedBadge(t) + m.mutedBadge(t) + flappingBadge(t))
+	b.WriteString("\n")
+	b.WriteString(renderSection("Status:", t.state.String()))
+	b.WriteString("\n")
+	b.WriteString(renderSection("Check:", t.checkType))
+	b.WriteString("\n")
+	if len(t.tags) > 0 {
+		b.WriteString(renderSection("Tags:", strings.Join(t.tags, ", ")))
+		b.WriteString("\n")
+	}
+	if t.severity != "" {
+		b.WriteString(renderSection("Severity:", t.severity))
+		b.WriteString("\n")
+	}
+	if t.description != "" {
+		b.WriteString(renderSection("Description:", t.description))
+		b.WriteString("\n")
+	}
+	if t.owner != "" {
+		b.WriteString(renderSection("Owner:", t.owner))
+		b.WriteString("\n")
+	}
+	if t.runbook != "" {
+		b.WriteString(renderSection("Runbook:", t.runbook))
+		b.WriteString("\n")
+	}
+	b.WriteString(renderSection("Schedule:", m.schedule) + " " + unknownStyle.Render(m.nextCheck(m.selected, t)))
+	b.WriteString("\n")
+	if u := t.uptime(); u >= 0 {
+		b.WriteString(renderSection("Uptime:", fmt.Sprintf("%.1f%% of %d checks", u, t.checks)))
+		b.WriteString("\n")
+	}
+	if len(t.maintenance) > 0 {
+		b.WriteString(renderMaintenance(t.maintenance, m.timezone))
+		b.WriteString("\n")
+	}
+	b.WriteString(renderSection("Downtime:", formatDuration(m.incidents.downtime(t.website, time.Now()))))
+	b.WriteString("\n")
+	b.WriteString(renderDomain(m, t))
+	b.WriteString(renderBudget(m, t))
+	if len(t.families) > 0 {
+		b.WriteString(renderFamilies(t.families))
+		b.WriteString("\n")
+	}
+	if len(t.ports) > 0 {
+		b.WriteString(renderPorts(t.ports))
+		b.WriteString("\n")
+	}
+	if t.tlsInfo != nil {
+		b.WriteString(renderCertChain(m, t.tlsInfo))
+		b.WriteString("\n")
+	}
+	if len(t.locations) > 0 {
+		b.WriteString(renderLocations(t, time.Now()))
+		b.WriteString("\n")
+	}
+	if t.healthEndpoint != "" && !t.lastChecked.IsZero() {
+		b.WriteString(renderCheckStates(t))
+		b.WriteString("\n")
+	}
+	if t.trace != nil {
+		b.WriteString("\n")
+		b.WriteString(renderTraceroute(m, t))
+	}
+
+	if len(t.headers) > 0 {
+		b.WriteString("\n")
+		b.WriteString(renderHeaders("Response headers:", t.headers, t.captureHeaders))
+	}
+	if len(t.healthHeaders) > 0 {
+		b.WriteString("\n")
+		b.WriteString(renderHeaders("Health response headers:", t.healthHeaders, t.captureHeaders))
+	}
+
+	// Full health response
+	if t.health != nil {
+		b.WriteString("\n")
+		b.WriteString(sectionTitle.Render("Health Endpoint:"))
+		b.WriteString("\n")
+		if out, err := json.MarshalIndent(t.health, "  ", "  "); err == nil {
+			b.WriteString("  " + healthValueStyle.Render(string(out)))
+			b.WriteString("\n")
+		}
+	}
+
+	if len(t.violations) > 0 {
+		b.WriteString("\n")
+		b.WriteString(renderViolations(t.violations))
+	}
+	if t.anomaly != "" {
+		b.WriteString("\n")
+		b.WriteString(warningStyle.Render("ANOMALY: " + t.anomaly))
+		b.WriteString("\n")
+	}
+
+	// Recent results, newest first
+	b.WriteString("\n")
+	b.WriteString(sectionTitle.Render("Recent checks:"))
+	b.WriteString("\n")
+	b.WriteString(renderChecks(m, t, detailCheckCount))
+
+	if incidents := m.incidents.recent(t.website, RecentIncidents); len(incidents) > 0 {
+		b.WriteString("\n")
+		b.WriteString(renderIncidents("Recent incidents:", incidents, m))
+	}
+
+	// Distinct recent errors, newest first
+	var errs []historyEntry
+	seen := map[string]bool{}
+	history := t.history.all()
+	for j := len(history) - 1; j >= 0 && len(errs) < detailErrorCount; j-- {
+		if e := history[j]; !e.OK && !seen[e.Error] {
+			seen[e.Error] = true
+			errs = append(errs, e)
+		}
+	}
+	if len(errs) > 0 {
+		b.WriteString("\n")
+		b.WriteString(sectionTitle.Render("Last errors:"))
+		b.WriteString("\n")
+		for _, e := range errs {
+			b.WriteString(fmt.Sprintf("  %s %s\n", m.formatTime(e.Time), downStyle.Render(e.Error)))
+		}
+	}
+	return b.String()
+}
+
+// formatTime formats t in the configured timezone.
+func (m model) formatTime(t time.Time) string {
+	if m.timezone != nil {
+		t = t.In(m.timezone)
+	}
+	return t.Format(DisplayTimeFormat)
+}
+
+// renderHeaders lists captured response headers in the configured order.
+func renderHeaders(title string, headers map[string]string, order []string) string {
+	var b strings.Builder
+	b.WriteString(sectionTitle.Render(title))
+	b.WriteString("\n")
+	for _, name := range order {
+		if v, ok := headers[name]; ok {
+			b.WriteString("  " + healthKeyStyle.Render(name+":") + " " + healthValueStyle.Render(v) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// renderIncidents lists incidents with their start, duration and cause.
+func renderIncidents(title string, incidents []incident, m model) string {
+	var b strings.Builder
+	b.WriteString(sectionTitle.Render(title))
+	b.WriteString("\n")
+	now := time.Now()
+	for _, inc := range incidents {
+		d := formatDuration(inc.duration(now))
+		state := infoStyle.Render("resolved after " + d)
+		if inc.ongoing() {
+			state = downStyle.Render("ongoing for " + d)
+		}
+		errLine, _, _ := strings.Cut(inc.Error, "\n")
+		b.WriteString(fmt.Sprintf("  %s %s %s %s\n",
+			healthKeyStyle.Render(inc.Target), m.formatTime(inc.Start), state,
+			healthValueStyle.Render(truncate(errLine, tableErrorWidth))))
+	}
+	return b.String()
+}
+
+// formatDuration rounds d to whole seconds for display.
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
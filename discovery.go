@@ -0,0 +1,174 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mooship/vivteno/pkg/monitor"
+)
+
+// discoveredSite is a website found through discovery, with the tags it was
+// given there.
+type discoveredSite struct {
+	website string
+	tags    []string
+}
+
+// discoverer is a source of websites, such as a Kubernetes cluster.
+type discoverer interface {
+	// discover returns the websites the source currently lists.
+	discover(ctx context.Context) ([]discoveredSite, error)
+}
+
+// discoverySource runs a discoverer every refresh interval and keeps the
+// websites it last found.
+type discoverySource struct {
+	// name prefixes the errors of the source, e.g. "Kubernetes discovery".
+	name    string
+	find    discoverer
+	refresh time.Duration
+
+	mu    sync.Mutex
+	sites []discoveredSite
+}
+
+// discovery gathers the websites of every enabled discovery source.
+type discovery struct {
+	sources []*discoverySource
+}
+
+// loadDiscovery reads the settings of every discovery source. It returns
+// nil when none is enabled.
+func loadDiscovery() (*discovery, error) {
+	d := &discovery{}
+	k, err := loadKubernetes()
+	if err != nil {
+		return nil, err
+	}
+	if k != nil {
+		d.sources = append(d.sources, &discoverySource{name: "Kubernetes discovery", find: k, refresh: k.refresh})
+	}
+	sd, err := loadPrometheusSD()
+	if err != nil {
+		return nil, err
+	}
+	if sd != nil {
+		d.sources = append(d.sources, &discoverySource{name: "Prometheus service discovery", find: sd, refresh: sd.refresh})
+	}
+	if len(d.sources) == 0 {
+		return nil, nil
+	}
+	return d, nil
+}
+
+// startDiscovery loads the discovery settings and, when discovery is enabled,
+// runs it once so the first configuration includes the discovered websites.
+func startDiscovery() (*discovery, []discoveredSite, error) {
+	d, err := loadDiscovery()
+	if err != nil || d == nil {
+		return nil, nil, err
+	}
+	for _, s := range d.sources {
+		if _, err := s.discover(context.Background()); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", s.name, err)
+		}
+	}
+	return d, d.current(), nil
+}
+
+// current returns the websites found by the last successful run of each
+// source, in the order of the sources. A website found by several sources
+// keeps the tags of the first.
+func (d *discovery) current() []discoveredSite {
+	if d == nil {
+		return nil
+	}
+	var sites []discoveredSite
+	for _, s := range d.sources {
+		s.mu.Lock()
+		for _, site := range s.sites {
+			if !slices.ContainsFunc(sites, func(other discoveredSite) bool { return other.website == site.website }) {
+				sites = append(sites, site)
+			}
+		}
+		s.mu.Unlock()
+	}
+	return sites
+}
+
+// discover runs the discoverer of s and returns the websites it found,
+// sorted, which become the current ones. Websites the monitor would reject
+// are skipped rather than failing the reload.
+func (s *discoverySource) discover(ctx context.Context) ([]discoveredSite, error) {
+	sites, err := s.find.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sites = slices.DeleteFunc(sites, func(site discoveredSite) bool {
+		_, err := monitor.ParseTarget(site.website)
+		return err != nil
+	})
+	slices.SortStableFunc(sites, func(a, b discoveredSite) int { return cmp.Compare(a.website, b.website) })
+	sites = slices.CompactFunc(sites, func(a, b discoveredSite) bool { return a.website == b.website })
+
+	s.mu.Lock()
+	s.sites = sites
+	s.mu.Unlock()
+	return sites, nil
+}
+
+// watchDiscovery re-runs every discovery source at its refresh interval
+// until ctx is cancelled and reloads the configuration when the websites it
+// found change. A failed run keeps the previous websites of the source.
+func watchDiscovery(ctx context.Context, p *tea.Program, d *discovery) {
+	for _, s := range d.sources {
+		go d.watch(ctx, p, s)
+	}
+}
+
+// watch re-runs the source s for watchDiscovery.
+func (d *discovery) watch(ctx context.Context, p *tea.Program, s *discoverySource) {
+	ticker := time.NewTicker(s.refresh)
+	defer ticker.Stop()
+	var failed bool
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		s.mu.Lock()
+		prev := s.sites
+		s.mu.Unlock()
+		sites, err := s.discover(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				p.Send(reloadErrMsg{fmt.Errorf("%s: %w", s.name, err)})
+				failed = true
+			}
+			continue
+		}
+		// After a failure, reload even if nothing changed to clear the error
+		if slices.EqualFunc(sites, prev, discoveredSite.equal) && !failed {
+			continue
+		}
+		cfg, err := loadConfig(d.current())
+		failed = err != nil
+		if err != nil {
+			p.Send(reloadErrMsg{err})
+			continue
+		}
+		p.Send(reloadMsg{cfg})
+	}
+}
+
+// equal reports whether two discovered websites are the same, with the same
+// tags.
+func (s discoveredSite) equal(other discoveredSite) bool {
+	return s.website == other.website && slices.Equal(s.tags, other.tags)
+}
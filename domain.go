@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mooship/vivteno/pkg/monitor"
+)
+
+// Domain expiry lookup settings
+const (
+	DefaultDomainRefresh = 12 * time.Hour
+	// DomainTimeout bounds one RDAP lookup, including redirects.
+	DomainTimeout = 15 * time.Second
+)
+
+// domainMsg delivers the outcome of a domain expiry lookup.
+type domainMsg struct {
+	domain string
+	expiry time.Time
+	err    error
+}
+
+// domainTickMsg triggers the periodic domain expiry lookups.
+type domainTickMsg struct{}
+
+// domains returns the distinct registrable domains to look up. With pending
+// set, only domains that have not been looked up yet are returned.
+func (m model) domains(pending bool) []string {
+	var domains []string
+	for _, t := range m.targets {
+		if t.domain == "" || slices.Contains(domains, t.domain) {
+			continue
+		}
+		if pending && (!t.domainExpiry.IsZero() || t.domainError != "") {
+			continue
+		}
+		domains = append(domains, t.domain)
+	}
+	return domains
+}
+
+// domainCmd looks up the expiry of each domain.
+func (m model) domainCmd(domains []string) tea.Cmd {
+	cmds := make([]tea.Cmd, len(domains))
+	for i, d := range domains {
+		cmds[i] = func() tea.Msg {
+			ctx, cancel := context.WithTimeout(m.ctx, DomainTimeout)
+			defer cancel()
+			expiry, err := monitor.DomainExpiry(ctx, monitor.DefaultHTTPClient, m.rdapServer, d)
+			return domainMsg{domain: d, expiry: expiry, err: err}
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+// domainTick schedules the next round of domain expiry lookups.
+func (m model) domainTick() tea.Cmd {
+	return tea.Tick(m.domainRefresh, func(time.Time) tea.Msg { return domainTickMsg{} })
+}
+
+// applyDomain records a lookup on every website registered under its
+// domain. A failed lookup keeps the last known expiry.
+func (m model) applyDomain(msg domainMsg) {
+	for _, t := range m.targets {
+		if t.domain != msg.domain {
+			continue
+		}
+		t.domainError = ""
+		if msg.err != nil {
+			t.domainError = msg.err.Error()
+			continue
+		}
+		t.domainExpiry = msg.expiry
+	}
+	if msg.err != nil {
+		m.logger.Warn("domain expiry lookup failed", "domain", msg.domain, "error", msg.err.Error())
+		return
+	}
+	m.logger.Info("domain expiry", "domain", msg.domain, "expires", msg.expiry, "days_left", daysUntil(msg.expiry))
+}
+
+// daysUntil returns the number of whole days until t, negative once past.
+func daysUntil(t time.Time) int {
+	return int(time.Until(t).Hours() / 24)
+}
+
+// renderDomain describes when the domain of a website expires, warning once
+// it is within the configured number of days. It is empty when expiry is
+// not monitored for the website or not known yet.
+func renderDomain(m model, t *target) string {
+	if t.domain == "" {
+		return ""
+	}
+	if t.domainExpiry.IsZero() {
+		if t.domainError == "" {
+			return ""
+		}
+		return renderSection("Domain:", t.domain) + " " + unknownStyle.Render("expiry unknown: "+t.domainError) + "\n"
+	}
+	days := daysUntil(t.domainExpiry)
+	expires := m.formatTime(t.domainExpiry)
+	switch {
+	case days < 0:
+		return warningStyle.Render(fmt.Sprintf("DOMAIN EXPIRED: %s on %s", t.domain, expires)) + "\n"
+	case days <= t.expiryDays:
+		return warningStyle.Render(fmt.Sprintf("DOMAIN EXPIRES SOON: %s in %d days (%s)", t.domain, days, expires)) + "\n"
+	}
+	return renderSection("Domain:", fmt.Sprintf("%s expires %s (%d days)", t.domain, expires, days)) + "\n"
+}
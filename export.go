@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Export file formats
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatJSON = "json"
+)
+
+// exportTimeFormat names export files after the time they were written.
+const exportTimeFormat = "20060102-150405"
+
+// exportData is a report of collected check results, uptime stats and
+// incidents.
+type exportData struct {
+	Generated time.Time      `json:"generated"`
+	Targets   []exportTarget `json:"targets"`
+	Incidents []incident     `json:"incidents"`
+}
+
+// exportTarget holds the stats of one website. Checks, uptime and results
+// are only known for a running session.
+type exportTarget struct {
+	Website         string         `json:"website"`
+	Checks          int            `json:"checks"`
+	Successes       int            `json:"successes"`
+	Uptime          *float64       `json:"uptime,omitempty"`
+	DowntimeSeconds float64        `json:"downtime_seconds"`
+	Incidents       int            `json:"incidents"`
+	Results         []exportResult `json:"results,omitempty"`
+}
+
+// exportResult is one check result.
+type exportResult struct {
+	Time      time.Time `json:"time"`
+	OK        bool      `json:"ok"`
+	LatencyMs int64     `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// exportData collects the results of the running session.
+func (m model) exportData(now time.Time) exportData {
+	e := exportData{Generated: now, Incidents: slices.Clone(m.incidents.items)}
+	for _, t := range m.targets {
+		et := exportTarget{
+			Website:         t.website,
+			Checks:          t.checks,
+			Successes:       t.successes,
+			DowntimeSeconds: m.incidents.downtime(t.website, now).Round(time.Second).Seconds(),
+			Incidents:       m.incidents.count(t.website),
+		}
+		if u := t.uptime(); u >= 0 {
+			et.Uptime = &u
+		}
+		for _, h := range t.history.all() {
+			et.Results = append(et.Results, exportResult{Time: h.Time, OK: h.OK, LatencyMs: h.Latency.Milliseconds(), Error: h.Error})
+		}
+		e.Targets = append(e.Targets, et)
+	}
+	return e
+}
+
+// exportIncidents builds a report from a persisted incident log, listing
+// every website that has had an incident.
+func exportIncidents(l *incidentLog, now time.Time) exportData {
+	e := exportData{Generated: now, Incidents: l.items}
+	var websites []string
+	for _, inc := range l.items {
+		if !slices.Contains(websites, inc.Target) {
+			websites = append(websites, inc.Target)
+		}
+	}
+	for _, w := range websites {
+		e.Targets = append(e.Targets, exportTarget{
+			Website:         w,
+			DowntimeSeconds: l.downtime(w, now).Round(time.Second).Seconds(),
+			Incidents:       l.count(w),
+		})
+	}
+	return e
+}
+
+// writeExport writes e to files named after base and returns their paths.
+// JSON goes to base.json; CSV is split into base-summary.csv,
+// base-incidents.csv and, when results were collected, base-checks.csv.
+func writeExport(e exportData, base, format string) ([]string, error) {
+	if format == ExportFormatJSON {
+		data, err := json.MarshalIndent(e, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		path := base + ".json"
+		return []string{path}, os.WriteFile(path, data, 0o644)
+	}
+
+	summary := [][]string{{"website", "checks", "successes", "uptime_percent", "downtime_seconds", "incidents"}}
+	checks := [][]string{{"website", "time", "ok", "latency_ms", "error"}}
+	for _, t := range e.Targets {
+		uptime := ""
+		if t.Uptime != nil {
+			uptime = strconv.FormatFloat(*t.Uptime, 'f', 2, 64)
+		}
+		summary = append(summary, []string{
+			t.Website, strconv.Itoa(t.Checks), strconv.Itoa(t.Successes), uptime,
+			strconv.FormatFloat(t.DowntimeSeconds, 'f', 0, 64), strconv.Itoa(t.Incidents),
+		})
+		for _, r := range t.Results {
+			checks = append(checks, []string{
+				t.Website, r.Time.Format(time.RFC3339), strconv.FormatBool(r.OK),
+				strconv.FormatInt(r.LatencyMs, 10), r.Error,
+			})
+		}
+	}
+	incidents := [][]string{{"website", "start", "end", "duration_seconds", "error"}}
+	for _, inc := range e.Incidents {
+		end := ""
+		if !inc.ongoing() {
+			end = inc.End.Format(time.RFC3339)
+		}
+		incidents = append(incidents, []string{
+			inc.Target, inc.Start.Format(time.RFC3339), end,
+			strconv.FormatFloat(inc.duration(e.Generated).Round(time.Second).Seconds(), 'f', 0, 64), inc.Error,
+		})
+	}
+
+	files := []struct {
+		suffix string
+		rows   [][]string
+	}{{"-summary.csv", summary}, {"-incidents.csv", incidents}, {"-checks.csv", checks}}
+	var paths []string
+	for _, f := range files {
+		if f.suffix == "-checks.csv" && len(f.rows) == 1 {
+			continue
+		}
+		path := base + f.suffix
+		if err := writeCSV(path, f.rows); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// writeCSV writes rows to a new file at path.
+func writeCSV(path string, rows [][]string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	if err := w.WriteAll(rows); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// exportMsg reports the files written by an export.
+type exportMsg struct {
+	paths []string
+	err   error
+}
+
+// exportCmd writes the session's results to exportDir in exportFormat.
+func (m model) exportCmd() tea.Cmd {
+	now := time.Now()
+	e := m.exportData(now)
+	base := filepath.Join(m.exportDir, "vivteno-"+now.Format(exportTimeFormat))
+	format := m.exportFormat
+	return func() tea.Msg {
+		paths, err := writeExport(e, base, format)
+		return exportMsg{paths: paths, err: err}
+	}
+}
+
+// runExport implements the export subcommand: it writes the incident history
+// from HISTORY_FILE and returns the process exit code.
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", ExportFormatCSV, "export format: csv or json")
+	output := fs.String("output", "", "base name of the export files (default vivteno-<time>)")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if *format != ExportFormatCSV && *format != ExportFormatJSON {
+		fmt.Printf("Invalid --format: %q (expected %q or %q)\n", *format, ExportFormatCSV, ExportFormatJSON)
+		return 1
+	}
+	loadEnv()
+	path := os.Getenv("HISTORY_FILE")
+	if path == "" {
+		fmt.Println("HISTORY_FILE must be set to export the incident history.")
+		return 1
+	}
+	l, err := loadIncidents(path)
+	if err != nil {
+		fmt.Printf("Invalid HISTORY_FILE: %v\n", err)
+		return 1
+	}
+	now := time.Now()
+	base := *output
+	if base == "" {
+		base = "vivteno-" + now.Format(exportTimeFormat)
+	}
+	base = strings.TrimSuffix(base, "."+*format)
+	paths, err := writeExport(exportIncidents(l, now), base, *format)
+	if err != nil {
+		fmt.Printf("Export failed: %v\n", err)
+		return 1
+	}
+	fmt.Println("Exported to " + strings.Join(paths, ", "))
+	return 0
+}
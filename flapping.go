@@ -0,0 +1,65 @@
+package main
+
+import (
+	"slices"
+	"time"
+)
+
+// DefaultFlapWindow is the period state changes are counted over unless
+// FLAP_WINDOW is set.
+const DefaultFlapWindow = time.Hour
+
+// flapPolicy decides when a website is flapping: it starts once more than
+// threshold state changes happened within window, and stops once at most
+// half as many did. A threshold of 0 disables flap detection.
+type flapPolicy struct {
+	threshold int
+	window    time.Duration
+}
+
+// updateFlapping notes a check at now, which changed the state of the
+// website if changed, and reports whether the website started or stopped
+// flapping.
+func (t *target) updateFlapping(now time.Time, changed bool, p flapPolicy) (started, stopped bool) {
+	if p.threshold <= 0 {
+		if t.flapping {
+			t.flapping, t.stateChanges = false, nil
+			return false, true
+		}
+		return false, false
+	}
+	if changed {
+		t.stateChanges = append(t.stateChanges, now)
+	}
+	t.stateChanges = slices.DeleteFunc(t.stateChanges, func(c time.Time) bool {
+		return now.Sub(c) > p.window
+	})
+	switch n := len(t.stateChanges); {
+	case !t.flapping && n > p.threshold:
+		t.flapping = true
+		return true, false
+	case t.flapping && n <= p.threshold/2:
+		t.flapping = false
+		return false, true
+	}
+	return false, false
+}
+
+// flappingBadge marks a flapping website, or is empty.
+func flappingBadge(t *target) string {
+	if !t.flapping {
+		return ""
+	}
+	return " " + flappingStyle.Render("FLAPPING")
+}
+
+// flappingCount returns the number of flapping targets.
+func (m model) flappingCount() int {
+	var n int
+	for _, t := range m.targets {
+		if t.flapping {
+			n++
+		}
+	}
+	return n
+}
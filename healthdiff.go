@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mooship/vivteno/pkg/monitor"
+	"github.com/mooship/vivteno/pkg/notify"
+)
+
+// HealthChangeHighlight is how long a health field that changed stays
+// highlighted.
+const HealthChangeHighlight = 5 * time.Minute
+
+// healthChange is a health field whose value differs from the previous
+// response.
+type healthChange struct {
+	// from is the previous value, unless added is set for a field that was
+	// not in the previous response.
+	from  any
+	added bool
+	at    time.Time
+}
+
+// healthChanges are the recent changes of the fields of a health response,
+// by path: the keys of nested objects joined with dots, e.g. "db.status".
+// Arrays are compared as a whole.
+type healthChanges map[string]healthChange
+
+// diff records in c the fields of cur that differ from prev, at the
+// path prefix.
+func (c healthChanges) diff(prev, cur map[string]any, prefix string, now time.Time) {
+	for k, v := range cur {
+		path := prefix + k
+		old, ok := prev[k]
+		switch {
+		case !ok:
+			c[path] = healthChange{added: true, at: now}
+		case isObject(old) && isObject(v):
+			c.diff(old.(map[string]any), v.(map[string]any), path+".", now)
+		case !reflect.DeepEqual(old, v):
+			c[path] = healthChange{from: old, at: now}
+		}
+	}
+}
+
+// isObject reports whether v is a decoded JSON object.
+func isObject(v any) bool {
+	_, ok := v.(map[string]any)
+	return ok
+}
+
+// prune drops the changes highlighted for longer than
+// HealthChangeHighlight.
+func (c healthChanges) prune(now time.Time) {
+	for path, ch := range c {
+		if now.Sub(ch.at) >= HealthChangeHighlight {
+			delete(c, path)
+		}
+	}
+}
+
+// value renders the value of the field at path, highlighted with its
+// previous value when it changed. A value holding fields that changed is
+// highlighted too.
+func (c healthChanges) value(path, s string) string {
+	if ch, ok := c[path]; ok {
+		was := " (new)"
+		if !ch.added {
+			was = " (was " + healthValueText(ch.from) + ")"
+		}
+		return changedStyle.Render(s) + unknownStyle.Render(was)
+	}
+	for p := range c {
+		if strings.HasPrefix(p, path+".") {
+			return changedStyle.Render(s)
+		}
+	}
+	return healthValueStyle.Render(s)
+}
+
+// healthValueText formats a health value for messages: strings as they are,
+// other values as JSON.
+func healthValueText(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(out)
+}
+
+// recordHealth compares a new health response of t with the previous
+// successful one, if any, to highlight the fields that changed, and returns
+// a command alerting on changes of its watched fields when it alerts on
+// them, or nil. Watched fields that changed are logged either way.
+func (m model) recordHealth(t *target, data map[string]any, r monitor.Report) tea.Cmd {
+	prev := t.lastHealth
+	t.health, t.lastHealth = data, data
+	if t.healthChanges == nil {
+		t.healthChanges = healthChanges{}
+	}
+	t.healthChanges.prune(r.Time)
+	if prev == nil {
+		return nil
+	}
+	t.healthChanges.diff(prev, data, "", r.Time)
+
+	var changes []string
+	for _, p := range t.healthWatch {
+		from, fromErr := p.Lookup(prev)
+		to, toErr := p.Lookup(data)
+		if (fromErr != nil && toErr != nil) || (fromErr == nil && toErr == nil && reflect.DeepEqual(from, to)) {
+			continue
+		}
+		fromText, toText := watchedValue(from, fromErr), watchedValue(to, toErr)
+		m.logger.Warn("health field changed", "target", t.website, "field", p.Label(), "from", fromText, "to", toText)
+		changes = append(changes, p.Label()+" changed from "+fromText+" to "+toText)
+	}
+	if len(changes) == 0 || !t.watchAlert || len(m.notifiers) == 0 || t.muted {
+		return nil
+	}
+	return m.deliver(notify.Event{
+		Target:      t.website,
+		State:       notify.StateChange,
+		Time:        r.Time,
+		URL:         r.Target.URL(),
+		Error:       strings.Join(changes, "; "),
+		Tags:        t.tags,
+		Severity:    notify.SeverityInfo,
+		Description: t.description,
+		Owner:       t.owner,
+		Runbook:     t.runbook,
+	})
+}
+
+// watchedValue formats the value of a watched field, or "none" when the
+// response did not have it.
+func watchedValue(v any, err error) string {
+	if err != nil {
+		return "none"
+	}
+	return healthValueText(v)
+}
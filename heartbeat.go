@@ -0,0 +1,109 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/mooship/vivteno/pkg/monitor"
+)
+
+// HeartbeatMaxMessage limits the failure message read from a heartbeat
+// request body.
+const HeartbeatMaxMessage = 1000
+
+// heartbeatStore holds the heartbeats of the websites using the heartbeat
+// check and the tokens their heartbeats must carry. It is kept across config
+// reloads, so a reload does not reset the time since their last beat.
+type heartbeatStore struct {
+	mu     sync.Mutex
+	beats  map[string]*monitor.Heartbeat
+	tokens map[string]string
+}
+
+func newHeartbeatStore() *heartbeatStore {
+	return &heartbeatStore{beats: make(map[string]*monitor.Heartbeat), tokens: make(map[string]string)}
+}
+
+// attach gives every heartbeat target its Heartbeat, reusing those of
+// websites that were already configured, records the tokens of their
+// heartbeats, one per target, and forgets removed websites.
+func (s *heartbeatStore) attach(targets []monitor.Target, tokens []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	beats := make(map[string]*monitor.Heartbeat)
+	s.tokens = make(map[string]string)
+	for i := range targets {
+		if targets[i].Check != monitor.CheckHeartbeat {
+			continue
+		}
+		h, ok := s.beats[targets[i].Name]
+		if !ok {
+			h = monitor.NewHeartbeat()
+		}
+		beats[targets[i].Name] = h
+		s.tokens[targets[i].Name] = tokens[i]
+		targets[i].Heartbeat = h
+	}
+	s.beats = beats
+}
+
+// get returns the Heartbeat of website and the token its heartbeats must
+// carry, or nil if it is not a heartbeat target.
+func (s *heartbeatStore) get(website string) (*monitor.Heartbeat, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.beats[website], s.tokens[website]
+}
+
+// heartbeatMsg reports that a heartbeat arrived for website, so its check
+// runs right away instead of at the next scheduled cycle.
+type heartbeatMsg struct {
+	website string
+}
+
+// handleHeartbeats registers the heartbeat URLs on mux:
+// /heartbeat/{site} records a successful beat and /heartbeat/{site}/fail a
+// failure, with the request body, if any, as its message. Heartbeats carry
+// the token of their website as a bearer token, since the dashboard served
+// alongside lists the websites. Any method is accepted so services can use
+// whatever their HTTP client sends.
+func handleHeartbeats(mux *http.ServeMux, store *heartbeatStore, send func(tea.Msg)) {
+	handle := func(ok bool) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			website := r.PathValue("site")
+			h, token := store.get(website)
+			if h == nil {
+				http.Error(w, fmt.Sprintf("unknown heartbeat website %q", website), http.StatusNotFound)
+				return
+			}
+			bearer, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+				http.Error(w, "invalid heartbeat token", http.StatusUnauthorized)
+				return
+			}
+			var message string
+			if !ok {
+				body, _ := io.ReadAll(io.LimitReader(r.Body, HeartbeatMaxMessage))
+				message = strings.TrimSpace(string(body))
+			}
+			h.Beat(ok, message)
+			send(heartbeatMsg{website: website})
+			_, _ = io.WriteString(w, "OK\n")
+		}
+	}
+	mux.HandleFunc("/heartbeat/{site}", handle(true))
+	mux.HandleFunc("/heartbeat/{site}/fail", handle(false))
+}
+
+// heartbeat runs the check of a website whose heartbeat just arrived.
+func (m model) heartbeat(msg heartbeatMsg) {
+	if i := m.index(msg.website); i >= 0 {
+		m.mon.Trigger(i)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// helpFooter is the footer of the help overlay.
+const helpFooter = "? or Esc close help, q or Ctrl+C quit."
+
+// keyBinding documents one key or group of keys in the help overlay.
+type keyBinding struct {
+	keys, action string
+}
+
+// keyBindings lists every key of the terminal UI, in the order the help
+// overlay shows them.
+var keyBindings = []keyBinding{
+	{"↑/↓, j/k", "select a website; in the detail view, switch to the previous or next one"},
+	{"gg, G", "select the first or last website"},
+	{"v", "start selecting several websites from the selected one, or stop and keep them selected"},
+	{"Space", "add the selected website, or the websites of a collapsed section, to the selection or remove them"},
+	{"Enter", "open the detail view of the selected website, or expand a collapsed section"},
+	{"Esc", "return to the overview, or clear the selection"},
+	{"r", "re-check the selected websites"},
+	{"R", "re-check all websites"},
+	{"p", "pause or resume the selected websites"},
+	{"P", "pause all websites, or resume them all when every one is paused"},
+	{"m", "mute or unmute the alerts of the selected websites for MUTE_DURATION; a down website is acknowledged, muted until it recovers"},
+	{"t", "toggle between the detailed view and the compact table"},
+	{"h", "show or hide the history pane"},
+	{"s", "group websites by tag, or list them ungrouped"},
+	{"c", "collapse or expand the section of the selected website"},
+	{"C", "collapse all sections, or expand them all"},
+	{"x", "export check results, uptime and incidents"},
+	{"?", "show or hide this help"},
+	{"q, Ctrl+C", "quit"},
+}
+
+// renderHelp renders the help overlay: every keybinding and the settings
+// the running instance uses.
+func renderHelp(m model) string {
+	var b strings.Builder
+	b.WriteString(sectionTitle.Render("Keybindings:"))
+	b.WriteString("\n")
+	width := 0
+	for _, k := range keyBindings {
+		width = max(width, len([]rune(k.keys)))
+	}
+	for _, k := range keyBindings {
+		b.WriteString("  " + healthKeyStyle.Render(tableCell(k.keys, width+2)) + healthValueStyle.Render(k.action))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(sectionTitle.Render("Configuration:"))
+	b.WriteString("\n")
+	for _, line := range helpConfig(m) {
+		b.WriteString("  " + line)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// helpConfig returns the settings listed in the help overlay.
+func helpConfig(m model) []string {
+	websites := fmt.Sprintf("%d", len(m.targets))
+	if n := m.pausedCount(); n > 0 {
+		websites += fmt.Sprintf(" (%d paused)", n)
+	}
+	var notifiers []string
+	for _, n := range m.notifiers {
+		if !slices.Contains(notifiers, n.Name()) {
+			notifiers = append(notifiers, n.Name())
+		}
+	}
+	if len(notifiers) == 0 {
+		notifiers = []string{"none"}
+	}
+	groupBy := m.groupBy
+	if groupBy == "" {
+		groupBy = "first tag"
+	}
+	return []string{
+		renderSection("Websites:", websites),
+		renderSection("Schedule:", m.schedule),
+		renderSection("Timezone:", m.timezone.String()),
+		renderSection("Failure threshold:", fmt.Sprintf("%d", m.failureThreshold)),
+		renderSection("Notifiers:", strings.Join(notifiers, ", ")),
+		renderSection("Group by:", groupBy),
+		renderSection("Export:", m.exportFormat+" to "+m.exportDir),
+	}
+}
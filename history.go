@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultHistorySize is the number of check results kept per website unless
+// HISTORY_SIZE is set.
+const DefaultHistorySize = 20
+
+// Number of check results listed in the detail view
+const detailCheckCount = 20
+
+// historyEntry is the outcome of one check cycle.
+type historyEntry struct {
+	Time    time.Time
+	OK      bool
+	Latency time.Duration
+	Error   string
+}
+
+// ring is a fixed-size buffer that overwrites its oldest item once full.
+type ring[T any] struct {
+	items []T
+	// next is the position the next item is written to; once the buffer
+	// is full it is also the position of the oldest item.
+	next int
+	full bool
+}
+
+func newRing[T any](size int) *ring[T] {
+	return &ring[T]{items: make([]T, max(size, 1))}
+}
+
+// push adds v, dropping the oldest item if the buffer is full.
+func (r *ring[T]) push(v T) {
+	r.items[r.next] = v
+	r.next = (r.next + 1) % len(r.items)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// all returns the items, oldest first.
+func (r *ring[T]) all() []T {
+	if !r.full {
+		return append([]T(nil), r.items[:r.next]...)
+	}
+	return append(append([]T(nil), r.items[r.next:]...), r.items[:r.next]...)
+}
+
+// resize returns a buffer of the given size holding the most recent items of
+// r.
+func (r *ring[T]) resize(size int) *ring[T] {
+	if size == len(r.items) {
+		return r
+	}
+	out := newRing[T](size)
+	items := r.all()
+	for _, v := range items[max(len(items)-len(out.items), 0):] {
+		out.push(v)
+	}
+	return out
+}
+
+// recordHistory adds a check result, keeping the most recent HISTORY_SIZE.
+func (t *target) recordHistory(e historyEntry) {
+	t.history.push(e)
+}
+
+// renderChecks lists up to limit of the most recent check results of t,
+// newest first; a limit of 0 lists all that are kept.
+func renderChecks(m model, t *target, limit int) string {
+	history := t.history.all()
+	if len(history) == 0 {
+		return "  " + unknownStyle.Render("no checks yet") + "\n"
+	}
+	if limit > 0 && len(history) > limit {
+		history = history[len(history)-limit:]
+	}
+	var b strings.Builder
+	for j := len(history) - 1; j >= 0; j-- {
+		e := history[j]
+		ts := m.formatTime(e.Time)
+		if e.OK {
+			b.WriteString(fmt.Sprintf("  %s %s %s\n", infoStyle.Render(glyphUp), ts, latencyStyle(t.latency, e.Latency, healthValueStyle).Render(fmt.Sprintf("%d ms", e.Latency.Milliseconds()))))
+		} else {
+			errLine, _, _ := strings.Cut(e.Error, "\n")
+			b.WriteString(fmt.Sprintf("  %s %s %s\n", downStyle.Render(glyphDown), ts, healthValueStyle.Render(truncate(errLine, tableErrorWidth*2))))
+		}
+	}
+	return b.String()
+}
+
+// renderHistoryPane renders the check history of the selected website.
+func renderHistoryPane(m model) string {
+	t := m.targets[m.selected]
+	return sectionTitle.Render("History of "+t.website+":") + "\n" + renderChecks(m, t, 0)
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Number of incidents listed in the overview
+const RecentIncidents = 5
+
+// incident is an outage of one website: from the check that marked it down
+// until the check that saw it recover. End is zero while it is ongoing.
+type incident struct {
+	Target string    `json:"target"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end,omitzero"`
+	Error  string    `json:"error"`
+}
+
+// ongoing reports whether the outage has not ended yet.
+func (i incident) ongoing() bool {
+	return i.End.IsZero()
+}
+
+// duration returns how long the outage lasted, or has lasted so far.
+func (i incident) duration(now time.Time) time.Duration {
+	if i.ongoing() {
+		return now.Sub(i.Start)
+	}
+	return i.End.Sub(i.Start)
+}
+
+// incidentLog records incidents in memory and, when path is set, persists
+// them as JSON so they survive restarts.
+type incidentLog struct {
+	path  string
+	items []incident
+	// mu serialises writes to path from concurrent save commands
+	mu sync.Mutex
+}
+
+// loadIncidents returns an incident log backed by path. A missing file is
+// not an error; an empty path keeps incidents in memory only.
+func loadIncidents(path string) (*incidentLog, error) {
+	l := &incidentLog{path: path}
+	if path == "" {
+		return l, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &l.items); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// open starts an incident for target unless one is already ongoing.
+func (l *incidentLog) open(target, errMsg string, t time.Time) bool {
+	if _, ok := l.current(target); ok {
+		return false
+	}
+	l.items = append(l.items, incident{Target: target, Start: t, Error: errMsg})
+	return true
+}
+
+// close ends the ongoing incident of target, if any.
+func (l *incidentLog) close(target string, t time.Time) bool {
+	idx, ok := l.current(target)
+	if !ok {
+		return false
+	}
+	l.items[idx].End = t
+	return true
+}
+
+// current returns the index of the ongoing incident of target.
+func (l *incidentLog) current(target string) (int, bool) {
+	for i := len(l.items) - 1; i >= 0; i-- {
+		if l.items[i].Target == target && l.items[i].ongoing() {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// recent returns up to n incidents, newest first. An empty target matches
+// every website.
+func (l *incidentLog) recent(target string, n int) []incident {
+	var out []incident
+	for i := len(l.items) - 1; i >= 0 && len(out) < n; i-- {
+		if target == "" || l.items[i].Target == target {
+			out = append(out, l.items[i])
+		}
+	}
+	return out
+}
+
+// count returns the number of incidents of target.
+func (l *incidentLog) count(target string) int {
+	n := 0
+	for _, inc := range l.items {
+		if inc.Target == target {
+			n++
+		}
+	}
+	return n
+}
+
+// downtime sums the duration of all incidents of target.
+func (l *incidentLog) downtime(target string, now time.Time) time.Duration {
+	var total time.Duration
+	for _, inc := range l.items {
+		if inc.Target == target {
+			total += inc.duration(now)
+		}
+	}
+	return total
+}
+
+// downtimeBetween sums how much of the period from since to now the
+// incidents of target covered.
+func (l *incidentLog) downtimeBetween(target string, since, now time.Time) time.Duration {
+	var total time.Duration
+	for _, inc := range l.items {
+		if inc.Target != target {
+			continue
+		}
+		start, end := inc.Start, inc.End
+		if inc.ongoing() || end.After(now) {
+			end = now
+		}
+		if start.Before(since) {
+			start = since
+		}
+		if d := end.Sub(start); d > 0 {
+			total += d
+		}
+	}
+	return total
+}
+
+// saveCmd returns a command that writes a copy of the log to its file, or
+// nil if the log is not persisted.
+func (l *incidentLog) saveCmd() tea.Cmd {
+	if l.path == "" {
+		return nil
+	}
+	items := append([]incident(nil), l.items...)
+	return func() tea.Msg {
+		if err := l.write(items); err != nil {
+			return storageErrMsg{err}
+		}
+		return nil
+	}
+}
+
+// write replaces the file atomically so a crash never leaves it truncated.
+func (l *incidentLog) write(items []incident) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	data, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(l.path, data, 0o600)
+}
+
+// writeFileAtomic replaces path with data through a temporary file, so
+// readers never see a partial file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// storageErrMsg reports a failed write of the history file.
+type storageErrMsg struct{ err error }
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mooship/vivteno/pkg/monitor"
+)
+
+// InfluxDB output settings
+const (
+	DefaultInfluxMeasurement = "vivteno"
+	DefaultInfluxInterval    = 10 * time.Second
+	// InfluxMaxPoints bounds the points buffered between writes, including
+	// those kept after a failed write; the oldest are dropped first.
+	InfluxMaxPoints = 10000
+	influxTimeout   = 10 * time.Second
+)
+
+// influxWriter pushes one point per check cycle to InfluxDB in line
+// protocol, through the v2 write API when a bucket is configured and the v1
+// API when a database is.
+type influxWriter struct {
+	url         string
+	token       string
+	username    string
+	password    string
+	measurement string
+	interval    time.Duration
+	client      *http.Client
+	logger      *slog.Logger
+
+	mu     sync.Mutex
+	points []string
+}
+
+// loadInflux reads the INFLUX_* settings. It returns nil when INFLUX_URL is
+// not set.
+func loadInflux(logger *slog.Logger) (*influxWriter, error) {
+	base := os.Getenv("INFLUX_URL")
+	if base == "" {
+		return nil, nil
+	}
+	if u, err := url.Parse(base); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid INFLUX_URL: %q", base)
+	}
+	w := &influxWriter{
+		token:       os.Getenv("INFLUX_TOKEN"),
+		username:    os.Getenv("INFLUX_USERNAME"),
+		password:    os.Getenv("INFLUX_PASSWORD"),
+		measurement: DefaultInfluxMeasurement,
+		interval:    DefaultInfluxInterval,
+		client:      &http.Client{Timeout: influxTimeout},
+		logger:      logger,
+	}
+	q := url.Values{"precision": {"ns"}}
+	bucket, database := os.Getenv("INFLUX_BUCKET"), os.Getenv("INFLUX_DATABASE")
+	switch {
+	case bucket != "" && database != "":
+		return nil, fmt.Errorf("invalid INFLUX_DATABASE: set INFLUX_BUCKET for InfluxDB 2 or INFLUX_DATABASE for InfluxDB 1, not both")
+	case bucket != "":
+		q.Set("bucket", bucket)
+		q.Set("org", os.Getenv("INFLUX_ORG"))
+		w.url = strings.TrimSuffix(base, "/") + "/api/v2/write?" + q.Encode()
+	case database != "":
+		q.Set("db", database)
+		if rp := os.Getenv("INFLUX_RETENTION_POLICY"); rp != "" {
+			q.Set("rp", rp)
+		}
+		w.url = strings.TrimSuffix(base, "/") + "/write?" + q.Encode()
+	default:
+		return nil, fmt.Errorf("invalid INFLUX_URL: set INFLUX_BUCKET (InfluxDB 2) or INFLUX_DATABASE (InfluxDB 1)")
+	}
+	if v := os.Getenv("INFLUX_MEASUREMENT"); v != "" {
+		w.measurement = v
+	}
+	if v := os.Getenv("INFLUX_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid INFLUX_INTERVAL: %q", v)
+		}
+		w.interval = d
+	}
+	return w, nil
+}
+
+// record adds a point for a check cycle of t, tagged with the state it led
+// to.
+func (w *influxWriter) record(t *target, r monitor.Report, ok bool) {
+	var b strings.Builder
+	b.WriteString(influxEscape(w.measurement, ", "))
+	for _, tag := range [][2]string{{"target", t.website}, {"check", t.checkType}, {"status", t.state.String()}} {
+		b.WriteString("," + tag[0] + "=" + influxEscape(tag[1], ",= "))
+	}
+	fields := []string{"ok=" + strconv.FormatBool(ok), "attempts=" + strconv.Itoa(r.Ping.Attempts) + "i"}
+	if r.Ping.Err == nil {
+		fields = append(fields, "latency_ms="+strconv.FormatFloat(float64(r.Ping.Latency)/float64(time.Millisecond), 'f', -1, 64))
+	}
+	if r.Ping.Probes != nil {
+		fields = append(fields, "packet_loss="+strconv.FormatFloat(r.Ping.Probes.Loss(), 'f', -1, 64))
+	}
+	if r.Maintenance {
+		fields = append(fields, "maintenance=true")
+	}
+	if !ok && t.lastError != "" {
+		fields = append(fields, `error="`+strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ").Replace(t.lastError)+`"`)
+	}
+	b.WriteString(" " + strings.Join(fields, ",") + " " + strconv.FormatInt(r.Time.UnixNano(), 10))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.points = append(w.points, b.String())
+	if n := len(w.points) - InfluxMaxPoints; n > 0 {
+		w.points = w.points[n:]
+	}
+}
+
+// run writes the buffered points every interval until ctx is cancelled,
+// then once more so the last checks are not lost.
+func (w *influxWriter) run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			final, cancel := context.WithTimeout(context.Background(), influxTimeout)
+			w.flush(final)
+			cancel()
+			return
+		case <-ticker.C:
+			w.flush(ctx)
+		}
+	}
+}
+
+// flush writes the buffered points. Points of a failed write are kept for
+// the next one.
+func (w *influxWriter) flush(ctx context.Context) {
+	w.mu.Lock()
+	points := w.points
+	w.points = nil
+	w.mu.Unlock()
+	if len(points) == 0 {
+		return
+	}
+	if err := w.write(ctx, points); err != nil {
+		w.logger.Error("writing to InfluxDB failed", "points", len(points), "error", err.Error())
+		w.mu.Lock()
+		w.points = append(points, w.points...)
+		if n := len(w.points) - InfluxMaxPoints; n > 0 {
+			w.points = w.points[n:]
+		}
+		w.mu.Unlock()
+	}
+}
+
+func (w *influxWriter) write(ctx context.Context, points []string) error {
+	body := strings.Join(points, "\n") + "\n"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader([]byte(body)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if w.token != "" {
+		req.Header.Set("Authorization", "Token "+w.token)
+	} else if w.username != "" {
+		req.SetBasicAuth(w.username, w.password)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// influxEscape backslash-escapes the characters of s that are special in
+// its position of a line protocol point.
+func influxEscape(s, special string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(special, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
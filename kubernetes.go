@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mooship/vivteno/pkg/monitor"
+)
+
+// Kubernetes discovery settings
+const (
+	DefaultKubernetesRefresh = time.Minute
+	// KubernetesTimeout bounds one round of API requests.
+	KubernetesTimeout = 10 * time.Second
+	// KubernetesServiceAccount is where a pod's API token and CA certificate
+	// are mounted.
+	KubernetesServiceAccount = "/var/run/secrets/kubernetes.io/serviceaccount"
+)
+
+// Kinds of Kubernetes objects websites are discovered from
+const (
+	kindServices  = "services"
+	kindIngresses = "ingresses"
+)
+
+// kubernetesDiscovery finds websites among the Services and Ingresses of a
+// Kubernetes cluster that match a label selector.
+type kubernetesDiscovery struct {
+	api       string
+	tokenFile string
+	namespace string
+	selector  string
+	kinds     []string
+	refresh   time.Duration
+	client    *http.Client
+}
+
+// loadKubernetes reads the KUBERNETES_* settings. It returns nil when
+// KUBERNETES_DISCOVERY is not set.
+func loadKubernetes() (*kubernetesDiscovery, error) {
+	v := os.Getenv("KUBERNETES_DISCOVERY")
+	if v == "" {
+		return nil, nil
+	}
+	d := &kubernetesDiscovery{
+		namespace: os.Getenv("KUBERNETES_NAMESPACE"),
+		selector:  os.Getenv("KUBERNETES_LABEL_SELECTOR"),
+		refresh:   DefaultKubernetesRefresh,
+		tokenFile: filepath.Join(KubernetesServiceAccount, "token"),
+	}
+	for _, kind := range parseList(v) {
+		switch strings.ToLower(kind) {
+		case "services", "service", "svc":
+			kind = kindServices
+		case "ingresses", "ingress", "ing":
+			kind = kindIngresses
+		default:
+			return nil, fmt.Errorf("invalid KUBERNETES_DISCOVERY: %q (expected services, ingresses or both)", kind)
+		}
+		if !slices.Contains(d.kinds, kind) {
+			d.kinds = append(d.kinds, kind)
+		}
+	}
+	if v := os.Getenv("KUBERNETES_REFRESH"); v != "" {
+		var err error
+		if d.refresh, err = time.ParseDuration(v); err != nil || d.refresh <= 0 {
+			return nil, fmt.Errorf("invalid KUBERNETES_REFRESH: %q", v)
+		}
+	}
+
+	// Outside a cluster the API is usually reached through kubectl proxy,
+	// which authenticates on our behalf.
+	d.api = os.Getenv("KUBERNETES_API")
+	if d.api == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, fmt.Errorf("invalid KUBERNETES_DISCOVERY: not running in a cluster, set KUBERNETES_API")
+		}
+		d.api = "https://" + net.JoinHostPort(host, port)
+	}
+	if u, err := url.Parse(d.api); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid KUBERNETES_API: %q", d.api)
+	}
+	d.api = strings.TrimSuffix(d.api, "/")
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	ca, err := os.ReadFile(filepath.Join(KubernetesServiceAccount, "ca.crt"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("reading the Kubernetes CA certificate: %w", err)
+	}
+	if len(ca) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("reading the Kubernetes CA certificate: no certificates found")
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	d.client = &http.Client{Transport: transport, Timeout: KubernetesTimeout}
+	return d, nil
+}
+
+// discover implements discoverer by querying the cluster.
+func (d *kubernetesDiscovery) discover(ctx context.Context) ([]discoveredSite, error) {
+	ctx, cancel := context.WithTimeout(ctx, KubernetesTimeout)
+	defer cancel()
+	var sites []string
+	for _, kind := range d.kinds {
+		var found []string
+		var err error
+		switch kind {
+		case kindServices:
+			var list serviceList
+			err = d.list(ctx, "/api/v1", kind, &list)
+			found = list.sites()
+		case kindIngresses:
+			var list ingressList
+			err = d.list(ctx, "/apis/networking.k8s.io/v1", kind, &list)
+			found = list.sites()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("listing %s: %w", kind, err)
+		}
+		sites = append(sites, found...)
+	}
+	out := make([]discoveredSite, len(sites))
+	for i, site := range sites {
+		out[i] = discoveredSite{website: site}
+	}
+	return out, nil
+}
+
+// list fetches the objects of a kind, in KUBERNETES_NAMESPACE or in every
+// namespace, that match the label selector.
+func (d *kubernetesDiscovery) list(ctx context.Context, group, kind string, into any) error {
+	path := group + "/" + kind
+	if d.namespace != "" {
+		path = group + "/namespaces/" + url.PathEscape(d.namespace) + "/" + kind
+	}
+	q := url.Values{}
+	if d.selector != "" {
+		q.Set("labelSelector", d.selector)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.api+path+"?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	// The token is re-read for every request because it is rotated
+	if token, err := os.ReadFile(d.tokenFile); err == nil {
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(into)
+}
+
+// serviceList holds the fields of a Service list that discovery uses.
+type serviceList struct {
+	Items []struct {
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Spec struct {
+			Type  string `json:"type"`
+			Ports []struct {
+				Name     string `json:"name"`
+				Port     int    `json:"port"`
+				Protocol string `json:"protocol"`
+			} `json:"ports"`
+		} `json:"spec"`
+	} `json:"items"`
+}
+
+// sites returns the cluster-internal URL of each Service, using its first
+// TCP port. Ports named https or numbered 443 are checked over HTTPS.
+func (l serviceList) sites() []string {
+	var sites []string
+	for _, svc := range l.Items {
+		if svc.Spec.Type == "ExternalName" {
+			continue
+		}
+		for _, p := range svc.Spec.Ports {
+			if p.Protocol != "" && p.Protocol != "TCP" {
+				continue
+			}
+			scheme := monitor.SchemeHTTP
+			if p.Port == 443 || strings.HasPrefix(p.Name, "https") {
+				scheme = monitor.SchemeHTTPS
+			}
+			host := svc.Metadata.Name + "." + svc.Metadata.Namespace + ".svc"
+			sites = append(sites, scheme+"://"+net.JoinHostPort(host, strconv.Itoa(p.Port)))
+			break
+		}
+	}
+	return sites
+}
+
+// ingressList holds the fields of an Ingress list that discovery uses.
+type ingressList struct {
+	Items []struct {
+		Spec struct {
+			TLS []struct {
+				Hosts []string `json:"hosts"`
+			} `json:"tls"`
+			Rules []struct {
+				Host string `json:"host"`
+			} `json:"rules"`
+		} `json:"spec"`
+	} `json:"items"`
+}
+
+// sites returns a URL for each host an Ingress routes, over HTTPS when the
+// Ingress terminates TLS for it. Wildcard hosts are skipped.
+func (l ingressList) sites() []string {
+	var sites []string
+	for _, ing := range l.Items {
+		for _, rule := range ing.Spec.Rules {
+			if rule.Host == "" || strings.HasPrefix(rule.Host, "*") {
+				continue
+			}
+			scheme := monitor.SchemeHTTP
+			for _, t := range ing.Spec.TLS {
+				if slices.Contains(t.Hosts, rule.Host) {
+					scheme = monitor.SchemeHTTPS
+				}
+			}
+			sites = append(sites, scheme+"://"+rule.Host)
+		}
+	}
+	return sites
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Layout of the website list
+const (
+	// DefaultWidth is the width assumed until the terminal reports its size.
+	DefaultWidth = 40
+	// cardMinWidth is the narrowest column of the multi-column grid; below
+	// twice this width websites are listed in a single column.
+	cardMinWidth = 60
+	// cardGap is the space between grid columns.
+	cardGap = 4
+	// tableFixedWidth is the width of the table columns before the last error.
+	tableFixedWidth = 4 + tableWebsiteWidth + tableLatencyWidth + tableUptimeWidth + tableTrendWidth + tableNextWidth
+	// tableErrorMinWidth is the narrowest last error column.
+	tableErrorMinWidth = 20
+)
+
+// lineWidth returns the width to render for: the terminal width once known,
+// DefaultWidth before.
+func (m model) lineWidth() int {
+	if m.width > 0 {
+		return m.width
+	}
+	return DefaultWidth
+}
+
+// columns returns the number of grid columns the website list uses, and
+// the width of each.
+func (m model) columns() (n, width int) {
+	if m.width < 2*cardMinWidth+cardGap {
+		return 1, m.width
+	}
+	n = min((m.width+cardGap)/(cardMinWidth+cardGap), len(m.targets))
+	return n, (m.width - cardGap*(n-1)) / n
+}
+
+// separator returns the line drawn between websites, spanning the terminal.
+func (m model) separator() string {
+	return strings.Repeat("-", m.lineWidth())
+}
+
+// errorWidth returns the width of the last error column of the table, which
+// takes up the rest of the terminal.
+func (m model) errorWidth() int {
+	if m.width == 0 {
+		return tableErrorWidth
+	}
+	return max(m.width-tableFixedWidth, tableErrorMinWidth)
+}
+
+// footer renders the keybinding help, wrapped to the terminal width.
+func (m model) footer(help string) string {
+	if m.width == 0 {
+		return footerStyle.Render(help)
+	}
+	return footerStyle.Width(m.width).Render(help)
+}
+
+// renderGrid lays out cards in rows of n columns of the given width,
+// separating rows with sep.
+func renderGrid(cards []string, n, width int, sep string) string {
+	cell := lipgloss.NewStyle().Width(width)
+	gap := strings.Repeat(" ", cardGap)
+	var rows []string
+	for i := 0; i < len(cards); i += n {
+		var cols []string
+		for j, card := range cards[i:min(i+n, len(cards))] {
+			if j > 0 {
+				cols = append(cols, gap)
+			}
+			cols = append(cols, cell.Render(fit(strings.TrimRight(card, "\n"), width)))
+		}
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, cols...))
+	}
+	return strings.Join(rows, "\n"+sep+"\n\n") + "\n"
+}
+
+// fit truncates every line of s to width cells, keeping styles intact and
+// marking cuts with an ellipsis. A width of 0 leaves s unchanged.
+func fit(s string, width int) string {
+	if width <= 0 {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		if ansi.StringWidth(l) > width {
+			// Padding is dropped rather than cut with an ellipsis
+			lines[i] = ansi.Truncate(strings.TrimRight(l, " "), width, "…")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Logging defaults
+const (
+	DefaultLogLevel  = "info"
+	DefaultLogFormat = "json"
+	// DefaultLogMaxSize is the size in megabytes at which LOG_FILE is rotated.
+	DefaultLogMaxSize = 10
+	// DefaultLogMaxBackups is the number of rotated files kept.
+	DefaultLogMaxBackups = 5
+)
+
+// LogStderr as LOG_FILE writes the log to standard error.
+const LogStderr = "stderr"
+
+// loadLogger builds the event log from the LOG_* settings. Without LOG_FILE
+// events are discarded. The returned closer releases the log file.
+func loadLogger() (*slog.Logger, io.Closer, error) {
+	path := os.Getenv("LOG_FILE")
+	if path == "" {
+		return slog.New(slog.DiscardHandler), nopWriteCloser{io.Discard}, nil
+	}
+
+	level := os.Getenv("LOG_LEVEL")
+	if level == "" {
+		level = DefaultLogLevel
+	}
+	var opts slog.HandlerOptions
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, nil, fmt.Errorf("invalid LOG_LEVEL: %q (expected debug, info, warn or error)", level)
+	}
+	opts.Level = lvl
+
+	format := strings.ToLower(os.Getenv("LOG_FORMAT"))
+	if format == "" {
+		format = DefaultLogFormat
+	}
+	if format != "json" && format != "text" {
+		return nil, nil, fmt.Errorf("invalid LOG_FORMAT: %q (expected json or text)", format)
+	}
+
+	var w io.WriteCloser
+	if path == LogStderr {
+		w = nopWriteCloser{os.Stderr}
+	} else {
+		maxSize, err := parseLogInt("LOG_MAX_SIZE", DefaultLogMaxSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		backups, err := parseLogInt("LOG_MAX_BACKUPS", DefaultLogMaxBackups)
+		if err != nil {
+			return nil, nil, err
+		}
+		f, err := openRotatingFile(path, int64(maxSize)<<20, backups)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid LOG_FILE: %w", err)
+		}
+		w = f
+	}
+
+	var h slog.Handler = slog.NewJSONHandler(w, &opts)
+	if format == "text" {
+		h = slog.NewTextHandler(w, &opts)
+	}
+	return slog.New(h), w, nil
+}
+
+// parseLogInt reads a non-negative integer setting.
+func parseLogInt(name string, def int) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s: %q", name, v)
+	}
+	return n, nil
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// rotatingFile is a log file that is renamed to path.1 once it reaches
+// maxSize, shifting older files up to path.<backups>. A maxSize of 0 never
+// rotates.
+type rotatingFile struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	backups int
+	f       *os.File
+	size    int64
+}
+
+func openRotatingFile(path string, maxSize int64, backups int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize, backups: backups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open appends to the file at path, creating it if needed.
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f, r.size = f, fi.Size()
+	return nil
+}
+
+// Write implements io.Writer. Each record is written whole, so a rotation
+// never splits one across files.
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate moves the current file to path.1, dropping the oldest backup. If
+// the file cannot be moved, writing continues at its end.
+func (r *rotatingFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+	if r.backups == 0 {
+		_ = os.Remove(r.path)
+	} else {
+		for i := r.backups - 1; i >= 1; i-- {
+			_ = os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+		}
+		_ = os.Rename(r.path, r.path+".1")
+	}
+	return r.open()
+}
+
+// Close implements io.Closer.
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
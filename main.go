@@ -2,29 +2,39 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"flag"
 	"fmt"
-	"io"
+	"log/slog"
 	"net"
-	"net/http"
 	"os"
 	"os/signal"
-	"regexp"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/joho/godotenv"
+	"github.com/mooship/vivteno/pkg/monitor"
 )
 
 // Constants for configuration and timeouts
 const (
-	DefaultSchedule     = "10s"
-	DefaultTCPTimeout   = 5 * time.Second
-	DefaultSleepBackoff = 10 * time.Second
-	DefaultTCPPort      = "80"
-	HTTPSScheme         = "https://"
+	DefaultSchedule = "10s"
+
+	// Interval between checks of a failing website, unless PING_SCHEDULE is shorter
+	DefaultDownSchedule = 5 * time.Second
+
+	// Consecutive failed checks before a website is marked down
+	DefaultFailureThreshold = 3
+
+	// Minimum time between notifications of a notifier for the same website
+	DefaultNotifyCooldown = 5 * time.Minute
+
+	// Health fields shown first, in this order, unless HEALTH_FIELD_ORDER is set
+	DefaultHealthFieldOrder = "status,version,uptime"
 
 	// Time format layouts
 	DisplayTimeFormat = "2006-01-02 15:04:05 MST"
@@ -35,44 +45,35 @@ const (
 	TimestampField3 = "date"
 )
 
-// --- Styles ---
-var (
-	headerStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("15")). // bright white
-			Background(lipgloss.Color("0")).  // black
-			Padding(0, 2).
-			MarginBottom(1)
-
-	sectionTitle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("7")). // light gray
-			PaddingRight(1)
-
-	infoStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("10")) // green
-
-	sectionBox = lipgloss.NewStyle().
-			PaddingLeft(0).
-			PaddingRight(0)
-
-	errorStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("9")). // red
-			Bold(true).
-			Padding(0, 1).
-			MarginTop(1)
-
-	healthKeyStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("7")). // light gray
-			Bold(true)
-
-	healthValueStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("15")) // white
-
-	footerStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("8")). // gray
-			Padding(1, 0).
-			MarginTop(1)
+// Number of latency samples kept per website for the sparkline
+const SparklineSamples = 20
+
+// sparkLevels are the glyphs used to draw latency sparklines, lowest first.
+var sparkLevels = []rune("▁▂▃▄▅▆▇█")
+
+// Footer texts listing the available keybindings
+const (
+	footerHelp       = "↑/↓ or j/k select, v/Space select several, Enter details, r/R re-check selected/all, p/P pause/resume selected/all, m mute, t table view, ? all keys, q or Ctrl+C quit."
+	detailFooterHelp = "Esc back, ↑/↓ or j/k switch site, r re-check, p pause/resume, m mute/unmute, ? all keys, q or Ctrl+C quit."
+)
+
+// Column widths and glyphs for the compact table view
+const (
+	tableWebsiteWidth = 28
+	tableLatencyWidth = 10
+	tableUptimeWidth  = 9
+	tableTrendWidth   = SparklineSamples + 2
+	tableNextWidth    = 9
+	tableErrorWidth   = 40
+
+	glyphUp       = "✓"
+	glyphDegraded = "!"
+	glyphDown     = "✗"
+	glyphUnknown  = "•"
+	glyphMaint    = "⚒"
+	glyphSlow     = "~"
+	glyphPaused   = "‖"
+	glyphFlapping = "↯"
 )
 
 // --- Helper functions for rendering sections ---
@@ -80,137 +81,699 @@ func renderSection(title, value string) string {
 	return fmt.Sprintf("%s %s", sectionTitle.Render(title), infoStyle.Render(value))
 }
 
-func renderHealthSection(data map[string]any, tz *time.Location) string {
+func renderHealthSection(data map[string]any, order []string, tz *time.Location, changes healthChanges) string {
 	var lines []string
 	lines = append(lines, sectionTitle.Render("Health Endpoint:"))
-	for k, v := range data {
-		if s, ok := v.(string); ok && (k == TimestampField1 || k == TimestampField2 || k == TimestampField3) {
-			if tz != nil {
-				if t, err := time.Parse(time.RFC3339, s); err == nil {
-					s = t.In(tz).Format(DisplayTimeFormat)
-				}
-			}
-			lines = append(lines, fmt.Sprintf("  %s %s", healthKeyStyle.Render(k+":"), healthValueStyle.Render(s)))
-		} else {
-			lines = append(lines, fmt.Sprintf("  %s %s", healthKeyStyle.Render(k+":"), healthValueStyle.Render(fmt.Sprintf("%v", v))))
+	for _, k := range healthKeys(data, order) {
+		v := data[k]
+		value := fmt.Sprintf("%v", v)
+		if s, ok := v.(string); ok {
+			value = formatTimestamp(k, s, tz)
 		}
+		lines = append(lines, fmt.Sprintf("  %s %s", healthKeyStyle.Render(k+":"), changes.value(k, value)))
 	}
 	return strings.Join(lines, "\n")
 }
 
-// --- Bubble Tea Model Methods ---
-func (m model) Init() tea.Cmd {
-	cmds := make([]tea.Cmd, len(m.websites))
-	for i, website := range m.websites {
-		cmds[i] = pingWebsiteCmdWithContext(m.ctx, website, i)
+// renderHealthFields renders only the configured JSONPath fields of a health
+// response. Object values are expanded as indented sub-fields.
+func renderHealthFields(data map[string]any, paths []monitor.Path, order []string, tz *time.Location, changes healthChanges) string {
+	var lines []string
+	lines = append(lines, sectionTitle.Render("Health Endpoint:"))
+	for _, p := range paths {
+		v, err := p.Lookup(data)
+		if err != nil {
+			lines = append(lines, fmt.Sprintf("  %s %s", healthKeyStyle.Render(p.Label()+":"), unknownStyle.Render("n/a")))
+			continue
+		}
+		lines = appendHealthValue(lines, p.Label(), p.Label(), v, 1, order, tz, changes)
 	}
-	return tea.Batch(cmds...)
+	return strings.Join(lines, "\n")
 }
 
-func schedulePing(schedule string, idx int) tea.Cmd {
-	dur, err := time.ParseDuration(schedule)
-	if err != nil {
-		dur = DefaultSleepBackoff
+// appendHealthValue appends a key/value line at the given indent level,
+// recursing into nested objects with their keys ordered by healthKeys. The
+// value is highlighted when the field at path changed.
+func appendHealthValue(lines []string, path, key string, v any, indent int, order []string, tz *time.Location, changes healthChanges) []string {
+	pad := strings.Repeat("  ", indent)
+	obj, ok := v.(map[string]any)
+	if !ok {
+		s := fmt.Sprintf("%v", v)
+		if str, isStr := v.(string); isStr {
+			s = formatTimestamp(key, str, tz)
+		}
+		return append(lines, fmt.Sprintf("%s%s %s", pad, healthKeyStyle.Render(key+":"), changes.value(path, s)))
 	}
-	return func() tea.Msg {
-		time.Sleep(dur)
-		return tickMsgWithIndex{Time: time.Now(), Index: idx}
+	lines = append(lines, pad+healthKeyStyle.Render(key+":"))
+	for _, k := range healthKeys(obj, order) {
+		lines = appendHealthValue(lines, path+"."+k, k, obj[k], indent+1, order, tz, changes)
 	}
+	return lines
 }
 
-type tickMsgWithIndex struct {
-	Time  time.Time
-	Index int
+// healthKeys returns the keys of a health object in display order: keys
+// named in order come first, in that order, followed by the rest sorted
+// alphabetically, so fields keep their place across refreshes.
+func healthKeys(obj map[string]any, order []string) []string {
+	keys := make([]string, 0, len(obj))
+	for _, k := range order {
+		if _, ok := obj[k]; ok && !slices.Contains(keys, k) {
+			keys = append(keys, k)
+		}
+	}
+	prioritized := len(keys)
+	for k := range obj {
+		if !slices.Contains(keys[:prioritized], k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys[prioritized:])
+	return keys
 }
 
-func pingWebsiteCmdWithContext(ctx context.Context, website string, idx int) tea.Cmd {
-	return func() tea.Msg {
-		start := time.Now()
-		dialer := &net.Dialer{Timeout: DefaultTCPTimeout}
-		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(website, DefaultTCPPort))
-		if err != nil {
-			return pingResultWithIndex{Result: "", Err: err, Index: idx}
-		}
-		_ = conn.Close()
-		elapsed := time.Since(start)
-		result := fmt.Sprintf(
-			"Ping to %s:\n  TCP connect successful\n  Time: %v ms",
-			website,
-			elapsed.Milliseconds(),
-		)
-		return pingResultWithIndex{Result: result, Err: nil, Index: idx}
+// formatTimestamp converts RFC 3339 values of well-known timestamp fields to
+// the display timezone. Other values are returned unchanged.
+func formatTimestamp(key, s string, tz *time.Location) string {
+	key = key[strings.LastIndex(key, ".")+1:]
+	if tz == nil || (key != TimestampField1 && key != TimestampField2 && key != TimestampField3) {
+		return s
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.In(tz).Format(DisplayTimeFormat)
 	}
+	return s
 }
 
-func fetchHealthCmdWithContext(ctx context.Context, website, healthEndpoint string, idx int) tea.Cmd {
-	return func() tea.Msg {
-		if healthEndpoint == "" {
-			return healthResultGenericWithIndex{Data: nil, Err: fmt.Errorf("health endpoint not configured"), Index: idx}
+// renderMaintenance describes the active or next maintenance window.
+func renderMaintenance(windows []monitor.Window, tz *time.Location) string {
+	now := time.Now()
+	if tz != nil {
+		now = now.In(tz)
+	}
+	if until, ok := monitor.InMaintenance(windows, now); ok {
+		return fmt.Sprintf("%s %s", sectionTitle.Render("Maintenance:"),
+			maintenanceStyle.Render("in progress until "+until.In(now.Location()).Format(DisplayTimeFormat)))
+	}
+	if start, end, ok := monitor.NextMaintenance(windows, now); ok {
+		return renderSection("Maintenance:", fmt.Sprintf("next %s - %s",
+			start.In(now.Location()).Format(DisplayTimeFormat), end.In(now.Location()).Format(DisplayTimeFormat)))
+	}
+	return renderSection("Maintenance:", "none scheduled")
+}
+
+// renderFamilies shows the latest result of each IP family of a dual-stack
+// website.
+func renderFamilies(results []monitor.FamilyResult) string {
+	var b strings.Builder
+	for i, r := range results {
+		if i > 0 {
+			b.WriteString("\n")
 		}
-		url := HTTPSScheme + website + healthEndpoint
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-		if err != nil {
-			return healthResultGenericWithIndex{Data: nil, Err: err, Index: idx}
+		title := sectionTitle.Render(monitor.FamilyLabel(r.Family) + ":")
+		if r.Err != nil {
+			b.WriteString(title + " " + downStyle.Render(glyphDown+" "+resultError(r.Result)))
+		} else {
+			b.WriteString(title + " " + infoStyle.Render(fmt.Sprintf("%s %d ms", glyphUp, r.Latency.Milliseconds())))
 		}
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return healthResultGenericWithIndex{Data: nil, Err: err, Index: idx}
+	}
+	return b.String()
+}
+
+// renderPorts shows the latest result of each port of a website with
+// several ports.
+func renderPorts(results []monitor.PortResult) string {
+	var b strings.Builder
+	for i, r := range results {
+		if i > 0 {
+			b.WriteString("\n")
 		}
-		defer resp.Body.Close()
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return healthResultGenericWithIndex{Data: nil, Err: err, Index: idx}
+		title := sectionTitle.Render("Port " + r.Port + ":")
+		if r.Err != nil {
+			b.WriteString(title + " " + downStyle.Render(glyphDown+" "+resultError(r.Result)))
+		} else {
+			b.WriteString(title + " " + infoStyle.Render(fmt.Sprintf("%s %d ms", glyphUp, r.Latency.Milliseconds())))
+		}
+	}
+	return b.String()
+}
+
+// renderCertChain shows the TLS session of the last check: whether the chain
+// validates and against which roots, each certificate the server sent, and
+// the stapled OCSP status.
+func renderCertChain(m model, info *monitor.TLSInfo) string {
+	var b strings.Builder
+	status := infoStyle.Render(glyphUp + " valid against " + info.Roots)
+	if info.VerifyError != "" {
+		status = downStyle.Render(glyphDown + " invalid against " + info.Roots + ": " + info.VerifyError)
+	}
+	b.WriteString(sectionTitle.Render("Certificate chain:") + " " + status + "\n")
+	now := time.Now()
+	for i, c := range info.Certificates {
+		validity := fmt.Sprintf("%s to %s", m.formatTime(c.NotBefore), m.formatTime(c.NotAfter))
+		switch {
+		case now.Before(c.NotBefore):
+			validity = downStyle.Render(validity + " (not yet valid)")
+		case now.After(c.NotAfter):
+			validity = downStyle.Render(validity + " (expired)")
+		default:
+			validity = healthValueStyle.Render(fmt.Sprintf("%s (%d days left)", validity, daysUntil(c.NotAfter)))
 		}
-		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			return healthResultGenericWithIndex{Data: nil, Err: fmt.Errorf("health endpoint HTTP %d: %s", resp.StatusCode, string(body)), Index: idx}
+		b.WriteString(fmt.Sprintf("  %s %s\n", healthKeyStyle.Render(fmt.Sprintf("%d.", i+1)), healthValueStyle.Render(c.Subject)))
+		b.WriteString("     " + healthKeyStyle.Render("Issuer:") + " " + healthValueStyle.Render(c.Issuer) + "\n")
+		b.WriteString("     " + healthKeyStyle.Render("Valid:") + " " + validity + "\n")
+		b.WriteString("     " + healthKeyStyle.Render("Key:") + " " + healthValueStyle.Render(c.Key) + "\n")
+	}
+	var ocsp string
+	switch info.OCSP {
+	case monitor.OCSPGood:
+		ocsp = infoStyle.Render(glyphUp + " good")
+		if !info.OCSPNextUpdate.IsZero() {
+			ocsp += healthValueStyle.Render(", next update " + m.formatTime(info.OCSPNextUpdate))
 		}
-		var data map[string]any
-		if err := json.Unmarshal(body, &data); err != nil {
-			return healthResultGenericWithIndex{Data: nil, Err: fmt.Errorf("invalid JSON from health endpoint: %w\nBody: %s", err, string(body)), Index: idx}
+	case monitor.OCSPRevoked:
+		ocsp = downStyle.Render(glyphDown + " revoked " + m.formatTime(info.OCSPRevokedAt))
+	case monitor.OCSPNone:
+		ocsp = unknownStyle.Render(info.OCSP)
+	default:
+		ocsp = degradedStyle.Render(glyphDegraded + " " + info.OCSP)
+	}
+	b.WriteString(sectionTitle.Render("OCSP stapling:") + " " + ocsp + "\n")
+	b.WriteString(sectionTitle.Render("Protocol:") + " " + healthValueStyle.Render(info.Version+", "+info.CipherSuite))
+	return b.String()
+}
+
+// renderCheckStates shows the state of the check and of the health endpoint of
+// a website, which are checked independently.
+func renderCheckStates(t *target) string {
+	line := func(title, err string) string {
+		if err != "" {
+			return sectionTitle.Render(title) + " " + downStyle.Render(glyphDown+" "+err)
 		}
-		return healthResultGenericWithIndex{Data: data, Err: nil, Index: idx}
+		return sectionTitle.Render(title) + " " + infoStyle.Render(glyphUp+" OK")
+	}
+	return line("Ping:", t.pingError) + "\n" + line("Health endpoint:", t.healthError)
+}
+
+// latencyStyle returns the style of a latency: yellow from the warning and red
+// from the critical threshold, base below them.
+func latencyStyle(l latencyThresholds, d time.Duration, base lipgloss.Style) lipgloss.Style {
+	switch l.level(d) {
+	case "critical":
+		return downStyle
+	case "warning":
+		return degradedStyle
+	}
+	return base
+}
+
+// statusGlyph returns the colored glyph of a status.
+func statusGlyph(st status) string {
+	switch st {
+	case statusDown:
+		return downStyle.Render(glyphDown)
+	case statusDegraded:
+		return degradedStyle.Render(glyphDegraded)
+	case statusSlow:
+		return degradedStyle.Render(glyphSlow)
+	case statusMaintenance:
+		return maintenanceStyle.Render(glyphMaint)
+	case statusUp:
+		return infoStyle.Render(glyphUp)
+	}
+	return unknownStyle.Render(glyphUnknown)
+}
+
+// renderViolations lists the health assertions a website's last response
+// violated.
+func renderViolations(violations []string) string {
+	var b strings.Builder
+	for _, v := range violations {
+		b.WriteString(warningStyle.Render("ASSERTION FAILED: "+v) + "\n")
 	}
+	return b.String()
 }
 
-type pingResultWithIndex struct {
-	Result string
-	Err    error
-	Index  int
+// pausedBadge marks a website whose checks are paused, or is empty.
+func pausedBadge(t *target) string {
+	if !t.paused {
+		return ""
+	}
+	return " " + pausedStyle.Render("PAUSED")
 }
 
-type healthResultGenericWithIndex struct {
-	Data  map[string]any
-	Err   error
-	Index int
+// statusStyle returns the style used for text describing a status.
+func statusStyle(st status) lipgloss.Style {
+	switch st {
+	case statusDown:
+		return downStyle
+	case statusDegraded, statusSlow:
+		return degradedStyle
+	case statusMaintenance:
+		return maintenanceStyle
+	case statusUp:
+		return infoStyle
+	}
+	return unknownStyle
+}
+
+// summaryOrder lists statuses from worst to best, as shown in the summary bar.
+var summaryOrder = []status{statusDown, statusDegraded, statusSlow, statusMaintenance, statusUnknown, statusUp}
+
+// overall returns the worst state of any website, in summaryOrder.
+func (m model) overall() status {
+	for _, st := range summaryOrder {
+		for _, t := range m.targets {
+			if t.state == st {
+				return st
+			}
+		}
+	}
+	return statusUp
+}
+
+// renderSummary renders the status bar: the number of websites in each
+// state, followed by the worst current state. Up, down and unknown are
+// always listed; degraded, slow and maintenance only when they occur, as are
+// quiet hours.
+func renderSummary(m model) string {
+	counts := make(map[status]int)
+	for _, t := range m.targets {
+		counts[t.state]++
+	}
+	worst := m.overall()
+	var parts []string
+	for _, st := range []status{statusUp, statusSlow, statusDegraded, statusDown, statusMaintenance, statusUnknown} {
+		if counts[st] == 0 && (st == statusDegraded || st == statusSlow || st == statusMaintenance) {
+			continue
+		}
+		parts = append(parts, statusGlyph(st)+" "+statusStyle(st).Render(fmt.Sprintf("%d %s", counts[st], st)))
+	}
+	if n := m.pausedCount(); n > 0 {
+		parts = append(parts, unknownStyle.Render(fmt.Sprintf("%s %d paused", glyphPaused, n)))
+	}
+	if n := m.flappingCount(); n > 0 {
+		parts = append(parts, degradedStyle.Render(fmt.Sprintf("%s %d flapping", glyphFlapping, n)))
+	}
+	if m.quiet.active(time.Now()) {
+		parts = append(parts, mutedStyle.Render("quiet hours"))
+	}
+	overall := sectionTitle.Render("Overall:") + statusStyle(worst).Render(strings.ToUpper(worst.String()))
+	return strings.Join(parts, "   ") + "   " + overall
+}
+
+// renderTable renders the compact dashboard: one row per website with a status
+// glyph, the last latency, uptime percentage, latency trend and the
+// (truncated) last error. Grouped websites are listed under their section
+// header.
+func renderTable(m model) string {
+	var lines []string
+	lines = append(lines, sectionTitle.Render(
+		cursor(false)+"  "+tableCell("Website", tableWebsiteWidth)+tableCell("Latency", tableLatencyWidth)+tableCell("Uptime", tableUptimeWidth)+tableCell("Trend", tableTrendWidth)+tableCell("Next", tableNextWidth)+"Last error",
+	))
+	for _, g := range m.groups() {
+		if g.name != "" {
+			lines = append(lines, renderGroupHeader(m, g))
+			if m.isCollapsed(g) {
+				continue
+			}
+		}
+		for _, i := range g.targets {
+			lines = append(lines, renderTableRow(m, i, m.targets[i]))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// tableCell pads s to a table column of the given width, truncating it to
+// leave a space before the next column.
+func tableCell(s string, width int) string {
+	return lipgloss.NewStyle().Width(width).Render(truncate(s, width-1))
+}
+
+// renderTableRow renders the table row of the website at i.
+func renderTableRow(m model, i int, t *target) string {
+	glyph := statusGlyph(t.state)
+	if t.paused {
+		glyph = unknownStyle.Render(glyphPaused)
+	}
+	latency := tableCell("-", tableLatencyWidth)
+	uptime := "-"
+	if t.lastPing != "" {
+		latency = latencyStyle(t.latency, t.lastLatency, lipgloss.NewStyle()).Width(tableLatencyWidth).
+			Render(fmt.Sprintf("%d ms", t.lastLatency.Milliseconds()))
+	}
+	if u := t.uptime(); u >= 0 {
+		uptime = fmt.Sprintf("%.1f%%", u)
+	}
+	lastErr, _, _ := strings.Cut(t.lastError, "\n")
+	if lastErr == "" && len(t.violations) > 0 {
+		lastErr = "assertion failed: " + t.violations[0]
+	}
+	if t.flapping {
+		lastErr = "flapping: " + lastErr
+	}
+	if t.paused {
+		lastErr = "paused"
+	}
+	return m.marker(i) + glyph + " " +
+		tableCell(t.website, tableWebsiteWidth) +
+		latency +
+		tableCell(uptime, tableUptimeWidth) +
+		tableCell(sparkline(t.latencyHistory), tableTrendWidth) +
+		unknownStyle.Width(tableNextWidth).Render(truncate(m.countdown(i, t), tableNextWidth-1)) +
+		truncate(lastErr, m.errorWidth())
+}
+
+// sparkline renders latency samples as a unicode sparkline scaled between the
+// smallest and largest sample.
+func sparkline(samples []time.Duration) string {
+	if len(samples) == 0 {
+		return ""
+	}
+	lo, hi := samples[0], samples[0]
+	for _, d := range samples {
+		lo = min(lo, d)
+		hi = max(hi, d)
+	}
+	out := make([]rune, len(samples))
+	for i, d := range samples {
+		level := 0
+		if hi > lo {
+			level = int(int64(d-lo) * int64(len(sparkLevels)-1) / int64(hi-lo))
+		}
+		out[i] = sparkLevels[level]
+	}
+	return string(out)
+}
+
+// cursor returns the selection marker shown in front of a website.
+func cursor(selected bool) string {
+	if selected {
+		return infoStyle.Render("▶ ")
+	}
+	return "  "
+}
+
+// truncate shortens s to at most width runes, marking the cut with an ellipsis.
+func truncate(s string, width int) string {
+	r := []rune(s)
+	if len(r) <= width {
+		return s
+	}
+	if width <= 1 {
+		return string(r[:width])
+	}
+	return string(r[:width-1]) + "…"
+}
+
+// --- Bubble Tea Model Methods ---
+func (m model) Init() tea.Cmd {
+	return tea.Batch(waitForReport(m.mon), m.domainCmd(m.domains(false)), m.domainTick(), clockTick())
+}
+
+// reportMsg delivers a monitor report to the Bubble Tea program.
+type reportMsg struct {
+	monitor.Report
+	// mon is the monitor that produced the report. Reports from a monitor
+	// replaced by a config reload are dropped.
+	mon *monitor.Monitor
+}
+
+// waitForReport waits for the next report from the monitor.
+func waitForReport(mon *monitor.Monitor) tea.Cmd {
+	return func() tea.Msg {
+		r, ok := <-mon.Reports()
+		if !ok {
+			return nil
+		}
+		return reportMsg{Report: r, mon: mon}
+	}
+}
+
+// applyReport updates the website of r with the outcome of a check cycle and
+// returns the commands for the resulting notifications, if any.
+func (m model) applyReport(r monitor.Report) tea.Cmd {
+	t := m.targets[r.Index]
+	if t.paused {
+		return nil
+	}
+	t.lastChecked = r.Time
+	t.families = r.Families
+	t.ports = r.Ports
+	t.probes = r.Ping.Probes
+	t.tlsInfo = r.Ping.TLS
+	t.headers = r.Ping.Headers
+	t.healthHeaders = nil
+	if r.Health != nil {
+		t.healthHeaders = r.Health.Headers
+	}
+	t.pinHostKey(&r.Ping)
+	t.pingError, t.healthError, t.anomaly = "", "", ""
+	if r.Ping.Err != nil {
+		t.pingError = resultError(r.Ping)
+		t.lastPing = ""
+	} else {
+		t.lastPing = fmt.Sprintf(
+			"Ping to %s:\n  %s\n  Time: %v ms",
+			t.website,
+			r.Ping.Detail,
+			r.Ping.Latency.Milliseconds(),
+		)
+		if r.Ping.Timing != nil {
+			t.lastPing += "\n  " + r.Ping.Timing.String()
+		}
+		if r.Ping.Attempts > 1 {
+			t.lastPing += fmt.Sprintf("\n  Succeeded after %d attempts", r.Ping.Attempts)
+		}
+		t.lastLatency = r.Ping.Latency
+		t.recordLatency(r.Ping.Latency)
+		t.anomaly = t.detectAnomaly(r.Ping.Latency)
+	}
+	t.healthTiming = nil
+	var changeCmd tea.Cmd
+	if r.Health != nil {
+		t.healthTiming = r.Health.Timing
+		if r.Health.Err == nil {
+			changeCmd = m.recordHealth(t, r.Health.Data, r)
+		} else {
+			t.health = nil
+			t.healthError = resultError(*r.Health)
+		}
+	}
+	t.lastError = reportError(r)
+	ok := r.OK()
+	t.violations = nil
+	for _, v := range r.Violations {
+		t.violations = append(t.violations, v.Error())
+	}
+	up := statusUp
+	slow := ok && t.latency.slow(r.Ping.Latency)
+	if slow {
+		up = statusSlow
+	}
+	if len(t.violations) > 0 || t.anomaly != "" {
+		up = statusDegraded
+	}
+	if slow && t.latency.alert {
+		ok = false
+		t.lastError = fmt.Sprintf("latency %d ms exceeds the critical threshold of %d ms",
+			r.Ping.Latency.Milliseconds(), t.latency.critical.Milliseconds())
+	} else if ok && t.anomaly != "" && t.latency.anomalyAlert {
+		ok = false
+		t.lastError = t.anomaly
+	}
+	t.recordHistory(historyEntry{Time: r.Time, OK: ok, Latency: r.Ping.Latency, Error: t.lastError})
+	attrs := []any{"target", t.website, "check", t.checkType, "ok", ok, "attempts", r.Ping.Attempts}
+	if r.Ping.Err == nil {
+		attrs = append(attrs, "latency_ms", r.Ping.Latency.Milliseconds())
+	}
+	if r.Ping.Probes != nil {
+		attrs = append(attrs, "packet_loss", r.Ping.Probes.Loss())
+	}
+	if r.Maintenance {
+		attrs = append(attrs, "maintenance", true)
+	}
+	if len(t.violations) > 0 {
+		attrs = append(attrs, "violations", t.violations)
+	}
+	if m.telemetry != nil {
+		m.telemetry.record(t.website, t.checkType, r, ok)
+	}
+	if ok {
+		m.logger.Info("check", attrs...)
+	} else {
+		m.logger.Warn("check", append(attrs, "error", t.lastError)...)
+	}
+	cmd := tea.Batch(m.finishCheck(r, ok, up), m.pushCmd(r, ok), m.budgetCmd(r), changeCmd, m.digestCmd(r.Time))
+	if m.influx != nil {
+		m.influx.record(t, r, ok)
+	}
+	if m.statsd != nil {
+		m.statsd.record(t, r, ok)
+	}
+	if m.mqtt != nil {
+		m.mqtt.record(t, r, ok)
+	}
+	return cmd
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
-	case tickMsgWithIndex:
-		return m, pingWebsiteCmdWithContext(m.ctx, m.websites[msg.Index], msg.Index)
-	case pingResultWithIndex:
-		if msg.Err != nil {
-			m.lastError[msg.Index] = msg.Err.Error()
-			m.lastPing[msg.Index] = ""
-			m.lastHealthGeneric[msg.Index] = nil
-			return m, schedulePing(m.schedule, msg.Index)
-		}
-		m.lastPing[msg.Index] = msg.Result
-		m.lastError[msg.Index] = ""
-		// Use per-website health endpoint
-		if len(m.healthEndpoint) > msg.Index && m.healthEndpoint[msg.Index] != "" {
-			return m, fetchHealthCmdWithContext(m.ctx, m.websites[msg.Index], m.healthEndpoint[msg.Index], msg.Index)
-		}
-		return m, schedulePing(m.schedule, msg.Index)
-	case healthResultGenericWithIndex:
-		if msg.Err == nil {
-			m.lastHealthGeneric[msg.Index] = msg.Data
+	case reportMsg:
+		if msg.mon != m.mon {
+			return m, nil
+		}
+		cmd := m.applyReport(msg.Report)
+		m.publish()
+		return m, tea.Batch(cmd, waitForReport(m.mon))
+	case domainMsg:
+		m.applyDomain(msg)
+		return m, nil
+	case tracerouteMsg:
+		m.applyTraceroute(msg)
+		return m, nil
+	case domainTickMsg:
+		return m, tea.Batch(m.domainCmd(m.domains(false)), m.domainTick())
+	case clockTickMsg:
+		return m, clockTick()
+	case bellMsg:
+		m.flashStart = time.Now()
+		return m, nil
+	case notifyErrMsg:
+		m.lastNotifyError = msg.err.Error()
+		return m, nil
+	case storageErrMsg:
+		m.logger.Error("saving history failed", "error", msg.err.Error())
+		m.lastStorageError = msg.err.Error()
+		return m, nil
+	case reloadMsg:
+		return m.reload(msg.cfg)
+	case controlMsg:
+		m.control(msg)
+		return m, nil
+	case heartbeatMsg:
+		m.heartbeat(msg)
+		return m, nil
+	case agentMsg:
+		m.applyAgent(msg)
+		m.publish()
+		return m, nil
+	case exportMsg:
+		m.lastExport, m.lastExportError = "", ""
+		if msg.err != nil {
+			m.lastExportError = msg.err.Error()
 		} else {
-			m.lastHealthGeneric[msg.Index] = nil
-			m.lastError[msg.Index] = msg.Err.Error()
+			m.lastExport = strings.Join(msg.paths, ", ")
 		}
-		return m, schedulePing(m.schedule, msg.Index)
+		return m, nil
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		return m, nil
+	case reloadErrMsg:
+		m.logger.Error("configuration reload failed", "error", msg.err.Error())
+		m.lastReloadError = msg.err.Error()
+		return m, nil
 	case tea.KeyMsg:
+		// The help overlay only closes or quits
+		if m.help && msg.String() != "ctrl+c" && msg.String() != "q" {
+			if msg.String() == "?" || msg.String() == "esc" {
+				m.help = false
+			}
+			return m, nil
+		}
+		prefix := m.pendingKey
+		m.pendingKey = ""
+		switch msg.String() {
+		case "?":
+			m.help = true
+			return m, nil
+		case "t":
+			m.tableView = !m.tableView
+			return m, nil
+		case "h":
+			m.historyPane = !m.historyPane
+			return m, nil
+		case "up", "k":
+			return m.move(-1), nil
+		case "down", "j":
+			return m.move(1), nil
+		case "g":
+			// gg moves to the first website
+			if prefix == "g" {
+				return m.move(-len(m.targets)), nil
+			}
+			m.pendingKey = "g"
+			return m, nil
+		case "G":
+			return m.move(len(m.targets)), nil
+		case "v":
+			return m.toggleVisual(), nil
+		case " ":
+			return m.toggleMark(), nil
+		case "s":
+			m.grouped = !m.grouped
+			return m, nil
+		case "c":
+			g := m.selectedGroup()
+			return m.setCollapsed(g, !m.isCollapsed(g)), nil
+		case "C":
+			// Collapse every section, or expand them all if all are collapsed
+			groups := m.groups()
+			collapse := slices.ContainsFunc(groups, func(g group) bool { return !m.isCollapsed(g) })
+			for _, g := range groups {
+				m = m.setCollapsed(g, collapse)
+			}
+			return m, nil
+		case "enter":
+			// Enter on a collapsed section expands it
+			if g := m.selectedGroup(); m.isCollapsed(g) {
+				return m.setCollapsed(g, false), nil
+			}
+			m.focused = true
+			return m, nil
+		case "esc":
+			if m.focused {
+				m.focused = false
+				return m, nil
+			}
+			return m.clearSelection(), nil
+		case "r":
+			for _, i := range m.selection() {
+				m.mon.Trigger(i)
+			}
+			return m.clearSelection(), nil
+		case "R":
+			m.mon.TriggerAll()
+			return m, nil
+		case "p":
+			// Pause the selection, or resume it if it is all paused
+			sel := m.selection()
+			pause := slices.ContainsFunc(sel, func(i int) bool { return !m.targets[i].paused })
+			for _, i := range sel {
+				m.setPaused(i, pause)
+			}
+			m.publish()
+			return m.clearSelection(), nil
+		case "m":
+			// Mute the selection, or unmute it if it is all muted
+			sel := m.selection()
+			mute := slices.ContainsFunc(sel, func(i int) bool { return !m.targets[i].muted })
+			for _, i := range sel {
+				m.setMuted(i, mute)
+			}
+			m.publish()
+			return m.clearSelection(), nil
+		case "P":
+			// Pause everything, or resume everything if all is paused
+			pause := slices.ContainsFunc(m.targets, func(t *target) bool { return !t.paused })
+			for i := range m.targets {
+				m.setPaused(i, pause)
+			}
+			m.publish()
+			return m, nil
+		case "x":
+			return m, m.exportCmd()
+		}
 		if msg.String() == "ctrl+c" || msg.String() == "q" {
 			m.quit = true
 			if m.cancel != nil {
@@ -222,149 +785,483 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-func (m model) View() string {
-	var b strings.Builder
+// reportError formats the failures of a check cycle: that of the check and
+// that of the health endpoint, which are reported together when both failed.
+func reportError(r monitor.Report) string {
+	switch {
+	case r.Ping.Err != nil && r.Health != nil && r.Health.Err != nil:
+		return resultError(r.Ping) + "; health endpoint: " + resultError(*r.Health)
+	case r.Ping.Err != nil:
+		return resultError(r.Ping)
+	case r.Health != nil && r.Health.Err != nil:
+		return resultError(*r.Health)
+	}
+	return ""
+}
 
-	// Header
-	b.WriteString(headerStyle.Render(" Vivteno - Website Health Monitor "))
-	b.WriteString("\n\n")
+// resultError formats a failed check result, noting retries if any were made.
+func resultError(r monitor.Result) string {
+	if r.Attempts > 1 {
+		return fmt.Sprintf("%v (after %d attempts)", r.Err, r.Attempts)
+	}
+	return r.Err.Error()
+}
+
+// finishCheck moves the website to its next state and fires notifications
+// when it goes down or recovers. ok is the outcome of r after latency
+// thresholds were applied and up the state it moves to if ok: up, slow or
+// degraded. Failures inside a maintenance window are neither counted nor
+// alerted on. While the website is flapping its notifications are held
+// back; once it stabilizes, the state it settled in is notified if it
+// differs from the last one notified. Every other check sends what the
+// notifiers' cooldowns held back, and reminders (see alertCmd).
+func (m model) finishCheck(r monitor.Report, ok bool, up status) tea.Cmd {
+	t := m.targets[r.Index]
+	prev, cur := t.record(ok, up, r.Maintenance, m.failureThreshold)
+	if cur != prev {
+		m.logger.Info("state change", "target", t.website, "from", prev.String(), "to", cur.String())
+	}
+	started, stopped := t.updateFlapping(r.Time, cur != prev, m.flap)
+	if started {
+		m.logger.Warn("flapping started", "target", t.website, "changes", len(t.stateChanges), "window", m.flap.window.String())
+	} else if stopped {
+		m.logger.Info("flapping stopped", "target", t.website, "state", cur.String())
+	}
+	var cmds []tea.Cmd
+	// Leaving down takes a successful check, whatever state it moves to
+	recovered := prev == statusDown && cur != statusDown
+	m.endMute(t, recovered, r.Time)
+	if cur != prev && (cur == statusDown || recovered) {
+		var changed bool
+		if cur == statusDown {
+			changed = m.incidents.open(t.website, t.lastError, r.Time)
+		} else {
+			changed = m.incidents.close(t.website, r.Time)
+		}
+		if changed {
+			cmds = append(cmds, m.pending.track(m.incidents.saveCmd()))
+		}
+		if !t.flapping {
+			cmds = append(cmds, m.notifyCmd(r, cur))
+			if cur == statusDown && t.traceroute != "" {
+				cmds = append(cmds, m.tracerouteCmd(r))
+			}
+			if cur == statusDown && !t.muted {
+				cmds = append(cmds, m.bellCmd())
+			}
+		}
+	} else if stopped && (cur == statusDown) != (t.notified == statusDown) {
+		cmds = append(cmds, m.notifyCmd(r, cur))
+		if cur == statusDown && !t.muted {
+			cmds = append(cmds, m.bellCmd())
+		}
+	} else if !t.flapping {
+		// Changes held back by a cooldown and reminders are sent later
+		cmds = append(cmds, m.alertCmd(r))
+	}
+	return tea.Batch(cmds...)
+}
 
-	// For each website, render its section
-	for i, website := range m.websites {
-		b.WriteString(renderSection("Website:", website))
+func renderCard(m model, i int, t *target) string {
+	var b strings.Builder
+	b.WriteString(m.marker(i) + renderSection("Website:", t.website) + pausedBadge(t) + m.mutedBadge(t) + flappingBadge(t))
+	if spark := sparkline(t.latencyHistory); spark != "" {
+		b.WriteString(" " + healthValueStyle.Render(spark))
+	}
+	b.WriteString("\n")
+	b.WriteString(renderSection("Schedule:", m.schedule) + " " + unknownStyle.Render(m.nextCheck(i, t)))
+	b.WriteString("\n")
+	b.WriteString(renderSection("Check:", t.checkType))
+	b.WriteString("\n")
+	if len(t.tags) > 0 {
+		b.WriteString(renderSection("Tags:", strings.Join(t.tags, ", ")))
+		b.WriteString("\n")
+	}
+	if len(t.maintenance) > 0 {
+		b.WriteString(renderMaintenance(t.maintenance, m.timezone))
+		b.WriteString("\n")
+	}
+	if d := m.incidents.downtime(t.website, time.Now()); d > 0 {
+		b.WriteString(renderSection("Downtime:", formatDuration(d)))
+		b.WriteString("\n")
+	}
+	b.WriteString(renderDomain(m, t))
+	b.WriteString(renderBudget(m, t))
+	if len(t.families) > 0 {
+		b.WriteString(renderFamilies(t.families))
 		b.WriteString("\n")
-		b.WriteString(renderSection("Schedule:", m.schedule))
+	}
+	if len(t.ports) > 0 {
+		b.WriteString(renderPorts(t.ports))
+		b.WriteString("\n")
+	}
+	if len(t.locations) > 0 {
+		b.WriteString(renderLocations(t, time.Now()))
+		b.WriteString("\n")
+	}
+	// Only one of check and health endpoint failing is worth telling apart
+	if t.healthEndpoint != "" && (t.pingError == "") != (t.healthError == "") {
+		b.WriteString(renderCheckStates(t))
 		b.WriteString("\n")
+	}
 
-		// Ping Section
-		if m.lastPing[i] != "" {
-			now := time.Now()
-			if m.timezone != nil {
-				now = now.In(m.timezone)
-			}
-			b.WriteString("\n")
-			b.WriteString(renderSection("Last checked:", now.Format(DisplayTimeFormat)))
-			b.WriteString("\n")
-			for j, line := range strings.Split(m.lastPing[i], "\n") {
-				if j == 0 {
-					b.WriteString(infoStyle.Render(line))
-				} else {
-					b.WriteString("\n" + infoStyle.Render(line))
-				}
+	// Ping Section
+	if t.lastPing != "" {
+		checked := t.lastChecked
+		if m.timezone != nil {
+			checked = checked.In(m.timezone)
+		}
+		b.WriteString("\n")
+		b.WriteString(renderSection("Last checked:", checked.Format(DisplayTimeFormat)))
+		b.WriteString("\n")
+		style := latencyStyle(t.latency, t.lastLatency, infoStyle)
+		for j, line := range strings.Split(t.lastPing, "\n") {
+			if j == 0 {
+				b.WriteString(style.Render(line))
+			} else {
+				b.WriteString("\n" + style.Render(line))
 			}
-			b.WriteString("\n")
 		}
+		b.WriteString("\n")
+	}
 
-		// Health Endpoint Section
-		if len(m.healthEndpoint) > i && m.healthEndpoint[i] != "" && m.lastHealthGeneric[i] != nil {
-			b.WriteString("\n")
-			b.WriteString(renderHealthSection(m.lastHealthGeneric[i], m.timezone))
-			b.WriteString("\n")
+	// Health Endpoint Section
+	if t.healthEndpoint != "" && t.health != nil {
+		b.WriteString("\n")
+		if len(t.healthFields) > 0 {
+			b.WriteString(renderHealthFields(t.health, t.healthFields, m.healthOrder, m.timezone, t.healthChanges))
+		} else {
+			b.WriteString(renderHealthSection(t.health, m.healthOrder, m.timezone, t.healthChanges))
+		}
+		if t.healthTiming != nil {
+			b.WriteString("\n  " + healthKeyStyle.Render("timing:") + " " + healthValueStyle.Render(t.healthTiming.String()))
 		}
+		b.WriteString("\n")
+	}
 
-		// Error Section
-		if m.lastError[i] != "" {
-			b.WriteString("\n")
-			b.WriteString(errorStyle.Render("FAILED: " + m.lastError[i]))
-			b.WriteString("\n")
+	// Error Section
+	if t.lastError != "" {
+		b.WriteString("\n")
+		switch t.state {
+		case statusDown:
+			b.WriteString(errorStyle.Render("FAILED: " + t.lastError))
+		case statusMaintenance:
+			b.WriteString(noticeStyle.Render("MAINTENANCE: " + t.lastError))
+		default:
+			b.WriteString(warningStyle.Render(
+				fmt.Sprintf("DEGRADED (%d/%d): %s", t.failures, m.failureThreshold, t.lastError)))
 		}
+		b.WriteString("\n")
+	} else if len(t.violations) > 0 {
+		b.WriteString("\n")
+		b.WriteString(renderViolations(t.violations))
+	} else if t.anomaly != "" {
+		b.WriteString("\n")
+		b.WriteString(warningStyle.Render("ANOMALY: " + t.anomaly))
+		b.WriteString("\n")
+	} else if t.state == statusSlow {
+		b.WriteString("\n")
+		b.WriteString(warningStyle.Render(fmt.Sprintf("SLOW: %d ms, critical threshold %d ms",
+			t.lastLatency.Milliseconds(), t.latency.critical.Milliseconds())))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// renderCards renders the cards of the websites at indices, in a grid on
+// wide terminals.
+func renderCards(m model, indices []int) string {
+	cards := make([]string, len(indices))
+	for j, i := range indices {
+		cards[j] = renderCard(m, i, m.targets[i])
+	}
+	if n, width := m.columns(); n > 1 {
+		return renderGrid(cards, n, width, m.separator())
+	}
+	return strings.Join(cards, "\n"+m.separator()+"\n\n")
+}
 
-		if len(m.websites) > 1 && i < len(m.websites)-1 {
-			b.WriteString("\n" + strings.Repeat("-", 40) + "\n\n")
+func (m model) View() string {
+	var b strings.Builder
+
+	// Header
+	header := headerStyle
+	if m.flashing(time.Now()) {
+		header = flashStyle
+	}
+	b.WriteString(header.Render(" Vivteno - Website Health Monitor "))
+	b.WriteString("\n\n")
+	b.WriteString(renderSummary(m))
+	b.WriteString("\n\n")
+
+	if m.help {
+		b.WriteString(renderHelp(m))
+		return fit(b.String(), m.width) + m.footer(helpFooter)
+	}
+
+	if m.focused {
+		b.WriteString(renderDetail(m))
+		return fit(b.String(), m.width) + m.footer(detailFooterHelp)
+	}
+
+	if m.tableView {
+		b.WriteString(renderTable(m))
+		b.WriteString("\n")
+		if m.historyPane {
+			b.WriteString("\n" + renderHistoryPane(m))
 		}
+		return fit(b.String(), m.width) + m.footer(m.listFooter())
 	}
 
-	// Footer
-	b.WriteString(footerStyle.Render("Press q or Ctrl+C to quit."))
+	// Websites, in a grid on wide terminals, under their section headers
+	var sections []string
+	for _, g := range m.groups() {
+		var section string
+		if g.name != "" {
+			section = renderGroupHeader(m, g) + "\n"
+			if m.isCollapsed(g) {
+				sections = append(sections, section)
+				continue
+			}
+			section += "\n"
+		}
+		sections = append(sections, section+renderCards(m, g.targets))
+	}
+	b.WriteString(strings.Join(sections, "\n"+m.separator()+"\n\n"))
 
-	return b.String()
+	if m.historyPane {
+		b.WriteString("\n" + m.separator() + "\n\n")
+		b.WriteString(renderHistoryPane(m))
+	}
+
+	if incidents := m.incidents.recent("", RecentIncidents); len(incidents) > 0 {
+		b.WriteString("\n" + m.separator() + "\n\n")
+		b.WriteString(renderIncidents("Recent incidents:", incidents, m))
+	}
+
+	if m.lastNotifyError != "" {
+		b.WriteString("\n")
+		b.WriteString(warningStyle.Render("Notification failed: " + m.lastNotifyError))
+		b.WriteString("\n")
+	}
+	if m.lastStorageError != "" {
+		b.WriteString("\n")
+		b.WriteString(warningStyle.Render("Saving history failed: " + m.lastStorageError))
+		b.WriteString("\n")
+	}
+	if m.lastExport != "" {
+		b.WriteString("\n")
+		b.WriteString(noticeStyle.Render("Exported to " + m.lastExport))
+		b.WriteString("\n")
+	}
+	if m.lastExportError != "" {
+		b.WriteString("\n")
+		b.WriteString(warningStyle.Render("Export failed: " + m.lastExportError))
+		b.WriteString("\n")
+	}
+	if m.lastReloadError != "" {
+		b.WriteString("\n")
+		b.WriteString(warningStyle.Render("Config reload failed, keeping the previous configuration: " + m.lastReloadError))
+		b.WriteString("\n")
+	}
+
+	return fit(b.String(), m.width) + m.footer(m.listFooter())
 }
 
-// --- Validation helpers ---
-func isValidHostname(host string) bool {
-	if net.ParseIP(host) != nil {
-		return true
+// exporter pushes check data to an external system until its context is
+// cancelled.
+type exporter interface {
+	run(ctx context.Context)
+}
+
+// exporters returns the enabled exporters.
+func (m model) exporters() []exporter {
+	var out []exporter
+	if m.telemetry != nil {
+		out = append(out, m.telemetry)
+	}
+	if m.influx != nil {
+		out = append(out, m.influx)
 	}
-	re := regexp.MustCompile(`^([a-zA-Z0-9-]+\.)*[a-zA-Z0-9-]+$`)
-	return re.MatchString(host)
+	if m.mqtt != nil {
+		out = append(out, m.mqtt)
+	}
+	return out
 }
 
-func isValidSchedule(s string) bool {
-	_, err := time.ParseDuration(s)
-	return err == nil
+// runExporters runs exporters in the background. The returned function
+// waits for their final writes once ctx is cancelled.
+func runExporters(ctx context.Context, exporters []exporter) (wait func()) {
+	var wg sync.WaitGroup
+	for _, e := range exporters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.run(ctx)
+		}()
+	}
+	return wg.Wait
 }
 
 // --- Main entrypoint ---
 func main() {
-	_ = godotenv.Load()
-	websiteEnv := os.Getenv("PING_WEBSITE")
-	schedule := os.Getenv("PING_SCHEDULE")
-	timezone := os.Getenv("TIMEZONE")
-	healthEndpointEnv := os.Getenv("HEALTH_ENDPOINT")
-
-	var websites []string
-	if err := json.Unmarshal([]byte(websiteEnv), &websites); err != nil || len(websites) == 0 {
-		fmt.Println("PING_WEBSITE must be a JSON array of at least one website, e.g. [\"example.com\"]")
-		os.Exit(1)
-	}
-	for _, w := range websites {
-		if !isValidHostname(w) {
-			fmt.Printf("Invalid website in PING_WEBSITE: %q\n", w)
-			os.Exit(1)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "run":
+			os.Exit(runTUI(os.Args[2:]))
+		case "check":
+			os.Exit(runCheck(os.Args[2:]))
+		case "status":
+			os.Exit(runStatus(os.Args[2:]))
+		case "validate":
+			os.Exit(runValidate(os.Args[2:]))
+		case "export":
+			os.Exit(runExport(os.Args[2:]))
+		case "statuspage":
+			os.Exit(runStatusPage(os.Args[2:]))
+		case "agent":
+			os.Exit(runAgent(os.Args[2:]))
 		}
 	}
-	if schedule == "" {
-		schedule = DefaultSchedule
+	os.Exit(runTUI(os.Args[1:]))
+}
+
+// runTUI implements the run subcommand, which is also the default: it
+// monitors the configured websites in the terminal UI, or once with --once.
+// It returns the process exit code.
+func runTUI(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	once := fs.Bool("once", false, "run a single round of checks, print a summary and exit")
+	format := fs.String("format", OnceFormatTable, "summary format for --once: table or json")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() > 0 {
+		fmt.Printf("Unknown command: %q (expected run, check, status, validate, export, statuspage or agent)\n", fs.Arg(0))
+		return 1
 	}
-	if !isValidSchedule(schedule) {
-		fmt.Printf("Invalid PING_SCHEDULE: %q\n", schedule)
-		os.Exit(1)
+	if *once && *format != OnceFormatTable && *format != OnceFormatJSON {
+		fmt.Printf("Invalid --format: %q (expected %q or %q)\n", *format, OnceFormatTable, OnceFormatJSON)
+		return 1
 	}
 
-	var loc *time.Location
-	var err error
-	if timezone != "" {
-		loc, err = time.LoadLocation(timezone)
-		if err != nil {
-			fmt.Printf("Invalid TIMEZONE: %q\n", timezone)
-			os.Exit(1)
-		}
-	} else {
-		loc = time.Local
-	}
-
-	// Parse HEALTH_ENDPOINT as array or fallback to single value for all
-	var healthEndpoints []string
-	if healthEndpointEnv != "" {
-		// Try to parse as JSON array
-		if err := json.Unmarshal([]byte(healthEndpointEnv), &healthEndpoints); err != nil {
-			// fallback: treat as single endpoint for all
-			healthEndpoints = make([]string, len(websites))
-			for i := range healthEndpoints {
-				healthEndpoints[i] = healthEndpointEnv
-			}
-		} else if len(healthEndpoints) != len(websites) {
-			fmt.Println("HEALTH_ENDPOINT must be a JSON array with the same length as PING_WEBSITE, or a single string.")
-			os.Exit(1)
-		}
-	} else {
-		healthEndpoints = make([]string, len(websites))
+	env := loadEnv()
+	logger, logFile, err := loadLogger()
+	if err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		return 1
+	}
+	defer logFile.Close()
+	// Libraries such as quic-go write warnings to the standard logger, which
+	// would garble the terminal UI, so they go to the event log instead
+	slog.SetDefault(logger)
+	tel, err := loadTelemetry(logger)
+	if err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		return 1
+	}
+	influx, err := loadInflux(logger)
+	if err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		return 1
+	}
+	statsd, err := loadStatsd(logger)
+	if err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		return 1
+	}
+	mqtt, err := loadMQTT(logger)
+	if err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		return 1
+	}
+	disc, discovered, err := startDiscovery()
+	if err != nil {
+		fmt.Printf("Discovery failed: %v\n", err)
+		return 1
+	}
+	cfg, err := loadConfig(discovered)
+	if err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		return 1
+	}
+	incidents, err := loadIncidents(cfg.historyFile)
+	if err != nil {
+		fmt.Printf("Invalid HISTORY_FILE: %v\n", err)
+		return 1
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
-	m := initialModel(websites, schedule, healthEndpoints, ctx, cancel)
-	m.timezone = loc
+	if *once {
+		code := runOnce(ctx, os.Stdout, cfg.newMonitor(), *format)
+		cancel()
+		return code
+	}
+	heartbeats := newHeartbeatStore()
+	heartbeats.attach(cfg.targets, cfg.heartbeatTokens)
+	mon := cfg.newMonitor()
+
+	cfg.theme.apply()
+	m := initialModel(cfg, ctx, cancel)
+	m.incidents = incidents
+	m.logger = logger
+	m.telemetry = tel
+	m.influx = influx
+	m.statsd = statsd
+	m.mqtt = mqtt
+	m.logger.Info("monitoring started", "targets", len(cfg.websites))
+	if cfg.webListen != "" || cfg.controlListen != "" {
+		m.store = &statusStore{started: time.Now()}
+		m.store.set(m.snapshot())
+		m.store.setDisplay(cfg.healthOrder, cfg.timezone)
+		m.store.setInterval(cfg.interval)
+	}
+	m.heartbeats = heartbeats
+	m = m.startMonitor(mon)
 	p := tea.NewProgram(m)
 
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-c
 		cancel()
 		p.Quit()
 	}()
-	if _, err := p.Run(); err != nil {
+	if cfg.webListen != "" {
+		ln, err := net.Listen("tcp", cfg.webListen)
+		if err != nil {
+			fmt.Printf("Invalid WEB_LISTEN: %v\n", err)
+			return 1
+		}
+		srv := newWebServer(cfg.webListen, m.store, heartbeats, cfg.agentToken, p.Send)
+		go func() { _ = srv.Serve(ln) }()
+		defer srv.Close()
+	}
+	if cfg.controlListen != "" {
+		ln, err := listenControl(cfg.controlListen)
+		if err != nil {
+			fmt.Printf("Invalid CONTROL_LISTEN: %v\n", err)
+			return 1
+		}
+		srv := newControlServer(m.store, p.Send)
+		go func() { _ = srv.Serve(ln) }()
+		defer srv.Close()
+	}
+	go watchConfig(ctx, p, env, disc, cfg.configWatch)
+	if disc != nil {
+		go watchDiscovery(ctx, p, disc)
+	}
+	wait := runExporters(ctx, m.exporters())
+	final, err := p.Run()
+	cancel()
+	if final, ok := final.(model); ok {
+		final.shutdown()
+	}
+	// Wait for the final telemetry and InfluxDB writes
+	wait()
+	if err != nil {
 		fmt.Println("Error running program:", err)
-		os.Exit(1)
+		return 1
 	}
+	return 0
 }
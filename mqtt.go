@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mooship/vivteno/pkg/monitor"
+)
+
+// MQTT output settings
+const (
+	DefaultMQTTTopic = "vivteno"
+	// MQTTMaxQueue bounds the results waiting to be published; newer ones
+	// are dropped while the broker is too slow or unreachable.
+	MQTTMaxQueue = 1000
+	mqttTimeout  = 10 * time.Second
+)
+
+// mqttTopicUnsafe replaces what cannot be part of a topic level: the level
+// separator and the wildcards of subscriptions.
+var mqttTopicUnsafe = strings.NewReplacer("/", "_", "+", "_", "#", "_")
+
+// mqttPublisher publishes the result of every check cycle to an MQTT broker
+// as a JSON message on a topic per website, e.g. "vivteno/example.com", so
+// home automation systems such as Home Assistant can react to outages.
+type mqttPublisher struct {
+	addr     string
+	tls      *tls.Config
+	clientID string
+	username string
+	password string
+	topic    string
+	retain   bool
+	logger   *slog.Logger
+	queue    chan mqttMessage
+
+	// conn and failing are only used by run.
+	conn    net.Conn
+	failing bool
+}
+
+// mqttMessage is a message waiting to be published.
+type mqttMessage struct {
+	topic   string
+	payload []byte
+}
+
+// mqttResult is the payload of a message: the outcome of a check cycle.
+type mqttResult struct {
+	Target string `json:"target"`
+	Check  string `json:"check"`
+	// Status is the state the check led to, e.g. "up" or "down".
+	Status    string    `json:"status"`
+	OK        bool      `json:"ok"`
+	LatencyMS *float64  `json:"latency_ms,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// loadMQTT reads the MQTT_* settings. It returns nil when MQTT_URL is not
+// set.
+func loadMQTT(logger *slog.Logger) (*mqttPublisher, error) {
+	raw := os.Getenv("MQTT_URL")
+	if raw == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "mqtt" && u.Scheme != "mqtts") || u.Hostname() == "" || u.User != nil || strings.Trim(u.Path, "/") != "" {
+		return nil, fmt.Errorf("invalid MQTT_URL: %q (expected mqtt://host:port or mqtts://host:port)", raw)
+	}
+	p := &mqttPublisher{
+		clientID: monitor.MQTTClientID(),
+		username: os.Getenv("MQTT_USERNAME"),
+		password: os.Getenv("MQTT_PASSWORD"),
+		topic:    DefaultMQTTTopic,
+		retain:   true,
+		logger:   logger,
+		queue:    make(chan mqttMessage, MQTTMaxQueue),
+	}
+	port := u.Port()
+	switch {
+	case port != "":
+	case u.Scheme == "mqtts":
+		port = monitor.DefaultMQTTTLSPort
+	default:
+		port = monitor.DefaultMQTTPort
+	}
+	p.addr = net.JoinHostPort(u.Hostname(), port)
+	if u.Scheme == "mqtts" {
+		p.tls = &tls.Config{ServerName: u.Hostname()}
+	}
+	if p.password != "" && p.username == "" {
+		return nil, fmt.Errorf("invalid MQTT_PASSWORD: set MQTT_USERNAME too")
+	}
+	if v := os.Getenv("MQTT_TOPIC"); v != "" {
+		if strings.ContainsAny(v, "+#") {
+			return nil, fmt.Errorf("invalid MQTT_TOPIC: %q (wildcards + and # are not allowed)", v)
+		}
+		p.topic = strings.TrimSuffix(v, "/")
+	}
+	if v := os.Getenv("MQTT_RETAIN"); v != "" {
+		if p.retain, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("invalid MQTT_RETAIN: %q", v)
+		}
+	}
+	return p, nil
+}
+
+// record queues the result of a check cycle of t for publishing, with the
+// state it led to.
+func (p *mqttPublisher) record(t *target, r monitor.Report, ok bool) {
+	res := mqttResult{Target: t.website, Check: t.checkType, Status: t.state.String(), OK: ok, Time: r.Time}
+	if r.Ping.Err == nil {
+		ms := float64(r.Ping.Latency) / float64(time.Millisecond)
+		res.LatencyMS = &ms
+	}
+	if !ok {
+		res.Error = t.lastError
+	}
+	payload, err := json.Marshal(res)
+	if err != nil {
+		return
+	}
+	select {
+	case p.queue <- mqttMessage{topic: p.topic + "/" + mqttTopicUnsafe.Replace(t.website), payload: payload}:
+	default:
+	}
+}
+
+// run publishes the queued results until ctx is cancelled, then drains the
+// queue.
+func (p *mqttPublisher) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			p.drain()
+			return
+		case msg := <-p.queue:
+			_ = p.publish(msg)
+		}
+	}
+}
+
+// drain publishes the results still queued, as long as the broker takes
+// them, and disconnects.
+func (p *mqttPublisher) drain() {
+	for len(p.queue) > 0 {
+		if p.publish(<-p.queue) != nil {
+			break
+		}
+	}
+	if p.conn != nil {
+		_ = monitor.MQTTDisconnect(p.conn)
+		p.close()
+	}
+}
+
+// publish sends msg, connecting to the broker first if needed. Only the
+// first of consecutive failures is logged, as they repeat for every check
+// while the broker is unreachable.
+func (p *mqttPublisher) publish(msg mqttMessage) error {
+	stale := p.conn != nil
+	err := p.send(msg)
+	if err != nil && stale {
+		// The broker may have closed the connection since the last message
+		err = p.send(msg)
+	}
+	if err != nil && !p.failing {
+		p.logger.Error("publishing to MQTT failed", "broker", p.addr, "error", err.Error())
+	} else if err == nil && p.failing {
+		p.logger.Info("publishing to MQTT recovered", "broker", p.addr)
+	}
+	p.failing = err != nil
+	return err
+}
+
+// send publishes msg over the current connection, or a new one.
+func (p *mqttPublisher) send(msg mqttMessage) error {
+	if p.conn == nil {
+		if err := p.connect(); err != nil {
+			return err
+		}
+	}
+	_ = p.conn.SetWriteDeadline(time.Now().Add(mqttTimeout))
+	if err := monitor.MQTTPublish(p.conn, msg.topic, msg.payload, p.retain); err != nil {
+		p.close()
+		return err
+	}
+	return nil
+}
+
+// connect opens a session with the broker.
+func (p *mqttPublisher) connect() error {
+	d := &net.Dialer{Timeout: mqttTimeout}
+	var conn net.Conn
+	var err error
+	if p.tls != nil {
+		conn, err = tls.DialWithDialer(d, "tcp", p.addr, p.tls)
+	} else {
+		conn, err = d.Dial("tcp", p.addr)
+	}
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Now().Add(mqttTimeout))
+	if err := monitor.MQTTConnect(conn, p.clientID, p.username, p.password); err != nil {
+		conn.Close()
+		return err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	p.conn = conn
+	return nil
+}
+
+// close drops the connection, so the next message reconnects.
+func (p *mqttPublisher) close() {
+	p.conn.Close()
+	p.conn = nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import "time"
+
+// DefaultMuteDuration is how long alerts stay muted unless MUTE_DURATION
+// says otherwise.
+const DefaultMuteDuration = time.Hour
+
+// setMuted mutes or unmutes the alerts of the website at i. A muted website
+// is still checked and shown as usual, but notifiers are told nothing about
+// it; a state change they missed meanwhile is sent after the first check
+// once it is unmuted. Mutes end after the mute duration, if any, and a
+// website muted while down is acknowledged: its mute also ends when it
+// recovers.
+func (m model) setMuted(i int, muted bool) {
+	t := m.targets[i]
+	if t.muted == muted {
+		return
+	}
+	t.muted, t.muteExpires, t.acknowledged = muted, time.Time{}, false
+	if !muted {
+		m.logger.Info("alerts unmuted", "target", t.website)
+		return
+	}
+	if m.muteDuration > 0 {
+		t.muteExpires = time.Now().Add(m.muteDuration)
+	}
+	t.acknowledged = t.state == statusDown
+	m.logger.Info("alerts muted", "target", t.website, "acknowledged", t.acknowledged, "until", t.muteExpires)
+}
+
+// endMute unmutes a website once its mute expired, or once it recovered if
+// it was acknowledged.
+func (m model) endMute(t *target, recovered bool, now time.Time) {
+	switch {
+	case !t.muted:
+		return
+	case t.acknowledged && recovered:
+		m.logger.Info("alerts unmuted on recovery", "target", t.website)
+	case !t.muteExpires.IsZero() && !now.Before(t.muteExpires):
+		m.logger.Info("mute expired", "target", t.website)
+	default:
+		return
+	}
+	t.muted, t.muteExpires, t.acknowledged = false, time.Time{}, false
+}
+
+// mutedBadge marks a website whose alerts are muted, as acknowledged when
+// it was muted while down, with the time the mute ends, or is empty.
+func (m model) mutedBadge(t *target) string {
+	if !t.muted {
+		return ""
+	}
+	label := "MUTED"
+	if t.acknowledged {
+		label = "ACK"
+	}
+	if !t.muteExpires.IsZero() {
+		until := t.muteExpires
+		if m.timezone != nil {
+			until = until.In(m.timezone)
+		}
+		label += " until " + until.Format("15:04")
+	}
+	return " " + mutedStyle.Render(label)
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mooship/vivteno/pkg/monitor"
+	"github.com/mooship/vivteno/pkg/notify"
+)
+
+// notifyErrMsg reports a failed notification delivery.
+type notifyErrMsg struct{ err error }
+
+// alertPolicy limits how often the notifiers are told about a website.
+// Durations are by notifier name; the empty name holds the default.
+type alertPolicy struct {
+	// cooldown is the minimum time between two notifications of a notifier
+	// for the same website.
+	cooldown map[string]time.Duration
+	// repeat is the interval of reminders while a website stays down, zero
+	// for none.
+	repeat map[string]time.Duration
+}
+
+// lookup returns the duration of the notifier name in durations.
+func lookup(durations map[string]time.Duration, name string) time.Duration {
+	if d, ok := durations[name]; ok {
+		return d
+	}
+	return durations[""]
+}
+
+// parseNotifierDurations parses a duration for every notifier, or a JSON
+// object of notifier names and durations such as {"pagerduty": "30m"}, in
+// which case notifiers it does not name get def.
+func parseNotifierDurations(value string, def time.Duration) (map[string]time.Duration, error) {
+	durations := map[string]time.Duration{"": def}
+	if value == "" {
+		return durations, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		if d < 0 {
+			return nil, fmt.Errorf("must not be negative, got %q", value)
+		}
+		durations[""] = d
+		return durations, nil
+	}
+	var byName map[string]string
+	if err := json.Unmarshal([]byte(value), &byName); err != nil {
+		return nil, fmt.Errorf("must be a duration or a JSON object of notifier names and durations, e.g. {\"pagerduty\": \"30m\"}")
+	}
+	for name, v := range byName {
+		if !slices.Contains(notifierNames, name) {
+			return nil, fmt.Errorf("unknown notifier %q (expected %s)", name, strings.Join(notifierNames, ", "))
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("%s: invalid duration %q", name, v)
+		}
+		durations[name] = d
+	}
+	return durations, nil
+}
+
+// alertState is what a notifier was last told about a website.
+type alertState struct {
+	state string
+	sent  time.Time
+}
+
+// alertKeys returns the key each notifier's alert state is kept under: its
+// name, numbered from the second notifier of the same name.
+func (m model) alertKeys() []string {
+	keys := make([]string, len(m.notifiers))
+	seen := make(map[string]int)
+	for i, n := range m.notifiers {
+		keys[i] = n.Name()
+		if seen[n.Name()]++; seen[n.Name()] > 1 {
+			keys[i] = fmt.Sprintf("%s#%d", n.Name(), seen[n.Name()])
+		}
+	}
+	return keys
+}
+
+// notifyCmd records that the website in r went down or recovered and
+// returns the command notifying it (see alertCmd).
+func (m model) notifyCmd(r monitor.Report, st status) tea.Cmd {
+	t := m.targets[r.Index]
+	t.notified = statusUp
+	if st == statusDown {
+		t.notified = statusDown
+	}
+	return m.alertCmd(r)
+}
+
+// alertCmd returns a command that tells every notifier the last notified
+// state of the website in r, or nil if none needs to be told. A notifier is
+// not told the state it was last told, nor a recovery from an outage it was
+// not told about, and is told nothing within its cooldown of the last
+// notification; a state change it missed that way is sent after a later
+// check, once the cooldown has elapsed. A notifier with a repeat interval
+// is reminded of an outage every interval while it lasts. Muted websites
+// alert nothing, and alerts held back during quiet hours go to the next
+// digest instead.
+func (m model) alertCmd(r monitor.Report) tea.Cmd {
+	t := m.targets[r.Index]
+	if t.notified == statusUnknown || len(m.notifiers) == 0 || t.muted {
+		return nil
+	}
+	state := notify.StateUp
+	if t.notified == statusDown {
+		state = notify.StateDown
+	}
+	now := time.Now()
+	var cmds []tea.Cmd
+	for i, key := range m.alertKeys() {
+		n := m.notifiers[i]
+		last, told := t.alerts[key]
+		reminder := false
+		switch {
+		case !told && state == notify.StateUp:
+			continue
+		case told && now.Sub(last.sent) < lookup(m.alerts.cooldown, n.Name()):
+			continue
+		case told && last.state == state:
+			repeat := lookup(m.alerts.repeat, n.Name())
+			if state != notify.StateDown || repeat == 0 || now.Sub(last.sent) < repeat {
+				continue
+			}
+			reminder = true
+		}
+		if t.alerts == nil {
+			t.alerts = make(map[string]alertState)
+		}
+		t.alerts[key] = alertState{state: state, sent: now}
+		e := m.stateEvent(r, state, reminder)
+		if m.holdBack(n, e, now) {
+			// The digest tells of the outage already
+			if !reminder {
+				m.hold(key, e)
+			}
+			continue
+		}
+		cmds = append(cmds, m.send(n, e))
+	}
+	return tea.Batch(cmds...)
+}
+
+// stateEvent returns the event telling that the website in r is down or
+// recovered. The error of a reminder says how long the outage has lasted; a
+// recovery carries the duration of the outage and its last error.
+func (m model) stateEvent(r monitor.Report, state string, reminder bool) notify.Event {
+	t := m.targets[r.Index]
+	e := notify.Event{
+		Target:      t.website,
+		State:       state,
+		Time:        r.Time,
+		URL:         r.Target.URL(),
+		Latency:     t.lastLatency,
+		Tags:        t.tags,
+		Severity:    t.severity,
+		Description: t.description,
+		Owner:       t.owner,
+		Runbook:     t.runbook,
+	}
+	if state != notify.StateDown {
+		if inc := m.incidents.recent(t.website, 1); len(inc) > 0 && !inc[0].ongoing() {
+			e.Downtime = inc[0].duration(r.Time).Round(time.Second)
+		}
+		e.Error = t.lastFailure()
+		return e
+	}
+	e.Error = t.lastError
+	var httpErr *monitor.HTTPError
+	if errors.As(r.Err(), &httpErr) {
+		e.Error = fmt.Sprintf("health endpoint HTTP %d", httpErr.StatusCode)
+		e.Body = httpErr.Body
+	}
+	if reminder {
+		if inc := m.incidents.recent(t.website, 1); len(inc) > 0 && inc[0].ongoing() {
+			e.Error = fmt.Sprintf("still down after %s: %s", formatDuration(inc[0].duration(time.Now())), e.Error)
+		} else {
+			e.Error = "still down: " + e.Error
+		}
+	}
+	return e
+}
+
+// deliver returns a command that sends e to every configured notifier, or
+// holds it back for the next digest during quiet hours.
+func (m model) deliver(e notify.Event) tea.Cmd {
+	now := time.Now()
+	var cmds []tea.Cmd
+	for i, key := range m.alertKeys() {
+		if n := m.notifiers[i]; m.holdBack(n, e, now) {
+			m.hold(key, e)
+		} else {
+			cmds = append(cmds, m.send(n, e))
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+// send returns a command that sends e to n.
+func (m model) send(n notify.Notifier, e notify.Event) tea.Cmd {
+	return m.pending.track(func() tea.Msg {
+		// Deliveries outlive quitting, so shutdown can complete them
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(m.ctx), notify.DefaultTimeout)
+		defer cancel()
+		if err := n.Notify(ctx, e); err != nil {
+			m.logger.Error("notification failed", "notifier", n.Name(), "target", e.Target, "state", e.State, "error", err.Error())
+			return notifyErrMsg{fmt.Errorf("%s: %w", n.Name(), err)}
+		}
+		m.logger.Info("notification sent", "notifier", n.Name(), "target", e.Target, "state", e.State)
+		return nil
+	})
+}
+
+// pushCmd returns a command reporting the outcome of a check of the website
+// in r to its push monitor, or nil if it has none. Failures inside a
+// maintenance window are reported as successes, so the push monitor does
+// not alert on them either.
+func (m model) pushCmd(r monitor.Report, ok bool) tea.Cmd {
+	t := m.targets[r.Index]
+	if t.pushURL == "" {
+		return nil
+	}
+	p := notify.NewPush(t.pushURL)
+	website, msg, latency := t.website, t.lastPing, r.Ping.Latency
+	if !ok {
+		msg = t.lastError
+		if r.Maintenance {
+			ok, msg = true, "maintenance: "+t.lastError
+		}
+	}
+	return m.pending.track(func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(m.ctx), notify.DefaultTimeout)
+		defer cancel()
+		if err := p.Ping(ctx, ok, msg, latency); err != nil {
+			m.logger.Error("push failed", "target", website, "error", err.Error())
+			return notifyErrMsg{fmt.Errorf("push for %s: %w", website, err)}
+		}
+		return nil
+	})
+}
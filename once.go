@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mooship/vivteno/pkg/monitor"
+)
+
+// Output formats and exit codes for --once mode
+const (
+	OnceFormatTable = "table"
+	OnceFormatJSON  = "json"
+
+	ExitAllUp          = 0
+	ExitTargetDown     = 2
+	ExitTargetDegraded = 3
+)
+
+// onceResult is the outcome of a single round of checks for one website.
+type onceResult struct {
+	Website string `json:"website"`
+	Check   string `json:"check"`
+	Up      bool   `json:"up"`
+	// Maintenance marks checks that ran inside a maintenance window; their
+	// failures do not fail the run.
+	Maintenance bool  `json:"maintenance,omitempty"`
+	LatencyMs   int64 `json:"latency_ms"`
+	// PacketLoss is set when several probes were sent per check.
+	PacketLoss *float64       `json:"packet_loss,omitempty"`
+	Health     map[string]any `json:"health,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	// Violations are the health assertions the response did not meet.
+	Violations []string `json:"violations,omitempty"`
+}
+
+// runOnce checks every target once, concurrently, writes a summary to w and
+// returns the process exit code: ExitTargetDown if any target failed, or
+// ExitTargetDegraded if any violated a health assertion.
+func runOnce(ctx context.Context, w io.Writer, mon *monitor.Monitor, format string) int {
+	reports := mon.CheckAll(ctx)
+	results := make([]onceResult, len(reports))
+	for i, r := range reports {
+		results[i] = newOnceResult(r)
+	}
+
+	if format == OnceFormatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(results)
+	} else {
+		writeOnceTable(w, results)
+	}
+	return onceExitCode(results)
+}
+
+// onceExitCode returns the process exit code for results: ExitTargetDown if
+// any target failed outside a maintenance window, ExitTargetDegraded if any
+// violated a health assertion, ExitAllUp otherwise.
+func onceExitCode(results []onceResult) int {
+	code := ExitAllUp
+	for _, r := range results {
+		if !r.Up && !r.Maintenance {
+			return ExitTargetDown
+		}
+		if len(r.Violations) > 0 {
+			code = ExitTargetDegraded
+		}
+	}
+	return code
+}
+
+func newOnceResult(r monitor.Report) onceResult {
+	res := onceResult{Website: r.Target.Name, Check: r.Target.Check, Up: r.OK(), Maintenance: r.Maintenance}
+	if r.Ping.Err == nil {
+		res.LatencyMs = r.Ping.Latency.Milliseconds()
+	}
+	if r.Ping.Probes != nil {
+		loss := r.Ping.Probes.Loss()
+		res.PacketLoss = &loss
+	}
+	if r.Health != nil {
+		res.Health = r.Health.Data
+	}
+	if err := r.Err(); err != nil {
+		res.Error = err.Error()
+	}
+	for _, v := range r.Violations {
+		res.Violations = append(res.Violations, v.Error())
+	}
+	return res
+}
+
+func writeOnceTable(w io.Writer, results []onceResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "WEBSITE\tCHECK\tSTATUS\tLATENCY\tERROR")
+	for _, r := range results {
+		status := "UP"
+		errText := r.Error
+		if len(r.Violations) > 0 {
+			status = "DEGRADED"
+			errText = "assertion failed: " + strings.Join(r.Violations, "; ")
+		}
+		latency := fmt.Sprintf("%d ms", r.LatencyMs)
+		if r.PacketLoss != nil && *r.PacketLoss > 0 {
+			latency += fmt.Sprintf(" (%.0f%% loss)", *r.PacketLoss*100)
+		}
+		if !r.Up {
+			status = "DOWN"
+			if r.Maintenance {
+				status = "MAINTENANCE"
+			}
+			if r.LatencyMs == 0 {
+				latency = "-"
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Website, r.Check, status, latency, strings.ReplaceAll(errText, "\n", " "))
+	}
+	_ = tw.Flush()
+}
@@ -0,0 +1,139 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Comparison operators of an Assertion, longest first so that "<=" is not
+// read as "<".
+var assertionOps = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// Assertion is an expectation about a value in a health response, such as
+// `status == "ok"` or `queue_depth < 100`.
+type Assertion struct {
+	Expr  string
+	Path  Path
+	Op    string
+	Value any
+}
+
+// ParseAssertion parses "<path> <op> <value>". The path is a JSONPath
+// expression; a leading "$." may be left out. The operator is one of ==, !=,
+// <, <=, > and >=. The value is a JSON literal ("ok", 100, true, null); a
+// bare word is taken as a string. <, <=, > and >= compare numbers.
+func ParseAssertion(s string) (Assertion, error) {
+	a := Assertion{Expr: strings.TrimSpace(s)}
+	idx := -1
+	for _, op := range assertionOps {
+		if i := strings.Index(a.Expr, op); i > 0 && (idx < 0 || i < idx) {
+			idx, a.Op = i, op
+		}
+	}
+	if idx < 0 {
+		return a, fmt.Errorf("assertion %q: expected <path> <op> <value> with op ==, !=, <, <=, > or >=", s)
+	}
+	expr := strings.TrimSpace(a.Expr[:idx])
+	if !strings.HasPrefix(expr, "$") {
+		expr = "$." + expr
+	}
+	path, err := ParsePath(expr)
+	if err != nil {
+		return a, fmt.Errorf("assertion %q: %w", s, err)
+	}
+	a.Path = path
+
+	raw := strings.TrimSpace(a.Expr[idx+len(a.Op):])
+	if raw == "" {
+		return a, fmt.Errorf("assertion %q: missing value", s)
+	}
+	if err := json.Unmarshal([]byte(raw), &a.Value); err != nil {
+		a.Value = raw
+	}
+	if _, ok := a.Value.(float64); !ok && a.Op != "==" && a.Op != "!=" {
+		return a, fmt.Errorf("assertion %q: %s needs a number", s, a.Op)
+	}
+	return a, nil
+}
+
+// Check evaluates the assertion against a decoded health response and
+// reports an error describing the violation, if any.
+func (a Assertion) Check(data map[string]any) error {
+	got, err := a.Path.Lookup(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", a.Expr, err)
+	}
+	var ok bool
+	switch a.Op {
+	case "==", "!=":
+		ok = jsonEqual(got, a.Value) == (a.Op == "==")
+	default:
+		n, isNum := number(got)
+		if !isNum {
+			return fmt.Errorf("%s: got %s, not a number", a.Expr, formatJSON(got))
+		}
+		want := a.Value.(float64)
+		switch a.Op {
+		case "<":
+			ok = n < want
+		case "<=":
+			ok = n <= want
+		case ">":
+			ok = n > want
+		case ">=":
+			ok = n >= want
+		}
+	}
+	if !ok {
+		return fmt.Errorf("%s: got %s", a.Expr, formatJSON(got))
+	}
+	return nil
+}
+
+// CheckAssertions evaluates every assertion and returns the violations.
+func CheckAssertions(assertions []Assertion, data map[string]any) []error {
+	var violations []error
+	for _, a := range assertions {
+		if err := a.Check(data); err != nil {
+			violations = append(violations, err)
+		}
+	}
+	return violations
+}
+
+// jsonEqual compares decoded JSON scalars. Objects and arrays are never
+// equal to a literal.
+func jsonEqual(got, want any) bool {
+	switch got.(type) {
+	case map[string]any, []any:
+		return false
+	}
+	return got == want
+}
+
+// number returns v as a float64 if it is a JSON number or a string holding
+// one, as some services report counters as strings.
+func number(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(v, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// formatJSON renders a decoded value for a violation message.
+func formatJSON(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	if len(b) > 80 {
+		return string(b[:77]) + "..."
+	}
+	return string(b)
+}
@@ -0,0 +1,317 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// Default ports of the backend checkers
+const (
+	DefaultRedisPort     = "6379"
+	DefaultMemcachedPort = "11211"
+	DefaultPostgresPort  = "5432"
+	DefaultMySQLPort     = "3306"
+)
+
+// backendMaxPacket bounds the size of a handshake or reply read from a
+// backend, so a misbehaving server cannot make the checker read forever.
+const backendMaxPacket = 64 << 10
+
+// dialBackend connects to the target on the port of a backend checker,
+// replaced by the target's BackendPort if it has one. The connection's
+// deadline is the end of the check.
+func dialBackend(ctx context.Context, t Target, port string, timeout time.Duration) (net.Conn, error) {
+	if t.BackendPort != "" {
+		port = t.BackendPort
+	}
+	conn, err := t.dial(ctx, &net.Dialer{Timeout: timeout}, net.JoinHostPort(t.Host, port))
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	return conn, nil
+}
+
+// readLine reads a CRLF-terminated line of a text protocol.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return "", fmt.Errorf("reply line longer than %d bytes", r.Size())
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+// RedisChecker sends PING to a Redis server and expects PONG. A server that
+// requires authentication answers with a NOAUTH error, which still shows it
+// is serving. Errors such as LOADING while the dataset is loaded fail the
+// check.
+type RedisChecker struct {
+	Port    string
+	Timeout time.Duration
+}
+
+// NewRedisChecker returns a RedisChecker using the default port and timeout.
+func NewRedisChecker() RedisChecker {
+	return RedisChecker{Port: DefaultRedisPort, Timeout: DefaultTCPTimeout}
+}
+
+// Name implements Checker.
+func (c RedisChecker) Name() string { return CheckRedis }
+
+// Check implements Checker.
+func (c RedisChecker) Check(ctx context.Context, t Target) Result {
+	timeout := t.connectTimeout(c.Timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	conn, err := dialBackend(ctx, t, c.Port, timeout)
+	if err != nil {
+		return Result{Err: err}
+	}
+	defer conn.Close()
+	if _, err := io.WriteString(conn, "*1\r\n$4\r\nPING\r\n"); err != nil {
+		return Result{Latency: time.Since(start), Err: fmt.Errorf("sending Redis PING: %w", err)}
+	}
+	reply, err := readLine(bufio.NewReader(conn))
+	elapsed := time.Since(start)
+	if err != nil {
+		return Result{Latency: elapsed, Err: fmt.Errorf("reading Redis reply: %w", err)}
+	}
+	data := map[string]any{"reply": reply}
+	switch {
+	case reply == "+PONG":
+		return Result{Latency: elapsed, Detail: "Redis PING: PONG", Data: data}
+	case strings.HasPrefix(reply, "-NOAUTH"):
+		return Result{Latency: elapsed, Detail: "Redis serving, authentication required", Data: data}
+	case strings.HasPrefix(reply, "-"):
+		return Result{Latency: elapsed, Data: data, Err: fmt.Errorf("Redis error: %s", reply[1:])}
+	}
+	return Result{Latency: elapsed, Data: data, Err: fmt.Errorf("unexpected reply to Redis PING: %q", reply)}
+}
+
+// MemcachedChecker asks a Memcached server for its version.
+type MemcachedChecker struct {
+	Port    string
+	Timeout time.Duration
+}
+
+// NewMemcachedChecker returns a MemcachedChecker using the default port and
+// timeout.
+func NewMemcachedChecker() MemcachedChecker {
+	return MemcachedChecker{Port: DefaultMemcachedPort, Timeout: DefaultTCPTimeout}
+}
+
+// Name implements Checker.
+func (c MemcachedChecker) Name() string { return CheckMemcached }
+
+// Check implements Checker.
+func (c MemcachedChecker) Check(ctx context.Context, t Target) Result {
+	timeout := t.connectTimeout(c.Timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	conn, err := dialBackend(ctx, t, c.Port, timeout)
+	if err != nil {
+		return Result{Err: err}
+	}
+	defer conn.Close()
+	if _, err := io.WriteString(conn, "version\r\n"); err != nil {
+		return Result{Latency: time.Since(start), Err: fmt.Errorf("sending Memcached version: %w", err)}
+	}
+	reply, err := readLine(bufio.NewReader(conn))
+	elapsed := time.Since(start)
+	if err != nil {
+		return Result{Latency: elapsed, Err: fmt.Errorf("reading Memcached reply: %w", err)}
+	}
+	version, ok := strings.CutPrefix(reply, "VERSION ")
+	if !ok {
+		return Result{Latency: elapsed, Err: fmt.Errorf("unexpected reply to Memcached version: %q", reply)}
+	}
+	return Result{Latency: elapsed, Detail: "Memcached " + version, Data: map[string]any{"version": version}}
+}
+
+// postgresAuth names the authentication requests of the PostgreSQL protocol.
+var postgresAuth = map[uint32]string{
+	0:  "none",
+	2:  "Kerberos",
+	3:  "password",
+	5:  "MD5",
+	7:  "GSSAPI",
+	9:  "SSPI",
+	10: "SASL",
+}
+
+// PostgresChecker starts a PostgreSQL session as the user "vivteno" and
+// stops once the server has answered, without authenticating. An
+// authentication request or an error about the user or database shows that
+// the server accepts sessions; errors of the classes 53 (insufficient
+// resources, e.g. too many connections) and 57 (e.g. the database system is
+// starting up) fail the check.
+type PostgresChecker struct {
+	Port    string
+	Timeout time.Duration
+}
+
+// NewPostgresChecker returns a PostgresChecker using the default port and
+// timeout.
+func NewPostgresChecker() PostgresChecker {
+	return PostgresChecker{Port: DefaultPostgresPort, Timeout: DefaultTCPTimeout}
+}
+
+// Name implements Checker.
+func (c PostgresChecker) Name() string { return CheckPostgres }
+
+// Check implements Checker.
+func (c PostgresChecker) Check(ctx context.Context, t Target) Result {
+	timeout := t.connectTimeout(c.Timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	conn, err := dialBackend(ctx, t, c.Port, timeout)
+	if err != nil {
+		return Result{Err: err}
+	}
+	defer conn.Close()
+
+	// StartupMessage: length, protocol 3.0 and NUL-terminated parameters
+	var params bytes.Buffer
+	for _, s := range []string{"user", "vivteno", "database", "vivteno", "application_name", "vivteno", ""} {
+		params.WriteString(s)
+		params.WriteByte(0)
+	}
+	msg := binary.BigEndian.AppendUint32(nil, uint32(8+params.Len()))
+	msg = binary.BigEndian.AppendUint32(msg, 3<<16)
+	msg = append(msg, params.Bytes()...)
+	if _, err := conn.Write(msg); err != nil {
+		return Result{Latency: time.Since(start), Err: fmt.Errorf("sending PostgreSQL startup: %w", err)}
+	}
+
+	var header [5]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return Result{Latency: time.Since(start), Err: fmt.Errorf("reading PostgreSQL reply: %w", err)}
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size < 4 || size > backendMaxPacket {
+		return Result{Latency: time.Since(start), Err: fmt.Errorf("invalid PostgreSQL message length %d", size)}
+	}
+	body := make([]byte, size-4)
+	if _, err := io.ReadFull(conn, body); err != nil {
+		return Result{Latency: time.Since(start), Err: fmt.Errorf("reading PostgreSQL reply: %w", err)}
+	}
+	elapsed := time.Since(start)
+	switch header[0] {
+	case 'R':
+		if len(body) < 4 {
+			return Result{Latency: elapsed, Err: errors.New("short PostgreSQL authentication request")}
+		}
+		auth, ok := postgresAuth[binary.BigEndian.Uint32(body)]
+		if !ok {
+			auth = fmt.Sprintf("method %d", binary.BigEndian.Uint32(body))
+		}
+		return Result{Latency: elapsed, Detail: "PostgreSQL serving, authentication: " + auth, Data: map[string]any{"auth": auth}}
+	case 'E':
+		fields := postgresErrorFields(body)
+		data := map[string]any{"code": fields['C'], "message": fields['M']}
+		if code := fields['C']; strings.HasPrefix(code, "53") || strings.HasPrefix(code, "57") {
+			return Result{Latency: elapsed, Data: data, Err: fmt.Errorf("PostgreSQL error %s: %s", code, fields['M'])}
+		}
+		return Result{Latency: elapsed, Detail: fmt.Sprintf("PostgreSQL serving (%s)", fields['M']), Data: data}
+	case 'v':
+		return Result{Latency: elapsed, Detail: "PostgreSQL serving, protocol version negotiated"}
+	}
+	return Result{Latency: elapsed, Err: fmt.Errorf("unexpected PostgreSQL message %q", header[0])}
+}
+
+// postgresErrorFields decodes the fields of an ErrorResponse, keyed by their
+// type, e.g. 'C' for the SQLSTATE code and 'M' for the message.
+func postgresErrorFields(body []byte) map[byte]string {
+	fields := make(map[byte]string)
+	for len(body) > 1 && body[0] != 0 {
+		typ := body[0]
+		value, rest, ok := bytes.Cut(body[1:], []byte{0})
+		if !ok {
+			break
+		}
+		fields[typ] = string(value)
+		body = rest
+	}
+	return fields
+}
+
+// MySQLChecker reads the handshake a MySQL or MariaDB server sends on
+// connecting and reports its version. A server refusing the connection,
+// e.g. with "Too many connections", sends an error instead, which fails the
+// check.
+type MySQLChecker struct {
+	Port    string
+	Timeout time.Duration
+}
+
+// NewMySQLChecker returns a MySQLChecker using the default port and timeout.
+func NewMySQLChecker() MySQLChecker {
+	return MySQLChecker{Port: DefaultMySQLPort, Timeout: DefaultTCPTimeout}
+}
+
+// Name implements Checker.
+func (c MySQLChecker) Name() string { return CheckMySQL }
+
+// Check implements Checker.
+func (c MySQLChecker) Check(ctx context.Context, t Target) Result {
+	timeout := t.connectTimeout(c.Timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	conn, err := dialBackend(ctx, t, c.Port, timeout)
+	if err != nil {
+		return Result{Err: err}
+	}
+	defer conn.Close()
+
+	// Packet header: 3-byte little-endian length and a sequence number
+	var header [4]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return Result{Latency: time.Since(start), Err: fmt.Errorf("reading MySQL handshake: %w", err)}
+	}
+	size := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	if size == 0 || size > backendMaxPacket {
+		return Result{Latency: time.Since(start), Err: fmt.Errorf("invalid MySQL packet length %d", size)}
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return Result{Latency: time.Since(start), Err: fmt.Errorf("reading MySQL handshake: %w", err)}
+	}
+	elapsed := time.Since(start)
+	switch payload[0] {
+	case 0xff:
+		// Error packet: code, optional "#" and SQLSTATE, message
+		if len(payload) < 3 {
+			return Result{Latency: elapsed, Err: errors.New("short MySQL error packet")}
+		}
+		code := binary.LittleEndian.Uint16(payload[1:])
+		message := payload[3:]
+		if len(message) >= 6 && message[0] == '#' {
+			message = message[6:]
+		}
+		return Result{Latency: elapsed, Err: fmt.Errorf("MySQL error %d: %s", code, message)}
+	case 10:
+		version, _, ok := bytes.Cut(payload[1:], []byte{0})
+		if !ok {
+			return Result{Latency: elapsed, Err: errors.New("malformed MySQL handshake")}
+		}
+		return Result{Latency: elapsed, Detail: "MySQL " + string(version), Data: map[string]any{"version": string(version)}}
+	}
+	return Result{Latency: elapsed, Err: fmt.Errorf("unsupported MySQL protocol version %d", payload[0])}
+}
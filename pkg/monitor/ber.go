@@ -0,0 +1,197 @@
+package monitor
+
+import (
+	"errors"
+	"slices"
+)
+
+// BER tags of the ASN.1 types SNMP uses (X.690, RFC 2578)
+const (
+	berInteger     = 0x02
+	berOctetString = 0x04
+	berNull        = 0x05
+	berObjectID    = 0x06
+	berSequenceTag = 0x30
+	berIPAddress   = 0x40
+	berCounter32   = 0x41
+	berGauge32     = 0x42
+	berTimeTicks   = 0x43
+	berCounter64   = 0x46
+)
+
+var errBER = errors.New("malformed BER encoding")
+
+// berTLV encodes an element with its tag and the definite length of its
+// content. Contents are shorter than 64 KiB, the limit of UDP.
+func berTLV(tag byte, content []byte) []byte {
+	n := len(content)
+	var b []byte
+	switch {
+	case n < 0x80:
+		b = []byte{tag, byte(n)}
+	case n <= 0xff:
+		b = []byte{tag, 0x81, byte(n)}
+	default:
+		b = []byte{tag, 0x82, byte(n >> 8), byte(n)}
+	}
+	return append(b, content...)
+}
+
+// berSequence encodes a SEQUENCE of encoded elements.
+func berSequence(elements ...[]byte) []byte {
+	return berTLV(berSequenceTag, slices.Concat(elements...))
+}
+
+// berInt encodes an integer in the fewest bytes of two's complement.
+func berInt(tag byte, v int64) []byte {
+	var b []byte
+	for {
+		b = append([]byte{byte(v)}, b...)
+		// The bytes left only repeat the sign bit of this one
+		if v >= -0x80 && v < 0x80 {
+			return berTLV(tag, b)
+		}
+		v >>= 8
+	}
+}
+
+// berOID encodes an object identifier; its first two arcs share a byte
+// and every arc is written in base 128.
+func berOID(oid []uint32) []byte {
+	var b []byte
+	arcs := append([]uint32{oid[0]*40 + oid[1]}, oid[2:]...)
+	for _, arc := range arcs {
+		enc := []byte{byte(arc & 0x7f)}
+		for arc >>= 7; arc > 0; arc >>= 7 {
+			enc = append([]byte{byte(arc&0x7f) | 0x80}, enc...)
+		}
+		b = append(b, enc...)
+	}
+	return berTLV(berObjectID, b)
+}
+
+// berValue is a decoded BER element: its tag and content, and where the
+// content starts in the message it was read from.
+type berValue struct {
+	tag byte
+	b   []byte
+	off int
+}
+
+// berRead reads the element at the start of b, which starts at off in the
+// message, and returns it with the number of bytes it takes. Lengths may use
+// the long form even when short, as some agents always write them so.
+func berRead(b []byte, off int) (berValue, int, error) {
+	if len(b) < 2 {
+		return berValue{}, 0, errBER
+	}
+	tag, n, header := b[0], int(b[1]), 2
+	if n&0x80 != 0 {
+		size := n & 0x7f
+		if size == 0 || size > 3 || len(b) < 2+size {
+			return berValue{}, 0, errBER
+		}
+		n = 0
+		for _, c := range b[2 : 2+size] {
+			n = n<<8 | int(c)
+		}
+		header += size
+	}
+	if len(b)-header < n {
+		return berValue{}, 0, errBER
+	}
+	return berValue{tag: tag, b: b[header : header+n], off: off + header}, header + n, nil
+}
+
+// berFields reads the message in b, which must be an element with the given
+// tag holding at least count elements, and returns those elements.
+func berFields(b []byte, tag byte, count int) ([]berValue, error) {
+	v, _, err := berRead(b, 0)
+	if err != nil {
+		return nil, err
+	}
+	if v.tag != tag {
+		return nil, errBER
+	}
+	fields, err := v.children()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) < count {
+		return nil, errBER
+	}
+	return fields, nil
+}
+
+// children reads the elements of a constructed element, such as a
+// SEQUENCE.
+func (v berValue) children() ([]berValue, error) {
+	var out []berValue
+	for rest, off := v.b, v.off; len(rest) > 0; {
+		child, n, err := berRead(rest, off)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, child)
+		rest, off = rest[n:], off+n
+	}
+	return out, nil
+}
+
+// int decodes a signed integer.
+func (v berValue) int() (int64, error) {
+	if len(v.b) == 0 || len(v.b) > 8 {
+		return 0, errBER
+	}
+	n := int64(int8(v.b[0]))
+	for _, c := range v.b[1:] {
+		n = n<<8 | int64(c)
+	}
+	return n, nil
+}
+
+// uint decodes an unsigned integer, such as a counter, which takes a
+// leading zero byte when its high bit is set.
+func (v berValue) uint() (uint64, error) {
+	b := v.b
+	if len(b) > 1 && b[0] == 0 {
+		b = b[1:]
+	}
+	if len(b) == 0 || len(b) > 8 {
+		return 0, errBER
+	}
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n, nil
+}
+
+// oid decodes an object identifier.
+func (v berValue) oid() ([]uint32, error) {
+	var arcs []uint32
+	var arc uint64
+	for i, c := range v.b {
+		arc = arc<<7 | uint64(c&0x7f)
+		if arc > 0xffffffff {
+			return nil, errBER
+		}
+		if c&0x80 != 0 {
+			if i == len(v.b)-1 {
+				return nil, errBER
+			}
+			continue
+		}
+		if arcs == nil {
+			first := min(arc/40, 2)
+			arcs = append(arcs, uint32(first), uint32(arc-first*40))
+		} else {
+			arcs = append(arcs, uint32(arc))
+		}
+		arc = 0
+	}
+	if len(arcs) == 0 {
+		return nil, errBER
+	}
+	return arcs, nil
+}
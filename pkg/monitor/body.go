@@ -0,0 +1,57 @@
+package monitor
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxBodyMatchSize is how much of a response body is searched by a
+// BodyMatch.
+const MaxBodyMatchSize = 1 << 20
+
+// BodyMatch is content that must appear in an HTTP response body, either a
+// plain substring or a regular expression.
+type BodyMatch struct {
+	Text   string
+	Regexp *regexp.Regexp
+}
+
+// ParseBodyMatch parses an expected body. A value wrapped in slashes, like
+// /"status":\s*"ok"/, is a regular expression; anything else is a substring.
+func ParseBodyMatch(s string) (*BodyMatch, error) {
+	if len(s) >= 2 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/") {
+		re, err := regexp.Compile(s[1 : len(s)-1])
+		if err != nil {
+			return nil, err
+		}
+		return &BodyMatch{Regexp: re}, nil
+	}
+	if s == "" {
+		return nil, fmt.Errorf("empty body match")
+	}
+	return &BodyMatch{Text: s}, nil
+}
+
+// Match reports an error if body does not contain the expected content.
+func (b *BodyMatch) Match(body []byte) error {
+	if b.Regexp != nil {
+		if !b.Regexp.Match(body) {
+			return fmt.Errorf("response body does not match %s", b)
+		}
+		return nil
+	}
+	if !bytes.Contains(body, []byte(b.Text)) {
+		return fmt.Errorf("response body does not contain %s", b)
+	}
+	return nil
+}
+
+// String returns the expectation as configured.
+func (b *BodyMatch) String() string {
+	if b.Regexp != nil {
+		return "/" + b.Regexp.String() + "/"
+	}
+	return fmt.Sprintf("%q", b.Text)
+}
@@ -0,0 +1,121 @@
+package monitor
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxBodySize limits the health response body read from targets that
+// do not set MaxBodySize.
+const DefaultMaxBodySize = 1 << 20
+
+// byteUnits are the size suffixes accepted by ParseByteSize, longest first
+// so "KiB" is not read as "B".
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30},
+	{"KB", 1000}, {"MB", 1000 * 1000}, {"GB", 1000 * 1000 * 1000},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30},
+	{"B", 1},
+}
+
+// ParseByteSize parses a positive size in bytes, with an optional unit:
+// B, KB, MB and GB count in powers of 1000, KiB, MiB and GiB (or K, M and G)
+// in powers of 1024, e.g. "512KiB" or "10MB".
+func ParseByteSize(s string) (int64, error) {
+	num, unit := strings.TrimSpace(s), int64(1)
+	for _, u := range byteUnits {
+		if n, ok := strings.CutSuffix(num, u.suffix); ok {
+			num, unit = strings.TrimSpace(n), u.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n <= 0 || n > (1<<62)/unit {
+		return 0, fmt.Errorf("invalid size %q, expected bytes or a size such as 512KiB or 10MB", s)
+	}
+	return n * unit, nil
+}
+
+// maxBodySize returns the largest health response body read from the
+// target.
+func (t Target) maxBodySize() int64 {
+	if t.MaxBodySize > 0 {
+		return t.MaxBodySize
+	}
+	return DefaultMaxBodySize
+}
+
+// BodyTooLargeError is returned for a health response whose body exceeds the
+// target's MaxBodySize, unless it is plain text, which is truncated.
+type BodyTooLargeError struct {
+	Limit int64
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("health response body exceeds the limit of %s", formatByteSize(e.Limit))
+}
+
+// formatByteSize formats a size in the largest binary unit dividing it.
+func formatByteSize(n int64) string {
+	switch {
+	case n%(1<<30) == 0:
+		return fmt.Sprintf("%d GiB", n>>30)
+	case n%(1<<20) == 0:
+		return fmt.Sprintf("%d MiB", n>>20)
+	case n%(1<<10) == 0:
+		return fmt.Sprintf("%d KiB", n>>10)
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+// limitedBody reads at most limit bytes of a response body and records
+// whether it held more. The first HealthTextMaxBytes read are kept for error
+// messages.
+type limitedBody struct {
+	r        io.LimitedReader
+	limit    int64
+	exceeded bool
+	head     []byte
+}
+
+// newLimitedBody returns a reader of the first limit bytes of r.
+func newLimitedBody(r io.Reader, limit int64) *limitedBody {
+	// One byte past the limit tells a body of exactly limit bytes from a
+	// larger one
+	return &limitedBody{r: io.LimitedReader{R: r, N: limit + 1}, limit: limit}
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if b.r.N == 0 {
+		// The byte past the limit is dropped
+		b.exceeded = true
+		n, err = max(n-1, 0), io.EOF
+	}
+	if len(b.head) < HealthTextMaxBytes {
+		b.head = append(b.head, p[:min(n, HealthTextMaxBytes-len(b.head))]...)
+	}
+	return n, err
+}
+
+// tooLarge returns a BodyTooLargeError if the body exceeded the limit.
+func (b *limitedBody) tooLarge() error {
+	if b.exceeded {
+		return &BodyTooLargeError{Limit: b.limit}
+	}
+	return nil
+}
+
+// excerpt returns the start of the body read so far, for error messages.
+func (b *limitedBody) excerpt() string {
+	s := strings.ToValidUTF8(string(b.head), "")
+	if len(b.head) == HealthTextMaxBytes {
+		s += "..."
+	}
+	return s
+}
@@ -0,0 +1,133 @@
+package monitor
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// OCSP stapling states of a TLSInfo
+const (
+	OCSPGood      = "good"
+	OCSPRevoked   = "revoked"
+	OCSPUnknown   = "unknown"
+	OCSPNone      = "not stapled"
+	OCSPMalformed = "invalid"
+)
+
+// TLSInfo describes the TLS session of a check: the certificate chain the
+// server presented, whether it validates, and the OCSP response stapled to
+// it.
+type TLSInfo struct {
+	Version     string
+	CipherSuite string
+	// Certificates are the certificates the server sent, leaf first.
+	Certificates []CertInfo
+	// Roots names the root store the chain was validated against: the
+	// system roots or a custom CA bundle.
+	Roots string
+	// VerifyError is why the chain does not validate, or empty when it
+	// does.
+	VerifyError string
+	// OCSP is the status of the stapled OCSP response, one of OCSPGood,
+	// OCSPRevoked, OCSPUnknown, OCSPNone or OCSPMalformed, with its
+	// NextUpdate and, once revoked, RevokedAt.
+	OCSP           string
+	OCSPNextUpdate time.Time
+	OCSPRevokedAt  time.Time
+}
+
+// CertInfo describes a certificate of a chain.
+type CertInfo struct {
+	Subject   string
+	Issuer    string
+	NotBefore time.Time
+	NotAfter  time.Time
+	// Key is the public key algorithm and size, e.g. "RSA 2048" or
+	// "ECDSA P-256".
+	Key string
+}
+
+// tlsInfo describes a TLS session, validating the chain against roots, or the
+// system roots when nil, for serverName. It also returns why the chain does
+// not validate.
+func tlsInfo(state tls.ConnectionState, roots *x509.CertPool, serverName string, now time.Time) (*TLSInfo, error) {
+	info := &TLSInfo{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		Roots:       "system roots",
+		OCSP:        OCSPNone,
+	}
+	if roots != nil {
+		info.Roots = "custom CA bundle"
+	}
+	for _, cert := range state.PeerCertificates {
+		info.Certificates = append(info.Certificates, CertInfo{
+			Subject:   cert.Subject.String(),
+			Issuer:    cert.Issuer.String(),
+			NotBefore: cert.NotBefore,
+			NotAfter:  cert.NotAfter,
+			Key:       publicKeyLabel(cert),
+		})
+	}
+	if len(state.PeerCertificates) == 0 {
+		return info, nil
+	}
+	leaf := state.PeerCertificates[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	chains, verifyErr := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		DNSName:       serverName,
+		CurrentTime:   now,
+	})
+	if verifyErr != nil {
+		info.VerifyError = verifyErr.Error()
+	}
+	if len(state.OCSPResponse) > 0 {
+		// The issuer is taken from the verified chain, or from what the
+		// server sent when it does not validate
+		var issuer *x509.Certificate
+		switch {
+		case len(chains) > 0 && len(chains[0]) > 1:
+			issuer = chains[0][1]
+		case len(state.PeerCertificates) > 1:
+			issuer = state.PeerCertificates[1]
+		}
+		info.OCSP = OCSPMalformed
+		if resp, err := ocsp.ParseResponseForCert(state.OCSPResponse, leaf, issuer); err == nil {
+			info.OCSPNextUpdate = resp.NextUpdate
+			switch resp.Status {
+			case ocsp.Good:
+				info.OCSP = OCSPGood
+			case ocsp.Revoked:
+				info.OCSP, info.OCSPRevokedAt = OCSPRevoked, resp.RevokedAt
+			default:
+				info.OCSP = OCSPUnknown
+			}
+		}
+	}
+	return info, verifyErr
+}
+
+// publicKeyLabel names the algorithm and size of the public key of cert.
+func publicKeyLabel(cert *x509.Certificate) string {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA " + key.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	}
+	return cert.PublicKeyAlgorithm.String()
+}
@@ -0,0 +1,36 @@
+package monitor
+
+import (
+	"context"
+	"time"
+)
+
+// Checker probes a target once.
+type Checker interface {
+	// Name identifies the probe type, e.g. "tcp" or "http".
+	Name() string
+	Check(ctx context.Context, t Target) Result
+}
+
+// Result is the outcome of a single Checker run.
+type Result struct {
+	Latency time.Duration
+	// Detail is a short human-readable summary of a successful check.
+	Detail string
+	// Data holds structured output of the check, such as a decoded health
+	// endpoint response.
+	Data map[string]any
+	Err  error
+	// Timing is the phase breakdown of HTTP-based checks.
+	Timing *Timing
+	// Attempts is how many times the check ran in this cycle, including
+	// retries.
+	Attempts int
+	// Probes is set when the target sends several probes per cycle.
+	Probes *ProbeStats
+	// Headers are the response headers of HTTP-based checks selected by
+	// the target's CaptureHeaders.
+	Headers map[string]string
+	// TLS describes the TLS session and certificate chain of TLS checks.
+	TLS *TLSInfo
+}
@@ -0,0 +1,43 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultCheckTimeout bounds every check attempt of a target that does not
+// set CheckTimeout.
+const DefaultCheckTimeout = time.Minute
+
+// checkTimeout returns the deadline of one check attempt of the target.
+func (t Target) checkTimeout() time.Duration {
+	if t.CheckTimeout > 0 {
+		return t.CheckTimeout
+	}
+	return DefaultCheckTimeout
+}
+
+// checkWithDeadline runs c against t with a context bounded by the target's
+// check timeout. A checker that does not return by then is abandoned and
+// the attempt fails, so a hung server cannot stall the target's checks.
+func checkWithDeadline(ctx context.Context, c Checker, t Target) Result {
+	timeout := t.checkTimeout()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	done := make(chan Result, 1)
+	go func() { done <- c.Check(ctx, t) }()
+	select {
+	case r := <-done:
+		if r.Err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			r.Err = fmt.Errorf("check timed out after %s: %w", timeout, r.Err)
+		}
+		return r
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return Result{Err: fmt.Errorf("check timed out after %s", timeout)}
+		}
+		return Result{Err: ctx.Err()}
+	}
+}
@@ -0,0 +1,54 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// DNSChecker resolves the target's hostname.
+type DNSChecker struct {
+	Resolver *net.Resolver
+}
+
+// NewDNSChecker returns a DNSChecker using the system resolver.
+func NewDNSChecker() DNSChecker {
+	return DNSChecker{Resolver: net.DefaultResolver}
+}
+
+// Name implements Checker.
+func (c DNSChecker) Name() string { return CheckDNS }
+
+// Check looks up the target's addresses, only A or AAAA records when the
+// target has an IP family. The target's DNS server takes precedence over the
+// checker's resolver.
+func (c DNSChecker) Check(ctx context.Context, t Target) Result {
+	resolver := c.Resolver
+	if t.DNSServer != "" {
+		resolver = t.resolver()
+	}
+	start := time.Now()
+	var addrs []string
+	ips, err := resolver.LookupIP(ctx, t.network("ip"), t.Host)
+	elapsed := time.Since(start)
+	if err != nil {
+		return Result{Latency: elapsed, Err: err}
+	}
+	for _, ip := range ips {
+		addrs = append(addrs, ip.String())
+	}
+	if len(addrs) == 0 {
+		return Result{Latency: elapsed, Err: fmt.Errorf("no addresses for %s", t.Host)}
+	}
+	detail := "Resolved to " + strings.Join(addrs, ", ")
+	if t.DNSServer != "" {
+		detail += " via " + t.DNSServer
+	}
+	return Result{
+		Latency: elapsed,
+		Detail:  detail,
+		Data:    map[string]any{"addresses": addrs},
+	}
+}
@@ -0,0 +1,122 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// IP address families a target can be checked over
+const (
+	// FamilyAny lets the resolver and dialer pick, as the system does.
+	FamilyAny  = ""
+	FamilyIPv4 = "v4"
+	FamilyIPv6 = "v6"
+	// FamilyBoth checks IPv4 and IPv6 separately in every cycle.
+	FamilyBoth = "both"
+)
+
+// ParseFamily validates an IP family: v4, v6, both, or any (or empty) for
+// the system default.
+func ParseFamily(s string) (string, error) {
+	switch strings.ToLower(s) {
+	case FamilyAny, "any":
+		return FamilyAny, nil
+	case FamilyIPv4, "ipv4", "4":
+		return FamilyIPv4, nil
+	case FamilyIPv6, "ipv6", "6":
+		return FamilyIPv6, nil
+	case FamilyBoth, "dual":
+		return FamilyBoth, nil
+	}
+	return "", fmt.Errorf("invalid IP family %q (expected v4, v6, both or any)", s)
+}
+
+// FamilyLabel returns the display name of an IP family.
+func FamilyLabel(family string) string {
+	switch family {
+	case FamilyIPv4:
+		return "IPv4"
+	case FamilyIPv6:
+		return "IPv6"
+	}
+	return "IP"
+}
+
+// FamilyResult is the outcome of checking a dual-stack target over one IP
+// family.
+type FamilyResult struct {
+	Family string
+	Result
+}
+
+// network restricts a Go network name such as "tcp" or "ip" to the target's
+// IP family.
+func (t Target) network(base string) string {
+	switch t.Family {
+	case FamilyIPv4:
+		return base + "4"
+	case FamilyIPv6:
+		return base + "6"
+	}
+	return base
+}
+
+// checkFamilies runs c over IPv4 and IPv6 concurrently.
+func (m *Monitor) checkFamilies(ctx context.Context, c Checker, t Target) []FamilyResult {
+	results := []FamilyResult{{Family: FamilyIPv4}, {Family: FamilyIPv6}}
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ft := t
+			ft.Family = results[i].Family
+			results[i].Result = m.Retry.run(ctx, c, ft)
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// combineFamilies merges per-family results into one that fails if any family
+// failed. Latency is that of the slowest family.
+func combineFamilies(results []FamilyResult) Result {
+	var combined Result
+	var errs []error
+	var details []string
+	for _, r := range results {
+		combined.Attempts = max(combined.Attempts, r.Attempts)
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", FamilyLabel(r.Family), r.Err))
+			continue
+		}
+		combined.Latency = max(combined.Latency, r.Latency)
+		details = append(details, FamilyLabel(r.Family)+": "+r.Detail)
+		if combined.Data == nil {
+			combined.Data = r.Data
+			combined.Timing = r.Timing
+		}
+	}
+	if len(errs) == 1 {
+		combined.Err = errs[0]
+	} else if len(errs) > 1 {
+		combined.Err = checkErrors(errs)
+	}
+	combined.Detail = strings.Join(details, "\n  ")
+	return combined
+}
+
+// checkErrors reports the failures of several families or ports on one line.
+type checkErrors []error
+
+func (e checkErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e checkErrors) Unwrap() []error { return e }
@@ -0,0 +1,240 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// gRPC checker settings
+const (
+	DefaultGRPCPort    = "443"
+	DefaultGRPCTimeout = 10 * time.Second
+)
+
+var grpcMethodPattern = regexp.MustCompile(`^[A-Za-z_][\w.]*/[A-Za-z_]\w*$`)
+
+// ParseGRPCMethod validates a fully-qualified unary method name such as
+// "shop.v1.Inventory/Status". A leading slash is accepted and removed.
+func ParseGRPCMethod(s string) (string, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "/")
+	if !grpcMethodPattern.MatchString(s) {
+		return "", fmt.Errorf("%q is not a gRPC method, expected package.Service/Method", s)
+	}
+	return s, nil
+}
+
+// GRPCChecker calls a gRPC server. Without a GRPCMethod on the target it
+// asks the standard health service (grpc.health.v1.Health/Check) whether the
+// server is serving; otherwise it looks up the method with server reflection,
+// calls it with the target's GRPCRequest and returns the response as Data so
+// that assertions can inspect it.
+//
+// HTTPS URL targets and bare hostnames are called over TLS, HTTP URL targets
+// in plaintext.
+type GRPCChecker struct {
+	Port    string
+	Timeout time.Duration
+}
+
+// NewGRPCChecker returns a GRPCChecker using the default port and timeout.
+func NewGRPCChecker() GRPCChecker {
+	return GRPCChecker{Port: DefaultGRPCPort, Timeout: DefaultGRPCTimeout}
+}
+
+// Name implements Checker.
+func (c GRPCChecker) Name() string { return CheckGRPC }
+
+// Check implements Checker.
+func (c GRPCChecker) Check(ctx context.Context, t Target) Result {
+	timeout := c.Timeout
+	if t.HealthTimeout > 0 {
+		timeout = t.HealthTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	conn, err := c.dial(t)
+	if err != nil {
+		return Result{Err: err}
+	}
+	defer conn.Close()
+	if t.GRPCMethod == "" {
+		resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+		elapsed := time.Since(start)
+		if err != nil {
+			return Result{Latency: elapsed, Err: err}
+		}
+		status := resp.GetStatus().String()
+		if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			return Result{Latency: elapsed, Err: fmt.Errorf("gRPC health status %s", status)}
+		}
+		return Result{Latency: elapsed, Detail: "gRPC health: " + status, Data: map[string]any{"status": status}}
+	}
+	data, err := callMethod(ctx, conn, t.GRPCMethod, t.GRPCRequest)
+	elapsed := time.Since(start)
+	if err != nil {
+		return Result{Latency: elapsed, Err: err}
+	}
+	return Result{Latency: elapsed, Detail: t.GRPCMethod + ": " + formatJSON(data), Data: data}
+}
+
+// dial creates a client connection to the target, connecting through its
+// proxy, IP family and DNS settings.
+func (c GRPCChecker) dial(t Target) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if t.Scheme != SchemeHTTP {
+		cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+		if t.TLSConfig != nil {
+			cfg = t.TLSConfig.Clone()
+		}
+		cfg.ServerName = t.Host
+		creds = credentials.NewTLS(cfg)
+	}
+	port := c.Port
+	if t.Scheme != "" {
+		port = t.portOr(c.Port)
+	}
+	dialer := &net.Dialer{Timeout: t.connectTimeout(DefaultTCPTimeout)}
+	return grpc.NewClient("passthrough:///"+net.JoinHostPort(t.Host, port),
+		grpc.WithTransportCredentials(creds),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return t.dial(ctx, dialer, addr)
+		}),
+	)
+}
+
+// callMethod calls a unary method described by server reflection with a
+// request decoded from JSON and returns the response decoded as JSON, with
+// field names as written in the .proto file and default values included.
+func callMethod(ctx context.Context, conn *grpc.ClientConn, method, request string) (map[string]any, error) {
+	service, name, _ := strings.Cut(method, "/")
+	files, err := reflectService(ctx, conn, service)
+	if err != nil {
+		return nil, fmt.Errorf("gRPC reflection: %w", err)
+	}
+	d, err := files.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, fmt.Errorf("gRPC reflection: service %s: %w", service, err)
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("gRPC reflection: %s is not a service", service)
+	}
+	md := sd.Methods().ByName(protoreflect.Name(name))
+	if md == nil {
+		return nil, fmt.Errorf("gRPC reflection: service %s has no method %s", service, name)
+	}
+	if md.IsStreamingClient() || md.IsStreamingServer() {
+		return nil, fmt.Errorf("%s is a streaming method, only unary methods can be called", method)
+	}
+	req := dynamicpb.NewMessage(md.Input())
+	if request != "" {
+		if err := protojson.Unmarshal([]byte(request), req); err != nil {
+			return nil, fmt.Errorf("gRPC request for %s: %w", method, err)
+		}
+	}
+	resp := dynamicpb.NewMessage(md.Output())
+	if err := conn.Invoke(ctx, "/"+method, req, resp); err != nil {
+		return nil, err
+	}
+	b, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(resp)
+	if err != nil {
+		return nil, fmt.Errorf("encoding gRPC response: %w", err)
+	}
+	var data map[string]any
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, fmt.Errorf("decoding gRPC response: %w", err)
+	}
+	return data, nil
+}
+
+// reflectService fetches the file describing service and the files it
+// depends on from the server's reflection service. Dependencies the server
+// does not return, such as well-known types, are taken from the ones linked
+// into this binary.
+func reflectService(ctx context.Context, conn *grpc.ClientConn, service string) (*protoregistry.Files, error) {
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.CloseSend()
+	fetch := func(req *reflectionpb.ServerReflectionRequest) ([]*descriptorpb.FileDescriptorProto, error) {
+		if err := stream.Send(req); err != nil {
+			return nil, err
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		if e := resp.GetErrorResponse(); e != nil {
+			return nil, errors.New(e.GetErrorMessage())
+		}
+		var fds []*descriptorpb.FileDescriptorProto
+		for _, raw := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			fd := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(raw, fd); err != nil {
+				return nil, fmt.Errorf("decoding file descriptor: %w", err)
+			}
+			fds = append(fds, fd)
+		}
+		return fds, nil
+	}
+
+	fds, err := fetch(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: service},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("service %s: %w", service, err)
+	}
+	// The first response usually carries the dependencies as well
+	set := &descriptorpb.FileDescriptorSet{}
+	queued := map[string]bool{}
+	enqueue := func(fds []*descriptorpb.FileDescriptorProto) {
+		for _, fd := range fds {
+			if !queued[fd.GetName()] {
+				queued[fd.GetName()] = true
+				set.File = append(set.File, fd)
+			}
+		}
+	}
+	enqueue(fds)
+	for i := 0; i < len(set.File); i++ {
+		for _, dep := range set.File[i].GetDependency() {
+			if queued[dep] {
+				continue
+			}
+			more, err := fetch(&reflectionpb.ServerReflectionRequest{
+				MessageRequest: &reflectionpb.ServerReflectionRequest_FileByFilename{FileByFilename: dep},
+			})
+			if err != nil {
+				local, lerr := protoregistry.GlobalFiles.FindFileByPath(dep)
+				if lerr != nil {
+					return nil, fmt.Errorf("file %s: %w", dep, err)
+				}
+				more = []*descriptorpb.FileDescriptorProto{protodesc.ToFileDescriptorProto(local)}
+			}
+			enqueue(more)
+		}
+	}
+	return protodesc.NewFiles(set)
+}
@@ -0,0 +1,191 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// H3Checker requests the target's URL over HTTP/3 (QUIC) on a new connection
+// and fails on error status codes, so QUIC endpoints can be checked apart
+// from the HTTP/1.1 and HTTP/2 ones. With the target's H3Compare set, the
+// same request is then sent over TCP, also on a new connection, and its
+// latency and protocol are reported next to those of HTTP/3; its failure
+// does not fail the check.
+type H3Checker struct {
+	// Client sends the comparison request over TCP.
+	Client  *http.Client
+	Timeout time.Duration
+}
+
+// NewH3Checker returns an H3Checker using DefaultHTTPClient for comparisons
+// and DefaultHTTPTimeout.
+func NewH3Checker() H3Checker {
+	return H3Checker{Client: DefaultHTTPClient, Timeout: DefaultHTTPTimeout}
+}
+
+// Name implements Checker.
+func (c H3Checker) Name() string { return CheckH3 }
+
+// Check implements Checker.
+func (c H3Checker) Check(ctx context.Context, t Target) Result {
+	timeout := c.Timeout
+	if t.HealthTimeout > 0 {
+		timeout = t.HealthTimeout
+	}
+	start := time.Now()
+	status, proto, err := c.request(ctx, t, timeout, c.roundTripH3(t))
+	elapsed := time.Since(start)
+	if err != nil {
+		return Result{Latency: elapsed, Err: err}
+	}
+	data := map[string]any{"status_code": status.code, "protocol": proto, "h3_ms": elapsed.Milliseconds()}
+	if status.code >= 400 {
+		return Result{Latency: elapsed, Data: data, Err: fmt.Errorf("HTTP %s", status.text)}
+	}
+	detail := "HTTP/3 " + status.text
+	if t.H3Compare {
+		detail += "\n  " + c.compare(ctx, t, timeout, elapsed, data)
+	}
+	return Result{Latency: elapsed, Detail: detail, Data: data}
+}
+
+// h3Status is the status of a response.
+type h3Status struct {
+	code int
+	text string
+}
+
+// request sends the target's request through rt, which is closed afterwards
+// so every check measures a new connection, and reads the response.
+func (c H3Checker) request(ctx context.Context, t Target, timeout time.Duration, rt http.RoundTripper) (h3Status, string, error) {
+	if closer, ok := rt.(io.Closer); ok {
+		defer closer.Close()
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := t.newRequest(ctx, t.URL())
+	if err != nil {
+		return h3Status{}, "", err
+	}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		return h3Status{}, "", err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	return h3Status{code: resp.StatusCode, text: resp.Status}, resp.Proto, nil
+}
+
+// compare sends the request over TCP, adds its outcome to data and describes
+// it next to the HTTP/3 latency h3, e.g. "HTTP/2.0 over TCP: 48 ms (HTTP/3:
+// 36 ms)".
+func (c H3Checker) compare(ctx context.Context, t Target, timeout, h3 time.Duration, data map[string]any) string {
+	tr, ok := t.transport(c.Client.Transport).(*http.Transport)
+	if !ok {
+		return "no TCP comparison: custom transport"
+	}
+	tr = tr.Clone()
+	tr.DisableKeepAlives = true
+	start := time.Now()
+	_, proto, err := c.request(ctx, t, timeout, closeIdle{tr})
+	elapsed := time.Since(start)
+	if err != nil {
+		data["tcp_error"] = err.Error()
+		return "over TCP: " + err.Error()
+	}
+	data["tcp_protocol"], data["tcp_ms"] = proto, elapsed.Milliseconds()
+	return fmt.Sprintf("%s over TCP: %d ms (HTTP/3: %d ms)", proto, elapsed.Milliseconds(), h3.Milliseconds())
+}
+
+// closeIdle makes an http.Transport an io.Closer releasing its connections.
+type closeIdle struct{ *http.Transport }
+
+func (c closeIdle) Close() error {
+	c.CloseIdleConnections()
+	return nil
+}
+
+// roundTripH3 returns an HTTP/3 transport for a single request to t, dialing
+// with its TLS, IP family, DNS and source address settings.
+func (c H3Checker) roundTripH3(t Target) *h3Transport {
+	h := &h3Transport{}
+	h.Transport = &http3.Transport{
+		TLSClientConfig: t.TLSConfig,
+		Dial: func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (*quic.Conn, error) {
+			raddr, pc, err := t.listenQUIC(ctx, addr)
+			if err != nil {
+				return nil, err
+			}
+			h.mu.Lock()
+			h.pcs = append(h.pcs, pc)
+			h.mu.Unlock()
+			return quic.DialEarly(ctx, pc, raddr, tlsCfg, cfg)
+		},
+	}
+	return h
+}
+
+// h3Transport is an HTTP/3 transport that also closes the sockets its
+// connections used.
+type h3Transport struct {
+	*http3.Transport
+	mu  sync.Mutex
+	pcs []net.PacketConn
+}
+
+func (h *h3Transport) Close() error {
+	err := h.Transport.Close()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, pc := range h.pcs {
+		pc.Close()
+	}
+	h.pcs = nil
+	return err
+}
+
+// listenQUIC resolves addr within the target's IP family with its DNS
+// server and opens a UDP socket to reach it from, on the target's source
+// address if set. The first address the source has a family for is used.
+func (t Target) listenQUIC(ctx context.Context, addr string) (*net.UDPAddr, net.PacketConn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	ips, err := t.resolver().LookupNetIP(ctx, t.network("ip"), host)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, ip := range ips {
+		ip = ip.Unmap()
+		family, network := FamilyIPv4, "udp4"
+		if ip.Is6() {
+			family, network = FamilyIPv6, "udp6"
+		}
+		local, err := t.sourceIP(family)
+		if err != nil {
+			if i < len(ips)-1 {
+				continue
+			}
+			return nil, nil, err
+		}
+		pc, err := net.ListenUDP(network, &net.UDPAddr{IP: local})
+		if err != nil {
+			return nil, nil, err
+		}
+		p, _ := strconv.Atoi(port)
+		return net.UDPAddrFromAddrPort(netip.AddrPortFrom(ip, uint16(p))), pc, nil
+	}
+	return nil, nil, fmt.Errorf("no address for %s", host)
+}
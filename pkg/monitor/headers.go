@@ -0,0 +1,46 @@
+package monitor
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// headerNamePattern matches the token characters allowed in header names.
+var headerNamePattern = regexp.MustCompile(`^[A-Za-z0-9!#$%&'*+.^_|~-]+$`)
+
+// ParseHeaderNames parses a comma-separated list of response header names
+// to capture, e.g. "Server, X-Request-ID, Cache-Control". Names are returned
+// in canonical form.
+func ParseHeaderNames(s string) ([]string, error) {
+	var names []string
+	for _, part := range strings.Split(s, ",") {
+		name := strings.TrimSpace(part)
+		if name == "" {
+			continue
+		}
+		if !headerNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid header name %q", name)
+		}
+		names = append(names, http.CanonicalHeaderKey(name))
+	}
+	return names, nil
+}
+
+// captureHeaders returns the headers of h named in names, with repeated
+// values joined by ", ", or nil if none of them is present.
+func captureHeaders(h http.Header, names []string) map[string]string {
+	var out map[string]string
+	for _, name := range names {
+		values := h.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]string, len(names))
+		}
+		out[name] = strings.Join(values, ", ")
+	}
+	return out
+}
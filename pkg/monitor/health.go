@@ -0,0 +1,100 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// HTTP client settings for health checks
+const (
+	DefaultHTTPTimeout         = 10 * time.Second
+	DefaultTLSHandshakeTimeout = 5 * time.Second
+	DefaultIdleConnTimeout     = 90 * time.Second
+	DefaultMaxIdleConnsPerHost = 4
+)
+
+// DefaultHTTPClient is shared by all health checks so connections are reused
+// across cycles and every request is bounded by an overall timeout.
+var DefaultHTTPClient = &http.Client{
+	Timeout: DefaultHTTPTimeout,
+	Transport: &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         (&net.Dialer{Timeout: DefaultTCPTimeout}).DialContext,
+		TLSHandshakeTimeout: DefaultTLSHandshakeTimeout,
+		IdleConnTimeout:     DefaultIdleConnTimeout,
+		MaxIdleConnsPerHost: DefaultMaxIdleConnsPerHost,
+		ForceAttemptHTTP2:   true,
+	},
+}
+
+// HTTPError is returned by the health checker when the endpoint responds
+// with a non-2xx status.
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("health endpoint HTTP %d: %s", e.StatusCode, e.Body)
+}
+
+// HealthChecker fetches a target's health endpoint and decodes its body as
+// JSON, XML or YAML, or keeps the first lines of a plain text body (see
+// decodeHealth). At most the target's MaxBodySize of the body is read.
+// The endpoint is requested with the target's scheme and port, defaulting to
+// HTTPS, and the target's Basic authentication credentials, if any.
+type HealthChecker struct {
+	Client *http.Client
+}
+
+// NewHealthChecker returns a HealthChecker using DefaultHTTPClient.
+func NewHealthChecker() HealthChecker {
+	return HealthChecker{Client: DefaultHTTPClient}
+}
+
+// Name implements Checker.
+func (c HealthChecker) Name() string { return "health" }
+
+// Check requests the target's health endpoint.
+func (c HealthChecker) Check(ctx context.Context, t Target) Result {
+	start := time.Now()
+	ctx, timing := withTrace(ctx)
+	data, headers, err := c.fetch(ctx, t)
+	return Result{Latency: time.Since(start), Data: data, Timing: timing, Headers: headers, Err: err}
+}
+
+// fetch requests the health endpoint and decodes its response. The captured
+// response headers are returned whenever a response arrived.
+func (c HealthChecker) fetch(ctx context.Context, t Target) (map[string]any, map[string]string, error) {
+	if t.HealthEndpoint == "" {
+		return nil, nil, fmt.Errorf("health endpoint not configured")
+	}
+	req, err := t.newRequest(ctx, t.healthURL())
+	if err != nil {
+		return nil, nil, err
+	}
+	if t.HealthUsername != "" || t.HealthPassword != "" {
+		req.SetBasicAuth(t.HealthUsername, t.HealthPassword)
+	}
+	resp, err := t.httpClient(c.Client).Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	headers := captureHeaders(resp.Header, t.CaptureHeaders)
+	body := newLimitedBody(resp.Body, t.maxBodySize())
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// The body of an error is kept up to the limit
+		b, err := io.ReadAll(body)
+		if err != nil {
+			return nil, headers, err
+		}
+		return nil, headers, &HTTPError{StatusCode: resp.StatusCode, Body: string(b)}
+	}
+	data, err := decodeHealth(resp.Header.Get("Content-Type"), resp.StatusCode, body)
+	return data, headers, err
+}
@@ -0,0 +1,208 @@
+package monitor
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Health response formats, detected from the Content-Type header
+const (
+	FormatJSON = "json"
+	FormatXML  = "xml"
+	FormatYAML = "yaml"
+	FormatText = "text"
+)
+
+// Limits of the body kept from plain text health responses
+const (
+	HealthTextLines    = 5
+	HealthTextMaxBytes = 500
+)
+
+// healthFormat returns the format declared by a Content-Type header, or
+// FormatText when it declares none of JSON, XML and YAML.
+func healthFormat(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return FormatText
+	}
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return FormatJSON
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		return FormatXML
+	case mediaType == "application/yaml" || mediaType == "application/x-yaml" || mediaType == "text/yaml" ||
+		mediaType == "text/x-yaml" || strings.HasSuffix(mediaType, "+yaml"):
+		return FormatYAML
+	}
+	return FormatText
+}
+
+// decodeHealth decodes a successful health response according to its
+// Content-Type. JSON objects are returned as they are, YAML mappings the way
+// JSON would decode them and XML documents as the content of their root
+// element (see decodeXML). A body that declares none of these formats is
+// still decoded if it is a JSON object, as many services send JSON as
+// text/plain; otherwise its first lines are returned as "body", with the
+// HTTP status as "status_code". A body that does not parse as the format it
+// declares is an error.
+// JSON and XML are decoded as they are read. A body larger than the limit
+// is an error unless it is plain text, which is cut at the limit.
+func decodeHealth(contentType string, statusCode int, body *limitedBody) (map[string]any, error) {
+	format := healthFormat(contentType)
+	switch format {
+	case FormatJSON:
+		var data map[string]any
+		if err := json.NewDecoder(body).Decode(&data); err != nil {
+			if tooLarge := body.tooLarge(); tooLarge != nil {
+				return nil, tooLarge
+			}
+			return nil, fmt.Errorf("invalid JSON from health endpoint: %w\nBody: %s", err, body.excerpt())
+		}
+		return data, nil
+	case FormatXML:
+		data, err := decodeXML(body)
+		if err != nil {
+			if tooLarge := body.tooLarge(); tooLarge != nil {
+				return nil, tooLarge
+			}
+			return nil, fmt.Errorf("invalid XML from health endpoint: %w\nBody: %s", err, body.excerpt())
+		}
+		return data, nil
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if format == FormatYAML {
+		if err := body.tooLarge(); err != nil {
+			return nil, err
+		}
+		data, err := decodeYAML(b)
+		if err != nil {
+			return nil, fmt.Errorf("invalid YAML from health endpoint: %w\nBody: %s", err, b)
+		}
+		return data, nil
+	}
+	var data map[string]any
+	if err := json.Unmarshal(b, &data); err == nil && data != nil {
+		return data, nil
+	}
+	return map[string]any{"status_code": float64(statusCode), "body": firstLines(string(b))}, nil
+}
+
+// firstLines returns the first HealthTextLines non-empty lines of s, cut at
+// HealthTextMaxBytes.
+func firstLines(s string) string {
+	var lines []string
+	for line := range strings.Lines(s) {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+		if len(lines) == HealthTextLines {
+			break
+		}
+	}
+	out := strings.Join(lines, "\n")
+	if len(out) > HealthTextMaxBytes {
+		out = strings.ToValidUTF8(out[:HealthTextMaxBytes], "") + "..."
+	}
+	return out
+}
+
+// decodeYAML decodes a YAML mapping into the types JSON decodes to, so
+// paths and assertions treat both alike.
+func decodeYAML(body []byte) (map[string]any, error) {
+	var v any
+	if err := yaml.Unmarshal(body, &v); err != nil {
+		return nil, err
+	}
+	if _, ok := v.(map[string]any); !ok {
+		return nil, errors.New("expected a mapping with string keys")
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var data map[string]any
+	return data, json.Unmarshal(b, &data)
+}
+
+// decodeXML decodes an XML document into the content of its root element:
+// child elements become keys holding their text, or an object if they have
+// children or attributes themselves, and repeated elements a list.
+// Attributes are stored as "@name" and the text of an element that also has
+// children or attributes as "#text". A root element holding only text is
+// returned as a single key. Values are strings; numeric comparisons accept
+// them.
+func decodeXML(body io.Reader) (map[string]any, error) {
+	d := xml.NewDecoder(body)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil, errors.New("no root element")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			v, err := decodeXMLElement(d, start)
+			if err != nil {
+				return nil, err
+			}
+			if obj, ok := v.(map[string]any); ok {
+				return obj, nil
+			}
+			return map[string]any{start.Name.Local: v}, nil
+		}
+	}
+}
+
+// decodeXMLElement decodes the element opened by start, up to its end.
+func decodeXMLElement(d *xml.Decoder, start xml.StartElement) (any, error) {
+	obj := make(map[string]any)
+	for _, a := range start.Attr {
+		obj["@"+a.Name.Local] = a.Value
+	}
+	var text strings.Builder
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			v, err := decodeXMLElement(d, tok)
+			if err != nil {
+				return nil, err
+			}
+			name := tok.Name.Local
+			switch prev := obj[name].(type) {
+			case nil:
+				obj[name] = v
+			case []any:
+				obj[name] = append(prev, v)
+			default:
+				obj[name] = []any{prev, v}
+			}
+		case xml.CharData:
+			text.Write(tok)
+		case xml.EndElement:
+			s := strings.TrimSpace(text.String())
+			if len(obj) == 0 {
+				return s, nil
+			}
+			if s != "" {
+				obj["#text"] = s
+			}
+			return obj, nil
+		}
+	}
+}
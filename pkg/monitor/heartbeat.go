@@ -0,0 +1,82 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultHeartbeatWindow is how long a heartbeat target may stay silent
+// unless it sets another window.
+const DefaultHeartbeatWindow = 5 * time.Minute
+
+// Heartbeat records the heartbeats a passive target sends, e.g. a cron job
+// or a service that cannot be reached from outside. It is safe for
+// concurrent use.
+type Heartbeat struct {
+	mu      sync.Mutex
+	started time.Time
+	last    time.Time
+	failed  bool
+	message string
+}
+
+// NewHeartbeat returns a Heartbeat that starts waiting for the first beat
+// now.
+func NewHeartbeat() *Heartbeat {
+	return &Heartbeat{started: time.Now()}
+}
+
+// Beat records a heartbeat. A beat that is not ok reports a failure of the
+// sender, described by message.
+func (h *Heartbeat) Beat(ok bool, message string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.last = time.Now()
+	h.failed = !ok
+	h.message = message
+}
+
+// HeartbeatChecker checks that a heartbeat target sent a successful beat
+// within its window. It does not connect anywhere.
+type HeartbeatChecker struct{}
+
+// NewHeartbeatChecker returns a HeartbeatChecker.
+func NewHeartbeatChecker() HeartbeatChecker { return HeartbeatChecker{} }
+
+// Name implements Checker.
+func (HeartbeatChecker) Name() string { return CheckHeartbeat }
+
+// Check implements Checker. Until the first beat arrives the target counts
+// as up for one window.
+func (HeartbeatChecker) Check(_ context.Context, t Target) Result {
+	h := t.Heartbeat
+	if h == nil {
+		return Result{Err: errors.New("heartbeats are only received by a running instance serving WEB_LISTEN")}
+	}
+	window := t.HeartbeatWindow
+	if window <= 0 {
+		window = DefaultHeartbeatWindow
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	if h.last.IsZero() {
+		if waited := now.Sub(h.started); waited > window {
+			return Result{Err: fmt.Errorf("no heartbeat received in %s", waited.Round(time.Second))}
+		}
+		return Result{Detail: "Waiting for the first heartbeat"}
+	}
+	age := now.Sub(h.last).Round(time.Second)
+	switch {
+	case h.failed && h.message != "":
+		return Result{Err: fmt.Errorf("heartbeat reported a failure %s ago: %s", age, h.message)}
+	case h.failed:
+		return Result{Err: fmt.Errorf("heartbeat reported a failure %s ago", age)}
+	case age > window:
+		return Result{Err: fmt.Errorf("no heartbeat for %s (expected within %s)", age, window)}
+	}
+	return Result{Detail: fmt.Sprintf("Last heartbeat %s ago", age)}
+}
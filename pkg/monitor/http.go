@@ -0,0 +1,85 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// HTTPChecker requests the target's URL (the root over HTTPS for bare
+// hostnames) and fails on error status codes, or when the body lacks the
+// target's ExpectBody content. Redirects are followed up to the target's
+// MaxRedirects and reported as hops; the first must match the target's
+// ExpectRedirect, if set.
+type HTTPChecker struct {
+	Client *http.Client
+}
+
+// NewHTTPChecker returns an HTTPChecker using DefaultHTTPClient.
+func NewHTTPChecker() HTTPChecker {
+	return HTTPChecker{Client: DefaultHTTPClient}
+}
+
+// Name implements Checker.
+func (c HTTPChecker) Name() string { return CheckHTTP }
+
+// Check requests the target's URL, with a GET unless the target sets
+// another method, and reports the status code.
+func (c HTTPChecker) Check(ctx context.Context, t Target) Result {
+	start := time.Now()
+	ctx, timing := withTrace(ctx)
+	req, err := t.newRequest(ctx, t.URL())
+	if err != nil {
+		return Result{Err: err}
+	}
+	client := *t.httpClient(c.Client)
+	var hops []Hop
+	client.CheckRedirect = t.followRedirects(&hops)
+	resp, err := client.Do(req)
+	if err != nil {
+		return Result{Err: err, Timing: timing}
+	}
+	var body []byte
+	if t.ExpectBody != nil {
+		body, err = io.ReadAll(io.LimitReader(resp.Body, MaxBodyMatchSize))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	elapsed := time.Since(start)
+	// A redirect that was not followed is the last hop
+	if location, lerr := resp.Location(); lerr == nil && resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		hops = append(hops, Hop{URL: resp.Request.URL.String(), StatusCode: resp.StatusCode, Location: location.String()})
+	}
+	headers := captureHeaders(resp.Header, t.CaptureHeaders)
+	data := map[string]any{"status_code": resp.StatusCode}
+	if len(hops) > 0 {
+		data["redirects"] = hopData(hops)
+	}
+	if resp.StatusCode >= 400 {
+		return Result{Latency: elapsed, Data: data, Timing: timing, Headers: headers, Err: fmt.Errorf("HTTP %s", resp.Status)}
+	}
+	if err != nil {
+		return Result{Latency: elapsed, Data: data, Timing: timing, Headers: headers, Err: fmt.Errorf("read body: %w", err)}
+	}
+	if t.ExpectRedirect != nil {
+		var first *Hop
+		if len(hops) > 0 {
+			first = &hops[0]
+		}
+		if err := t.ExpectRedirect.match(first); err != nil {
+			return Result{Latency: elapsed, Data: data, Timing: timing, Headers: headers, Err: err}
+		}
+	}
+	if t.ExpectBody != nil {
+		if err := t.ExpectBody.Match(body); err != nil {
+			return Result{Latency: elapsed, Data: data, Timing: timing, Headers: headers, Err: fmt.Errorf("HTTP %s: %w", resp.Status, err)}
+		}
+	}
+	detail := "HTTP " + resp.Status
+	for _, h := range hops {
+		detail += "\n  " + h.String()
+	}
+	return Result{Latency: elapsed, Detail: detail, Data: data, Timing: timing, Headers: headers}
+}
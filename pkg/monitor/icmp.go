@@ -0,0 +1,116 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// DefaultICMPTimeout bounds how long the ICMP checker waits for a reply.
+const DefaultICMPTimeout = 5 * time.Second
+
+// IANA protocol numbers of ICMP for IPv4 and IPv6
+const (
+	protocolICMP     = 1
+	protocolICMPIPv6 = 58
+)
+
+// icmpFamily holds the socket and message settings of one IP family.
+type icmpFamily struct {
+	lookup, udp, raw, listen string
+	protocol                 int
+	request, reply           icmp.Type
+}
+
+var (
+	icmpIPv4 = icmpFamily{"ip4", "udp4", "ip4:icmp", "0.0.0.0", protocolICMP, ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply}
+	icmpIPv6 = icmpFamily{"ip6", "udp6", "ip6:ipv6-icmp", "::", protocolICMPIPv6, ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply}
+)
+
+// ICMPChecker sends an ICMP echo request and waits for the reply. It uses an
+// unprivileged datagram socket where the OS allows it and falls back to a
+// raw socket, which requires elevated privileges.
+type ICMPChecker struct {
+	Timeout time.Duration
+}
+
+// NewICMPChecker returns an ICMPChecker using the default timeout.
+func NewICMPChecker() ICMPChecker {
+	return ICMPChecker{Timeout: DefaultICMPTimeout}
+}
+
+// Name implements Checker.
+func (c ICMPChecker) Name() string { return CheckICMP }
+
+// Check pings the first IPv4 address of the target, or its first IPv6
+// address when the target's family or source address is IPv6.
+func (c ICMPChecker) Check(ctx context.Context, t Target) Result {
+	f := t.pingFamily()
+	ips, err := t.resolver().LookupIP(ctx, f.lookup, t.Host)
+	if err != nil {
+		return Result{Err: err}
+	}
+	ip := ips[0]
+	_, listen, err := t.pingSource(f)
+	if err != nil {
+		return Result{Err: err}
+	}
+
+	privileged := false
+	conn, err := icmp.ListenPacket(f.udp, listen)
+	if err != nil {
+		conn, err = icmp.ListenPacket(f.raw, listen)
+		if err != nil {
+			return Result{Err: fmt.Errorf("icmp socket: %w", err)}
+		}
+		privileged = true
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(t.connectTimeout(c.Timeout))
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+
+	seq := int(time.Now().UnixNano() & 0xffff)
+	msg := icmp.Message{
+		Type: f.request,
+		Body: &icmp.Echo{ID: os.Getpid() & 0xffff, Seq: seq, Data: []byte("vivteno")},
+	}
+	wb, err := msg.Marshal(nil)
+	if err != nil {
+		return Result{Err: err}
+	}
+	var dst net.Addr = &net.UDPAddr{IP: ip}
+	if privileged {
+		dst = &net.IPAddr{IP: ip}
+	}
+
+	start := time.Now()
+	if _, err := conn.WriteTo(wb, dst); err != nil {
+		return Result{Err: err}
+	}
+	rb := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFrom(rb)
+		if err != nil {
+			return Result{Err: err}
+		}
+		reply, err := icmp.ParseMessage(f.protocol, rb[:n])
+		if err != nil || reply.Type != f.reply {
+			continue
+		}
+		// Unprivileged sockets rewrite the echo ID, so match on sequence only
+		if echo, ok := reply.Body.(*icmp.Echo); ok && echo.Seq == seq {
+			elapsed := time.Since(start)
+			return Result{Latency: elapsed, Detail: "ICMP echo reply from " + ip.String()}
+		}
+	}
+}
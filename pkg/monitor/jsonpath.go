@@ -0,0 +1,109 @@
+package monitor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Lookup evaluates a simple JSONPath expression against a decoded JSON value.
+// Supported syntax is the root "$" followed by any number of child accessors:
+// ".name", "['name']" and "[index]", e.g. "$.dependencies.db.status" or
+// "$.checks[0]['response time']".
+func Lookup(v any, expr string) (any, error) {
+	path, err := ParsePath(expr)
+	if err != nil {
+		return nil, err
+	}
+	return path.Lookup(v)
+}
+
+// Path is a parsed JSONPath expression. Each step is either a string key or
+// an int index.
+type Path struct {
+	Expr  string
+	steps []any
+}
+
+// ParsePath parses a JSONPath expression without evaluating it.
+func ParsePath(expr string) (Path, error) {
+	p := Path{Expr: expr}
+	s := strings.TrimSpace(expr)
+	if !strings.HasPrefix(s, "$") {
+		return p, fmt.Errorf("jsonpath %q: must start with $", expr)
+	}
+	s = s[1:]
+	for s != "" {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+			end := strings.IndexAny(s, ".[")
+			if end < 0 {
+				end = len(s)
+			}
+			if end == 0 {
+				return p, fmt.Errorf("jsonpath %q: empty field name", expr)
+			}
+			p.steps = append(p.steps, s[:end])
+			s = s[end:]
+		case '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return p, fmt.Errorf("jsonpath %q: unterminated [", expr)
+			}
+			inner := strings.TrimSpace(s[1:end])
+			s = s[end+1:]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				p.steps = append(p.steps, inner[1:len(inner)-1])
+				continue
+			}
+			n, err := strconv.Atoi(inner)
+			if err != nil {
+				return p, fmt.Errorf("jsonpath %q: invalid index %q", expr, inner)
+			}
+			p.steps = append(p.steps, n)
+		default:
+			return p, fmt.Errorf("jsonpath %q: unexpected %q", expr, s[0])
+		}
+	}
+	return p, nil
+}
+
+// Lookup evaluates the path against v.
+func (p Path) Lookup(v any) (any, error) {
+	cur := v
+	for _, step := range p.steps {
+		switch step := step.(type) {
+		case string:
+			obj, ok := cur.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: %q is not an object", p.Expr, step)
+			}
+			next, ok := obj[step]
+			if !ok {
+				return nil, fmt.Errorf("%s: field %q not found", p.Expr, step)
+			}
+			cur = next
+		case int:
+			arr, ok := cur.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: [%d] is not an array", p.Expr, step)
+			}
+			if step < 0 || step >= len(arr) {
+				return nil, fmt.Errorf("%s: index %d out of range", p.Expr, step)
+			}
+			cur = arr[step]
+		}
+	}
+	return cur, nil
+}
+
+// Label returns a short display name for the path: the expression without
+// the leading "$.".
+func (p Path) Label() string {
+	label := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(p.Expr), "$"), ".")
+	if label == "" {
+		return "$"
+	}
+	return label
+}
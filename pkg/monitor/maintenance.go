@@ -0,0 +1,120 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Window is a maintenance window during which failures of a target are
+// expected. It is either a single absolute period (Start/End) or a recurring
+// period that starts whenever Schedule matches and lasts Duration.
+type Window struct {
+	Start, End time.Time
+	Schedule   *Cron
+	Duration   time.Duration
+	loc        *time.Location
+}
+
+// ParseWindow parses a maintenance window. Two forms are accepted:
+//
+//	2026-10-20T22:00:00Z/2026-10-21T02:00:00Z   absolute start/end (RFC 3339)
+//	0 2 * * 0 2h                                five cron fields and a duration
+//
+// Cron schedules are evaluated in loc.
+func ParseWindow(s string, loc *time.Location) (Window, error) {
+	s = strings.TrimSpace(s)
+	if loc == nil {
+		loc = time.Local
+	}
+	if start, end, ok := strings.Cut(s, "/"); ok && !strings.Contains(s, " ") {
+		st, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return Window{}, fmt.Errorf("maintenance window %q: start: %w", s, err)
+		}
+		en, err := time.Parse(time.RFC3339, end)
+		if err != nil {
+			return Window{}, fmt.Errorf("maintenance window %q: end: %w", s, err)
+		}
+		if !en.After(st) {
+			return Window{}, fmt.Errorf("maintenance window %q: end must be after start", s)
+		}
+		return Window{Start: st, End: en, loc: loc}, nil
+	}
+	fields := strings.Fields(s)
+	if len(fields) != 6 {
+		return Window{}, fmt.Errorf("maintenance window %q: expected start/end or 5 cron fields and a duration", s)
+	}
+	cron, err := ParseCron(strings.Join(fields[:5], " "))
+	if err != nil {
+		return Window{}, fmt.Errorf("maintenance window %q: %w", s, err)
+	}
+	d, err := time.ParseDuration(fields[5])
+	if err != nil || d <= 0 {
+		return Window{}, fmt.Errorf("maintenance window %q: invalid duration %q", s, fields[5])
+	}
+	return Window{Schedule: &cron, Duration: d, loc: loc}, nil
+}
+
+// Active reports whether t falls inside the window and, if so, when the
+// current occurrence ends.
+func (w Window) Active(t time.Time) (time.Time, bool) {
+	if w.Schedule == nil {
+		return w.End, !t.Before(w.Start) && t.Before(w.End)
+	}
+	t = t.In(w.loc)
+	for start := t.Truncate(time.Minute); t.Sub(start) < w.Duration; start = start.Add(-time.Minute) {
+		if w.Schedule.Matches(start) {
+			return start.Add(w.Duration), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Next returns the start and end of the next occurrence beginning after t.
+// ok is false if the window never starts again.
+func (w Window) Next(t time.Time) (start, end time.Time, ok bool) {
+	if w.Schedule == nil {
+		return w.Start, w.End, t.Before(w.Start)
+	}
+	start = w.Schedule.Next(t.In(w.loc))
+	if start.IsZero() {
+		return start, start, false
+	}
+	return start, start.Add(w.Duration), true
+}
+
+// String returns the window in the form it was configured.
+func (w Window) String() string {
+	if w.Schedule == nil {
+		return w.Start.Format(time.RFC3339) + "/" + w.End.Format(time.RFC3339)
+	}
+	return w.Schedule.Expr + " " + w.Duration.String()
+}
+
+// InMaintenance reports whether any of the windows is active at t and, if
+// so, when the latest-ending active window closes.
+func InMaintenance(windows []Window, t time.Time) (time.Time, bool) {
+	var until time.Time
+	active := false
+	for _, w := range windows {
+		if end, ok := w.Active(t); ok {
+			active = true
+			if end.After(until) {
+				until = end
+			}
+		}
+	}
+	return until, active
+}
+
+// NextMaintenance returns the earliest upcoming window start after t.
+func NextMaintenance(windows []Window, t time.Time) (start, end time.Time, ok bool) {
+	for _, w := range windows {
+		s, e, found := w.Next(t)
+		if found && (!ok || s.Before(start)) {
+			start, end, ok = s, e, true
+		}
+	}
+	return start, end, ok
+}
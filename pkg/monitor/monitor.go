@@ -0,0 +1,468 @@
+// Package monitor is the reusable core of vivteno: it runs checks against a
+// set of targets on a schedule and reports the results, independent of any
+// user interface.
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"math/rand/v2"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultInterval is used when a Monitor is created with a non-positive interval.
+const DefaultInterval = 10 * time.Second
+
+// DefaultMaxConcurrent is the concurrency limit of a new Monitor.
+const DefaultMaxConcurrent = 32
+
+// Target is a single monitored website.
+type Target struct {
+	// Name is the target as configured, used for display.
+	Name string
+	// Host is the hostname or IP address to check.
+	Host string
+	// Scheme, Port and Path are set when the target is configured as a URL.
+	// Checkers fall back to their own defaults when they are empty.
+	Scheme string
+	Port   string
+	Path   string
+	// Check selects the registered checker used to probe the target. Empty
+	// means the Monitor's default Ping checker.
+	Check string
+	// HealthEndpoint is an optional path (e.g. "/health") fetched after a
+	// successful check, from HealthBaseURL if set and otherwise from the
+	// target's scheme (HTTPS by default) and port.
+	HealthEndpoint string
+	// HealthBaseURL, if set, is the URL HealthEndpoint is appended to, e.g.
+	// to check a load balancer but fetch health from an origin server (see
+	// ParseHealthBaseURL).
+	HealthBaseURL string
+	// MaxBodySize limits the bytes read from the health endpoint's response
+	// (see BodyTooLargeError). Zero means DefaultMaxBodySize.
+	MaxBodySize int64
+	// Maintenance lists windows during which failures are expected.
+	Maintenance []Window
+	// ConnectTimeout bounds connection-level checks (TCP, TLS, ICMP) and
+	// HealthTimeout bounds HTTP requests (HTTP check and health endpoint)
+	// and gRPC calls.
+	// Zero means the checker's default.
+	ConnectTimeout time.Duration
+	HealthTimeout  time.Duration
+	// CheckTimeout bounds every attempt of every check of the target as a
+	// whole, including probes and checkers without their own timeout.
+	// Zero means DefaultCheckTimeout.
+	CheckTimeout time.Duration
+	// TLSConfig customises HTTPS requests and TLS checks, e.g. with a client
+	// certificate or CA bundle (see LoadTLSConfig). Nil uses the defaults.
+	TLSConfig *tls.Config
+	// ExpectBody, if set, must be found in the response body of HTTP checks.
+	ExpectBody *BodyMatch
+	// ExpectRedirect, if set, is the redirect HTTP checks must receive
+	// first (see Redirect).
+	ExpectRedirect *Redirect
+	// Ports, when set, are checked separately in every cycle instead of
+	// Port, e.g. 80, 443 and 5432 of one host (see ParsePorts).
+	Ports []string
+	// CaptureHeaders names the response headers HTTP checks and health
+	// endpoints report in Result.Headers (see ParseHeaderNames).
+	CaptureHeaders []string
+	// MaxRedirects is how many redirects HTTP checks follow; a longer chain
+	// fails the check. Zero means DefaultMaxRedirects and a negative value
+	// follows none, so the redirect response itself is the result.
+	MaxRedirects int
+	// Proxy routes TCP, TLS and HTTP checks through an HTTP(S) or SOCKS5
+	// proxy (see ParseProxy). Nil connects directly; HTTP requests then
+	// still honour HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+	Proxy *url.URL
+	// Family restricts checks to IPv4 or IPv6, or checks both separately
+	// (see ParseFamily). It is not applied to connections through Proxy.
+	Family string
+	// DNSServer, as host:port, tls://host:port or a DNS over HTTPS URL,
+	// resolves the target's hostname instead of the system resolver (see
+	// ParseDNSServer). Connections through Proxy leave
+	// resolution to the proxy.
+	DNSServer string
+	// Source, an IP address or the name of a network interface, is the
+	// local address the target's checks and traceroutes leave from, e.g.
+	// to check it through one uplink or a VPN (see ParseSource). Empty
+	// leaves the choice to the system.
+	Source string
+	// Probes is how many times the check runs per cycle (see ProbeStats).
+	// Values below 2 run it once. The health endpoint is fetched once.
+	Probes int
+	// HealthUsername and HealthPassword, when either is set, are sent as
+	// HTTP Basic authentication with health endpoint requests.
+	HealthUsername string
+	HealthPassword string
+	// Assertions are evaluated against a successful health response, or the
+	// response of GRPCMethod for targets without a health endpoint (see
+	// ParseAssertion). Violations are reported without failing the cycle.
+	Assertions []Assertion
+	// Schema, when set, is the JSON Schema the same response must match
+	// (see ParseSchema). Its violations are reported like those of
+	// Assertions.
+	Schema *Schema
+	// Method, Body and ContentType shape the requests of HTTP checks and the
+	// health endpoint. An empty Method means GET; ContentType, which
+	// defaults to DefaultContentType, is only sent with a Body.
+	Method      string
+	Body        string
+	ContentType string
+	// GRPCMethod, e.g. "shop.v1.Inventory/Status", is called by the gRPC
+	// checker instead of the standard health service, with GRPCRequest as
+	// its JSON-encoded request (see GRPCChecker).
+	GRPCMethod  string
+	GRPCRequest string
+	// SSHPort replaces port 22 for the SSH checker, and SSHFingerprint, if
+	// set, is the host key fingerprint it expects (see SSHChecker).
+	SSHPort        string
+	SSHFingerprint string
+	// BackendPort replaces the default port of the Redis, Memcached,
+	// PostgreSQL and MySQL checkers.
+	BackendPort string
+	// Heartbeat receives the beats of a heartbeat target, which fails when
+	// no successful beat arrived within HeartbeatWindow (zero means
+	// DefaultHeartbeatWindow).
+	Heartbeat       *Heartbeat
+	HeartbeatWindow time.Duration
+	// WebSocketPath replaces the target's path for the WebSocket handshake,
+	// and WebSocketPing makes the checker wait for the answer to a ping.
+	WebSocketPath string
+	WebSocketPing bool
+	// H3Compare makes the HTTP/3 checker also send its request over TCP and
+	// report both latencies.
+	H3Compare bool
+	// NTPMaxOffset is the largest clock offset the NTP checker accepts;
+	// zero means DefaultNTPMaxOffset.
+	NTPMaxOffset time.Duration
+	// SNMP configures the SNMP checker; nil queries DefaultSNMPOID with
+	// SNMPv2c and DefaultSNMPCommunity.
+	SNMP *SNMP
+}
+
+// Report is the outcome of one check cycle for a target.
+type Report struct {
+	Index  int
+	Target Target
+	Time   time.Time
+	Ping   Result
+	// Health is nil when the target has no health endpoint.
+	Health *Result
+	// Maintenance is true when the check ran inside one of the target's
+	// maintenance windows.
+	Maintenance bool
+	// Families holds the IPv4 and IPv6 results of a FamilyBoth target; Ping
+	// then combines them and fails if either failed.
+	Families []FamilyResult
+	// Ports holds the result of each port of a target with several Ports;
+	// Ping then combines them and fails if any failed.
+	Ports []PortResult
+	// Violations lists the target's Assertions that the health response did
+	// not meet, and where it broke its Schema. They leave the report OK: the
+	// site responds, but degraded.
+	Violations []error
+}
+
+// Err returns the first failure of the cycle, or nil if every check passed.
+// When both the check and the health endpoint failed, it is that of the
+// check.
+func (r Report) Err() error {
+	if r.Ping.Err != nil {
+		return r.Ping.Err
+	}
+	if r.Health != nil {
+		return r.Health.Err
+	}
+	return nil
+}
+
+// OK reports whether every check in the cycle passed.
+func (r Report) OK() bool {
+	return r.Err() == nil
+}
+
+// Monitor checks a set of targets on a fixed interval.
+type Monitor struct {
+	Targets  []Target
+	Interval time.Duration
+	// DownInterval, when positive, replaces Interval for a target whose last
+	// check cycle failed outside a maintenance window, so it is checked at a
+	// different pace until it recovers.
+	DownInterval time.Duration
+	// Ping is run first for targets that do not select a checker; Health runs
+	// afterwards for targets with a health endpoint, whatever the result of
+	// the first check.
+	Ping   Checker
+	Health Checker
+	// Retry is applied to every check before a failure is reported.
+	Retry RetryPolicy
+	// MaxConcurrent limits how many check cycles run at the same time.
+	// Zero or less means no limit. It must not change after the first check.
+	MaxConcurrent int
+	// Stagger spreads the first checks of the targets evenly across
+	// Interval instead of running them all at once.
+	Stagger bool
+	// Jitter, when positive, adds a random delay of up to Jitter to every
+	// wait between check cycles, so targets sharing a schedule drift apart.
+	Jitter time.Duration
+
+	reports  chan Report
+	triggers []chan struct{}
+	paused   []atomic.Bool
+	semOnce  sync.Once
+	sem      chan struct{}
+	// nextAt holds when each target is checked next, in Unix nanoseconds,
+	// or zero while it is not waiting for a check.
+	nextAt []atomic.Int64
+}
+
+// New creates a Monitor with the default TCP and health checkers.
+func New(targets []Target, interval time.Duration) *Monitor {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	triggers := make([]chan struct{}, len(targets))
+	for i := range triggers {
+		triggers[i] = make(chan struct{}, 1)
+	}
+	return &Monitor{
+		Targets:       targets,
+		Interval:      interval,
+		Ping:          NewTCPChecker(),
+		Health:        NewHealthChecker(),
+		Retry:         DefaultRetryPolicy(),
+		MaxConcurrent: DefaultMaxConcurrent,
+		reports:       make(chan Report),
+		triggers:      triggers,
+		paused:        make([]atomic.Bool, len(targets)),
+		nextAt:        make([]atomic.Int64, len(targets)),
+	}
+}
+
+// Reports returns the channel on which Run delivers results. It is closed
+// when Run returns.
+func (m *Monitor) Reports() <-chan Report {
+	return m.reports
+}
+
+// Run checks every target immediately, or at its offset when Stagger is
+// set, and then once per interval (or DownInterval while it fails) until ctx
+// is cancelled. Each target is scheduled independently.
+func (m *Monitor) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := range m.Targets {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m.loop(ctx, i)
+		}(i)
+	}
+	wg.Wait()
+	close(m.reports)
+}
+
+// Trigger skips the pending wait of the target at idx so it is checked right
+// away. If a check is already running, another one starts when it finishes.
+func (m *Monitor) Trigger(idx int) {
+	if idx < 0 || idx >= len(m.triggers) {
+		return
+	}
+	select {
+	case m.triggers[idx] <- struct{}{}:
+	default:
+	}
+}
+
+// TriggerAll calls Trigger for every target.
+func (m *Monitor) TriggerAll() {
+	for i := range m.triggers {
+		m.Trigger(i)
+	}
+}
+
+// Pause stops scheduling checks of the target at idx until Resume. A check
+// that is already running still delivers its report. Pausing before Run
+// starts the target paused.
+func (m *Monitor) Pause(idx int) {
+	if idx >= 0 && idx < len(m.paused) {
+		m.paused[idx].Store(true)
+	}
+}
+
+// Resume schedules checks of a paused target again, starting right away.
+func (m *Monitor) Resume(idx int) {
+	if idx >= 0 && idx < len(m.paused) && m.paused[idx].Swap(false) {
+		m.Trigger(idx)
+	}
+}
+
+// Paused reports whether the target at idx is paused.
+func (m *Monitor) Paused(idx int) bool {
+	return idx >= 0 && idx < len(m.paused) && m.paused[idx].Load()
+}
+
+// NextCheck returns when the target at idx is checked next, or the zero
+// time while it is being checked or paused, and before Run starts.
+func (m *Monitor) NextCheck(idx int) time.Time {
+	if idx < 0 || idx >= len(m.nextAt) || m.Paused(idx) {
+		return time.Time{}
+	}
+	if at := m.nextAt[idx].Load(); at != 0 {
+		return time.Unix(0, at)
+	}
+	return time.Time{}
+}
+
+func (m *Monitor) loop(ctx context.Context, idx int) {
+	wait := m.offset(idx)
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	m.nextAt[idx].Store(time.Now().Add(wait).UnixNano())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-m.triggers[idx]:
+			timer.Stop()
+		}
+		m.nextAt[idx].Store(0)
+		// A paused target waits, with its timer stopped, for Resume
+		if m.Paused(idx) {
+			continue
+		}
+		r := m.Check(ctx, idx)
+		select {
+		case m.reports <- r:
+		case <-ctx.Done():
+			return
+		}
+		wait = m.next(r)
+		timer.Reset(wait)
+		m.nextAt[idx].Store(time.Now().Add(wait).UnixNano())
+	}
+}
+
+// offset returns the wait before the first check of the target at idx.
+func (m *Monitor) offset(idx int) time.Duration {
+	if !m.Stagger || len(m.Targets) < 2 {
+		return 0
+	}
+	return m.Interval * time.Duration(idx) / time.Duration(len(m.Targets))
+}
+
+// next returns the wait before the check cycle following r.
+func (m *Monitor) next(r Report) time.Duration {
+	wait := m.Interval
+	if m.DownInterval > 0 && !r.OK() && !r.Maintenance {
+		wait = m.DownInterval
+	}
+	if m.Jitter > 0 {
+		wait += rand.N(m.Jitter)
+	}
+	return wait
+}
+
+// Check runs a single check cycle for the target at idx. When MaxConcurrent
+// cycles are already running it waits for a free slot first.
+func (m *Monitor) Check(ctx context.Context, idx int) Report {
+	t := m.Targets[idx]
+	if release, ok := m.acquire(ctx); ok {
+		defer release()
+	}
+	r := Report{Index: idx, Target: t, Time: time.Now()}
+	_, r.Maintenance = InMaintenance(t.Maintenance, r.Time)
+	c := m.checker(t)
+	if t.Probes > 1 {
+		c = probeChecker{Checker: c, count: t.Probes}
+	}
+	if len(t.Ports) > 0 {
+		r.Ports = m.checkPorts(ctx, c, t)
+		r.Ping = combinePorts(r.Ports)
+	} else if t.Family == FamilyBoth {
+		r.Families = m.checkFamilies(ctx, c, t)
+		r.Ping = combineFamilies(r.Families)
+	} else {
+		r.Ping = m.Retry.run(ctx, c, t)
+	}
+	// The health endpoint is fetched even when the first check failed, so a
+	// firewalled port does not hide the health of the application
+	if t.HealthEndpoint != "" {
+		h := m.Retry.run(ctx, m.Health, t)
+		r.Health = &h
+		if h.Err == nil {
+			r.Violations = checkResponse(t, h.Data)
+		}
+	} else if r.Ping.Err == nil && t.GRPCMethod != "" {
+		r.Violations = checkResponse(t, r.Ping.Data)
+	}
+	return r
+}
+
+// checkResponse returns where the health response of t violates its
+// Assertions and its Schema.
+func checkResponse(t Target, data map[string]any) []error {
+	violations := CheckAssertions(t.Assertions, data)
+	if t.Schema != nil {
+		violations = append(violations, t.Schema.Validate(data)...)
+	}
+	return violations
+}
+
+// acquire takes a slot from the concurrency limit. ok is false when there is
+// no limit or ctx was cancelled while waiting; the checks then run (and fail
+// fast on the cancelled context) without holding a slot.
+func (m *Monitor) acquire(ctx context.Context) (release func(), ok bool) {
+	m.semOnce.Do(func() {
+		if m.MaxConcurrent > 0 {
+			m.sem = make(chan struct{}, m.MaxConcurrent)
+		}
+	})
+	if m.sem == nil {
+		return nil, false
+	}
+	select {
+	case m.sem <- struct{}{}:
+		return func() { <-m.sem }, true
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+// checker returns the checker selected by the target, falling back to Ping.
+func (m *Monitor) checker(t Target) Checker {
+	if t.Check == "" {
+		return m.Ping
+	}
+	c, err := NewChecker(t.Check)
+	if err != nil {
+		return errChecker{err}
+	}
+	return c
+}
+
+// errChecker reports a configuration error as a failed check.
+type errChecker struct{ err error }
+
+func (c errChecker) Name() string                         { return "error" }
+func (c errChecker) Check(context.Context, Target) Result { return Result{Err: c.err} }
+
+// CheckAll runs one check cycle for every target concurrently, bounded by
+// MaxConcurrent, and returns the reports in target order.
+func (m *Monitor) CheckAll(ctx context.Context) []Report {
+	reports := make([]Report, len(m.Targets))
+	var wg sync.WaitGroup
+	for i := range m.Targets {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			reports[i] = m.Check(ctx, i)
+		}(i)
+	}
+	wg.Wait()
+	return reports
+}
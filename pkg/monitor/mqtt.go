@@ -0,0 +1,194 @@
+package monitor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Default ports of MQTT brokers, without and with TLS
+const (
+	DefaultMQTTPort    = "1883"
+	DefaultMQTTTLSPort = "8883"
+)
+
+// MQTT 3.1.1 control packet types, in the high nibble of the first byte
+const (
+	mqttConnect    = 1
+	mqttConnAck    = 2
+	mqttPublish    = 3
+	mqttPingReq    = 12
+	mqttPingResp   = 13
+	mqttDisconnect = 14
+)
+
+// MQTTRefusedError is the return code of a CONNACK refusing a connection.
+type MQTTRefusedError byte
+
+// mqttRefusals describe the return codes of MQTT 3.1.1.
+var mqttRefusals = map[MQTTRefusedError]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+func (r MQTTRefusedError) Error() string {
+	if s, ok := mqttRefusals[r]; ok {
+		return "MQTT connection refused: " + s
+	}
+	return fmt.Sprintf("MQTT connection refused with code %d", byte(r))
+}
+
+// authentication reports whether the broker refused the credentials, which
+// still shows it is serving.
+func (r MQTTRefusedError) authentication() bool {
+	return r == 4 || r == 5
+}
+
+// MQTTClientID returns a random client identifier, so vivteno never takes
+// over the session of another client or instance.
+func MQTTClientID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return "vivteno-" + hex.EncodeToString(b)
+}
+
+// MQTTConnect starts an MQTT 3.1.1 session with a clean session and no keep
+// alive over rw, with credentials when username is set, and reads the
+// broker's CONNACK. A refusal is returned as MQTTRefusedError.
+func MQTTConnect(rw io.ReadWriter, clientID, username, password string) error {
+	body := mqttString(nil, "MQTT")
+	flags := byte(0x02) // clean session
+	if username != "" {
+		flags |= 0x80
+		if password != "" {
+			flags |= 0x40
+		}
+	}
+	// Protocol level 4, then the flags and a keep alive of zero, so the
+	// broker never drops a session that is idle between messages
+	body = append(body, 4, flags, 0, 0)
+	body = mqttString(body, clientID)
+	if username != "" {
+		body = mqttString(body, username)
+		if password != "" {
+			body = mqttString(body, password)
+		}
+	}
+	if _, err := rw.Write(mqttPacket(mqttConnect<<4, body)); err != nil {
+		return fmt.Errorf("sending MQTT CONNECT: %w", err)
+	}
+	// CONNACK: type, length 2, session present flag and return code
+	var reply [4]byte
+	if _, err := io.ReadFull(rw, reply[:]); err != nil {
+		return fmt.Errorf("reading MQTT CONNACK: %w", err)
+	}
+	if reply[0]>>4 != mqttConnAck || reply[1] != 2 {
+		return fmt.Errorf("unexpected MQTT reply % x instead of CONNACK", reply)
+	}
+	if reply[3] != 0 {
+		return MQTTRefusedError(reply[3])
+	}
+	return nil
+}
+
+// MQTTPublish sends a message to a topic with QoS 0, retained by the broker
+// for new subscribers when retain is set.
+func MQTTPublish(w io.Writer, topic string, payload []byte, retain bool) error {
+	header := byte(mqttPublish << 4)
+	if retain {
+		header |= 0x01
+	}
+	_, err := w.Write(mqttPacket(header, append(mqttString(nil, topic), payload...)))
+	return err
+}
+
+// MQTTDisconnect ends an MQTT session cleanly, so the broker discards it.
+func MQTTDisconnect(w io.Writer) error {
+	_, err := w.Write(mqttPacket(mqttDisconnect<<4, nil))
+	return err
+}
+
+// mqttPacket frames a control packet: its first byte, the length of body
+// as a variable-length integer, and body.
+func mqttPacket(header byte, body []byte) []byte {
+	packet := []byte{header}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		if n /= 128; n > 0 {
+			b |= 0x80
+		}
+		packet = append(packet, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(packet, body...)
+}
+
+// mqttString appends s to b as a length-prefixed UTF-8 string.
+func mqttString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// MQTTChecker connects to an MQTT broker with MQTT 3.1.1 and sends PINGREQ,
+// expecting PINGRESP. A broker refusing the connection for bad credentials
+// or lack of authorization still shows it is serving, as the Redis NOAUTH
+// error does; other refusals, such as server unavailable, fail the check.
+type MQTTChecker struct {
+	Port    string
+	Timeout time.Duration
+}
+
+// NewMQTTChecker returns an MQTTChecker using the default port and timeout.
+func NewMQTTChecker() MQTTChecker {
+	return MQTTChecker{Port: DefaultMQTTPort, Timeout: DefaultTCPTimeout}
+}
+
+// Name implements Checker.
+func (c MQTTChecker) Name() string { return CheckMQTT }
+
+// Check implements Checker.
+func (c MQTTChecker) Check(ctx context.Context, t Target) Result {
+	timeout := t.connectTimeout(c.Timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	conn, err := dialBackend(ctx, t, c.Port, timeout)
+	if err != nil {
+		return Result{Err: err}
+	}
+	defer conn.Close()
+	err = MQTTConnect(conn, MQTTClientID(), "", "")
+	var refused MQTTRefusedError
+	switch {
+	case errors.As(err, &refused) && refused.authentication():
+		return Result{Latency: time.Since(start), Detail: "MQTT broker serving, authentication required",
+			Data: map[string]any{"connack": mqttRefusals[refused]}}
+	case err != nil:
+		return Result{Latency: time.Since(start), Err: err}
+	}
+	if _, err := conn.Write(mqttPacket(mqttPingReq<<4, nil)); err != nil {
+		return Result{Latency: time.Since(start), Err: fmt.Errorf("sending MQTT PINGREQ: %w", err)}
+	}
+	var reply [2]byte
+	_, err = io.ReadFull(conn, reply[:])
+	elapsed := time.Since(start)
+	if err != nil {
+		return Result{Latency: elapsed, Err: fmt.Errorf("reading MQTT PINGRESP: %w", err)}
+	}
+	if reply[0]>>4 != mqttPingResp || reply[1] != 0 {
+		return Result{Latency: elapsed, Err: fmt.Errorf("unexpected MQTT reply % x instead of PINGRESP", reply)}
+	}
+	_ = MQTTDisconnect(conn)
+	return Result{Latency: elapsed, Detail: "MQTT PINGREQ: PINGRESP", Data: map[string]any{"connack": "accepted"}}
+}
@@ -0,0 +1,149 @@
+package monitor
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Defaults for the NTP checker
+const (
+	DefaultNTPPort      = "123"
+	DefaultNTPMaxOffset = 100 * time.Millisecond
+)
+
+// NTP packet layout (RFC 5905)
+const (
+	ntpPacketSize = 48
+	ntpVersion    = 4
+	ntpModeClient = 3
+	ntpModeServer = 4
+	// ntpUnsynchronized is the leap indicator of a server without a
+	// synchronized clock.
+	ntpUnsynchronized = 3
+)
+
+// ntpEpoch is the start of NTP time, 1900-01-01 UTC.
+var ntpEpoch = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// NTPChecker queries an NTP server over UDP and reports its stratum and
+// the offset of its clock from the local one. Offsets beyond the target's
+// NTPMaxOffset fail the check, so the local clock must itself be in sync.
+type NTPChecker struct {
+	Port    string
+	Timeout time.Duration
+}
+
+// NewNTPChecker returns an NTPChecker using the default port and timeout.
+func NewNTPChecker() NTPChecker {
+	return NTPChecker{Port: DefaultNTPPort, Timeout: DefaultTCPTimeout}
+}
+
+// Name implements Checker.
+func (c NTPChecker) Name() string { return CheckNTP }
+
+// Check sends one client request to the target, on the target's own port if
+// it has one. Latency is the round-trip delay of the request.
+func (c NTPChecker) Check(ctx context.Context, t Target) Result {
+	timeout := t.connectTimeout(c.Timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	d, err := t.bind(&net.Dialer{Timeout: timeout, Resolver: t.resolver()}, t.network("udp"))
+	if err != nil {
+		return Result{Err: err}
+	}
+	conn, err := d.DialContext(ctx, t.network("udp"), net.JoinHostPort(t.Host, t.portOr(c.Port)))
+	if err != nil {
+		return Result{Err: err}
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	req := make([]byte, ntpPacketSize)
+	req[0] = ntpVersion<<3 | ntpModeClient
+	sent := time.Now()
+	// The transmit time is echoed back as the origin time of the reply,
+	// which tells the reply apart from stale or spoofed packets
+	binary.BigEndian.PutUint64(req[40:], toNTPTime(sent))
+	if _, err := conn.Write(req); err != nil {
+		return Result{Err: err}
+	}
+	resp := make([]byte, ntpPacketSize)
+	for {
+		n, err := conn.Read(resp)
+		if err != nil {
+			return Result{Err: err}
+		}
+		if n >= ntpPacketSize && resp[0]&7 == ntpModeServer && binary.BigEndian.Uint64(resp[24:]) == binary.BigEndian.Uint64(req[40:]) {
+			break
+		}
+	}
+	received := time.Now()
+
+	stratum := int(resp[1])
+	ref := ntpReference(stratum, resp[12:16])
+	if stratum == 0 {
+		return Result{Latency: received.Sub(sent), Err: fmt.Errorf("NTP server refused the request (kiss code %s)", ref)}
+	}
+	if resp[0]>>6 == ntpUnsynchronized {
+		return Result{Latency: received.Sub(sent), Err: errors.New("NTP server clock is not synchronized")}
+	}
+	serverReceived := fromNTPTime(binary.BigEndian.Uint64(resp[32:]))
+	serverSent := fromNTPTime(binary.BigEndian.Uint64(resp[40:]))
+	offset := (serverReceived.Sub(sent) + serverSent.Sub(received)) / 2
+	delay := received.Sub(sent) - serverSent.Sub(serverReceived)
+
+	r := Result{
+		Latency: delay,
+		Detail:  fmt.Sprintf("Stratum %d (reference %s), clock offset %s", stratum, ref, formatOffset(offset)),
+		Data: map[string]any{
+			"stratum":   stratum,
+			"reference": ref,
+			"offset_ms": float64(offset.Microseconds()) / 1000,
+		},
+	}
+	maxOffset := t.NTPMaxOffset
+	if maxOffset <= 0 {
+		maxOffset = DefaultNTPMaxOffset
+	}
+	if offset.Abs() > maxOffset {
+		r.Err = fmt.Errorf("clock offset %s exceeds %s", formatOffset(offset), maxOffset)
+	}
+	return r
+}
+
+// toNTPTime converts t to a 64-bit NTP timestamp: seconds since ntpEpoch
+// and a binary fraction of a second.
+func toNTPTime(t time.Time) uint64 {
+	d := t.Sub(ntpEpoch)
+	sec := uint64(d / time.Second)
+	frac := uint64(d%time.Second) << 32 / uint64(time.Second)
+	return sec<<32 | frac
+}
+
+// fromNTPTime converts a 64-bit NTP timestamp to a time.
+func fromNTPTime(ts uint64) time.Time {
+	sec, frac := ts>>32, ts&0xffffffff
+	return ntpEpoch.Add(time.Duration(sec)*time.Second + time.Duration(frac*uint64(time.Second)>>32))
+}
+
+// ntpReference formats the reference ID of a reply: the upstream server's
+// IPv4 address from stratum 2, and an ASCII code at stratum 1 (the clock
+// source, e.g. GPS) and 0 (a kiss code, e.g. RATE).
+func ntpReference(stratum int, id []byte) string {
+	if stratum >= 2 {
+		return net.IP(id).String()
+	}
+	return strings.TrimRight(string(id), "\x00")
+}
+
+// formatOffset formats a clock offset with its sign, in milliseconds.
+func formatOffset(d time.Duration) string {
+	return fmt.Sprintf("%+.3f ms", float64(d.Microseconds())/1000)
+}
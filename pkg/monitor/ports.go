@@ -0,0 +1,75 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ParsePorts parses a comma-separated list of ports checked separately,
+// e.g. "80, 443, 5432".
+func ParsePorts(s string) ([]string, error) {
+	var ports []string
+	for _, part := range strings.Split(s, ",") {
+		port := strings.TrimSpace(part)
+		if port == "" {
+			continue
+		}
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return nil, fmt.Errorf("invalid port %q", port)
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+// PortResult is the outcome of checking one port of a target with several
+// Ports.
+type PortResult struct {
+	Port string
+	Result
+}
+
+// checkPorts runs c against every port of t concurrently.
+func (m *Monitor) checkPorts(ctx context.Context, c Checker, t Target) []PortResult {
+	results := make([]PortResult, len(t.Ports))
+	var wg sync.WaitGroup
+	for i, port := range t.Ports {
+		results[i].Port = port
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			pt := t
+			pt.Port = port
+			results[i].Result = m.Retry.run(ctx, c, pt)
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// combinePorts merges per-port results into one that fails if any port
+// failed. Latency is that of the slowest port.
+func combinePorts(results []PortResult) Result {
+	var combined Result
+	var errs []error
+	var details []string
+	for _, r := range results {
+		combined.Attempts = max(combined.Attempts, r.Attempts)
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("port %s: %w", r.Port, r.Err))
+			continue
+		}
+		combined.Latency = max(combined.Latency, r.Latency)
+		details = append(details, "Port "+r.Port+": "+r.Detail)
+	}
+	if len(errs) == 1 {
+		combined.Err = errs[0]
+	} else if len(errs) > 1 {
+		combined.Err = checkErrors(errs)
+	}
+	combined.Detail = strings.Join(details, "\n  ")
+	return combined
+}
@@ -0,0 +1,87 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ProbeInterval is the pause between the probes of one check cycle.
+const ProbeInterval = 200 * time.Millisecond
+
+// ProbeStats summarises the probes of a check cycle, like the statistics
+// printed by ping.
+type ProbeStats struct {
+	Sent int
+	Lost int
+	// Min, Avg and Max are latencies of the successful probes.
+	Min time.Duration
+	Avg time.Duration
+	Max time.Duration
+}
+
+// Loss returns the fraction of probes that failed, from 0 to 1.
+func (s ProbeStats) Loss() float64 {
+	if s.Sent == 0 {
+		return 0
+	}
+	return float64(s.Lost) / float64(s.Sent)
+}
+
+// String formats the statistics as "5 probes, 20% loss, min/avg/max
+// 10/12/15 ms".
+func (s ProbeStats) String() string {
+	out := fmt.Sprintf("%d probes, %.0f%% loss", s.Sent, s.Loss()*100)
+	if s.Lost < s.Sent {
+		out += fmt.Sprintf(", min/avg/max %d/%d/%d ms", s.Min.Milliseconds(), s.Avg.Milliseconds(), s.Max.Milliseconds())
+	}
+	return out
+}
+
+// probeChecker runs a checker several times per cycle. The cycle fails only
+// when every probe fails; its latency is the average of the successful
+// probes.
+type probeChecker struct {
+	Checker
+	count int
+}
+
+// Check implements Checker.
+func (c probeChecker) Check(ctx context.Context, t Target) Result {
+	stats := &ProbeStats{}
+	var last, ok Result
+	var total time.Duration
+	for i := range c.count {
+		if i > 0 {
+			timer := time.NewTimer(ProbeInterval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return last
+			case <-timer.C:
+			}
+		}
+		last = c.Checker.Check(ctx, t)
+		stats.Sent++
+		if last.Err != nil {
+			stats.Lost++
+			continue
+		}
+		ok = last
+		total += last.Latency
+		if stats.Min == 0 || last.Latency < stats.Min {
+			stats.Min = last.Latency
+		}
+		stats.Max = max(stats.Max, last.Latency)
+	}
+	if stats.Lost == stats.Sent {
+		last.Err = fmt.Errorf("all %d probes failed, last: %w", stats.Sent, last.Err)
+		last.Probes = stats
+		return last
+	}
+	stats.Avg = total / time.Duration(stats.Sent-stats.Lost)
+	ok.Latency = stats.Avg
+	ok.Detail += "\n  " + stats.String()
+	ok.Probes = stats
+	return ok
+}
@@ -0,0 +1,82 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// DefaultRDAPServer redirects RDAP queries to the registry responsible for
+// each top-level domain.
+const DefaultRDAPServer = "https://rdap.org"
+
+// ErrNoRegistrableDomain is returned for hosts that are not registered with
+// a domain registry, such as IP addresses and cluster-internal names.
+var ErrNoRegistrableDomain = errors.New("no registrable domain")
+
+// RegistrableDomain returns the domain host is registered under, e.g.
+// "example.co.uk" for "www.example.co.uk".
+func RegistrableDomain(host string) (string, error) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if net.ParseIP(host) != nil {
+		return "", ErrNoRegistrableDomain
+	}
+	// Suffixes missing from the list, like "svc" or "internal", are not
+	// delegated by ICANN and have no registry to ask
+	if suffix, icann := publicsuffix.PublicSuffix(host); !icann && !strings.Contains(suffix, ".") {
+		return "", ErrNoRegistrableDomain
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return "", ErrNoRegistrableDomain
+	}
+	return domain, nil
+}
+
+// DomainExpiry looks up when a registered domain expires using RDAP, the
+// successor of WHOIS. server is the base URL of an RDAP service, such as
+// DefaultRDAPServer.
+func DomainExpiry(ctx context.Context, client *http.Client, server, domain string) (time.Time, error) {
+	u := strings.TrimSuffix(server, "/") + "/domain/" + url.PathEscape(domain)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	req.Header.Set("Accept", "application/rdap+json, application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return time.Time{}, fmt.Errorf("domain %s not found", domain)
+	case resp.StatusCode != http.StatusOK:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return time.Time{}, fmt.Errorf("RDAP HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var info struct {
+		Events []struct {
+			Action string    `json:"eventAction"`
+			Date   time.Time `json:"eventDate"`
+		} `json:"events"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return time.Time{}, fmt.Errorf("decoding RDAP response: %w", err)
+	}
+	for _, e := range info.Events {
+		if e.Action == "expiration" {
+			return e.Date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("registry does not publish the expiry of %s", domain)
+}
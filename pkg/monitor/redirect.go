@@ -0,0 +1,130 @@
+package monitor
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxRedirects is how many redirects HTTP checks follow unless the
+// target sets another limit, as net/http does.
+const DefaultMaxRedirects = 10
+
+// Hop is one redirect received by an HTTP check.
+type Hop struct {
+	URL        string
+	StatusCode int
+	// Location is the absolute URL redirected to.
+	Location string
+}
+
+func (h Hop) String() string {
+	return fmt.Sprintf("%d %s → %s", h.StatusCode, h.URL, h.Location)
+}
+
+// Redirect is the redirect an HTTP check expects as the first response of
+// its target, e.g. a 301 from http:// to https://.
+type Redirect struct {
+	// StatusCode is the expected 3xx status, or zero for any.
+	StatusCode int
+	// Location is the expected target of the redirect: an absolute URL, or
+	// a path compared with the path and query of the redirect. Empty
+	// accepts any.
+	Location string
+}
+
+// ParseRedirect parses an expected redirect: a 3xx status code, a location,
+// or both separated by a space, e.g. "301 https://example.com/".
+func ParseRedirect(s string) (*Redirect, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("invalid redirect %q, expected a status code, a location or both, e.g. \"301 https://example.com/\"", s)
+	}
+	var r Redirect
+	if code, err := strconv.Atoi(fields[0]); err == nil {
+		if code < 300 || code > 399 {
+			return nil, fmt.Errorf("invalid redirect %q: %d is not a redirect status", s, code)
+		}
+		r.StatusCode = code
+		fields = fields[1:]
+	}
+	if len(fields) == 1 {
+		if u, err := url.Parse(fields[0]); err != nil || (!u.IsAbs() && !strings.HasPrefix(fields[0], "/")) {
+			return nil, fmt.Errorf("invalid redirect %q: location must be an absolute URL or a path", s)
+		}
+		r.Location = fields[0]
+	} else if len(fields) > 1 {
+		return nil, fmt.Errorf("invalid redirect %q, expected a status code, a location or both, e.g. \"301 https://example.com/\"", s)
+	}
+	return &r, nil
+}
+
+func (r Redirect) String() string {
+	switch {
+	case r.Location == "":
+		return strconv.Itoa(r.StatusCode)
+	case r.StatusCode == 0:
+		return "redirect to " + r.Location
+	}
+	return fmt.Sprintf("%d to %s", r.StatusCode, r.Location)
+}
+
+// match checks the first hop of a check, or nil if it was not redirected.
+func (r Redirect) match(hop *Hop) error {
+	if hop == nil {
+		return fmt.Errorf("expected redirect %s, got none", r)
+	}
+	location := hop.Location
+	if strings.HasPrefix(r.Location, "/") {
+		if u, err := url.Parse(hop.Location); err == nil {
+			location = u.RequestURI()
+		}
+	}
+	if (r.StatusCode != 0 && hop.StatusCode != r.StatusCode) || (r.Location != "" && location != r.Location) {
+		return fmt.Errorf("expected redirect %s, got %d to %s", r, hop.StatusCode, hop.Location)
+	}
+	return nil
+}
+
+// maxRedirects returns how many redirects HTTP checks of the target follow.
+func (t Target) maxRedirects() int {
+	switch {
+	case t.MaxRedirects < 0:
+		return 0
+	case t.MaxRedirects == 0:
+		return DefaultMaxRedirects
+	}
+	return t.MaxRedirects
+}
+
+// followRedirects returns a CheckRedirect function for an http.Client that
+// records each redirect followed in hops and stops at the target's limit.
+// Without redirects allowed, the redirect response itself is returned.
+func (t Target) followRedirects(hops *[]Hop) func(*http.Request, []*http.Request) error {
+	limit := t.maxRedirects()
+	return func(req *http.Request, via []*http.Request) error {
+		if limit == 0 {
+			return http.ErrUseLastResponse
+		}
+		if len(*hops) >= limit {
+			return fmt.Errorf("more than %d redirects", limit)
+		}
+		*hops = append(*hops, Hop{
+			URL:        via[len(via)-1].URL.String(),
+			StatusCode: req.Response.StatusCode,
+			Location:   req.URL.String(),
+		})
+		return nil
+	}
+}
+
+// hopData converts hops into the JSON-like values of Result.Data.
+func hopData(hops []Hop) []any {
+	out := make([]any, len(hops))
+	for i, h := range hops {
+		out[i] = map[string]any{"url": h.URL, "status_code": h.StatusCode, "location": h.Location}
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Names of the built-in checkers
+const (
+	CheckTCP  = "tcp"
+	CheckHTTP = "http"
+	CheckICMP = "icmp"
+	CheckDNS  = "dns"
+	CheckTLS  = "tls"
+	CheckGRPC = "grpc"
+	CheckSSH  = "ssh"
+	CheckNTP  = "ntp"
+	CheckSNMP = "snmp"
+	CheckH3   = "h3"
+
+	CheckWebSocket = "websocket"
+
+	CheckHeartbeat = "heartbeat"
+
+	CheckRedis     = "redis"
+	CheckMemcached = "memcached"
+	CheckPostgres  = "postgres"
+	CheckMySQL     = "mysql"
+	CheckMQTT      = "mqtt"
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]func() Checker{}
+)
+
+func init() {
+	Register(CheckTCP, func() Checker { return NewTCPChecker() })
+	Register(CheckHTTP, func() Checker { return NewHTTPChecker() })
+	Register(CheckICMP, func() Checker { return NewICMPChecker() })
+	Register(CheckDNS, func() Checker { return NewDNSChecker() })
+	Register(CheckTLS, func() Checker { return NewTLSChecker() })
+	Register(CheckGRPC, func() Checker { return NewGRPCChecker() })
+	Register(CheckSSH, func() Checker { return NewSSHChecker() })
+	Register(CheckNTP, func() Checker { return NewNTPChecker() })
+	Register(CheckSNMP, func() Checker { return NewSNMPChecker() })
+	Register(CheckH3, func() Checker { return NewH3Checker() })
+	Register(CheckWebSocket, func() Checker { return NewWebSocketChecker() })
+	Register(CheckRedis, func() Checker { return NewRedisChecker() })
+	Register(CheckMemcached, func() Checker { return NewMemcachedChecker() })
+	Register(CheckPostgres, func() Checker { return NewPostgresChecker() })
+	Register(CheckMySQL, func() Checker { return NewMySQLChecker() })
+	Register(CheckMQTT, func() Checker { return NewMQTTChecker() })
+	Register(CheckHeartbeat, func() Checker { return NewHeartbeatChecker() })
+}
+
+// Register makes a checker available under name so targets can select it.
+// Registering a name twice replaces the previous factory.
+func Register(name string, factory func() Checker) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = factory
+}
+
+// NewChecker returns a new instance of the checker registered under name.
+func NewChecker(name string) (Checker, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown check type %q (available: %v)", name, Checkers())
+	}
+	return factory(), nil
+}
+
+// Checkers returns the names of all registered checkers in sorted order.
+func Checkers() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
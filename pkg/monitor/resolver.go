@@ -0,0 +1,212 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default ports of DNS servers configured without one
+const (
+	DefaultDNSPort = "53"
+	DefaultDoTPort = "853"
+)
+
+// Encrypted DNS server schemes: DNS over TLS and DNS over HTTPS
+const (
+	DNSSchemeTLS   = "tls"
+	DNSSchemeHTTPS = "https"
+)
+
+// DoHMaxResponse limits the size of a DNS over HTTPS response, the largest
+// DNS message.
+const DoHMaxResponse = 65535
+
+// ParseDNSServer validates a DNS server: a plain address such as "1.1.1.1",
+// "1.1.1.1:53" or "[2606:4700:4700::1111]:53", returned as host:port; a DNS
+// over TLS server such as "tls://1.1.1.1" or "tls://dns.google:853",
+// returned as tls://host:port; or the URL of a DNS over HTTPS server such as
+// "https://cloudflare-dns.com/dns-query", returned as it is.
+func ParseDNSServer(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, DNSSchemeHTTPS+"://"):
+		u, err := url.Parse(s)
+		if err != nil || u.Host == "" || !IsValidHostname(u.Hostname()) {
+			return "", fmt.Errorf("invalid DNS over HTTPS server %q", s)
+		}
+		return s, nil
+	case strings.HasPrefix(s, DNSSchemeTLS+"://"):
+		addr, err := parseHostPort(strings.TrimPrefix(s, DNSSchemeTLS+"://"), DefaultDoTPort)
+		if err != nil {
+			return "", fmt.Errorf("invalid DNS over TLS server %q", s)
+		}
+		return DNSSchemeTLS + "://" + addr, nil
+	case strings.Contains(s, "://"):
+		return "", fmt.Errorf("unsupported DNS server %q (expected host[:port], tls://host[:port] or an https:// URL)", s)
+	}
+	addr, err := parseHostPort(s, DefaultDNSPort)
+	if err != nil {
+		return "", fmt.Errorf("invalid DNS server %q", s)
+	}
+	return addr, nil
+}
+
+// parseHostPort validates host or host:port and returns it as host:port,
+// using def when s has no port.
+func parseHostPort(s, def string) (string, error) {
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		host, port = s, def
+	}
+	if !IsValidHostname(host) {
+		return "", errors.New("invalid host")
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// resolvers caches one resolver per DNS server and source address.
+var resolvers sync.Map
+
+// resolver returns the resolver used for the target's lookups: one querying
+// its DNS server from its source address, or the system resolver when it
+// has none. The hostname of an encrypted DNS server is itself resolved by
+// the system resolver.
+func (t Target) resolver() *net.Resolver {
+	if t.DNSServer == "" {
+		return net.DefaultResolver
+	}
+	key := [2]string{t.DNSServer, t.Source}
+	if cached, ok := resolvers.Load(key); ok {
+		return cached.(*net.Resolver)
+	}
+	server := t.DNSServer
+	// Queries leave from the source address whatever the target's family,
+	// which only applies to the addresses looked up
+	src := Target{Source: t.Source}
+	var dial func(ctx context.Context, network, _ string) (net.Conn, error)
+	switch {
+	case strings.HasPrefix(server, DNSSchemeHTTPS+"://"):
+		client := dohClient
+		if src.Source != "" {
+			client = &http.Client{Transport: src.transport(nil)}
+		}
+		dial = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return &dohConn{ctx: ctx, client: client, url: server}, nil
+		}
+	case strings.HasPrefix(server, DNSSchemeTLS+"://"):
+		addr := strings.TrimPrefix(server, DNSSchemeTLS+"://")
+		host, _, _ := net.SplitHostPort(addr)
+		// Queries are sent over TLS with the length prefix of DNS over TCP,
+		// which the resolver uses for connections that are not packet based
+		dial = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			nd, err := src.bind(&net.Dialer{}, "tcp")
+			if err != nil {
+				return nil, err
+			}
+			d := tls.Dialer{NetDialer: nd, Config: &tls.Config{ServerName: host}}
+			return d.DialContext(ctx, "tcp", addr)
+		}
+	default:
+		// Queries go to server over whichever protocol the resolver picks
+		dial = func(ctx context.Context, network, _ string) (net.Conn, error) {
+			d, err := src.bind(&net.Dialer{}, network)
+			if err != nil {
+				return nil, err
+			}
+			return d.DialContext(ctx, network, server)
+		}
+	}
+	r := &net.Resolver{PreferGo: true, Dial: dial}
+	actual, _ := resolvers.LoadOrStore(key, r)
+	return actual.(*net.Resolver)
+}
+
+// dohClient sends the DNS over HTTPS queries of resolvers without a source
+// address.
+var dohClient = &http.Client{}
+
+// dohConn carries the DNS queries of a resolver to a DNS over HTTPS server
+// (RFC 8484). The resolver writes each query with the length prefix of DNS
+// over TCP; it is posted to the server when complete, and the answer is read
+// back with the same prefix.
+type dohConn struct {
+	ctx    context.Context
+	client *http.Client
+	url    string
+	query  []byte
+	resp   bytes.Buffer
+}
+
+func (c *dohConn) Write(b []byte) (int, error) {
+	c.query = append(c.query, b...)
+	for len(c.query) >= 2 {
+		n := int(binary.BigEndian.Uint16(c.query))
+		if len(c.query) < 2+n {
+			break
+		}
+		answer, err := c.exchange(c.query[2 : 2+n])
+		if err != nil {
+			return 0, err
+		}
+		c.resp.Write(binary.BigEndian.AppendUint16(nil, uint16(len(answer))))
+		c.resp.Write(answer)
+		c.query = c.query[2+n:]
+	}
+	return len(b), nil
+}
+
+// exchange posts one DNS message to the server and returns its answer.
+func (c *dohConn) exchange(msg []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodPost, c.url, bytes.NewReader(msg))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DNS over HTTPS server returned HTTP %d", resp.StatusCode)
+	}
+	answer, err := io.ReadAll(io.LimitReader(resp.Body, DoHMaxResponse+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(answer) > DoHMaxResponse {
+		return nil, errors.New("DNS over HTTPS response too large")
+	}
+	return answer, nil
+}
+
+func (c *dohConn) Read(b []byte) (int, error) {
+	if c.resp.Len() == 0 {
+		return 0, io.EOF
+	}
+	return c.resp.Read(b)
+}
+
+func (c *dohConn) Close() error                     { return nil }
+func (c *dohConn) LocalAddr() net.Addr              { return dohAddr(c.url) }
+func (c *dohConn) RemoteAddr() net.Addr             { return dohAddr(c.url) }
+func (c *dohConn) SetDeadline(time.Time) error      { return nil }
+func (c *dohConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *dohConn) SetWriteDeadline(time.Time) error { return nil }
+
+// dohAddr is the address of a DNS over HTTPS server: its URL.
+type dohAddr string
+
+func (a dohAddr) Network() string { return DNSSchemeHTTPS }
+func (a dohAddr) String() string  { return string(a) }
@@ -0,0 +1,76 @@
+package monitor
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Defaults for retrying failed checks
+const (
+	DefaultRetryAttempts  = 1
+	DefaultRetryBaseDelay = 500 * time.Millisecond
+	DefaultRetryMaxDelay  = 10 * time.Second
+)
+
+// RetryPolicy controls how often a failing check is retried within a cycle
+// before the failure is reported.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first. Values
+	// below 1 are treated as 1 (no retries).
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry; it doubles for every
+	// further retry up to MaxDelay, or without bound when MaxDelay is zero.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DefaultRetryPolicy performs a single attempt without retries.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: DefaultRetryAttempts,
+		BaseDelay:   DefaultRetryBaseDelay,
+		MaxDelay:    DefaultRetryMaxDelay,
+	}
+}
+
+// delay returns the wait before the given retry (1 for the first retry) with
+// exponential growth and up to 50% random jitter.
+func (p RetryPolicy) delay(retry int) time.Duration {
+	d := p.BaseDelay
+	// Doubling stops short of overflowing when there is no MaxDelay
+	for i := 1; i < retry && (p.MaxDelay <= 0 || d < p.MaxDelay) && d < math.MaxInt64/2; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+// run executes c against t, retrying failures according to the policy. Each
+// attempt is bounded by the target's check timeout. The returned Result is
+// from the last attempt and records how many were made.
+func (p RetryPolicy) run(ctx context.Context, c Checker, t Target) Result {
+	attempts := max(p.MaxAttempts, 1)
+	var r Result
+	for i := 1; ; i++ {
+		r = checkWithDeadline(ctx, c, t)
+		r.Attempts = i
+		if r.Err == nil || i >= attempts {
+			return r
+		}
+		timer := time.NewTimer(p.delay(i))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return r
+		case <-timer.C:
+		}
+	}
+}
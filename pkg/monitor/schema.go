@@ -0,0 +1,509 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// SchemaMaxErrors bounds the validation errors reported per response; the
+// rest are counted.
+const SchemaMaxErrors = 5
+
+// schemaKeywords are the JSON Schema keywords Schema validates. Others, such
+// as format, are accepted and ignored, as validators treat them as
+// annotations by default.
+var schemaKeywords = []string{
+	"type", "enum", "const",
+	"minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum", "multipleOf",
+	"minLength", "maxLength", "pattern",
+	"items", "prefixItems", "minItems", "maxItems", "uniqueItems", "contains",
+	"properties", "required", "additionalProperties", "patternProperties", "minProperties", "maxProperties",
+	"allOf", "anyOf", "oneOf", "not", "if", "then", "else", "$ref",
+}
+
+// Schema is a JSON Schema health responses must match, e.g. to catch a
+// service that still answers 200 but broke its contract. It supports the
+// keywords of schemaKeywords, with references within the schema itself
+// ("#/$defs/check"), in the semantics of draft 2020-12 and, for items and
+// exclusive bounds, of draft 4 to 7.
+type Schema struct {
+	// Source is the file the schema was read from, or "inline".
+	Source   string
+	root     any
+	patterns map[string]*regexp.Regexp
+}
+
+// ParseSchema parses a JSON Schema given inline, as a JSON object, or as
+// the path of a file holding one. Patterns and references are checked up
+// front so a broken schema fails the configuration rather than every
+// check.
+func ParseSchema(s string) (*Schema, error) {
+	s = strings.TrimSpace(s)
+	sch := &Schema{Source: "inline", patterns: make(map[string]*regexp.Regexp)}
+	data := []byte(s)
+	if !strings.HasPrefix(s, "{") {
+		var err error
+		if data, err = os.ReadFile(s); err != nil {
+			return nil, err
+		}
+		sch.Source = s
+	}
+	if err := json.Unmarshal(data, &sch.root); err != nil {
+		return nil, fmt.Errorf("schema %s: %w", sch.Source, err)
+	}
+	if _, ok := sch.root.(map[string]any); !ok {
+		return nil, fmt.Errorf("schema %s: expected a JSON object", sch.Source)
+	}
+	if err := sch.compile(sch.root, "#"); err != nil {
+		return nil, fmt.Errorf("schema %s: %w", sch.Source, err)
+	}
+	return sch, nil
+}
+
+// Keywords whose values are subschemas, by how they hold them. The values of
+// other keywords, such as enum, const, default and examples, are data and
+// are not walked.
+var (
+	schemaSubschemaKeywords = []string{
+		"items", "additionalItems", "contains", "additionalProperties", "propertyNames",
+		"unevaluatedItems", "unevaluatedProperties", "not", "if", "then", "else",
+	}
+	schemaSubschemaListKeywords = []string{"items", "prefixItems", "allOf", "anyOf", "oneOf"}
+	schemaSubschemaMapKeywords  = []string{"properties", "patternProperties", "dependentSchemas", "$defs", "definitions"}
+)
+
+// compile walks the schema at loc by keyword, compiling its patterns and
+// resolving its references.
+func (s *Schema) compile(node any, loc string) error {
+	schema, ok := node.(map[string]any)
+	if !ok {
+		return nil
+	}
+	if p, ok := schema["pattern"].(string); ok {
+		if err := s.compilePattern(p, loc); err != nil {
+			return err
+		}
+	}
+	if props, ok := schema["patternProperties"].(map[string]any); ok {
+		for p := range props {
+			if err := s.compilePattern(p, loc); err != nil {
+				return err
+			}
+		}
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		if _, err := s.resolve(ref); err != nil {
+			return fmt.Errorf("%s: %w", loc, err)
+		}
+	}
+	for _, key := range schemaSubschemaKeywords {
+		if sub, ok := schema[key].(map[string]any); ok {
+			if err := s.compile(sub, loc+"/"+key); err != nil {
+				return err
+			}
+		}
+	}
+	for _, key := range schemaSubschemaListKeywords {
+		subs, _ := schema[key].([]any)
+		for i, sub := range subs {
+			if err := s.compile(sub, loc+"/"+key+"/"+strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+	}
+	for _, key := range schemaSubschemaMapKeywords {
+		subs, _ := schema[key].(map[string]any)
+		for name, sub := range subs {
+			if err := s.compile(sub, loc+"/"+key+"/"+name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) compilePattern(p, loc string) error {
+	if _, ok := s.patterns[p]; ok {
+		return nil
+	}
+	re, err := regexp.Compile(p)
+	if err != nil {
+		return fmt.Errorf("%s: invalid pattern %q: %w", loc, p, err)
+	}
+	s.patterns[p] = re
+	return nil
+}
+
+// pattern returns the compiled pattern p. Patterns only reachable through a
+// reference into a location compile does not walk are compiled on use.
+func (s *Schema) pattern(p string) (*regexp.Regexp, error) {
+	if re := s.patterns[p]; re != nil {
+		return re, nil
+	}
+	return regexp.Compile(p)
+}
+
+// resolve returns the schema a reference within the schema points to, as a
+// JSON pointer from its root ("#/$defs/check").
+func (s *Schema) resolve(ref string) (any, error) {
+	pointer, ok := strings.CutPrefix(ref, "#")
+	if !ok {
+		return nil, fmt.Errorf("unsupported $ref %q: only references within the schema (#/...) are supported", ref)
+	}
+	node := s.root
+	if pointer == "" {
+		return node, nil
+	}
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		switch n := node.(type) {
+		case map[string]any:
+			node, ok = n[token]
+		case []any:
+			i, err := strconv.Atoi(token)
+			ok = err == nil && i >= 0 && i < len(n)
+			if ok {
+				node = n[i]
+			}
+		default:
+			ok = false
+		}
+		if !ok {
+			return nil, fmt.Errorf("$ref %q points to nothing", ref)
+		}
+	}
+	return node, nil
+}
+
+// Validate checks a decoded health response against the schema and returns
+// the violations, at most SchemaMaxErrors of them followed by a count of
+// the others. Each names the location of the offending value as a
+// JSONPath, e.g. "$.checks[0].status".
+func (s *Schema) Validate(data any) []error {
+	errs := s.validate(data, s.root, "$", 0)
+	if len(errs) > SchemaMaxErrors {
+		more := len(errs) - SchemaMaxErrors
+		errs = append(errs[:SchemaMaxErrors], fmt.Errorf("schema: and %d more violations", more))
+	}
+	return errs
+}
+
+// schemaMaxDepth bounds the references followed, so a schema referring to
+// itself without consuming the value cannot loop forever.
+const schemaMaxDepth = 64
+
+// validate checks v, found at path, against the schema node.
+func (s *Schema) validate(v any, node any, path string, depth int) []error {
+	fail := func(format string, args ...any) []error {
+		return []error{fmt.Errorf("schema: %s: "+format, append([]any{path}, args...)...)}
+	}
+	switch node := node.(type) {
+	case bool:
+		if !node {
+			return fail("no value is allowed")
+		}
+		return nil
+	case map[string]any:
+		if depth > schemaMaxDepth {
+			return fail("$ref nesting too deep")
+		}
+		var errs []error
+		if ref, ok := node["$ref"].(string); ok {
+			target, _ := s.resolve(ref)
+			errs = append(errs, s.validate(v, target, path, depth+1)...)
+		}
+		if t, ok := node["type"]; ok && !schemaTypeMatches(v, t) {
+			// The other keywords are meaningless for a value of another
+			// type
+			return append(errs, fail("expected %s, got %s", schemaTypeNames(t), jsonType(v))...)
+		}
+		if enum, ok := node["enum"].([]any); ok && !slices.ContainsFunc(enum, func(e any) bool { return jsonDeepEqual(v, e) }) {
+			errs = append(errs, fail("expected one of %s, got %s", formatJSON(enum), formatJSON(v))...)
+		}
+		if c, ok := node["const"]; ok && !jsonDeepEqual(v, c) {
+			errs = append(errs, fail("expected %s, got %s", formatJSON(c), formatJSON(v))...)
+		}
+		switch v := v.(type) {
+		case float64:
+			errs = append(errs, s.validateNumber(v, node, fail)...)
+		case string:
+			errs = append(errs, s.validateString(v, node, fail)...)
+		case []any:
+			errs = append(errs, s.validateArray(v, node, path, depth, fail)...)
+		case map[string]any:
+			errs = append(errs, s.validateObject(v, node, path, depth, fail)...)
+		}
+		errs = append(errs, s.validateCombinators(v, node, path, depth, fail)...)
+		return errs
+	}
+	return nil
+}
+
+func (s *Schema) validateNumber(n float64, node map[string]any, fail func(string, ...any) []error) []error {
+	var errs []error
+	bound := func(key string) (float64, bool) {
+		b, ok := node[key].(float64)
+		return b, ok
+	}
+	if b, ok := bound("minimum"); ok && n < b {
+		errs = append(errs, fail("%v is less than the minimum of %v", n, b)...)
+	}
+	if b, ok := bound("maximum"); ok && n > b {
+		errs = append(errs, fail("%v is greater than the maximum of %v", n, b)...)
+	}
+	// Draft 4 used booleans modifying minimum and maximum
+	if b, ok := bound("exclusiveMinimum"); ok && n <= b {
+		errs = append(errs, fail("%v must be greater than %v", n, b)...)
+	} else if node["exclusiveMinimum"] == true {
+		if b, ok := bound("minimum"); ok && n == b {
+			errs = append(errs, fail("%v must be greater than %v", n, b)...)
+		}
+	}
+	if b, ok := bound("exclusiveMaximum"); ok && n >= b {
+		errs = append(errs, fail("%v must be less than %v", n, b)...)
+	} else if node["exclusiveMaximum"] == true {
+		if b, ok := bound("maximum"); ok && n == b {
+			errs = append(errs, fail("%v must be less than %v", n, b)...)
+		}
+	}
+	if m, ok := bound("multipleOf"); ok && m > 0 {
+		if q := n / m; math.Abs(q-math.Round(q)) > 1e-9 {
+			errs = append(errs, fail("%v is not a multiple of %v", n, m)...)
+		}
+	}
+	return errs
+}
+
+func (s *Schema) validateString(str string, node map[string]any, fail func(string, ...any) []error) []error {
+	var errs []error
+	n := utf8.RuneCountInString(str)
+	if m, ok := node["minLength"].(float64); ok && float64(n) < m {
+		errs = append(errs, fail("%s is shorter than %v characters", formatJSON(str), m)...)
+	}
+	if m, ok := node["maxLength"].(float64); ok && float64(n) > m {
+		errs = append(errs, fail("%s is longer than %v characters", formatJSON(str), m)...)
+	}
+	if p, ok := node["pattern"].(string); ok {
+		if re, err := s.pattern(p); err != nil {
+			errs = append(errs, fail("invalid pattern %q: %v", p, err)...)
+		} else if !re.MatchString(str) {
+			errs = append(errs, fail("%s does not match %q", formatJSON(str), p)...)
+		}
+	}
+	return errs
+}
+
+func (s *Schema) validateArray(a []any, node map[string]any, path string, depth int, fail func(string, ...any) []error) []error {
+	var errs []error
+	if m, ok := node["minItems"].(float64); ok && float64(len(a)) < m {
+		errs = append(errs, fail("expected at least %v items, got %d", m, len(a))...)
+	}
+	if m, ok := node["maxItems"].(float64); ok && float64(len(a)) > m {
+		errs = append(errs, fail("expected at most %v items, got %d", m, len(a))...)
+	}
+	if node["uniqueItems"] == true {
+		for i := range a {
+			if slices.ContainsFunc(a[:i], func(e any) bool { return jsonDeepEqual(a[i], e) }) {
+				errs = append(errs, fail("item %d is a duplicate", i)...)
+				break
+			}
+		}
+	}
+	// prefixItems (2020-12), or items as an array (draft 4 to 7), validate
+	// the first items one by one; items as a schema validates the others
+	prefix, _ := node["prefixItems"].([]any)
+	rest := node["items"]
+	if tuple, ok := rest.([]any); ok {
+		prefix, rest = tuple, node["additionalItems"]
+	}
+	for i, item := range a {
+		itemPath := path + "[" + strconv.Itoa(i) + "]"
+		switch {
+		case i < len(prefix):
+			errs = append(errs, s.validate(item, prefix[i], itemPath, depth)...)
+		case rest != nil:
+			errs = append(errs, s.validate(item, rest, itemPath, depth)...)
+		}
+	}
+	if contains, ok := node["contains"]; ok && !slices.ContainsFunc(a, func(e any) bool { return len(s.validate(e, contains, path, depth)) == 0 }) {
+		errs = append(errs, fail("no item matches contains")...)
+	}
+	return errs
+}
+
+func (s *Schema) validateObject(o map[string]any, node map[string]any, path string, depth int, fail func(string, ...any) []error) []error {
+	var errs []error
+	if required, ok := node["required"].([]any); ok {
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				if _, present := o[name]; !present {
+					errs = append(errs, fail("missing required property %q", name)...)
+				}
+			}
+		}
+	}
+	if m, ok := node["minProperties"].(float64); ok && float64(len(o)) < m {
+		errs = append(errs, fail("expected at least %v properties, got %d", m, len(o))...)
+	}
+	if m, ok := node["maxProperties"].(float64); ok && float64(len(o)) > m {
+		errs = append(errs, fail("expected at most %v properties, got %d", m, len(o))...)
+	}
+	props, _ := node["properties"].(map[string]any)
+	patternProps, _ := node["patternProperties"].(map[string]any)
+	additional, hasAdditional := node["additionalProperties"]
+	keys := make([]string, 0, len(o))
+	for k := range o {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		keyPath := childPath(path, k)
+		matched := false
+		if p, ok := props[k]; ok {
+			matched = true
+			errs = append(errs, s.validate(o[k], p, keyPath, depth)...)
+		}
+		for pattern, p := range patternProps {
+			if re, err := s.pattern(pattern); err != nil {
+				errs = append(errs, fail("invalid pattern %q: %v", pattern, err)...)
+			} else if re.MatchString(k) {
+				matched = true
+				errs = append(errs, s.validate(o[k], p, keyPath, depth)...)
+			}
+		}
+		if !matched && hasAdditional {
+			if additional == false {
+				errs = append(errs, fail("unexpected property %q", k)...)
+			} else {
+				errs = append(errs, s.validate(o[k], additional, keyPath, depth)...)
+			}
+		}
+	}
+	return errs
+}
+
+func (s *Schema) validateCombinators(v any, node map[string]any, path string, depth int, fail func(string, ...any) []error) []error {
+	var errs []error
+	matches := func(sub any) bool { return len(s.validate(v, sub, path, depth+1)) == 0 }
+	if all, ok := node["allOf"].([]any); ok {
+		for _, sub := range all {
+			errs = append(errs, s.validate(v, sub, path, depth+1)...)
+		}
+	}
+	if anyOf, ok := node["anyOf"].([]any); ok && !slices.ContainsFunc(anyOf, matches) {
+		errs = append(errs, fail("matches none of anyOf")...)
+	}
+	if oneOf, ok := node["oneOf"].([]any); ok {
+		n := 0
+		for _, sub := range oneOf {
+			if matches(sub) {
+				n++
+			}
+		}
+		if n != 1 {
+			errs = append(errs, fail("matches %d of oneOf, expected exactly 1", n)...)
+		}
+	}
+	if not, ok := node["not"]; ok && matches(not) {
+		errs = append(errs, fail("matches the schema of not")...)
+	}
+	if cond, ok := node["if"]; ok {
+		branch, has := node["else"]
+		if matches(cond) {
+			branch, has = node["then"]
+		}
+		if has {
+			errs = append(errs, s.validate(v, branch, path, depth+1)...)
+		}
+	}
+	return errs
+}
+
+// schemaTypeMatches reports whether v is of the type, or one of the types,
+// of a type keyword. Integers are numbers without a fractional part.
+func schemaTypeMatches(v any, t any) bool {
+	switch t := t.(type) {
+	case string:
+		got := jsonType(v)
+		return got == t || (t == "number" && got == "integer")
+	case []any:
+		return slices.ContainsFunc(t, func(e any) bool { return schemaTypeMatches(v, e) })
+	}
+	return true
+}
+
+// schemaTypeNames formats the types of a type keyword.
+func schemaTypeNames(t any) string {
+	if list, ok := t.([]any); ok {
+		names := make([]string, len(list))
+		for i, e := range list {
+			names[i] = fmt.Sprint(e)
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+// jsonType names the JSON Schema type of a decoded JSON value.
+func jsonType(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// jsonDeepEqual compares decoded JSON values, including objects and arrays.
+func jsonDeepEqual(a, b any) bool {
+	switch a := a.(type) {
+	case []any:
+		b, ok := b.([]any)
+		return ok && slices.EqualFunc(a, b, jsonDeepEqual)
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			if w, ok := b[k]; !ok || !jsonDeepEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// childPath appends a property to a JSONPath, in bracket notation when the
+// name is not a plain identifier.
+func childPath(path, name string) string {
+	for i, r := range name {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return path + "[" + strconv.Quote(name) + "]"
+		}
+	}
+	if name == "" {
+		return path + `[""]`
+	}
+	return path + "." + name
+}
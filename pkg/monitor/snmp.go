@@ -0,0 +1,519 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Defaults for the SNMP checker
+const (
+	DefaultSNMPPort      = "161"
+	DefaultSNMPCommunity = "public"
+	// DefaultSNMPOID is queried when a target lists no OIDs: the time since
+	// the agent started, which every agent serves.
+	DefaultSNMPOID = "sysUpTime.0"
+)
+
+// SNMP versions
+const (
+	SNMPv2c = "2c"
+	SNMPv3  = "3"
+)
+
+// snmpMaxMessage is the largest SNMP message read, the largest UDP payload.
+const snmpMaxMessage = 65507
+
+// SNMP PDU types and the exceptions agents answer for OIDs they do not
+// serve (RFC 3416)
+const (
+	snmpGetRequest  = 0xa0
+	snmpGetResponse = 0xa2
+	snmpReport      = 0xa8
+
+	snmpNoSuchObject   = 0x80
+	snmpNoSuchInstance = 0x81
+	snmpEndOfMibView   = 0x82
+)
+
+// snmpErrors names the error statuses of responses (RFC 3416).
+var snmpErrors = []string{
+	"noError", "tooBig", "noSuchName", "badValue", "readOnly", "genErr",
+	"noAccess", "wrongType", "wrongLength", "wrongEncoding", "wrongValue",
+	"noCreation", "inconsistentValue", "resourceUnavailable", "commitFailed",
+	"undoFailed", "authorizationError", "notWritable", "inconsistentName",
+}
+
+// snmpNames are the objects of the standard MIBs that SNMPOIDs may name
+// instead of giving their numeric OID; the instance still follows, e.g.
+// "sysUpTime.0" or "ifOperStatus.2".
+var snmpNames = map[string]string{
+	"sysDescr":          "1.3.6.1.2.1.1.1",
+	"sysObjectID":       "1.3.6.1.2.1.1.2",
+	"sysUpTime":         "1.3.6.1.2.1.1.3",
+	"sysContact":        "1.3.6.1.2.1.1.4",
+	"sysName":           "1.3.6.1.2.1.1.5",
+	"sysLocation":       "1.3.6.1.2.1.1.6",
+	"ifNumber":          "1.3.6.1.2.1.2.1",
+	"ifDescr":           "1.3.6.1.2.1.2.2.1.2",
+	"ifSpeed":           "1.3.6.1.2.1.2.2.1.5",
+	"ifAdminStatus":     "1.3.6.1.2.1.2.2.1.7",
+	"ifOperStatus":      "1.3.6.1.2.1.2.2.1.8",
+	"ifInDiscards":      "1.3.6.1.2.1.2.2.1.13",
+	"ifInErrors":        "1.3.6.1.2.1.2.2.1.14",
+	"ifOutDiscards":     "1.3.6.1.2.1.2.2.1.19",
+	"ifOutErrors":       "1.3.6.1.2.1.2.2.1.20",
+	"ifName":            "1.3.6.1.2.1.31.1.1.1.1",
+	"ifAlias":           "1.3.6.1.2.1.31.1.1.1.18",
+	"hrSystemUptime":    "1.3.6.1.2.1.25.1.1",
+	"hrSystemProcesses": "1.3.6.1.2.1.25.1.6",
+}
+
+// SNMP configures the SNMP checker for a target.
+type SNMP struct {
+	// Version is SNMPv2c or SNMPv3; empty means SNMPv2c.
+	Version string
+	// Community authenticates SNMPv2c requests; empty means
+	// DefaultSNMPCommunity.
+	Community string
+	// User is the SNMPv3 user. Requests are signed with AuthPassword using
+	// AuthProtocol, and encrypted with PrivPassword using PrivProtocol,
+	// when those passwords are set (see ParseSNMPAuthProtocol and
+	// ParseSNMPPrivProtocol).
+	User         string
+	AuthProtocol string
+	AuthPassword string
+	PrivProtocol string
+	PrivPassword string
+	// OIDs are the objects queried, in a single request; empty means
+	// DefaultSNMPOID.
+	OIDs []SNMPOID
+}
+
+// SNMPOID is an object the SNMP checker queries, with an optional
+// expectation about its value, such as `ifOperStatus.2 == 1`.
+type SNMPOID struct {
+	// Name is the object as configured, used in results.
+	Name string
+	OID  []uint32
+	// Op and Value compare the value of the object; an empty Op only
+	// reports it.
+	Op    string
+	Value any
+}
+
+// ParseSNMPVersion validates an SNMP version: 2c or 3, and empty for the
+// default of 2c.
+func ParseSNMPVersion(s string) (string, error) {
+	switch strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "v") {
+	case "", SNMPv2c:
+		return SNMPv2c, nil
+	case SNMPv3:
+		return SNMPv3, nil
+	}
+	return "", fmt.Errorf("invalid SNMP version %q (expected 2c or 3)", s)
+}
+
+// ParseSNMPOIDs parses the objects an SNMP check queries, separated by
+// ";", e.g. "sysUpTime.0; ifOperStatus.2 == 1; 1.3.6.1.4.1.2021.10.1.5.1 < 300".
+// Each is a numeric OID or the name of a standard object followed by its
+// instance, optionally followed by an operator (==, !=, <, <=, > or >=)
+// and a value, as in HEALTH_ASSERT: a JSON literal, or a bare word taken
+// as a string. <, <=, > and >= compare numbers.
+func ParseSNMPOIDs(s string) ([]SNMPOID, error) {
+	var oids []SNMPOID
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		o, err := parseSNMPOID(part)
+		if err != nil {
+			return nil, err
+		}
+		oids = append(oids, o)
+	}
+	return oids, nil
+}
+
+// parseSNMPOID parses one object of ParseSNMPOIDs.
+func parseSNMPOID(s string) (SNMPOID, error) {
+	name := s
+	var o SNMPOID
+	idx := -1
+	for _, op := range assertionOps {
+		if i := strings.Index(s, op); i > 0 && (idx < 0 || i < idx) {
+			idx, o.Op = i, op
+		}
+	}
+	if idx >= 0 {
+		name = strings.TrimSpace(s[:idx])
+		raw := strings.TrimSpace(s[idx+len(o.Op):])
+		if raw == "" {
+			return o, fmt.Errorf("SNMP OID %q: missing value", s)
+		}
+		if err := json.Unmarshal([]byte(raw), &o.Value); err != nil {
+			o.Value = raw
+		}
+		if _, ok := o.Value.(float64); !ok && o.Op != "==" && o.Op != "!=" {
+			return o, fmt.Errorf("SNMP OID %q: %s needs a number", s, o.Op)
+		}
+	}
+	o.Name = name
+	numeric := strings.TrimPrefix(name, ".")
+	if prefix, instance, _ := strings.Cut(name, "."); snmpNames[prefix] != "" {
+		numeric = snmpNames[prefix]
+		if instance != "" {
+			numeric += "." + instance
+		}
+	}
+	for _, arc := range strings.Split(numeric, ".") {
+		n, err := strconv.ParseUint(arc, 10, 32)
+		if err != nil {
+			return o, fmt.Errorf("SNMP OID %q: expected a numeric OID such as 1.3.6.1.2.1.1.3.0 or a standard object such as sysUpTime.0", name)
+		}
+		o.OID = append(o.OID, uint32(n))
+	}
+	if len(o.OID) < 2 || o.OID[0] > 2 || (o.OID[0] < 2 && o.OID[1] >= 40) {
+		return o, fmt.Errorf("SNMP OID %q is not a valid OID", name)
+	}
+	return o, nil
+}
+
+// check compares the value of the object with its expectation and reports
+// an error describing the mismatch, if any.
+func (o SNMPOID) check(got any) error {
+	if o.Op == "" {
+		return nil
+	}
+	var ok bool
+	switch o.Op {
+	case "==", "!=":
+		want := o.Value
+		if n, isNum := number(got); isNum {
+			if w, wantNum := o.Value.(float64); wantNum {
+				got, want = n, w
+			}
+		}
+		ok = jsonEqual(got, want) == (o.Op == "==")
+	default:
+		n, isNum := number(got)
+		if !isNum {
+			return fmt.Errorf("%s %s %s: got %s, not a number", o.Name, o.Op, formatJSON(o.Value), formatJSON(got))
+		}
+		want := o.Value.(float64)
+		switch o.Op {
+		case "<":
+			ok = n < want
+		case "<=":
+			ok = n <= want
+		case ">":
+			ok = n > want
+		case ">=":
+			ok = n >= want
+		}
+	}
+	if !ok {
+		return fmt.Errorf("%s %s %s: got %s", o.Name, o.Op, formatJSON(o.Value), formatJSON(got))
+	}
+	return nil
+}
+
+// snmpVar is a variable binding of a response: an OID and its value, or
+// the exception the agent answered for it.
+type snmpVar struct {
+	oid   []uint32
+	value any
+	// ticks is set for TimeTicks values, in hundredths of a second.
+	ticks bool
+	// exception is set when the agent does not serve the OID.
+	exception string
+}
+
+// SNMPChecker queries a target's OIDs with an SNMP GET over UDP, with
+// SNMPv2c or SNMPv3 (see SNMP), and fails when the agent does not answer,
+// does not serve one of the OIDs or answers a value its expectation
+// rejects. The values are reported by the names of the OIDs.
+type SNMPChecker struct {
+	Port    string
+	Timeout time.Duration
+}
+
+// NewSNMPChecker returns an SNMPChecker using the default port and timeout.
+func NewSNMPChecker() SNMPChecker {
+	return SNMPChecker{Port: DefaultSNMPPort, Timeout: DefaultTCPTimeout}
+}
+
+// Name implements Checker.
+func (c SNMPChecker) Name() string { return CheckSNMP }
+
+// Check sends one GET request for every OID of the target, on the target's
+// own port if it has one; SNMPv3 first discovers the agent's engine with
+// another request. Latency is the round-trip time of the GET.
+func (c SNMPChecker) Check(ctx context.Context, t Target) Result {
+	timeout := t.connectTimeout(c.Timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	d, err := t.bind(&net.Dialer{Timeout: timeout, Resolver: t.resolver()}, t.network("udp"))
+	if err != nil {
+		return Result{Err: err}
+	}
+	conn, err := d.DialContext(ctx, t.network("udp"), net.JoinHostPort(t.Host, t.portOr(c.Port)))
+	if err != nil {
+		return Result{Err: err}
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	cfg := SNMP{}
+	if t.SNMP != nil {
+		cfg = *t.SNMP
+	}
+	if len(cfg.OIDs) == 0 {
+		def, _ := parseSNMPOID(DefaultSNMPOID)
+		cfg.OIDs = []SNMPOID{def}
+	}
+	var vars []snmpVar
+	var latency time.Duration
+	if cfg.Version == SNMPv3 {
+		vars, latency, err = snmpGetV3(conn, cfg)
+	} else {
+		vars, latency, err = snmpGetV2c(conn, cfg)
+	}
+	if err != nil {
+		return Result{Latency: latency, Err: err}
+	}
+	if len(vars) != len(cfg.OIDs) {
+		return Result{Latency: latency, Err: fmt.Errorf("SNMP agent answered %d values for %d OIDs", len(vars), len(cfg.OIDs))}
+	}
+
+	r := Result{Latency: latency, Data: make(map[string]any, len(vars))}
+	var details []string
+	var errs []error
+	for i, o := range cfg.OIDs {
+		v := vars[i]
+		switch {
+		case !slices.Equal(v.oid, o.OID):
+			errs = append(errs, fmt.Errorf("%s: SNMP agent answered another OID, %s", o.Name, formatOID(v.oid)))
+			continue
+		case v.exception != "":
+			errs = append(errs, fmt.Errorf("%s: %s", o.Name, v.exception))
+			continue
+		}
+		r.Data[o.Name] = v.value
+		details = append(details, o.Name+" = "+formatSNMPValue(v))
+		if err := o.check(v.value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	r.Detail = strings.Join(details, ", ")
+	r.Err = errors.Join(errs...)
+	return r
+}
+
+// snmpGetV2c sends an SNMPv2c GET for every OID of cfg and returns the
+// variables of the response.
+func snmpGetV2c(conn net.Conn, cfg SNMP) ([]snmpVar, time.Duration, error) {
+	community := cfg.Community
+	if community == "" {
+		community = DefaultSNMPCommunity
+	}
+	id := rand.Int32()
+	msg := berSequence(
+		berInt(berInteger, 1), // SNMPv2c
+		berTLV(berOctetString, []byte(community)),
+		snmpGetPDU(id, cfg.OIDs),
+	)
+	start := time.Now()
+	if _, err := conn.Write(msg); err != nil {
+		return nil, 0, err
+	}
+	b := make([]byte, snmpMaxMessage)
+	for {
+		n, err := conn.Read(b)
+		if err != nil {
+			if isTimeout(err) {
+				err = fmt.Errorf("no SNMP response, check the community: %w", err)
+			}
+			return nil, time.Since(start), err
+		}
+		fields, err := berFields(b[:n], berSequenceTag, 3)
+		if err != nil || fields[2].tag != snmpGetResponse {
+			continue
+		}
+		vars, err := snmpResponse(fields[2], id, cfg.OIDs)
+		if errors.Is(err, errSNMPOtherRequest) {
+			continue
+		}
+		return vars, time.Since(start), err
+	}
+}
+
+// errSNMPOtherRequest marks responses to another request, e.g. a late
+// answer to an earlier check.
+var errSNMPOtherRequest = errors.New("response to another request")
+
+// snmpGetPDU encodes a GET request for oids.
+func snmpGetPDU(id int32, oids []SNMPOID) []byte {
+	var binds [][]byte
+	for _, o := range oids {
+		binds = append(binds, berSequence(berOID(o.OID), berTLV(berNull, nil)))
+	}
+	return berTLV(snmpGetRequest, slices.Concat(
+		berInt(berInteger, int64(id)),
+		berInt(berInteger, 0),
+		berInt(berInteger, 0),
+		berSequence(binds...),
+	))
+}
+
+// snmpResponse decodes the variables of a response PDU to the request id.
+func snmpResponse(pdu berValue, id int32, oids []SNMPOID) ([]snmpVar, error) {
+	fields, err := pdu.children()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) != 4 {
+		return nil, errors.New("malformed SNMP response")
+	}
+	if got, err := fields[0].int(); err != nil || got != int64(id) {
+		return nil, errSNMPOtherRequest
+	}
+	status, err := fields[1].int()
+	if err != nil {
+		return nil, err
+	}
+	if status != 0 {
+		name := fmt.Sprintf("error %d", status)
+		if status > 0 && int(status) < len(snmpErrors) {
+			name = snmpErrors[status]
+		}
+		index, _ := fields[2].int()
+		if index > 0 && int(index) <= len(oids) {
+			return nil, fmt.Errorf("SNMP agent answered %s for %s", name, oids[index-1].Name)
+		}
+		return nil, fmt.Errorf("SNMP agent answered %s", name)
+	}
+	binds, err := fields[3].children()
+	if err != nil {
+		return nil, err
+	}
+	vars := make([]snmpVar, 0, len(binds))
+	for _, bind := range binds {
+		v, err := parseSNMPVar(bind)
+		if err != nil {
+			return nil, err
+		}
+		vars = append(vars, v)
+	}
+	return vars, nil
+}
+
+// parseSNMPVar decodes a variable binding. Numbers are decoded as float64,
+// octet strings as strings unless they hold binary data, shown in hex, and
+// OIDs and IP addresses in their dotted notation.
+func parseSNMPVar(bind berValue) (snmpVar, error) {
+	var v snmpVar
+	fields, err := bind.children()
+	if err != nil {
+		return v, err
+	}
+	if len(fields) != 2 || fields[0].tag != berObjectID {
+		return v, errors.New("malformed SNMP variable binding")
+	}
+	if v.oid, err = fields[0].oid(); err != nil {
+		return v, err
+	}
+	value := fields[1]
+	switch value.tag {
+	case berInteger:
+		n, err := value.int()
+		if err != nil {
+			return v, err
+		}
+		v.value = float64(n)
+	case berCounter32, berGauge32, berTimeTicks, berCounter64:
+		n, err := value.uint()
+		if err != nil {
+			return v, err
+		}
+		v.value, v.ticks = float64(n), value.tag == berTimeTicks
+	case berOctetString:
+		v.value = snmpString(value.b)
+	case berObjectID:
+		oid, err := value.oid()
+		if err != nil {
+			return v, err
+		}
+		v.value = formatOID(oid)
+	case berIPAddress:
+		v.value = net.IP(value.b).String()
+	case berNull:
+	case snmpNoSuchObject:
+		v.exception = "no such object"
+	case snmpNoSuchInstance:
+		v.exception = "no such instance"
+	case snmpEndOfMibView:
+		v.exception = "end of MIB view"
+	default:
+		v.value = fmt.Sprintf("% x", value.b)
+	}
+	return v, nil
+}
+
+// snmpString decodes an octet string: text without its trailing NULs, or
+// binary data such as a MAC address in hex, e.g. "00:1a:2b:3c:4d:5e".
+func snmpString(b []byte) string {
+	text := strings.TrimRight(string(b), "\x00")
+	if strings.IndexFunc(text, func(r rune) bool { return r == utf8.RuneError || (r < ' ' && r != '\t' && r != '\n' && r != '\r') }) < 0 {
+		return text
+	}
+	hex := make([]string, len(b))
+	for i, c := range b {
+		hex[i] = fmt.Sprintf("%02x", c)
+	}
+	return strings.Join(hex, ":")
+}
+
+// formatSNMPValue formats the value of a variable for display, with
+// TimeTicks also as a duration.
+func formatSNMPValue(v snmpVar) string {
+	switch value := v.value.(type) {
+	case float64:
+		s := strconv.FormatFloat(value, 'f', -1, 64)
+		if v.ticks {
+			s += " (" + (time.Duration(value) * 10 * time.Millisecond).Round(time.Second).String() + ")"
+		}
+		return s
+	case string:
+		return strconv.Quote(value)
+	case nil:
+		return "null"
+	}
+	return fmt.Sprint(v.value)
+}
+
+// formatOID formats an OID in dotted notation.
+func formatOID(oid []uint32) string {
+	arcs := make([]string, len(oid))
+	for i, arc := range oid {
+		arcs[i] = strconv.FormatUint(uint64(arc), 10)
+	}
+	return strings.Join(arcs, ".")
+}
+
+// isTimeout reports whether err is a network timeout.
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
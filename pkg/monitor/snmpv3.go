@@ -0,0 +1,475 @@
+package monitor
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SNMPv3 authentication protocols
+const (
+	SNMPAuthMD5    = "md5"
+	SNMPAuthSHA    = "sha"
+	SNMPAuthSHA224 = "sha224"
+	SNMPAuthSHA256 = "sha256"
+	SNMPAuthSHA384 = "sha384"
+	SNMPAuthSHA512 = "sha512"
+)
+
+// SNMPv3 privacy protocols; AES is AES-128.
+const (
+	SNMPPrivDES = "des"
+	SNMPPrivAES = "aes"
+)
+
+// SNMPMinPassword is the length SNMPv3 passwords must have at least, as
+// agents refuse shorter ones (RFC 3414).
+const SNMPMinPassword = 8
+
+// SNMPv3 message settings of the user-based security model (RFC 3412,
+// RFC 3414)
+const (
+	snmpFlagAuth       = 0x01
+	snmpFlagPriv       = 0x02
+	snmpFlagReportable = 0x04
+	snmpSecurityUSM    = 3
+	// snmpKeyExpansion is how many bytes of repeated password are hashed
+	// into a key.
+	snmpKeyExpansion = 1 << 20
+)
+
+// snmpAuthProtocol is an HMAC of the user-based security model, truncated
+// to macLen bytes (RFC 3414, RFC 7860).
+type snmpAuthProtocol struct {
+	hash   func() hash.Hash
+	macLen int
+}
+
+var snmpAuthProtocols = map[string]snmpAuthProtocol{
+	SNMPAuthMD5:    {md5.New, 12},
+	SNMPAuthSHA:    {sha1.New, 12},
+	SNMPAuthSHA224: {sha256.New224, 16},
+	SNMPAuthSHA256: {sha256.New, 24},
+	SNMPAuthSHA384: {sha512.New384, 32},
+	SNMPAuthSHA512: {sha512.New, 48},
+}
+
+// snmpNotInTimeWindow is reported when a request carries a stale clock of
+// the agent's engine.
+const snmpNotInTimeWindow = "1.3.6.1.6.3.15.1.1.2.0"
+
+// snmpReports describe the counters agents report when they reject an
+// SNMPv3 request (RFC 3414).
+var snmpReports = map[string]string{
+	"1.3.6.1.6.3.15.1.1.1.0": "unsupported security level",
+	snmpNotInTimeWindow:      "not in time window",
+	"1.3.6.1.6.3.15.1.1.3.0": "unknown user name",
+	"1.3.6.1.6.3.15.1.1.4.0": "unknown engine ID",
+	"1.3.6.1.6.3.15.1.1.5.0": "wrong digest, check the authentication protocol and password",
+	"1.3.6.1.6.3.15.1.1.6.0": "decryption error, check the privacy protocol and password",
+}
+
+// ParseSNMPAuthProtocol validates an SNMPv3 authentication protocol: md5,
+// sha (SHA-1), sha224, sha256, sha384 or sha512, and empty for sha.
+func ParseSNMPAuthProtocol(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return SNMPAuthSHA, nil
+	}
+	if _, ok := snmpAuthProtocols[s]; !ok {
+		return "", fmt.Errorf("invalid SNMP authentication protocol %q (expected md5, sha, sha224, sha256, sha384 or sha512)", s)
+	}
+	return s, nil
+}
+
+// ParseSNMPPrivProtocol validates an SNMPv3 privacy protocol: des or aes,
+// and empty for aes.
+func ParseSNMPPrivProtocol(s string) (string, error) {
+	switch s = strings.ToLower(strings.TrimSpace(s)); s {
+	case "", SNMPPrivAES:
+		return SNMPPrivAES, nil
+	case SNMPPrivDES:
+		return SNMPPrivDES, nil
+	}
+	return "", fmt.Errorf("invalid SNMP privacy protocol %q (expected des or aes)", s)
+}
+
+// snmpEngine identifies the authoritative engine of an agent, with its
+// clock.
+type snmpEngine struct {
+	id    []byte
+	boots int64
+	time  int64
+}
+
+// snmpUSM secures SNMPv3 messages for a user of the user-based security
+// model, with the user's keys localized to the agent's engine. The zero
+// value sends the unauthenticated requests of engine discovery.
+type snmpUSM struct {
+	user   string
+	engine snmpEngine
+	auth   snmpAuthProtocol
+	priv   string
+	// authKey and privKey are nil without authentication and privacy.
+	authKey []byte
+	privKey []byte
+}
+
+// newSNMPUSM localizes the keys of the user of cfg to engine.
+func newSNMPUSM(cfg SNMP, engine snmpEngine) *snmpUSM {
+	u := &snmpUSM{user: cfg.User, engine: engine, priv: cfg.PrivProtocol}
+	if cfg.AuthPassword == "" {
+		return u
+	}
+	protocol, ok := snmpAuthProtocols[cfg.AuthProtocol]
+	if !ok {
+		protocol = snmpAuthProtocols[SNMPAuthSHA]
+	}
+	u.auth = protocol
+	u.authKey = snmpKey(protocol.hash, cfg.AuthPassword, engine.id)
+	if cfg.PrivPassword != "" {
+		u.privKey = snmpKey(protocol.hash, cfg.PrivPassword, engine.id)
+	}
+	return u
+}
+
+// snmpKey derives the key of a password localized to an engine (RFC 3414,
+// A.2): the hash of the password repeated over a megabyte, hashed again
+// between the engine's ID.
+func snmpKey(h func() hash.Hash, password string, engineID []byte) []byte {
+	d := h()
+	chunk := make([]byte, 64)
+	for n := 0; n < snmpKeyExpansion; n += len(chunk) {
+		for i := range chunk {
+			chunk[i] = password[(n+i)%len(password)]
+		}
+		d.Write(chunk)
+	}
+	ku := d.Sum(nil)
+	d.Reset()
+	d.Write(ku)
+	d.Write(engineID)
+	d.Write(ku)
+	return d.Sum(nil)
+}
+
+// snmpGetV3 discovers the engine of the agent, then sends an SNMPv3 GET
+// for every OID of cfg and returns the variables of the response.
+func snmpGetV3(conn net.Conn, cfg SNMP) ([]snmpVar, time.Duration, error) {
+	b := make([]byte, snmpMaxMessage)
+	// The agent answers a request without user nor engine ID with a report
+	// carrying both its engine ID and clock
+	discovery, _, err := snmpExchange(conn, b, &snmpUSM{}, rand.Int32(), nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("SNMPv3 engine discovery: %w", err)
+	}
+	if len(discovery.engine.id) == 0 {
+		return nil, 0, errors.New("SNMPv3 engine discovery: the agent did not report its engine ID")
+	}
+	u := newSNMPUSM(cfg, discovery.engine)
+	for retried := false; ; retried = true {
+		id := rand.Int32()
+		start := time.Now()
+		m, pdu, err := snmpExchange(conn, b, u, id, cfg.OIDs)
+		latency := time.Since(start)
+		if err != nil {
+			return nil, latency, err
+		}
+		if pdu.tag == snmpGetResponse {
+			vars, err := snmpResponse(pdu, id, cfg.OIDs)
+			return vars, latency, err
+		}
+		report := snmpReportOID(pdu)
+		if report == snmpNotInTimeWindow && !retried {
+			// The agent rebooted since discovery; the report carries its
+			// new clock
+			u.engine.boots, u.engine.time = m.engine.boots, m.engine.time
+			continue
+		}
+		reason, ok := snmpReports[report]
+		if !ok {
+			reason = "report " + report
+		}
+		return nil, latency, fmt.Errorf("SNMP agent rejected the request: %s", reason)
+	}
+}
+
+// snmpV3Message is a decoded SNMPv3 message.
+type snmpV3Message struct {
+	msgID      int64
+	flags      byte
+	engine     snmpEngine
+	authParams berValue
+	privParams berValue
+	// data is the scoped PDU, or its encryption.
+	data berValue
+}
+
+// snmpExchange sends a GET request for oids secured by u, and waits for the
+// answer to it. It returns the message of the answer and its PDU: a
+// response, or a report of an error.
+func snmpExchange(conn net.Conn, b []byte, u *snmpUSM, id int32, oids []SNMPOID) (snmpV3Message, berValue, error) {
+	msgID := rand.Int32()
+	req, err := u.encode(msgID, snmpGetPDU(id, oids))
+	if err != nil {
+		return snmpV3Message{}, berValue{}, err
+	}
+	if _, err := conn.Write(req); err != nil {
+		return snmpV3Message{}, berValue{}, err
+	}
+	for {
+		n, err := conn.Read(b)
+		if err != nil {
+			if isTimeout(err) {
+				err = fmt.Errorf("no SNMP response: %w", err)
+			}
+			return snmpV3Message{}, berValue{}, err
+		}
+		m, err := parseSNMPv3(b[:n])
+		if err != nil || m.msgID != int64(msgID) {
+			continue
+		}
+		authenticated := m.flags&snmpFlagAuth != 0
+		if authenticated && (u.authKey == nil || !u.verify(b[:n], m)) {
+			continue
+		}
+		scoped := m.data
+		if m.flags&snmpFlagPriv != 0 {
+			if !authenticated || m.data.tag != berOctetString {
+				continue
+			}
+			plain, err := u.decrypt(m)
+			if err != nil {
+				return m, berValue{}, err
+			}
+			if scoped, _, err = berRead(plain, 0); err != nil {
+				return m, berValue{}, err
+			}
+		}
+		fields, err := scoped.children()
+		if err != nil || scoped.tag != berSequenceTag || len(fields) != 3 {
+			return m, berValue{}, errors.New("malformed SNMPv3 scoped PDU")
+		}
+		pdu := fields[2]
+		// Only reports of errors may go unauthenticated, as an agent
+		// cannot authenticate to a user it does not know
+		if pdu.tag != snmpReport && u.authKey != nil && !authenticated {
+			continue
+		}
+		return m, pdu, nil
+	}
+}
+
+// encode secures pdu into an SNMPv3 message: signed when the user has an
+// authentication key, and its scoped PDU encrypted when it has a privacy
+// key.
+func (u *snmpUSM) encode(msgID int32, pdu []byte) ([]byte, error) {
+	flags := byte(snmpFlagReportable)
+	data := berSequence(berTLV(berOctetString, u.engine.id), berTLV(berOctetString, nil), pdu)
+	var authParams, privParams []byte
+	if u.authKey != nil {
+		flags |= snmpFlagAuth
+		authParams = make([]byte, u.auth.macLen)
+	}
+	if u.privKey != nil {
+		flags |= snmpFlagPriv
+		encrypted, salt, err := u.encrypt(data)
+		if err != nil {
+			return nil, err
+		}
+		data, privParams = berTLV(berOctetString, encrypted), salt
+	}
+	head := slices.Concat(
+		berTLV(berOctetString, u.engine.id),
+		berInt(berInteger, u.engine.boots),
+		berInt(berInteger, u.engine.time),
+		berTLV(berOctetString, []byte(u.user)),
+	)
+	secBody := slices.Concat(head, berTLV(berOctetString, authParams), berTLV(berOctetString, privParams))
+	sec := berTLV(berSequenceTag, secBody)
+	secOctets := berTLV(berOctetString, sec)
+	prefix := slices.Concat(
+		berInt(berInteger, 3), // SNMPv3
+		berSequence(
+			berInt(berInteger, int64(msgID)),
+			berInt(berInteger, snmpMaxMessage),
+			berTLV(berOctetString, []byte{flags}),
+			berInt(berInteger, snmpSecurityUSM),
+		),
+	)
+	body := slices.Concat(prefix, secOctets, data)
+	msg := berTLV(berSequenceTag, body)
+	if u.authKey != nil {
+		// The MAC covers the message with zeros in its place, which starts
+		// after the headers of the message, of the security parameters and
+		// of their sequence, the fields before it and its own 2-byte header
+		off := (len(msg) - len(body)) + len(prefix) + (len(secOctets) - len(sec)) + (len(sec) - len(secBody)) + len(head) + 2
+		copy(msg[off:], u.mac(msg))
+	}
+	return msg, nil
+}
+
+// mac returns the truncated HMAC of a message.
+func (u *snmpUSM) mac(msg []byte) []byte {
+	h := hmac.New(u.auth.hash, u.authKey)
+	h.Write(msg)
+	return h.Sum(nil)[:u.auth.macLen]
+}
+
+// verify reports whether the message in b carries the MAC of the user.
+func (u *snmpUSM) verify(b []byte, m snmpV3Message) bool {
+	got := m.authParams.b
+	if len(got) != u.auth.macLen {
+		return false
+	}
+	msg := slices.Clone(b)
+	clear(msg[m.authParams.off : m.authParams.off+len(got)])
+	return hmac.Equal(u.mac(msg), got)
+}
+
+// encrypt encrypts a scoped PDU and returns it with the salt of its
+// initialization vector, which the message carries as its privacy
+// parameters: DES-CBC with the engine's boots and a random number (RFC
+// 3414, 8.1.1.1), or AES-128-CFB with a random number (RFC 3826, 3.1.2.1).
+func (u *snmpUSM) encrypt(plain []byte) ([]byte, []byte, error) {
+	salt := make([]byte, 8)
+	if u.priv == SNMPPrivDES {
+		binary.BigEndian.PutUint32(salt, uint32(u.engine.boots))
+		binary.BigEndian.PutUint32(salt[4:], rand.Uint32())
+		block, err := des.NewCipher(u.privKey[:8])
+		if err != nil {
+			return nil, nil, err
+		}
+		// DES encrypts whole blocks; decoders ignore the padding after the
+		// scoped PDU
+		padded := slices.Concat(plain, make([]byte, (des.BlockSize-len(plain)%des.BlockSize)%des.BlockSize))
+		out := make([]byte, len(padded))
+		cipher.NewCBCEncrypter(block, u.desIV(salt)).CryptBlocks(out, padded)
+		return out, salt, nil
+	}
+	binary.BigEndian.PutUint64(salt, rand.Uint64())
+	block, err := aes.NewCipher(u.privKey[:16])
+	if err != nil {
+		return nil, nil, err
+	}
+	out := make([]byte, len(plain))
+	// CFB is what RFC 3826 specifies; the MAC of the message authenticates
+	// it
+	cipher.NewCFBEncrypter(block, aesIV(u.engine, salt)).XORKeyStream(out, plain)
+	return out, salt, nil
+}
+
+// decrypt decrypts the scoped PDU of a message, with the clock of the
+// agent's engine it carries.
+func (u *snmpUSM) decrypt(m snmpV3Message) ([]byte, error) {
+	salt, data := m.privParams.b, m.data.b
+	if u.privKey == nil || len(salt) != 8 {
+		return nil, errors.New("SNMP agent encrypted a response the request did not ask to")
+	}
+	out := make([]byte, len(data))
+	if u.priv == SNMPPrivDES {
+		if len(data)%des.BlockSize != 0 {
+			return nil, errors.New("malformed SNMPv3 encrypted PDU")
+		}
+		block, err := des.NewCipher(u.privKey[:8])
+		if err != nil {
+			return nil, err
+		}
+		cipher.NewCBCDecrypter(block, u.desIV(salt)).CryptBlocks(out, data)
+		return out, nil
+	}
+	block, err := aes.NewCipher(u.privKey[:16])
+	if err != nil {
+		return nil, err
+	}
+	cipher.NewCFBDecrypter(block, aesIV(m.engine, salt)).XORKeyStream(out, data)
+	return out, nil
+}
+
+// desIV is the initialization vector of DES: the second half of the
+// privacy key XORed with the salt.
+func (u *snmpUSM) desIV(salt []byte) []byte {
+	iv := make([]byte, des.BlockSize)
+	for i := range iv {
+		iv[i] = u.privKey[8+i] ^ salt[i]
+	}
+	return iv
+}
+
+// aesIV is the initialization vector of AES: the engine's boots and time
+// followed by the salt.
+func aesIV(e snmpEngine, salt []byte) []byte {
+	iv := make([]byte, 0, aes.BlockSize)
+	iv = binary.BigEndian.AppendUint32(iv, uint32(e.boots))
+	iv = binary.BigEndian.AppendUint32(iv, uint32(e.time))
+	return append(iv, salt...)
+}
+
+// parseSNMPv3 decodes an SNMPv3 message using the user-based security
+// model.
+func parseSNMPv3(b []byte) (snmpV3Message, error) {
+	var m snmpV3Message
+	fields, err := berFields(b, berSequenceTag, 4)
+	if err != nil {
+		return m, err
+	}
+	if version, err := fields[0].int(); err != nil || version != 3 {
+		return m, errBER
+	}
+	global, err := fields[1].children()
+	if err != nil || len(global) != 4 || len(global[2].b) != 1 {
+		return m, errBER
+	}
+	if m.msgID, err = global[0].int(); err != nil {
+		return m, err
+	}
+	m.flags = global[2].b[0]
+	sec, _, err := berRead(fields[2].b, fields[2].off)
+	if err != nil {
+		return m, err
+	}
+	params, err := sec.children()
+	if err != nil || len(params) != 6 {
+		return m, errBER
+	}
+	m.engine.id = slices.Clone(params[0].b)
+	if m.engine.boots, err = params[1].int(); err != nil {
+		return m, err
+	}
+	if m.engine.time, err = params[2].int(); err != nil {
+		return m, err
+	}
+	m.authParams, m.privParams, m.data = params[4], params[5], fields[3]
+	return m, nil
+}
+
+// snmpReportOID returns the OID of the counter a report PDU carries.
+func snmpReportOID(pdu berValue) string {
+	fields, err := pdu.children()
+	if err != nil || len(fields) != 4 {
+		return ""
+	}
+	binds, err := fields[3].children()
+	if err != nil || len(binds) == 0 {
+		return ""
+	}
+	v, err := parseSNMPVar(binds[0])
+	if err != nil {
+		return ""
+	}
+	return formatOID(v.oid)
+}
@@ -0,0 +1,134 @@
+package monitor
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ParseSource validates the local address a target's checks leave from: an
+// IP address such as "192.0.2.10", or the name of a network interface such
+// as "wg0", whose address is looked up on every connection so an interface
+// that comes up later, such as a VPN, is picked up.
+func ParseSource(s string) (string, error) {
+	if ip := net.ParseIP(s); ip != nil {
+		if ip.IsUnspecified() {
+			return "", fmt.Errorf("source address %s is unspecified", s)
+		}
+		return ip.String(), nil
+	}
+	if s == "" || len(s) > 15 || strings.ContainsAny(s, " /:") {
+		return "", fmt.Errorf("invalid source %q (expected an IP address or an interface name, e.g. eth1)", s)
+	}
+	return s, nil
+}
+
+// SourceFamily returns the IP family of a source IP address, or FamilyAny
+// for an interface, which may have addresses of both.
+func SourceFamily(source string) string {
+	ip := net.ParseIP(source)
+	switch {
+	case ip == nil:
+		return FamilyAny
+	case ip.To4() != nil:
+		return FamilyIPv4
+	}
+	return FamilyIPv6
+}
+
+// sourceIP returns the address connections of the target over an IP family
+// leave from: its Source, or the first address of the interface it names,
+// preferring IPv4 when the family is FamilyAny. Link-local IPv6 addresses
+// are skipped, as they only reach the link. It is nil without a Source.
+func (t Target) sourceIP(family string) (net.IP, error) {
+	if t.Source == "" {
+		return nil, nil
+	}
+	if ip := net.ParseIP(t.Source); ip != nil {
+		if family != FamilyAny && SourceFamily(t.Source) != family {
+			return nil, fmt.Errorf("source address %s is not an %s address", t.Source, FamilyLabel(family))
+		}
+		return ip, nil
+	}
+	iface, err := net.InterfaceByName(t.Source)
+	if err != nil {
+		return nil, fmt.Errorf("source interface %s: %w", t.Source, err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("source interface %s: %w", t.Source, err)
+	}
+	var v4, v6 net.IP
+	for _, a := range addrs {
+		n, ok := a.(*net.IPNet)
+		if !ok || n.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if n.IP.To4() != nil && v4 == nil {
+			v4 = n.IP
+		} else if n.IP.To4() == nil && v6 == nil {
+			v6 = n.IP
+		}
+	}
+	switch {
+	case family != FamilyIPv6 && v4 != nil:
+		return v4, nil
+	case family != FamilyIPv4 && v6 != nil:
+		return v6, nil
+	case family == FamilyAny:
+		return nil, fmt.Errorf("source interface %s has no IP address", t.Source)
+	}
+	return nil, fmt.Errorf("source interface %s has no %s address", t.Source, FamilyLabel(family))
+}
+
+// bind returns d adjusted to leave from the target's Source for connections
+// over network, such as "tcp" or "udp6", or d itself without a Source.
+func (t Target) bind(d *net.Dialer, network string) (*net.Dialer, error) {
+	if t.Source == "" {
+		return d, nil
+	}
+	family := t.Family
+	switch {
+	case strings.HasSuffix(network, "4"):
+		family = FamilyIPv4
+	case strings.HasSuffix(network, "6"):
+		family = FamilyIPv6
+	}
+	ip, err := t.sourceIP(family)
+	if err != nil {
+		return nil, err
+	}
+	bound := *d
+	// The dialer only tries the addresses of the host that are of the
+	// family of its local address
+	if strings.HasPrefix(network, "udp") {
+		bound.LocalAddr = &net.UDPAddr{IP: ip}
+	} else {
+		bound.LocalAddr = &net.TCPAddr{IP: ip}
+	}
+	return &bound, nil
+}
+
+// pingFamily returns the family the ICMP checker and traceroutes use: IPv6
+// when the target's family or source address is IPv6, IPv4 otherwise.
+func (t Target) pingFamily() icmpFamily {
+	if t.Family == FamilyIPv6 || SourceFamily(t.Source) == FamilyIPv6 {
+		return icmpIPv6
+	}
+	return icmpIPv4
+}
+
+// pingSource returns the address the ICMP sockets of the target for family
+// f are bound to, and listen on: its source address, or nil and any
+// address.
+func (t Target) pingSource(f icmpFamily) (net.IP, string, error) {
+	family := FamilyIPv4
+	if f == icmpIPv6 {
+		family = FamilyIPv6
+	}
+	ip, err := t.sourceIP(family)
+	if err != nil || ip == nil {
+		return nil, f.listen, err
+	}
+	return ip, ip.String(), nil
+}
@@ -0,0 +1,143 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultSSHPort is the port used by the SSH checker.
+const DefaultSSHPort = "22"
+
+// sshMaxPreamble bounds the lines a server may send before its version
+// banner.
+const sshMaxPreamble = 20
+
+// errHostKeyRead stops the SSH handshake once the host key is known; the
+// checker never authenticates.
+var errHostKeyRead = errors.New("host key read")
+
+// HostKeyError is returned by the SSH checker when the server presents a
+// host key other than the expected one.
+type HostKeyError struct {
+	Fingerprint string
+	Expected    string
+}
+
+func (e *HostKeyError) Error() string {
+	return fmt.Sprintf("SSH host key changed: got %s, expected %s", e.Fingerprint, e.Expected)
+}
+
+// ParseFingerprint validates a host key fingerprint in the SHA256 format
+// printed by ssh-keygen -l, e.g. "SHA256:uNiVztksCsDhcc0u9e8BujQXVUpKZIDTMczCvj3tD2s".
+func ParseFingerprint(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	hash, ok := strings.CutPrefix(s, "SHA256:")
+	if !ok || len(hash) != 43 || strings.Trim(hash, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/") != "" {
+		return "", fmt.Errorf("%q is not a SHA256 host key fingerprint, as printed by ssh-keygen -l", s)
+	}
+	return s, nil
+}
+
+// SSHChecker connects to an SSH server, reads its version banner and starts
+// the key exchange to learn its host key, without logging in. The host key
+// is reported as a SHA256 fingerprint, as printed by ssh-keygen -l, and
+// checked against the target's SSHFingerprint if it has one. The target's
+// SSHPort replaces the checker's Port.
+type SSHChecker struct {
+	Port    string
+	Timeout time.Duration
+}
+
+// NewSSHChecker returns an SSHChecker using the default port and timeout.
+func NewSSHChecker() SSHChecker {
+	return SSHChecker{Port: DefaultSSHPort, Timeout: DefaultTCPTimeout}
+}
+
+// Name implements Checker.
+func (c SSHChecker) Name() string { return CheckSSH }
+
+// Check implements Checker.
+func (c SSHChecker) Check(ctx context.Context, t Target) Result {
+	timeout := t.connectTimeout(c.Timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	port := c.Port
+	if t.SSHPort != "" {
+		port = t.SSHPort
+	}
+	addr := net.JoinHostPort(t.Host, port)
+	conn, err := t.dial(ctx, &net.Dialer{Timeout: timeout}, addr)
+	if err != nil {
+		return Result{Err: err}
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	// Read the banner here, then replay it to the SSH client
+	br := bufio.NewReader(conn)
+	var preamble strings.Builder
+	var banner string
+	for range sshMaxPreamble {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return Result{Latency: time.Since(start), Err: fmt.Errorf("reading SSH banner: %w", err)}
+		}
+		preamble.WriteString(line)
+		if strings.HasPrefix(line, "SSH-") {
+			banner = strings.TrimRight(line, "\r\n")
+			break
+		}
+	}
+	if banner == "" {
+		return Result{Latency: time.Since(start), Err: fmt.Errorf("no SSH banner in the first %d lines", sshMaxPreamble)}
+	}
+
+	var key ssh.PublicKey
+	cfg := &ssh.ClientConfig{
+		User: "vivteno",
+		HostKeyCallback: func(_ string, _ net.Addr, k ssh.PublicKey) error {
+			key = k
+			return errHostKeyRead
+		},
+		Timeout: timeout,
+	}
+	replay := replayConn{Conn: conn, r: io.MultiReader(strings.NewReader(preamble.String()), br)}
+	if _, _, _, err := ssh.NewClientConn(replay, addr, cfg); key == nil {
+		return Result{Latency: time.Since(start), Err: fmt.Errorf("SSH handshake: %w", err)}
+	}
+	elapsed := time.Since(start)
+	fingerprint := ssh.FingerprintSHA256(key)
+	data := map[string]any{
+		"banner":      banner,
+		"key_type":    key.Type(),
+		"fingerprint": fingerprint,
+	}
+	if t.SSHFingerprint != "" && fingerprint != t.SSHFingerprint {
+		return Result{Latency: elapsed, Data: data, Err: &HostKeyError{Fingerprint: fingerprint, Expected: t.SSHFingerprint}}
+	}
+	return Result{
+		Latency: elapsed,
+		Detail:  fmt.Sprintf("%s, %s key %s", banner, key.Type(), fingerprint),
+		Data:    data,
+	}
+}
+
+// replayConn reads from r instead of the connection, so bytes consumed
+// before the SSH client took over are read again.
+type replayConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c replayConn) Read(p []byte) (int, error) { return c.r.Read(p) }
@@ -0,0 +1,179 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Schemes accepted in target URLs
+const (
+	SchemeHTTP  = "http"
+	SchemeHTTPS = "https"
+)
+
+var hostnamePattern = regexp.MustCompile(`^([a-zA-Z0-9-]+\.)*[a-zA-Z0-9-]+$`)
+
+// ParseTarget parses a target as configured by the user: either a bare
+// hostname or IP address ("example.com"), or a URL whose scheme, port and
+// path are used by the checks ("https://example.com:8443/api").
+func ParseTarget(s string) (Target, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "://") {
+		if !IsValidHostname(s) {
+			return Target{}, fmt.Errorf("invalid hostname %q", s)
+		}
+		return Target{Name: s, Host: s}, nil
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return Target{}, err
+	}
+	if u.Scheme != SchemeHTTP && u.Scheme != SchemeHTTPS {
+		return Target{}, fmt.Errorf("unsupported scheme %q in %q", u.Scheme, s)
+	}
+	host := u.Hostname()
+	if !IsValidHostname(host) {
+		return Target{}, fmt.Errorf("invalid hostname %q in %q", host, s)
+	}
+	t := Target{Name: s, Host: host, Scheme: u.Scheme, Port: u.Port(), Path: u.RequestURI()}
+	if t.Port == "" {
+		t.Port = defaultPort(u.Scheme)
+	}
+	return t, nil
+}
+
+// IsValidHostname reports whether host is an IP address or a syntactically
+// valid hostname.
+func IsValidHostname(host string) bool {
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	return hostnamePattern.MatchString(host)
+}
+
+func defaultPort(scheme string) string {
+	if scheme == SchemeHTTP {
+		return "80"
+	}
+	return "443"
+}
+
+// portOr returns the target's port, or def if the target does not set one.
+func (t Target) portOr(def string) string {
+	if t.Port != "" {
+		return t.Port
+	}
+	return def
+}
+
+// baseURL returns scheme://host[:port] for HTTP requests to the target,
+// defaulting to HTTPS and omitting the port when it is the scheme default.
+func (t Target) baseURL() string {
+	scheme := t.Scheme
+	if scheme == "" {
+		scheme = SchemeHTTPS
+	}
+	host := t.Host
+	if t.Port != "" && t.Port != defaultPort(scheme) {
+		host = net.JoinHostPort(t.Host, t.Port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	return scheme + "://" + host
+}
+
+// URL returns the full URL requested by HTTP checks.
+func (t Target) URL() string {
+	path := t.Path
+	if path == "" {
+		path = "/"
+	}
+	return t.baseURL() + path
+}
+
+// ParseHealthBaseURL validates a base URL for health endpoints: an http or
+// https URL with a host and an optional port and path prefix, such as
+// "http://origin.internal:8080/app". A trailing slash is removed.
+func ParseHealthBaseURL(s string) (string, error) {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != SchemeHTTP && u.Scheme != SchemeHTTPS) || u.Host == "" {
+		return "", fmt.Errorf("invalid health base URL %q, expected an http or https URL", s)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("invalid health base URL %q: query and fragment are not allowed", s)
+	}
+	if !IsValidHostname(u.Hostname()) {
+		return "", fmt.Errorf("invalid hostname %q in %q", u.Hostname(), s)
+	}
+	return strings.TrimSuffix(s, "/"), nil
+}
+
+// healthURL returns the URL of the target's health endpoint.
+func (t Target) healthURL() string {
+	if t.HealthBaseURL != "" {
+		return t.HealthBaseURL + t.HealthEndpoint
+	}
+	return t.baseURL() + t.HealthEndpoint
+}
+
+// DefaultContentType is sent with request bodies unless the target sets
+// another.
+const DefaultContentType = "application/json"
+
+// newRequest creates an HTTP request to url with the target's method and
+// body.
+func (t Target) newRequest(ctx context.Context, url string) (*http.Request, error) {
+	method := t.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	var body io.Reader
+	if t.Body != "" {
+		body = strings.NewReader(t.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if t.Body != "" {
+		ct := t.ContentType
+		if ct == "" {
+			ct = DefaultContentType
+		}
+		req.Header.Set("Content-Type", ct)
+	}
+	return req, nil
+}
+
+// connectTimeout returns the target's connect timeout, or def if unset.
+func (t Target) connectTimeout(def time.Duration) time.Duration {
+	if t.ConnectTimeout > 0 {
+		return t.ConnectTimeout
+	}
+	return def
+}
+
+// httpClient returns c, or a copy of it using the target's TLS, proxy, IP
+// family, DNS and source address settings and bounded by its health timeout
+// when those are set.
+func (t Target) httpClient(c *http.Client) *http.Client {
+	custom := t.TLSConfig != nil || t.Proxy != nil || t.network("tcp") != "tcp" || t.DNSServer != "" || t.Source != ""
+	if t.HealthTimeout <= 0 && !custom {
+		return c
+	}
+	client := *c
+	if t.HealthTimeout > 0 {
+		client.Timeout = t.HealthTimeout
+	}
+	if custom {
+		client.Transport = t.transport(c.Transport)
+	}
+	return &client
+}
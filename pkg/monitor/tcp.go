@@ -0,0 +1,40 @@
+package monitor
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// Defaults for the TCP checker
+const (
+	DefaultTCPTimeout = 5 * time.Second
+	DefaultTCPPort    = "80"
+)
+
+// TCPChecker measures how long it takes to open a TCP connection to a target.
+type TCPChecker struct {
+	Port    string
+	Timeout time.Duration
+}
+
+// NewTCPChecker returns a TCPChecker using the default port and timeout.
+func NewTCPChecker() TCPChecker {
+	return TCPChecker{Port: DefaultTCPPort, Timeout: DefaultTCPTimeout}
+}
+
+// Name implements Checker.
+func (c TCPChecker) Name() string { return CheckTCP }
+
+// Check performs a single TCP connect to the target, on the target's own port
+// if it has one.
+func (c TCPChecker) Check(ctx context.Context, t Target) Result {
+	start := time.Now()
+	dialer := &net.Dialer{Timeout: t.connectTimeout(c.Timeout)}
+	conn, err := t.dial(ctx, dialer, net.JoinHostPort(t.Host, t.portOr(c.Port)))
+	if err != nil {
+		return Result{Err: err}
+	}
+	_ = conn.Close()
+	return Result{Latency: time.Since(start), Detail: "TCP connect successful"}
+}
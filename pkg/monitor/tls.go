@@ -0,0 +1,89 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+)
+
+// DefaultTLSPort is the port used by the TLS checker.
+const DefaultTLSPort = "443"
+
+// TLSChecker performs a TLS handshake and reports on the server certificate.
+type TLSChecker struct {
+	Port    string
+	Timeout time.Duration
+}
+
+// NewTLSChecker returns a TLSChecker using the default port and timeout.
+func NewTLSChecker() TLSChecker {
+	return TLSChecker{Port: DefaultTLSPort, Timeout: DefaultTLSHandshakeTimeout}
+}
+
+// Name implements Checker.
+func (c TLSChecker) Name() string { return CheckTLS }
+
+// port uses the target's port for HTTPS URL targets and the checker's port
+// otherwise.
+func (c TLSChecker) port(t Target) string {
+	if t.Scheme == SchemeHTTPS {
+		return t.portOr(c.Port)
+	}
+	return c.Port
+}
+
+// Check performs the handshake. Certificate verification failures, including
+// expiry, and a stapled OCSP response revoking the certificate are reported
+// as errors. The result carries the TLSInfo of the session either way, so the
+// chain of a failing server can be inspected.
+func (c TLSChecker) Check(ctx context.Context, t Target) Result {
+	start := time.Now()
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if t.TLSConfig != nil {
+		cfg = t.TLSConfig.Clone()
+	}
+	cfg.ServerName = t.Host
+	// The chain is verified after the handshake, which would otherwise end
+	// before the certificates can be described
+	insecure := cfg.InsecureSkipVerify
+	cfg.InsecureSkipVerify = true
+	dialer := &net.Dialer{Timeout: t.connectTimeout(c.Timeout)}
+	raw, err := t.dial(ctx, dialer, net.JoinHostPort(t.Host, c.port(t)))
+	if err != nil {
+		return Result{Err: err}
+	}
+	conn := tls.Client(raw, cfg)
+	defer conn.Close()
+	hsCtx, cancel := context.WithTimeout(ctx, t.connectTimeout(c.Timeout))
+	defer cancel()
+	if err := conn.HandshakeContext(hsCtx); err != nil {
+		return Result{Err: err}
+	}
+	elapsed := time.Since(start)
+	state := conn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return Result{Latency: elapsed, Err: fmt.Errorf("no peer certificate")}
+	}
+	info, err := tlsInfo(state, cfg.RootCAs, t.Host, time.Now())
+	if err != nil && !insecure {
+		return Result{Latency: elapsed, Err: fmt.Errorf("tls: failed to verify certificate: %w", err), TLS: info}
+	}
+	if info.OCSP == OCSPRevoked {
+		return Result{Latency: elapsed, Err: fmt.Errorf("certificate revoked at %s (stapled OCSP response)", info.OCSPRevokedAt.Format(time.DateOnly)), TLS: info}
+	}
+	cert := state.PeerCertificates[0]
+	daysLeft := int(time.Until(cert.NotAfter).Hours() / 24)
+	return Result{
+		Latency: elapsed,
+		TLS:     info,
+		Detail:  fmt.Sprintf("Certificate valid until %s (%d days)", cert.NotAfter.Format(time.DateOnly), daysLeft),
+		Data: map[string]any{
+			"issuer":    cert.Issuer.CommonName,
+			"subject":   cert.Subject.CommonName,
+			"not_after": cert.NotAfter.Format(time.RFC3339),
+			"days_left": daysLeft,
+		},
+	}
+}
@@ -0,0 +1,44 @@
+package monitor
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// LoadTLSConfig builds a client TLS configuration for HTTPS checks. certFile
+// and keyFile load a client certificate for mutual TLS, caFile replaces the
+// system roots with a custom CA bundle, and insecure disables server
+// certificate verification. It returns nil when no option is set.
+func LoadTLSConfig(certFile, keyFile, caFile string, insecure bool) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" && caFile == "" && !insecure {
+		return nil, nil
+	}
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: insecure,
+	}
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("client certificate and key must be set together")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", caFile)
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
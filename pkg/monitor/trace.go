@@ -0,0 +1,71 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"strings"
+	"time"
+)
+
+// Timing breaks the latency of an HTTP request down into its phases. Phases
+// that did not happen, such as DNS and connect on a reused connection, are
+// zero.
+type Timing struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	// TTFB is the time from sending the request to the first response byte.
+	TTFB   time.Duration
+	Reused bool
+}
+
+// String formats the non-zero phases, e.g. "DNS 3ms · Connect 10ms · TTFB 40ms".
+func (t Timing) String() string {
+	var parts []string
+	add := func(name string, d time.Duration) {
+		if d > 0 {
+			parts = append(parts, fmt.Sprintf("%s %dms", name, d.Milliseconds()))
+		}
+	}
+	add("DNS", t.DNS)
+	add("Connect", t.Connect)
+	add("TLS", t.TLS)
+	add("TTFB", t.TTFB)
+	if t.Reused {
+		parts = append(parts, "reused connection")
+	}
+	return strings.Join(parts, " · ")
+}
+
+// withTrace returns a context that records the phases of an HTTP request
+// into the returned Timing.
+func withTrace(ctx context.Context) (context.Context, *Timing) {
+	t := &Timing{}
+	var dnsStart, connStart, tlsStart, wroteRequest time.Time
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.DNS = time.Since(dnsStart) },
+		ConnectStart: func(string, string) {
+			if connStart.IsZero() {
+				connStart = time.Now()
+			}
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				t.Connect = time.Since(connStart)
+			}
+		},
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				t.TLS = time.Since(tlsStart)
+			}
+		},
+		GotConn:              func(info httptrace.GotConnInfo) { t.Reused = info.Reused },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { wroteRequest = time.Now() },
+		GotFirstResponseByte: func() { t.TTFB = time.Since(wroteRequest) },
+	}
+	return httptrace.WithClientTrace(ctx, trace), t
+}
@@ -0,0 +1,325 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// Traceroute probe protocols
+const (
+	TracerouteUDP = "udp"
+	TracerouteTCP = "tcp"
+)
+
+// Traceroute settings
+const (
+	TracerouteMaxHops = 30
+	// TracerouteHopTimeout is how long a probe waits for its reply.
+	TracerouteHopTimeout = time.Second
+	// TracerouteMaxSilent ends a traceroute after that many consecutive
+	// hops did not reply, as the path is unlikely to answer further on.
+	TracerouteMaxSilent = 5
+	// tracerouteUDPPort is the destination port of the first UDP probe; each
+	// hop uses the next one, as the classic traceroute does.
+	tracerouteUDPPort = 33434
+	// IANA protocol numbers of the transports the probes use
+	protocolTCP = 6
+	protocolUDP = 17
+)
+
+// ParseTraceroute validates a traceroute protocol: udp, tcp, or off (or
+// empty) for none.
+func ParseTraceroute(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "off", "false", "no":
+		return "", nil
+	case TracerouteUDP:
+		return TracerouteUDP, nil
+	case TracerouteTCP:
+		return TracerouteTCP, nil
+	}
+	return "", fmt.Errorf("invalid traceroute %q (expected udp, tcp or off)", s)
+}
+
+// TraceHop is one step of a traceroute. Addr is empty when the hop did not reply
+// in time.
+type TraceHop struct {
+	TTL  int
+	Addr string
+	RTT  time.Duration
+	// Unreachable is set when the hop reported the destination unreachable.
+	Unreachable bool
+}
+
+// Trace is the outcome of a traceroute.
+type Trace struct {
+	Protocol    string
+	Destination string
+	Hops        []TraceHop
+	// Reached is set when the destination answered the last probe.
+	Reached bool
+}
+
+// icmpQuote is an ICMP error about one of the probes: the router that sent
+// it and the addresses of the probe it quotes.
+type icmpQuote struct {
+	from    string
+	at      time.Time
+	final   bool
+	proto   int
+	dst     net.IP
+	srcPort int
+	dstPort int
+}
+
+// Traceroute sends probes with increasing TTLs towards the first address of
+// the target, as the ICMP checker picks it, and records the routers that
+// report them expired. UDP probes go to unused ports from 33434, which the
+// destination rejects; TCP probes are connection attempts to the target's
+// port, which the destination accepts or refuses. Both need a raw ICMP
+// socket to read the replies of routers, which requires elevated
+// privileges.
+func Traceroute(ctx context.Context, t Target, protocol string) (Trace, error) {
+	f := t.pingFamily()
+	ips, err := t.resolver().LookupIP(ctx, f.lookup, t.Host)
+	if err != nil {
+		return Trace{}, err
+	}
+	ip := ips[0]
+	trace := Trace{Protocol: protocol, Destination: ip.String()}
+	src, listen, err := t.pingSource(f)
+	if err != nil {
+		return trace, err
+	}
+
+	conn, err := icmp.ListenPacket(f.raw, listen)
+	if err != nil {
+		return trace, fmt.Errorf("traceroute needs a raw ICMP socket: %w", err)
+	}
+	defer conn.Close()
+	quotes := make(chan icmpQuote, 16)
+	go readICMPQuotes(conn, f, quotes)
+
+	port, _ := strconv.Atoi(t.portOr(defaultPort(t.Scheme)))
+	silent := 0
+	for ttl := 1; ttl <= TracerouteMaxHops && silent < TracerouteMaxSilent; ttl++ {
+		if err := ctx.Err(); err != nil {
+			return trace, err
+		}
+		var hop TraceHop
+		var reached bool
+		if protocol == TracerouteTCP {
+			hop, reached, err = probeTCP(ctx, f, src, ip, port, ttl, quotes)
+		} else {
+			hop, reached, err = probeUDP(ctx, f, src, ip, ttl, quotes)
+		}
+		if err != nil {
+			return trace, err
+		}
+		trace.Hops = append(trace.Hops, hop)
+		if reached {
+			trace.Reached = true
+			return trace, nil
+		}
+		if hop.Unreachable {
+			return trace, nil
+		}
+		if hop.Addr == "" {
+			silent++
+		} else {
+			silent = 0
+		}
+	}
+	return trace, nil
+}
+
+// probeUDP sends one UDP probe with the given TTL and waits for the ICMP
+// error it causes. The destination is reached when it is the one rejecting
+// the probe. Probes leave from src, unless it is nil.
+func probeUDP(ctx context.Context, f icmpFamily, src, ip net.IP, ttl int, quotes <-chan icmpQuote) (TraceHop, bool, error) {
+	conn, err := net.ListenUDP(f.udp, &net.UDPAddr{IP: src})
+	if err != nil {
+		return TraceHop{}, false, err
+	}
+	defer conn.Close()
+	if f == icmpIPv6 {
+		err = ipv6.NewPacketConn(conn).SetHopLimit(ttl)
+	} else {
+		err = ipv4.NewPacketConn(conn).SetTTL(ttl)
+	}
+	if err != nil {
+		return TraceHop{}, false, err
+	}
+	dst := &net.UDPAddr{IP: ip, Port: tracerouteUDPPort + ttl - 1}
+	srcPort := conn.LocalAddr().(*net.UDPAddr).Port
+	start := time.Now()
+	if _, err := conn.WriteTo([]byte("vivteno"), dst); err != nil {
+		return TraceHop{}, false, err
+	}
+	timer := time.NewTimer(TracerouteHopTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case q := <-quotes:
+			if q.proto != protocolUDP || !q.dst.Equal(ip) || q.dstPort != dst.Port || q.srcPort != srcPort {
+				continue
+			}
+			hop := TraceHop{TTL: ttl, Addr: q.from, RTT: q.at.Sub(start)}
+			if q.final {
+				if q.from == ip.String() {
+					return hop, true, nil
+				}
+				hop.Unreachable = true
+			}
+			return hop, false, nil
+		case <-timer.C:
+			return TraceHop{TTL: ttl}, false, nil
+		case <-ctx.Done():
+			return TraceHop{}, false, ctx.Err()
+		}
+	}
+}
+
+// probeTCP attempts a connection with the given TTL and waits for it to be
+// accepted or refused, which means the destination was reached, or for the
+// ICMP error it causes. Probes leave from src, unless it is nil.
+func probeTCP(ctx context.Context, f icmpFamily, src, ip net.IP, port, ttl int, quotes <-chan icmpQuote) (TraceHop, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, TracerouteHopTimeout)
+	defer cancel()
+	// The probe is bound before it connects, rather than through the
+	// dialer's LocalAddr, so its port is known by the time ICMP errors
+	// arrive and tells them from those of earlier probes still on their way
+	var srcPort atomic.Int32
+	d := net.Dialer{Control: func(_, _ string, c syscall.RawConn) error {
+		if err := setTTL(c, f == icmpIPv6, ttl); err != nil {
+			return err
+		}
+		port, err := bindPort(c, f == icmpIPv6, src)
+		srcPort.Store(int32(port))
+		return err
+	}}
+	type dialed struct {
+		at  time.Time
+		err error
+	}
+	done := make(chan dialed, 1)
+	start := time.Now()
+	go func() {
+		conn, err := d.DialContext(ctx, "tcp"+f.lookup[2:], net.JoinHostPort(ip.String(), strconv.Itoa(port)))
+		if err == nil {
+			conn.Close()
+		}
+		done <- dialed{time.Now(), err}
+	}()
+	for {
+		select {
+		case r := <-done:
+			if r.err == nil || errors.Is(r.err, syscall.ECONNREFUSED) {
+				return TraceHop{TTL: ttl, Addr: ip.String(), RTT: r.at.Sub(start)}, true, nil
+			}
+			if ctx.Err() == nil {
+				// The connection failed before the probe timed out, e.g.
+				// because the hop reported the destination unreachable
+				// and the ICMP error is still on its way
+				continue
+			}
+			return TraceHop{TTL: ttl}, false, nil
+		case q := <-quotes:
+			if q.proto != protocolTCP || !q.dst.Equal(ip) || q.dstPort != port || q.srcPort != int(srcPort.Load()) {
+				continue
+			}
+			hop := TraceHop{TTL: ttl, Addr: q.from, RTT: q.at.Sub(start), Unreachable: q.final}
+			return hop, false, nil
+		case <-ctx.Done():
+			if err := context.Cause(ctx); !errors.Is(err, context.DeadlineExceeded) {
+				return TraceHop{}, false, err
+			}
+			return TraceHop{TTL: ttl}, false, nil
+		}
+	}
+}
+
+// readICMPQuotes reads the ICMP errors conn receives and sends those quoting
+// a UDP or TCP packet to quotes, until conn is closed. Errors are dropped
+// while quotes is full.
+func readICMPQuotes(conn *icmp.PacketConn, f icmpFamily, quotes chan<- icmpQuote) {
+	b := make([]byte, 1500)
+	for {
+		n, from, err := conn.ReadFrom(b)
+		if err != nil {
+			return
+		}
+		at := time.Now()
+		msg, err := icmp.ParseMessage(f.protocol, b[:n])
+		if err != nil {
+			continue
+		}
+		var data []byte
+		final := false
+		switch body := msg.Body.(type) {
+		case *icmp.TimeExceeded:
+			data = body.Data
+		case *icmp.DstUnreach:
+			data, final = body.Data, true
+		default:
+			continue
+		}
+		q, ok := parseQuote(data, f == icmpIPv6)
+		if !ok {
+			continue
+		}
+		q.from, q.at, q.final = addrIP(from), at, final
+		select {
+		case quotes <- q:
+		default:
+		}
+	}
+}
+
+// parseQuote reads the IP header and the start of the transport header of
+// the packet an ICMP error quotes.
+func parseQuote(data []byte, v6 bool) (icmpQuote, bool) {
+	var q icmpQuote
+	var header int
+	if v6 {
+		// Extension headers are not followed; probes do not use them
+		if len(data) < ipv6.HeaderLen {
+			return q, false
+		}
+		header, q.proto, q.dst = ipv6.HeaderLen, int(data[6]), net.IP(data[24:40])
+	} else {
+		if len(data) < ipv4.HeaderLen {
+			return q, false
+		}
+		header, q.proto, q.dst = int(data[0]&0x0f)*4, int(data[9]), net.IP(data[16:20])
+	}
+	if len(data) < header+4 || (q.proto != protocolUDP && q.proto != protocolTCP) {
+		return q, false
+	}
+	ports := data[header:]
+	q.srcPort = int(ports[0])<<8 | int(ports[1])
+	q.dstPort = int(ports[2])<<8 | int(ports[3])
+	return q, true
+}
+
+// addrIP returns the IP address of a raw socket peer.
+func addrIP(a net.Addr) string {
+	switch a := a.(type) {
+	case *net.IPAddr:
+		return a.IP.String()
+	case *net.UDPAddr:
+		return a.IP.String()
+	}
+	return a.String()
+}
@@ -0,0 +1,87 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Dialer settings of transports with their own DNS server, as in
+// http.DefaultTransport
+const (
+	transportDialTimeout = 30 * time.Second
+	transportKeepAlive   = 30 * time.Second
+)
+
+// transportKey identifies a transport customised for a target.
+type transportKey struct {
+	base   http.RoundTripper
+	tls    *tls.Config
+	proxy  string
+	family string
+	dns    string
+	source string
+}
+
+// transports caches one transport per base and target settings so targets
+// with their own TLS or proxy configuration still reuse connections across
+// checks.
+var transports sync.Map
+
+// transport returns base adjusted for the target's TLS, proxy, IP family, DNS
+// and source address settings.
+// Bases that are not *http.Transport are returned unchanged.
+func (t Target) transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	key := transportKey{base: base, tls: t.TLSConfig, family: t.network("tcp"), dns: t.DNSServer, source: t.Source}
+	if t.Proxy != nil {
+		key.proxy = t.Proxy.String()
+	}
+	if cached, ok := transports.Load(key); ok {
+		return cached.(http.RoundTripper)
+	}
+	tr, ok := base.(*http.Transport)
+	if !ok {
+		return base
+	}
+	clone := tr.Clone()
+	if t.TLSConfig != nil {
+		clone.TLSClientConfig = t.TLSConfig
+	}
+	// Connections to a proxy keep to its own family and resolver, as with
+	// dial, but leave from the source address as well
+	network, custom := "tcp", t.Source != ""
+	if t.Proxy != nil {
+		clone.Proxy = http.ProxyURL(t.Proxy)
+	} else {
+		network, custom = t.network("tcp"), custom || t.DNSServer != ""
+	}
+	if network != "tcp" || custom {
+		dial := clone.DialContext
+		if custom {
+			d := &net.Dialer{Timeout: transportDialTimeout, KeepAlive: transportKeepAlive}
+			if t.Proxy == nil {
+				d.Resolver = t.resolver()
+			}
+			dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				bound, err := t.bind(d, network)
+				if err != nil {
+					return nil, err
+				}
+				return bound.DialContext(ctx, network, addr)
+			}
+		} else if dial == nil {
+			dial = (&net.Dialer{}).DialContext
+		}
+		clone.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return dial(ctx, network, addr)
+		}
+	}
+	actual, _ := transports.LoadOrStore(key, clone)
+	return actual.(http.RoundTripper)
+}
@@ -0,0 +1,19 @@
+//go:build !unix
+
+package monitor
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// setTTL is not supported on this platform, so TCP traceroutes fail.
+func setTTL(syscall.RawConn, bool, int) error {
+	return errors.New("TCP traceroute is not supported on this platform")
+}
+
+// bindPort is not supported on this platform either.
+func bindPort(syscall.RawConn, bool, net.IP) (int, error) {
+	return 0, errors.New("TCP traceroute is not supported on this platform")
+}
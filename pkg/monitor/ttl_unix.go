@@ -0,0 +1,59 @@
+//go:build unix
+
+package monitor
+
+import (
+	"net"
+	"syscall"
+)
+
+// setTTL sets the TTL, or hop limit for IPv6, of the packets a socket sends.
+func setTTL(c syscall.RawConn, v6 bool, ttl int) error {
+	var err error
+	cerr := c.Control(func(fd uintptr) {
+		if v6 {
+			err = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_UNICAST_HOPS, ttl)
+		} else {
+			err = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TTL, ttl)
+		}
+	})
+	if cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+// bindPort binds a socket to src, or to any address when nil, on a port the
+// system picks, and returns that port.
+func bindPort(c syscall.RawConn, v6 bool, src net.IP) (int, error) {
+	var port int
+	var err error
+	cerr := c.Control(func(fd uintptr) {
+		var sa syscall.Sockaddr
+		if v6 {
+			a := &syscall.SockaddrInet6{}
+			copy(a.Addr[:], src.To16())
+			sa = a
+		} else {
+			a := &syscall.SockaddrInet4{}
+			copy(a.Addr[:], src.To4())
+			sa = a
+		}
+		if err = syscall.Bind(int(fd), sa); err != nil {
+			return
+		}
+		if sa, err = syscall.Getsockname(int(fd)); err != nil {
+			return
+		}
+		switch a := sa.(type) {
+		case *syscall.SockaddrInet4:
+			port = a.Port
+		case *syscall.SockaddrInet6:
+			port = a.Port
+		}
+	})
+	if cerr != nil {
+		return 0, cerr
+	}
+	return port, err
+}
@@ -0,0 +1,209 @@
+package monitor
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// webSocketGUID is appended to the handshake key to compute the accept key
+// (RFC 6455).
+const webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket frame opcodes
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xA
+)
+
+// wsMaxFrame bounds the payload of a frame the checker reads; larger frames
+// are skipped.
+const wsMaxFrame = 1 << 20
+
+// WebSocketChecker opens a WebSocket connection to the target's URL, or to
+// its WebSocketPath: wss:// for bare hostnames and https:// sites, ws:// for
+// http:// sites. With WebSocketPing it then sends a ping frame and waits for
+// the pong or any message. Latency is that of the handshake.
+type WebSocketChecker struct {
+	Client *http.Client
+}
+
+// NewWebSocketChecker returns a WebSocketChecker using DefaultHTTPClient.
+func NewWebSocketChecker() WebSocketChecker {
+	return WebSocketChecker{Client: DefaultHTTPClient}
+}
+
+// Name implements Checker.
+func (c WebSocketChecker) Name() string { return CheckWebSocket }
+
+// Check performs the handshake and closes the connection cleanly.
+func (c WebSocketChecker) Check(ctx context.Context, t Target) Result {
+	start := time.Now()
+	ctx, timing := withTrace(ctx)
+	url := t.URL()
+	if t.WebSocketPath != "" {
+		url = t.baseURL() + t.WebSocketPath
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Result{Err: err}
+	}
+	nonce := make([]byte, 16)
+	_, _ = rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	client := *t.httpClient(c.Client)
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Do(req)
+	if err != nil {
+		return Result{Err: err, Timing: timing}
+	}
+	defer resp.Body.Close()
+	elapsed := time.Since(start)
+	data := map[string]any{"status_code": resp.StatusCode}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		err := fmt.Errorf("handshake failed: HTTP %s", resp.Status)
+		if location := resp.Header.Get("Location"); location != "" {
+			err = fmt.Errorf("handshake failed: HTTP %s, redirected to %s", resp.Status, location)
+		}
+		return Result{Latency: elapsed, Data: data, Timing: timing, Err: err}
+	}
+	if !strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") {
+		return Result{Latency: elapsed, Data: data, Timing: timing, Err: fmt.Errorf("handshake failed: server upgraded to %q", resp.Header.Get("Upgrade"))}
+	}
+	sum := sha1.Sum([]byte(key + webSocketGUID))
+	if resp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(sum[:]) {
+		return Result{Latency: elapsed, Data: data, Timing: timing, Err: errors.New("handshake failed: invalid Sec-WebSocket-Accept")}
+	}
+	conn, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		return Result{Latency: elapsed, Data: data, Timing: timing, Err: errors.New("handshake failed: connection cannot be upgraded")}
+	}
+	// Reads and writes on the upgraded connection stop with the check
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	detail := "WebSocket handshake completed (HTTP " + resp.Status + ")"
+	if t.WebSocketPing {
+		pingStart := time.Now()
+		reply, err := wsPingPong(conn)
+		if err != nil {
+			return Result{Latency: elapsed, Data: data, Timing: timing, Err: err}
+		}
+		pong := time.Since(pingStart)
+		data["pong_ms"] = pong.Milliseconds()
+		detail += fmt.Sprintf("\n  %s received %d ms after ping", reply, pong.Milliseconds())
+	}
+	// Close politely; the server's answer is not awaited
+	_ = wsWriteFrame(conn, wsClose, binary.BigEndian.AppendUint16(nil, 1000))
+	return Result{Latency: elapsed, Detail: detail, Data: data, Timing: timing}
+}
+
+// wsPingPong sends a ping frame and reads frames until the pong or a message
+// arrives, returning which one did.
+func wsPingPong(conn io.ReadWriter) (string, error) {
+	if err := wsWriteFrame(conn, wsPing, []byte("vivteno")); err != nil {
+		return "", fmt.Errorf("sending ping: %w", err)
+	}
+	for {
+		opcode, payload, err := wsReadFrame(conn)
+		if err != nil {
+			return "", fmt.Errorf("waiting for pong: %w", err)
+		}
+		switch opcode {
+		case wsPong:
+			return "Pong", nil
+		case wsText, wsBinary, wsContinuation:
+			return "Message", nil
+		case wsClose:
+			if len(payload) >= 2 {
+				return "", fmt.Errorf("server closed the connection: code %d %s", binary.BigEndian.Uint16(payload), payload[2:])
+			}
+			return "", errors.New("server closed the connection")
+		}
+	}
+}
+
+// wsWriteFrame writes a single masked frame, as clients must.
+func wsWriteFrame(w io.Writer, opcode byte, payload []byte) error {
+	frame := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, 0x80|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	mask := make([]byte, 4)
+	_, _ = rand.Read(mask)
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, err := w.Write(frame)
+	return err
+}
+
+// wsReadFrame reads one frame and returns its opcode and payload. The
+// payload of frames larger than wsMaxFrame is discarded.
+func wsReadFrame(r io.Reader) (byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode := head[0] & 0x0f
+	n := uint64(head[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	var mask []byte
+	if head[1]&0x80 != 0 {
+		mask = make([]byte, 4)
+		if _, err := io.ReadFull(r, mask); err != nil {
+			return 0, nil, err
+		}
+	}
+	if n > wsMaxFrame {
+		_, err := io.CopyN(io.Discard, r, int64(n))
+		return opcode, nil, err
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	if mask != nil {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return opcode, payload, nil
+}
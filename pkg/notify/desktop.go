@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// windowsToastScript shows a toast notification on Windows. The title and
+// body are passed through the environment to avoid quoting issues.
+const windowsToastScript = `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$t = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$x = $t.GetElementsByTagName('text')
+$x.Item(0).AppendChild($t.CreateTextNode($env:VIVTENO_TITLE)) > $null
+$x.Item(1).AppendChild($t.CreateTextNode($env:VIVTENO_BODY)) > $null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('Vivteno').Show([Windows.UI.Notifications.ToastNotification]::new($t))`
+
+// Desktop shows notifications using the platform's native mechanism:
+// notify-send on Linux/BSD, osascript on macOS and a PowerShell toast on
+// Windows.
+type Desktop struct{}
+
+// Name implements Notifier.
+func (Desktop) Name() string { return "desktop" }
+
+// Notify implements Notifier.
+func (Desktop) Notify(ctx context.Context, e Event) error {
+	title := fmt.Sprintf("Vivteno: %s recovered", e.Target)
+	body := fmt.Sprintf("%s is reachable again.", e.Target)
+	if e.Downtime > 0 {
+		body = fmt.Sprintf("%s is reachable again after %s down.", e.Target, e.Downtime)
+	}
+	critical := false
+	switch e.State {
+	case StateDown:
+		title = fmt.Sprintf("Vivteno: %s is down", e.Target)
+		body = e.Error
+		critical = true
+	case StateBudget:
+		title = fmt.Sprintf("Vivteno: %s error budget", e.Target)
+		body = e.Error
+	case StateChange:
+		title = fmt.Sprintf("Vivteno: %s health changed", e.Target)
+		body = e.Error
+	case StateDigest:
+		title = "Vivteno: " + digestTitle(e)
+		body = digestText(e)
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.CommandContext(ctx, "osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run",
+			title, body)
+	case "windows":
+		cmd = exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", windowsToastScript)
+		cmd.Env = append(os.Environ(), "VIVTENO_TITLE="+title, "VIVTENO_BODY="+body)
+	default:
+		urgency := "normal"
+		if critical {
+			urgency = "critical"
+		}
+		cmd = exec.CommandContext(ctx, "notify-send", "-a", "Vivteno", "-u", urgency, title, body)
+	}
+	return cmd.Run()
+}
@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+)
+
+// filterEvent applies keep to e, or to the events of a digest, which is
+// kept with those it accepts. ok is false when nothing is left to send.
+func filterEvent(e Event, keep func(Event) bool) (Event, bool) {
+	if e.State != StateDigest {
+		return e, keep(e)
+	}
+	var kept []Event
+	for _, d := range e.Digest {
+		if keep(d) {
+			kept = append(kept, d)
+		}
+	}
+	e.Digest = kept
+	return e, len(kept) > 0
+}
+
+// digestTitle titles a digest event, e.g. "3 alerts during quiet hours".
+func digestTitle(e Event) string {
+	if len(e.Digest) == 1 {
+		return "1 alert during quiet hours"
+	}
+	return fmt.Sprintf("%d alerts during quiet hours", len(e.Digest))
+}
+
+// digestLines describes the events of a digest, one per line, e.g.
+// "02:14 example.com is down: connection refused".
+func digestLines(e Event) []string {
+	lines := make([]string, len(e.Digest))
+	for i, d := range e.Digest {
+		line := d.Time.Format("15:04") + " " + d.Target
+		switch d.State {
+		case StateDown:
+			line += " is down"
+			if d.Error != "" {
+				line += ": " + d.Error
+			}
+		case StateBudget, StateChange:
+			line += ": " + d.Error
+		default:
+			line += " recovered"
+			if d.Downtime > 0 {
+				line += " after " + d.Downtime.String()
+			}
+		}
+		lines[i] = line
+	}
+	return lines
+}
+
+// digestText is the body of a digest event: its events, one per line.
+func digestText(e Event) string {
+	return strings.Join(digestLines(e), "\n")
+}
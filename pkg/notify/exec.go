@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExecMaxOutput limits the command output included in an error.
+const ExecMaxOutput = 500
+
+// Exec runs a shell command on every state change, e.g. to restart a
+// service when its target goes down. Budget and change events are not state
+// changes and are ignored. The event is passed in the environment:
+//
+//	TARGET   the target
+//	STATE    down or up
+//	LATENCY  latency of the last successful check in milliseconds, or empty
+//	ERROR    the failure of a down event, the last failure of the outage for
+//	         an up event, or empty
+//	DOWNTIME how long the target was down in seconds for an up event, or
+//	         empty
+//	URL      link to the target, or empty
+//	TIME     time of the event in RFC 3339 format
+//	TAGS     comma-separated tags of the target, or empty
+//	SEVERITY severity of the event (critical, error, warning or info), or
+//	         empty for a digest
+//	OWNER    owner of the target, or empty
+//	RUNBOOK  link to the runbook of the target, or empty
+//
+// The command runs with sh -c, or cmd /C on Windows, and must finish within
+// the delivery timeout; a non-zero exit status is reported as a failure.
+type Exec struct {
+	Command string
+}
+
+// Name implements Notifier.
+func (Exec) Name() string { return "exec" }
+
+// Notify implements Notifier.
+func (x Exec) Notify(ctx context.Context, e Event) error {
+	if e.State == StateBudget || e.State == StateChange {
+		return nil
+	}
+	if e.State == StateDigest {
+		// One run for the whole digest, with its events as ERROR
+		e.Error = digestText(e)
+	}
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", x.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", x.Command)
+	}
+	var latency, downtime string
+	if e.Latency > 0 {
+		latency = strconv.FormatInt(e.Latency.Milliseconds(), 10)
+	}
+	if e.Downtime > 0 {
+		downtime = strconv.FormatInt(int64(e.Downtime.Seconds()), 10)
+	}
+	cmd.Env = append(os.Environ(),
+		"TARGET="+e.Target,
+		"STATE="+e.State,
+		"LATENCY="+latency,
+		"ERROR="+e.Error,
+		"DOWNTIME="+downtime,
+		"URL="+e.URL,
+		"TIME="+e.Time.Format(time.RFC3339),
+		"TAGS="+strings.Join(e.Tags, ","),
+		"SEVERITY="+e.severity(),
+		"OWNER="+e.Owner,
+		"RUNBOOK="+e.Runbook,
+	)
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(out.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, Truncate(msg, ExecMaxOutput))
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// GotifyMaxBody limits the response body included in a Gotify message.
+const GotifyMaxBody = 1000
+
+// gotifyPriorities are the default priorities of each state, from 0 to 10.
+// Gotify clients alert loudly from 8, normally from 4 and quietly below.
+var gotifyPriorities = map[string]int{StateDown: 8, StateBudget: 5, StateChange: 4, StateUp: 3}
+
+// ParseGotifyPriorities parses the priority of each state, from 0 to 10, as
+// a comma-separated list such as "down=9,budget=6,up=2". A single number
+// sets the priority of down alerts. States not listed keep their default
+// priority: 8 when down, 5 for error budget warnings, 4 for health changes
+// and 3 on recovery.
+func ParseGotifyPriorities(s string) (map[string]int, error) {
+	priorities := maps.Clone(gotifyPriorities)
+	if p, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+		if p < 0 || p > 10 {
+			return nil, fmt.Errorf("invalid Gotify priority %d (expected 0-10)", p)
+		}
+		priorities[StateDown] = p
+		return priorities, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		state, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if _, known := gotifyPriorities[state]; !ok || !known {
+			return nil, fmt.Errorf("invalid Gotify priority %q (expected state=priority with state down, budget, change or up)", part)
+		}
+		p, err := strconv.Atoi(value)
+		if err != nil || p < 0 || p > 10 {
+			return nil, fmt.Errorf("invalid Gotify priority %q (expected 0-10)", value)
+		}
+		priorities[state] = p
+	}
+	return priorities, nil
+}
+
+// Gotify pushes messages to a self-hosted Gotify server through the token of
+// an application.
+type Gotify struct {
+	Server string
+	Token  string
+	// Priorities maps states to message priorities (see
+	// ParseGotifyPriorities).
+	Priorities map[string]int
+	Client     *http.Client
+}
+
+// NewGotify returns a Gotify notifier with the default priorities using
+// DefaultHTTPClient.
+func NewGotify(server, token string) Gotify {
+	return Gotify{Server: strings.TrimSuffix(server, "/"), Token: token, Priorities: gotifyPriorities, Client: DefaultHTTPClient}
+}
+
+// Name implements Notifier.
+func (Gotify) Name() string { return "gotify" }
+
+// Notify implements Notifier.
+func (g Gotify) Notify(ctx context.Context, e Event) error {
+	title := e.Target + " recovered"
+	switch e.State {
+	case StateDown:
+		title = e.Target + " is down"
+	case StateBudget:
+		title = e.Target + " error budget"
+	case StateChange:
+		title = e.Target + " health changed"
+	}
+	msg := map[string]any{
+		"title":    title,
+		"message":  gotifyMessage(e),
+		"priority": g.Priorities[e.State],
+	}
+	if e.State == StateDigest {
+		// Quiet hours are over, but the digest is no reason to wake anyone
+		msg["title"], msg["message"], msg["priority"] = digestTitle(e), digestText(e), g.Priorities[StateUp]
+	}
+	if e.URL != "" {
+		msg["extras"] = map[string]any{
+			"client::notification": map[string]any{"click": map[string]string{"url": e.URL}},
+		}
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.Server+"/message", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", g.Token)
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		// Errors carry error and errorDescription, e.g. Unauthorized
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		var gerr struct {
+			Error       string `json:"error"`
+			Description string `json:"errorDescription"`
+		}
+		if json.Unmarshal(body, &gerr) == nil && gerr.Error != "" {
+			return fmt.Errorf("HTTP %d: %s: %s", resp.StatusCode, gerr.Error, gerr.Description)
+		}
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// gotifyMessage formats the body of a Gotify message.
+func gotifyMessage(e Event) string {
+	var lines []string
+	if e.Downtime > 0 {
+		lines = append(lines, "Downtime: "+e.Downtime.String())
+	}
+	if e.Error != "" {
+		lines = append(lines, errorLabel(e)+": "+e.Error)
+	}
+	if e.Description != "" {
+		lines = append(lines, "Description: "+e.Description)
+	}
+	if e.Owner != "" {
+		lines = append(lines, "Owner: "+e.Owner)
+	}
+	if e.Runbook != "" {
+		lines = append(lines, "Runbook: "+e.Runbook)
+	}
+	if e.Latency > 0 {
+		lines = append(lines, fmt.Sprintf("Latency: %d ms", e.Latency.Milliseconds()))
+	}
+	if e.Body != "" {
+		lines = append(lines, Truncate(e.Body, GotifyMaxBody))
+	}
+	if len(e.Tags) > 0 {
+		lines = append(lines, "Tags: "+strings.Join(e.Tags, ", "))
+	}
+	lines = append(lines, e.Time.Format("2006-01-02 15:04:05 MST"))
+	return strings.Join(lines, "\n")
+}
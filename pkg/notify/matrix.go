@@ -0,0 +1,195 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// MatrixMaxBody limits the response body included in a Matrix message.
+const MatrixMaxBody = 1500
+
+// matrixTxn makes transaction IDs unique within the process; the start time
+// keeps them unique across restarts.
+var (
+	matrixTxn   atomic.Uint64
+	matrixStart = time.Now().UnixNano()
+)
+
+// Matrix posts messages to a room through the client-server API of a
+// homeserver, as the user of the access token, which must have joined the
+// room. Room may be a room ID ("!abc:example.org") or an alias
+// ("#ops:example.org"), which is resolved on every message.
+type Matrix struct {
+	Homeserver string
+	Token      string
+	Room       string
+	Client     *http.Client
+}
+
+// NewMatrix returns a Matrix notifier using DefaultHTTPClient.
+func NewMatrix(homeserver, token, room string) Matrix {
+	return Matrix{Homeserver: strings.TrimSuffix(homeserver, "/"), Token: token, Room: room, Client: DefaultHTTPClient}
+}
+
+// ParseMatrixRoom validates a room ID or alias.
+func ParseMatrixRoom(s string) (string, error) {
+	if len(s) < 2 || (s[0] != '!' && s[0] != '#') || !strings.Contains(s, ":") {
+		return "", fmt.Errorf("invalid Matrix room %q, expected a room ID (!abc:example.org) or alias (#ops:example.org)", s)
+	}
+	return s, nil
+}
+
+// Name implements Notifier.
+func (Matrix) Name() string { return "matrix" }
+
+// Notify implements Notifier.
+func (m Matrix) Notify(ctx context.Context, e Event) error {
+	room := m.Room
+	if strings.HasPrefix(room, "#") {
+		var err error
+		if room, err = m.resolveAlias(ctx, room); err != nil {
+			return fmt.Errorf("resolving room alias %s: %w", m.Room, err)
+		}
+	}
+	plain, formatted := matrixMessage(e)
+	payload, err := json.Marshal(map[string]any{
+		"msgtype":        "m.text",
+		"body":           plain,
+		"format":         "org.matrix.custom.html",
+		"formatted_body": formatted,
+	})
+	if err != nil {
+		return err
+	}
+	txn := fmt.Sprintf("vivteno-%d-%d", matrixStart, matrixTxn.Add(1))
+	endpoint := m.Homeserver + "/_matrix/client/v3/rooms/" + url.PathEscape(room) + "/send/m.room.message/" + txn
+	_, err = m.do(ctx, http.MethodPut, endpoint, payload)
+	return err
+}
+
+// resolveAlias returns the room ID of a room alias.
+func (m Matrix) resolveAlias(ctx context.Context, alias string) (string, error) {
+	body, err := m.do(ctx, http.MethodGet, m.Homeserver+"/_matrix/client/v3/directory/room/"+url.PathEscape(alias), nil)
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		RoomID string `json:"room_id"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || resp.RoomID == "" {
+		return "", fmt.Errorf("unexpected response: %s", body)
+	}
+	return resp.RoomID, nil
+}
+
+// do sends an authenticated request to the homeserver and returns the body
+// of a successful response.
+func (m Matrix) do(ctx context.Context, method, endpoint string, payload []byte) ([]byte, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+m.Token)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := m.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode != http.StatusOK {
+		// Errors carry errcode and error, e.g. M_FORBIDDEN
+		var merr struct {
+			Code    string `json:"errcode"`
+			Message string `json:"error"`
+		}
+		if json.Unmarshal(data, &merr) == nil && merr.Code != "" {
+			return nil, fmt.Errorf("HTTP %d: %s: %s", resp.StatusCode, merr.Code, merr.Message)
+		}
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, Truncate(string(data), 512))
+	}
+	return data, nil
+}
+
+// matrixMessage formats an event as plain text and as HTML.
+func matrixMessage(e Event) (plain, formatted string) {
+	var p, f strings.Builder
+	if e.State == StateDigest {
+		fmt.Fprintf(&p, "🌙 %s\n", digestTitle(e))
+		fmt.Fprintf(&f, "🌙 <strong>%s</strong><br>", digestTitle(e))
+		for _, line := range digestLines(e) {
+			fmt.Fprintf(&p, "%s\n", line)
+			fmt.Fprintf(&f, "%s<br>", html.EscapeString(line))
+		}
+		ts := e.Time.Format("2006-01-02 15:04:05 MST")
+		p.WriteString(ts)
+		fmt.Fprintf(&f, "<em>%s</em>", ts)
+		return p.String(), f.String()
+	}
+	switch e.State {
+	case StateDown:
+		fmt.Fprintf(&p, "🔴 %s is down\n", e.Target)
+		fmt.Fprintf(&f, "🔴 <strong>%s is down</strong><br>", html.EscapeString(e.Target))
+	case StateBudget:
+		fmt.Fprintf(&p, "🟠 %s error budget\n", e.Target)
+		fmt.Fprintf(&f, "🟠 <strong>%s error budget</strong><br>", html.EscapeString(e.Target))
+	case StateChange:
+		fmt.Fprintf(&p, "🔵 %s health changed\n", e.Target)
+		fmt.Fprintf(&f, "🔵 <strong>%s health changed</strong><br>", html.EscapeString(e.Target))
+	default:
+		fmt.Fprintf(&p, "🟢 %s recovered\n", e.Target)
+		fmt.Fprintf(&f, "🟢 <strong>%s recovered</strong><br>", html.EscapeString(e.Target))
+	}
+	if e.Latency > 0 {
+		fmt.Fprintf(&p, "Latency: %d ms\n", e.Latency.Milliseconds())
+		fmt.Fprintf(&f, "Latency: %d ms<br>", e.Latency.Milliseconds())
+	}
+	if e.Downtime > 0 {
+		fmt.Fprintf(&p, "Downtime: %s\n", e.Downtime)
+		fmt.Fprintf(&f, "Downtime: %s<br>", e.Downtime)
+	}
+	if e.Error != "" {
+		fmt.Fprintf(&p, "%s: %s\n", errorLabel(e), e.Error)
+		fmt.Fprintf(&f, "%s: <code>%s</code><br>", errorLabel(e), html.EscapeString(e.Error))
+	}
+	if e.Description != "" {
+		fmt.Fprintf(&p, "Description: %s\n", e.Description)
+		fmt.Fprintf(&f, "Description: %s<br>", html.EscapeString(e.Description))
+	}
+	if e.Owner != "" {
+		fmt.Fprintf(&p, "Owner: %s\n", e.Owner)
+		fmt.Fprintf(&f, "Owner: %s<br>", html.EscapeString(e.Owner))
+	}
+	if e.Runbook != "" {
+		fmt.Fprintf(&p, "Runbook: %s\n", e.Runbook)
+		fmt.Fprintf(&f, "Runbook: <a href=\"%s\">%s</a><br>", html.EscapeString(e.Runbook), html.EscapeString(e.Runbook))
+	}
+	if e.Body != "" {
+		body := Truncate(e.Body, MatrixMaxBody)
+		fmt.Fprintf(&p, "%s\n", body)
+		fmt.Fprintf(&f, "<pre><code>%s</code></pre>", html.EscapeString(body))
+	}
+	if len(e.Tags) > 0 {
+		fmt.Fprintf(&p, "Tags: %s\n", strings.Join(e.Tags, ", "))
+		fmt.Fprintf(&f, "Tags: %s<br>", html.EscapeString(strings.Join(e.Tags, ", ")))
+	}
+	ts := e.Time.Format("2006-01-02 15:04:05 MST")
+	p.WriteString(ts)
+	fmt.Fprintf(&f, "<em>%s</em>", ts)
+	return p.String(), f.String()
+}
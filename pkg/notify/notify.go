@@ -0,0 +1,217 @@
+// Package notify delivers alerts about target state changes to external
+// channels such as desktop notifications and chat services.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// States reported in events. StateBudget is not a state change but a
+// warning that the target is using up the error budget of its SLO,
+// StateChange reports watched fields of its health response that changed,
+// and StateDigest gathers the events held back during quiet hours.
+const (
+	StateDown   = "down"
+	StateUp     = "up"
+	StateBudget = "budget"
+	StateChange = "change"
+	StateDigest = "digest"
+)
+
+// Severities of events, from the most to the least severe. DefaultSeverity
+// applies to targets without one.
+const (
+	SeverityCritical = "critical"
+	SeverityError    = "error"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+	DefaultSeverity  = SeverityError
+)
+
+// severities lists the severities from the most to the least severe.
+var severities = []string{SeverityCritical, SeverityError, SeverityWarning, SeverityInfo}
+
+// ParseSeverity validates a severity.
+func ParseSeverity(s string) (string, error) {
+	if !slices.Contains(severities, s) {
+		return "", fmt.Errorf("invalid severity %q (expected critical, error, warning or info)", s)
+	}
+	return s, nil
+}
+
+// severityRank orders severities, 0 being the most severe. An empty
+// severity ranks as DefaultSeverity.
+func severityRank(s string) int {
+	if i := slices.Index(severities, s); i >= 0 {
+		return i
+	}
+	return slices.Index(severities, DefaultSeverity)
+}
+
+// severity returns the severity of e, or DefaultSeverity when its target
+// sets none. Digests have no severity.
+func (e Event) severity() string {
+	if e.Severity == "" && e.State != StateDigest {
+		return DefaultSeverity
+	}
+	return e.Severity
+}
+
+// DefaultTimeout bounds a single notification delivery.
+const DefaultTimeout = 10 * time.Second
+
+// DefaultHTTPClient is used by notifiers that talk to HTTP APIs.
+var DefaultHTTPClient = &http.Client{Timeout: DefaultTimeout}
+
+// Event describes a state change of a target.
+type Event struct {
+	Target string
+	State  string
+	Time   time.Time
+	// URL links to the target, e.g. for clickable notifications.
+	URL string
+	// Latency is the latency of the last successful check, if any.
+	Latency time.Duration
+	// Error is the failure that caused a down event, the last failure seen
+	// during the outage for an up event, the state of the error budget for
+	// a budget event, or the fields that changed for a change event, e.g.
+	// "version changed from 1.2 to 1.3".
+	Error string
+	// Downtime is how long the target was down, for an up event.
+	Downtime time.Duration
+	// Body is the response body of a failed health endpoint, when available.
+	Body string
+	// Tags are the labels of the target, such as "prod" or "region=eu".
+	Tags []string
+	// Severity is how severe the event is, one of the Severity constants,
+	// or empty when its target sets none, which counts as DefaultSeverity.
+	Severity string
+	// Description says what the target is, Owner who is responsible for
+	// it and Runbook links to how to handle its alerts. All are optional.
+	Description string
+	Owner       string
+	Runbook     string
+	// Digest holds the events of a digest event, oldest first; it has no
+	// Target of its own.
+	Digest []Event
+}
+
+// errorLabel names the error of e in messages.
+func errorLabel(e Event) string {
+	switch e.State {
+	case StateUp:
+		return "Last error"
+	case StateChange:
+		return "Changes"
+	}
+	return "Error"
+}
+
+// Notifier delivers events to one channel.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, e Event) error
+}
+
+// ForTargets wraps n so it only receives events for the named targets.
+func ForTargets(n Notifier, targets []string) Notifier {
+	return targetFilter{Notifier: n, targets: targets}
+}
+
+type targetFilter struct {
+	Notifier
+	targets []string
+}
+
+func (f targetFilter) Notify(ctx context.Context, e Event) error {
+	e, ok := filterEvent(e, func(e Event) bool { return slices.Contains(f.targets, e.Target) })
+	if !ok {
+		return nil
+	}
+	return f.Notifier.Notify(ctx, e)
+}
+
+// ForTags wraps n so it only receives events for targets whose tags match
+// one of filters (see MatchTags).
+func ForTags(n Notifier, filters []string) Notifier {
+	return tagFilter{Notifier: n, filters: filters}
+}
+
+type tagFilter struct {
+	Notifier
+	filters []string
+}
+
+func (f tagFilter) Notify(ctx context.Context, e Event) error {
+	e, ok := filterEvent(e, func(e Event) bool { return MatchTags(e.Tags, f.filters) })
+	if !ok {
+		return nil
+	}
+	return f.Notifier.Notify(ctx, e)
+}
+
+// Route selects the events of targets whose tags match one of Tags (see
+// MatchTags), or of every target when Tags is empty, with at least the
+// severity Severity, or of any severity when it is empty.
+type Route struct {
+	Tags     []string
+	Severity string
+}
+
+// Match reports whether r selects e.
+func (r Route) Match(e Event) bool {
+	if len(r.Tags) > 0 && !MatchTags(e.Tags, r.Tags) {
+		return false
+	}
+	return r.Severity == "" || severityRank(e.Severity) <= severityRank(r.Severity)
+}
+
+// ForRoutes wraps n so it only receives events matching one of routes.
+func ForRoutes(n Notifier, routes []Route) Notifier {
+	return routeFilter{Notifier: n, routes: routes}
+}
+
+type routeFilter struct {
+	Notifier
+	routes []Route
+}
+
+func (f routeFilter) Notify(ctx context.Context, e Event) error {
+	e, ok := filterEvent(e, func(e Event) bool { return slices.ContainsFunc(f.routes, func(r Route) bool { return r.Match(e) }) })
+	if !ok {
+		return nil
+	}
+	return f.Notifier.Notify(ctx, e)
+}
+
+// MatchTags reports whether any of tags matches one of filters. A filter
+// "region=eu" matches that tag only; a filter without a value, such as
+// "region", also matches the tag "region" with any value.
+func MatchTags(tags, filters []string) bool {
+	for _, f := range filters {
+		for _, t := range tags {
+			if t == f || (!strings.Contains(f, "=") && strings.HasPrefix(t, f+"=")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Truncate shortens s to at most n bytes without splitting a UTF-8 sequence,
+// marking the cut with an ellipsis.
+func Truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "…"
+}
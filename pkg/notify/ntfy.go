@@ -0,0 +1,141 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ntfy settings
+const (
+	// NtfyServer is the public instance used when no server is configured.
+	NtfyServer = "https://ntfy.sh"
+	// NtfyMaxBody limits the response body included in a push notification.
+	NtfyMaxBody = 500
+)
+
+// ntfy message priorities, from 1 (min) to 5 (urgent)
+var ntfyPriorities = map[string]int{"min": 1, "low": 2, "default": 3, "high": 4, "urgent": 5, "max": 5}
+
+// ParseNtfyPriority accepts a priority name (min, low, default, high, urgent)
+// or number from 1 to 5.
+func ParseNtfyPriority(s string) (int, error) {
+	if p, ok := ntfyPriorities[strings.ToLower(s)]; ok {
+		return p, nil
+	}
+	if p, err := strconv.Atoi(s); err == nil && p >= 1 && p <= 5 {
+		return p, nil
+	}
+	return 0, fmt.Errorf("invalid ntfy priority %q (expected min, low, default, high, urgent or 1-5)", s)
+}
+
+// Ntfy publishes push notifications to an ntfy topic.
+type Ntfy struct {
+	Server string
+	Topic  string
+	// Token is an optional access token for protected topics.
+	Token string
+	// Priority is used for down events; recoveries use the default priority.
+	Priority int
+	// Tags are added to every message, after the state emoji.
+	Tags   []string
+	Client *http.Client
+}
+
+// NewNtfy returns an Ntfy notifier with high-priority down alerts using
+// DefaultHTTPClient.
+func NewNtfy(server, topic string) Ntfy {
+	if server == "" {
+		server = NtfyServer
+	}
+	return Ntfy{Server: strings.TrimSuffix(server, "/"), Topic: topic, Priority: ntfyPriorities["high"], Client: DefaultHTTPClient}
+}
+
+// Name implements Notifier.
+func (Ntfy) Name() string { return "ntfy" }
+
+// Notify implements Notifier.
+func (n Ntfy) Notify(ctx context.Context, e Event) error {
+	msg := map[string]any{
+		"topic":    n.Topic,
+		"title":    e.Target + " recovered",
+		"message":  ntfyMessage(e),
+		"priority": ntfyPriorities["default"],
+		"tags":     append([]string{"white_check_mark"}, n.Tags...),
+	}
+	switch e.State {
+	case StateDown:
+		msg["title"] = e.Target + " is down"
+		msg["priority"] = n.Priority
+		msg["tags"] = append([]string{"rotating_light"}, n.Tags...)
+	case StateBudget:
+		msg["title"] = e.Target + " error budget"
+		msg["priority"] = n.Priority
+		msg["tags"] = append([]string{"warning"}, n.Tags...)
+	case StateChange:
+		msg["title"] = e.Target + " health changed"
+		msg["tags"] = append([]string{"information_source"}, n.Tags...)
+	case StateDigest:
+		msg["title"] = digestTitle(e)
+		msg["message"] = digestText(e)
+		msg["tags"] = append([]string{"crescent_moon"}, n.Tags...)
+	}
+	if e.URL != "" {
+		msg["click"] = e.URL
+	}
+	if e.Runbook != "" {
+		msg["actions"] = []map[string]string{{"action": "view", "label": "Runbook", "url": e.Runbook}}
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.Server, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.Token)
+	}
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// ntfyMessage formats the body of a push notification.
+func ntfyMessage(e Event) string {
+	var lines []string
+	if e.Downtime > 0 {
+		lines = append(lines, "Downtime: "+e.Downtime.String())
+	}
+	if e.Error != "" {
+		lines = append(lines, errorLabel(e)+": "+e.Error)
+	}
+	if e.Description != "" {
+		lines = append(lines, "Description: "+e.Description)
+	}
+	if e.Owner != "" {
+		lines = append(lines, "Owner: "+e.Owner)
+	}
+	if e.Latency > 0 {
+		lines = append(lines, fmt.Sprintf("Latency: %d ms", e.Latency.Milliseconds()))
+	}
+	if e.Body != "" {
+		lines = append(lines, Truncate(e.Body, NtfyMaxBody))
+	}
+	lines = append(lines, e.Time.Format("2006-01-02 15:04:05 MST"))
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,252 @@
+package notify
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Opsgenie Alert API settings
+const (
+	OpsgenieAPI = "https://api.opsgenie.com"
+	// OpsgenieAPIEU is the API of accounts hosted in the EU.
+	OpsgenieAPIEU = "https://api.eu.opsgenie.com"
+	// OpsgenieMaxBody limits the response body attached to an alert.
+	OpsgenieMaxBody = 2000
+	// DefaultOpsgeniePriority is used for new alerts unless configured.
+	DefaultOpsgeniePriority = "P3"
+)
+
+// OpsgenieEscalation raises an open alert to Priority once its target has
+// been down for After.
+type OpsgenieEscalation struct {
+	After    time.Duration
+	Priority string
+}
+
+// ParseOpsgeniePriorities parses a priority schedule such as
+// "P3,15m=P2,1h=P1": alerts open at P3, are raised to P2 after 15 minutes and
+// to P1 after an hour. A bare priority sets the initial one, which defaults
+// to DefaultOpsgeniePriority. The schedule is returned sorted, starting with
+// the initial priority at 0.
+func ParseOpsgeniePriorities(s string) ([]OpsgenieEscalation, error) {
+	steps := []OpsgenieEscalation{{Priority: DefaultOpsgeniePriority}}
+	var initial bool
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		after, priority, found := strings.Cut(part, "=")
+		if !found {
+			if initial {
+				return nil, fmt.Errorf("invalid Opsgenie priorities %q: more than one initial priority", s)
+			}
+			initial, after, priority = true, "0s", part
+		}
+		priority = strings.ToUpper(strings.TrimSpace(priority))
+		if !slices.Contains([]string{"P1", "P2", "P3", "P4", "P5"}, priority) {
+			return nil, fmt.Errorf("invalid Opsgenie priority %q (expected P1 to P5)", priority)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(after))
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid Opsgenie escalation delay %q", after)
+		}
+		if d == 0 {
+			steps[0].Priority = priority
+			continue
+		}
+		steps = append(steps, OpsgenieEscalation{After: d, Priority: priority})
+	}
+	slices.SortStableFunc(steps, func(a, b OpsgenieEscalation) int { return cmp.Compare(a.After, b.After) })
+	return steps, nil
+}
+
+// Opsgenie creates an alert when a target goes down and closes it when the
+// target recovers. Alerts are identified by an alias derived from the
+// target, so repeated alerts update one alert instead of opening new ones.
+// While an alert is open its priority is raised according to Priorities.
+type Opsgenie struct {
+	APIKey string
+	// API is the base URL of the Alert API, OpsgenieAPI or OpsgenieAPIEU.
+	API string
+	// Priorities is the escalation schedule; the first entry is the
+	// priority of new alerts (see ParseOpsgeniePriorities).
+	Priorities []OpsgenieEscalation
+	Client     *http.Client
+}
+
+// opsgenieEscalations holds the pending priority raises of each open alert.
+// It is shared by all Opsgenie notifiers so a notifier replaced by a
+// configuration reload can still cancel them when the alert closes.
+var opsgenieEscalations = struct {
+	sync.Mutex
+	timers map[string][]*time.Timer
+}{timers: make(map[string][]*time.Timer)}
+
+// NewOpsgenie returns an Opsgenie notifier raising DefaultOpsgeniePriority
+// alerts without escalation through OpsgenieAPI using DefaultHTTPClient.
+func NewOpsgenie(apiKey string) Opsgenie {
+	return Opsgenie{
+		APIKey:     apiKey,
+		API:        OpsgenieAPI,
+		Priorities: []OpsgenieEscalation{{Priority: DefaultOpsgeniePriority}},
+		Client:     DefaultHTTPClient,
+	}
+}
+
+// Name implements Notifier.
+func (Opsgenie) Name() string { return "opsgenie" }
+
+// Notify implements Notifier.
+func (o Opsgenie) Notify(ctx context.Context, e Event) error {
+	if e.State == StateDigest {
+		// Alerts are raised as they happen, whatever the hour
+		return nil
+	}
+	alias := OpsgenieAlias(e.Target)
+	priority := DefaultOpsgeniePriority
+	if len(o.Priorities) > 0 {
+		priority = o.Priorities[0].Priority
+	}
+	if e.State == StateBudget || e.State == StateChange {
+		// A separate alert, neither escalated nor closed on recovery
+		return o.send(ctx, http.MethodPost, "/v2/alerts", opsgenieAlert(e, alias+"/"+e.State, priority))
+	}
+	cancelOpsgenieEscalations(alias)
+	if e.State != StateDown {
+		note := e.Target + " recovered"
+		if e.Downtime > 0 {
+			note += " after " + e.Downtime.String() + " down"
+		}
+		if e.Error != "" {
+			note += "; last error: " + e.Error
+		}
+		return o.send(ctx, http.MethodPost, "/v2/alerts/"+url.PathEscape(alias)+"/close?identifierType=alias", map[string]any{
+			"source": "vivteno",
+			// Notes are limited to 25000 characters
+			"note": Truncate(note, 20000),
+		})
+	}
+	if err := o.send(ctx, http.MethodPost, "/v2/alerts", opsgenieAlert(e, alias, priority)); err != nil {
+		return err
+	}
+	o.scheduleEscalations(alias)
+	return nil
+}
+
+// scheduleEscalations raises the priority of the alert once each step of the
+// schedule is due. Failed raises are not retried.
+func (o Opsgenie) scheduleEscalations(alias string) {
+	opsgenieEscalations.Lock()
+	defer opsgenieEscalations.Unlock()
+	for _, step := range o.Priorities {
+		if step.After <= 0 {
+			continue
+		}
+		priority := step.Priority
+		opsgenieEscalations.timers[alias] = append(opsgenieEscalations.timers[alias], time.AfterFunc(step.After, func() {
+			ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+			defer cancel()
+			_ = o.send(ctx, http.MethodPut, "/v2/alerts/"+url.PathEscape(alias)+"/priority?identifierType=alias",
+				map[string]any{"priority": priority})
+		}))
+	}
+}
+
+// cancelOpsgenieEscalations stops the pending priority raises of an alert.
+func cancelOpsgenieEscalations(alias string) {
+	opsgenieEscalations.Lock()
+	defer opsgenieEscalations.Unlock()
+	for _, t := range opsgenieEscalations.timers[alias] {
+		t.Stop()
+	}
+	delete(opsgenieEscalations.timers, alias)
+}
+
+// send makes an Alert API request. The API accepts requests asynchronously
+// and answers 202.
+func (o Opsgenie) send(ctx context.Context, method, path string, msg map[string]any) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(o.API, "/")+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "GenieKey "+o.APIKey)
+	resp, err := o.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// OpsgenieAlias returns the alias identifying the alert of a target.
+func OpsgenieAlias(target string) string {
+	return "vivteno/" + target
+}
+
+// opsgenieAlert describes a down, budget or change event.
+func opsgenieAlert(e Event, alias, priority string) map[string]any {
+	details := map[string]string{"target": e.Target}
+	if e.URL != "" {
+		details["url"] = e.URL
+	}
+	if e.Latency > 0 {
+		details["latency_ms"] = fmt.Sprint(e.Latency.Milliseconds())
+	}
+	if e.Owner != "" {
+		details["owner"] = e.Owner
+	}
+	if e.Runbook != "" {
+		details["runbook"] = e.Runbook
+	}
+	message := e.Target + " is down"
+	switch e.State {
+	case StateBudget:
+		message = e.Target + " error budget"
+	case StateChange:
+		message = e.Target + " health changed"
+	}
+	description := e.Error
+	if e.Description != "" {
+		description += "\n\nDescription: " + e.Description
+	}
+	if e.Owner != "" {
+		description += "\nOwner: " + e.Owner
+	}
+	if e.Runbook != "" {
+		description += "\nRunbook: " + e.Runbook
+	}
+	if e.Body != "" {
+		description += "\n\nResponse body:\n" + Truncate(e.Body, OpsgenieMaxBody)
+	}
+	return map[string]any{
+		// Messages are limited to 130 characters
+		"message":     Truncate(message, 125),
+		"alias":       alias,
+		"description": Truncate(description, 15000),
+		"priority":    priority,
+		"source":      "vivteno",
+		"entity":      e.Target,
+		"details":     details,
+		"tags":        []string{"vivteno"},
+	}
+}
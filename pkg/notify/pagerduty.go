@@ -0,0 +1,167 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// PagerDuty Events API v2 settings
+const (
+	PagerDutyAPI = "https://events.pagerduty.com/v2/enqueue"
+	// PagerDutyMaxBody limits the response body attached to an incident.
+	PagerDutyMaxBody = 2000
+)
+
+// PagerDuty event severities
+var pagerDutySeverities = []string{"critical", "error", "warning", "info"}
+
+// ParsePagerDutySeverity validates an event severity.
+func ParsePagerDutySeverity(s string) (string, error) {
+	if !slices.Contains(pagerDutySeverities, s) {
+		return "", fmt.Errorf("invalid PagerDuty severity %q (expected critical, error, warning or info)", s)
+	}
+	return s, nil
+}
+
+// PagerDuty triggers an incident when a target goes down and resolves it when
+// the target recovers. Incidents have the severity of the target, or
+// Severity when it has none. Both events share a dedup key derived from the
+// target, so repeated alerts update one incident instead of opening new ones.
+type PagerDuty struct {
+	// RoutingKey is the integration key of an Events API v2 service.
+	RoutingKey string
+	Severity   string
+	Client     *http.Client
+}
+
+// NewPagerDuty returns a PagerDuty notifier raising critical incidents using
+// DefaultHTTPClient.
+func NewPagerDuty(routingKey string) PagerDuty {
+	return PagerDuty{RoutingKey: routingKey, Severity: "critical", Client: DefaultHTTPClient}
+}
+
+// Name implements Notifier.
+func (PagerDuty) Name() string { return "pagerduty" }
+
+// Notify implements Notifier.
+func (p PagerDuty) Notify(ctx context.Context, e Event) error {
+	if e.State == StateDigest {
+		// Incidents are raised as they happen, whatever the hour
+		return nil
+	}
+	msg := map[string]any{
+		"routing_key":  p.RoutingKey,
+		"event_action": "resolve",
+		"dedup_key":    PagerDutyDedupKey(e.Target),
+	}
+	switch e.State {
+	case StateDown:
+		msg["event_action"] = "trigger"
+		// The severity of the target, if it sets one, overrides the
+		// notifier's
+		severity := p.Severity
+		if e.Severity != "" {
+			severity = e.Severity
+		}
+		msg["payload"] = pagerDutyPayload(e, severity)
+		if links := pagerDutyLinks(e); len(links) > 0 {
+			msg["links"] = links
+		}
+	case StateBudget, StateChange:
+		// A warning of its own, so it neither replaces nor resolves an outage
+		severity := "warning"
+		if e.State == StateChange {
+			severity = "info"
+		}
+		msg["event_action"] = "trigger"
+		msg["dedup_key"] = PagerDutyDedupKey(e.Target) + "/" + e.State
+		msg["payload"] = pagerDutyPayload(e, severity)
+		if e.Runbook != "" {
+			msg["links"] = []map[string]string{{"href": e.Runbook, "text": "Runbook"}}
+		}
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, PagerDutyAPI, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// PagerDutyDedupKey returns the key identifying the incident of a target.
+func PagerDutyDedupKey(target string) string {
+	return "vivteno/" + target
+}
+
+// pagerDutyLinks links an incident to the target and its runbook.
+func pagerDutyLinks(e Event) []map[string]string {
+	var links []map[string]string
+	if e.URL != "" {
+		links = append(links, map[string]string{"href": e.URL, "text": e.Target})
+	}
+	if e.Runbook != "" {
+		links = append(links, map[string]string{"href": e.Runbook, "text": "Runbook"})
+	}
+	return links
+}
+
+// pagerDutyPayload describes a down, budget or change event.
+func pagerDutyPayload(e Event, severity string) map[string]any {
+	summary := e.Target + " is down"
+	switch e.State {
+	case StateBudget:
+		summary = e.Target + " error budget"
+	case StateChange:
+		summary = e.Target + " health changed"
+	}
+	if e.Error != "" {
+		summary += ": " + e.Error
+	}
+	details := map[string]any{}
+	if e.Error != "" {
+		details["error"] = e.Error
+	}
+	if e.Latency > 0 {
+		details["latency_ms"] = e.Latency.Milliseconds()
+	}
+	if e.Body != "" {
+		details["body"] = Truncate(e.Body, PagerDutyMaxBody)
+	}
+	if e.Description != "" {
+		details["description"] = e.Description
+	}
+	if e.Owner != "" {
+		details["owner"] = e.Owner
+	}
+	if e.Runbook != "" {
+		details["runbook"] = e.Runbook
+	}
+	return map[string]any{
+		// Summaries are limited to 1024 characters
+		"summary":        Truncate(summary, 1000),
+		"source":         e.Target,
+		"severity":       severity,
+		"timestamp":      e.Time.Format(time.RFC3339),
+		"component":      "vivteno",
+		"custom_details": details,
+	}
+}
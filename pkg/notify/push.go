@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PushMaxMessage limits the check message sent with a push.
+const PushMaxMessage = 1000
+
+// ParsePushURL validates the URL of a push monitor: a healthchecks.io ping
+// URL such as "https://hc-ping.com/<uuid>", or an Uptime Kuma push URL such
+// as "https://kuma.example.com/api/push/<token>".
+func ParsePushURL(s string) (string, error) {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid push URL %q, expected an http or https URL", s)
+	}
+	return s, nil
+}
+
+// Push reports the outcome of every check of a target to a push monitor
+// (also called a dead man's switch), which alerts when the reports fail or
+// stop arriving, e.g. because vivteno itself is down. Unlike a Notifier it
+// is called after each check, not on state changes.
+//
+// Uptime Kuma push URLs, recognised by their /api/push/ path, are requested
+// with the status, message and latency as query parameters. Other URLs are
+// treated as healthchecks.io ping URLs: successes are posted to the URL and
+// failures to its /fail variant, with the check message as the body.
+type Push struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewPush returns a Push for pushURL using DefaultHTTPClient.
+func NewPush(pushURL string) Push {
+	return Push{URL: pushURL, Client: DefaultHTTPClient}
+}
+
+// kuma reports whether the URL is an Uptime Kuma push URL.
+func (p Push) kuma() bool {
+	return strings.Contains(p.URL, "/api/push/")
+}
+
+// Ping reports a check that succeeded if ok, with msg describing it and the
+// latency of the check.
+func (p Push) Ping(ctx context.Context, ok bool, msg string, latency time.Duration) error {
+	msg = Truncate(msg, PushMaxMessage)
+	var req *http.Request
+	var err error
+	if p.kuma() {
+		u, perr := url.Parse(p.URL)
+		if perr != nil {
+			return perr
+		}
+		q := u.Query()
+		q.Set("status", "up")
+		if !ok {
+			q.Set("status", "down")
+		}
+		q.Set("msg", msg)
+		q.Set("ping", "")
+		if latency > 0 {
+			q.Set("ping", strconv.FormatInt(latency.Milliseconds(), 10))
+		}
+		u.RawQuery = q.Encode()
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	} else {
+		target := p.URL
+		if !ok {
+			target = strings.TrimSuffix(target, "/") + "/fail"
+		}
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(msg))
+		if err == nil {
+			req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+		}
+	}
+	if err != nil {
+		return err
+	}
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, body)
+	}
+	if p.kuma() {
+		// Uptime Kuma answers unknown or paused monitors with 200 and ok: false
+		var reply struct {
+			OK  bool   `json:"ok"`
+			Msg string `json:"msg"`
+		}
+		if err := json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&reply); err == nil && !reply.OK {
+			return fmt.Errorf("Uptime Kuma: %s", reply.Msg)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// SMTP transport security modes
+const (
+	SMTPStartTLS = "starttls"
+	SMTPTLS      = "tls"
+	SMTPPlain    = "none"
+)
+
+// Default email templates. Both are text/template strings executed with the
+// Event.
+const (
+	DefaultSMTPSubject = `[vivteno] {{.Target}} {{if eq .State "down"}}is DOWN{{else if eq .State "budget"}}error budget{{else if eq .State "change"}}health changed{{else}}is back up{{end}}`
+	DefaultSMTPBody    = `{{.Target}} {{if eq .State "down"}}is down{{else if eq .State "budget"}}is using up its error budget{{else if eq .State "change"}}health changed{{else}}recovered{{end}} at {{.Time.Format "2006-01-02 15:04:05 MST"}}.
+{{if .Latency}}
+Latency: {{.Latency.Milliseconds}} ms
+{{- end}}
+{{- if .Downtime}}
+Downtime: {{.Downtime}}
+{{- end}}
+{{- if .Error}}
+{{if eq .State "up"}}Last error{{else if eq .State "change"}}Changes{{else}}Error{{end}}: {{.Error}}
+{{- end}}
+{{- if .Description}}
+Description: {{.Description}}
+{{- end}}
+{{- if .Owner}}
+Owner: {{.Owner}}
+{{- end}}
+{{- if .Runbook}}
+Runbook: {{.Runbook}}
+{{- end}}
+{{- if .Body}}
+
+Response body:
+{{.Body}}
+{{- end}}
+`
+)
+
+// SMTP emails events to per-target recipient lists.
+type SMTP struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	// Security is one of SMTPStartTLS, SMTPTLS or SMTPPlain.
+	Security string
+	// Recipients maps a target name to the addresses notified about it.
+	// Targets without recipients are skipped.
+	Recipients map[string][]string
+	Subject    *template.Template
+	Body       *template.Template
+}
+
+// ParseTemplate parses a subject or body template for SMTP.
+func ParseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Parse(text)
+}
+
+// Name implements Notifier.
+func (SMTP) Name() string { return "smtp" }
+
+// Notify implements Notifier.
+func (s SMTP) Notify(ctx context.Context, e Event) error {
+	if e.State == StateDigest {
+		return s.notifyDigest(ctx, e)
+	}
+	to := s.Recipients[e.Target]
+	if len(to) == 0 {
+		return nil
+	}
+	msg, err := s.message(e, to)
+	if err != nil {
+		return err
+	}
+	return s.send(ctx, msg, to)
+}
+
+// notifyDigest emails every recipient list the part of a digest about its
+// targets, in a message of its own rather than from the templates.
+func (s SMTP) notifyDigest(ctx context.Context, e Event) error {
+	var lists [][]string
+	digests := make(map[string]Event)
+	for _, d := range e.Digest {
+		to := s.Recipients[d.Target]
+		if len(to) == 0 {
+			continue
+		}
+		key := strings.Join(to, ",")
+		part, ok := digests[key]
+		if !ok {
+			part = Event{State: StateDigest, Time: e.Time}
+			lists = append(lists, to)
+		}
+		part.Digest = append(part.Digest, d)
+		digests[key] = part
+	}
+	for _, to := range lists {
+		part := digests[strings.Join(to, ",")]
+		body := digestText(part) + "\n"
+		if err := s.send(ctx, s.envelope("[vivteno] "+digestTitle(part), body, to), to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send delivers a rendered message to the recipients in to.
+func (s SMTP) send(ctx context.Context, msg []byte, to []string) error {
+	c, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if s.Security == SMTPStartTLS {
+		if err := c.StartTLS(&tls.Config{ServerName: s.Host, MinVersion: tls.VersionTLS12}); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+	if s.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+	if err := c.Mail(s.From); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := c.Rcpt(addr); err != nil {
+			return fmt.Errorf("recipient %s: %w", addr, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// dial connects to the server, bounding the whole session by ctx.
+func (s SMTP) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.Host, s.Port)
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if s.Security == SMTPTLS {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: s.Host, MinVersion: tls.VersionTLS12})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	c, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// message renders the templates into an RFC 5322 message.
+func (s SMTP) message(e Event, to []string) ([]byte, error) {
+	var subject, body bytes.Buffer
+	e.Severity = e.severity()
+	if err := s.Subject.Execute(&subject, e); err != nil {
+		return nil, fmt.Errorf("subject template: %w", err)
+	}
+	if err := s.Body.Execute(&body, e); err != nil {
+		return nil, fmt.Errorf("body template: %w", err)
+	}
+	return s.envelope(subject.String(), body.String(), to), nil
+}
+
+// envelope wraps a subject and plain text body into an RFC 5322 message.
+func (s SMTP) envelope(subject, body string, to []string) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", headerEscaper.Replace(subject)))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	return b.Bytes()
+}
+
+// headerEscaper keeps rendered header values on a single line.
+var headerEscaper = strings.NewReplacer("\r", " ", "\n", " ")
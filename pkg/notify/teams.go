@@ -0,0 +1,170 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// TeamsMaxBody limits the response body included in a Teams card.
+const TeamsMaxBody = 1500
+
+// ParseTeamsWebhook validates the URL of a Teams incoming webhook, either a
+// Workflows webhook or a legacy connector.
+func ParseTeamsWebhook(s string) (string, error) {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return "", errors.New("invalid Teams webhook URL, expected an https URL")
+	}
+	return s, nil
+}
+
+// Teams posts Adaptive Cards to a Microsoft Teams channel through an
+// incoming webhook. Cards are colored by state: red when down, green on
+// recovery, orange for error budget warnings and blue for health changes.
+type Teams struct {
+	Webhook string
+	Client  *http.Client
+}
+
+// NewTeams returns a Teams notifier using DefaultHTTPClient.
+func NewTeams(webhook string) Teams {
+	return Teams{Webhook: webhook, Client: DefaultHTTPClient}
+}
+
+// Name implements Notifier.
+func (Teams) Name() string { return "teams" }
+
+// Notify implements Notifier.
+func (t Teams) Notify(ctx context.Context, e Event) error {
+	payload, err := json.Marshal(map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content":     teamsCard(e),
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.Webhook, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		// The webhook URL is a secret; keep it out of the error
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	// Workflows webhooks answer 202, legacy connectors 200
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// teamsCard formats an event as an Adaptive Card.
+func teamsCard(e Event) map[string]any {
+	if e.State == StateDigest {
+		return teamsDigestCard(e)
+	}
+	title, color := "🟢 "+e.Target+" recovered", "Good"
+	switch e.State {
+	case StateDown:
+		title, color = "🔴 "+e.Target+" is down", "Attention"
+	case StateBudget:
+		title, color = "🟠 "+e.Target+" error budget", "Warning"
+	case StateChange:
+		title, color = "🔵 "+e.Target+" health changed", "Accent"
+	}
+	body := []map[string]any{{
+		"type":   "TextBlock",
+		"text":   title,
+		"size":   "Large",
+		"weight": "Bolder",
+		"color":  color,
+		"wrap":   true,
+	}}
+	facts := []map[string]string{{"title": "Time", "value": e.Time.Format("2006-01-02 15:04:05 MST")}}
+	if e.Latency > 0 {
+		facts = append(facts, map[string]string{"title": "Latency", "value": fmt.Sprintf("%d ms", e.Latency.Milliseconds())})
+	}
+	if e.Downtime > 0 {
+		facts = append(facts, map[string]string{"title": "Downtime", "value": e.Downtime.String()})
+	}
+	if e.Error != "" {
+		facts = append(facts, map[string]string{"title": errorLabel(e), "value": e.Error})
+	}
+	if e.Description != "" {
+		facts = append(facts, map[string]string{"title": "Description", "value": e.Description})
+	}
+	if e.Owner != "" {
+		facts = append(facts, map[string]string{"title": "Owner", "value": e.Owner})
+	}
+	if len(e.Tags) > 0 {
+		facts = append(facts, map[string]string{"title": "Tags", "value": strings.Join(e.Tags, ", ")})
+	}
+	body = append(body, map[string]any{"type": "FactSet", "facts": facts})
+	if e.Body != "" {
+		body = append(body, map[string]any{
+			"type":     "TextBlock",
+			"text":     Truncate(e.Body, TeamsMaxBody),
+			"fontType": "Monospace",
+			"wrap":     true,
+		})
+	}
+	card := map[string]any{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"msteams": map[string]string{"width": "Full"},
+		"body":    body,
+	}
+	var actions []map[string]string
+	if e.URL != "" {
+		actions = append(actions, map[string]string{"type": "Action.OpenUrl", "title": "Open " + e.Target, "url": e.URL})
+	}
+	if e.Runbook != "" {
+		actions = append(actions, map[string]string{"type": "Action.OpenUrl", "title": "Open runbook", "url": e.Runbook})
+	}
+	if len(actions) > 0 {
+		card["actions"] = actions
+	}
+	return card
+}
+
+// teamsDigestCard formats a digest event as an Adaptive Card with one line
+// per event.
+func teamsDigestCard(e Event) map[string]any {
+	body := []map[string]any{{
+		"type":   "TextBlock",
+		"text":   "🌙 " + digestTitle(e),
+		"size":   "Large",
+		"weight": "Bolder",
+		"wrap":   true,
+	}}
+	for _, line := range digestLines(e) {
+		body = append(body, map[string]any{"type": "TextBlock", "text": line, "wrap": true, "spacing": "None"})
+	}
+	body = append(body, map[string]any{"type": "TextBlock", "text": e.Time.Format("2006-01-02 15:04:05 MST"), "isSubtle": true})
+	return map[string]any{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"msteams": map[string]string{"width": "Full"},
+		"body":    body,
+	}
+}
@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Telegram Bot API settings
+const (
+	TelegramAPI = "https://api.telegram.org"
+	// Messages are limited to 4096 characters; long bodies are truncated.
+	TelegramMaxBody = 1500
+)
+
+// telegramEscaper escapes the characters reserved by Telegram's MarkdownV2.
+var telegramEscaper = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`,
+	"~", `\~`, "`", "\\`", ">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`,
+	"|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+
+// codeEscaper escapes text placed inside MarkdownV2 code blocks.
+var codeEscaper = strings.NewReplacer(`\`, `\\`, "`", "\\`")
+
+// Telegram sends messages to a chat through a bot.
+type Telegram struct {
+	Token  string
+	ChatID string
+	Client *http.Client
+}
+
+// NewTelegram returns a Telegram notifier using DefaultHTTPClient.
+func NewTelegram(token, chatID string) Telegram {
+	return Telegram{Token: token, ChatID: chatID, Client: DefaultHTTPClient}
+}
+
+// Name implements Notifier.
+func (Telegram) Name() string { return "telegram" }
+
+// Notify implements Notifier.
+func (t Telegram) Notify(ctx context.Context, e Event) error {
+	payload, err := json.Marshal(map[string]any{
+		"chat_id":    t.ChatID,
+		"text":       telegramMessage(e),
+		"parse_mode": "MarkdownV2",
+	})
+	if err != nil {
+		return err
+	}
+	endpoint := TelegramAPI + "/bot" + t.Token + "/sendMessage"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		// The request URL contains the bot token; keep it out of the error
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// telegramMessage formats an event as MarkdownV2.
+func telegramMessage(e Event) string {
+	var b strings.Builder
+	if e.State == StateDigest {
+		fmt.Fprintf(&b, "🌙 *%s*\n", telegramEscaper.Replace(digestTitle(e)))
+		for _, line := range digestLines(e) {
+			fmt.Fprintf(&b, "%s\n", telegramEscaper.Replace(line))
+		}
+		fmt.Fprintf(&b, "_%s_", telegramEscaper.Replace(e.Time.Format("2006-01-02 15:04:05 MST")))
+		return b.String()
+	}
+	switch e.State {
+	case StateDown:
+		fmt.Fprintf(&b, "🔴 *%s is down*\n", telegramEscaper.Replace(e.Target))
+	case StateBudget:
+		fmt.Fprintf(&b, "🟠 *%s error budget*\n", telegramEscaper.Replace(e.Target))
+	case StateChange:
+		fmt.Fprintf(&b, "🔵 *%s health changed*\n", telegramEscaper.Replace(e.Target))
+	default:
+		fmt.Fprintf(&b, "🟢 *%s recovered*\n", telegramEscaper.Replace(e.Target))
+	}
+	if e.Latency > 0 {
+		fmt.Fprintf(&b, "Latency: %s ms\n", telegramEscaper.Replace(fmt.Sprint(e.Latency.Milliseconds())))
+	}
+	if e.Downtime > 0 {
+		fmt.Fprintf(&b, "Downtime: %s\n", telegramEscaper.Replace(e.Downtime.String()))
+	}
+	if e.Error != "" {
+		fmt.Fprintf(&b, "%s: `%s`\n", errorLabel(e), codeEscaper.Replace(e.Error))
+	}
+	if e.Description != "" {
+		fmt.Fprintf(&b, "Description: %s\n", telegramEscaper.Replace(e.Description))
+	}
+	if e.Owner != "" {
+		fmt.Fprintf(&b, "Owner: %s\n", telegramEscaper.Replace(e.Owner))
+	}
+	if e.Runbook != "" {
+		fmt.Fprintf(&b, "Runbook: %s\n", telegramEscaper.Replace(e.Runbook))
+	}
+	if e.Body != "" {
+		fmt.Fprintf(&b, "```\n%s\n```\n", codeEscaper.Replace(Truncate(e.Body, TelegramMaxBody)))
+	}
+	fmt.Fprintf(&b, "_%s_", telegramEscaper.Replace(e.Time.Format("2006-01-02 15:04:05 MST")))
+	return b.String()
+}
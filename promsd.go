@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mooship/vivteno/pkg/monitor"
+	"gopkg.in/yaml.v3"
+)
+
+// Prometheus service discovery settings
+const (
+	DefaultPrometheusSDRefresh = time.Minute
+	// PrometheusSDTimeout bounds one request to an HTTP SD endpoint.
+	PrometheusSDTimeout = 10 * time.Second
+	// PrometheusSDMaxBody bounds the size of a file or response read.
+	PrometheusSDMaxBody = 10 << 20
+)
+
+// prometheusSD reads websites from the target groups Prometheus file_sd
+// files and http_sd endpoints list, e.g.
+// [{"targets": ["example.com:443"], "labels": {"env": "prod"}}], so
+// existing service discovery outputs can be reused. Labels become tags of
+// their targets.
+type prometheusSD struct {
+	// sources are URLs of HTTP SD endpoints and paths or glob patterns of
+	// SD files.
+	sources []string
+	refresh time.Duration
+	client  *http.Client
+}
+
+// targetGroup is an entry of the Prometheus SD format.
+type targetGroup struct {
+	Targets []string          `json:"targets" yaml:"targets"`
+	Labels  map[string]string `json:"labels" yaml:"labels"`
+}
+
+// loadPrometheusSD reads the PROMETHEUS_SD settings. It returns nil when
+// PROMETHEUS_SD is not set.
+func loadPrometheusSD() (*prometheusSD, error) {
+	v := os.Getenv("PROMETHEUS_SD")
+	if v == "" {
+		return nil, nil
+	}
+	d := &prometheusSD{
+		sources: parseList(v),
+		refresh: DefaultPrometheusSDRefresh,
+		client:  &http.Client{Timeout: PrometheusSDTimeout},
+	}
+	for _, src := range d.sources {
+		if strings.Contains(src, "://") {
+			if u, err := url.Parse(src); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, fmt.Errorf("invalid PROMETHEUS_SD: %q (expected an http or https URL, or a file path)", src)
+			}
+		} else if _, err := filepath.Match(src, ""); err != nil {
+			return nil, fmt.Errorf("invalid PROMETHEUS_SD: %q: %w", src, err)
+		}
+	}
+	if v := os.Getenv("PROMETHEUS_SD_REFRESH"); v != "" {
+		var err error
+		if d.refresh, err = time.ParseDuration(v); err != nil || d.refresh <= 0 {
+			return nil, fmt.Errorf("invalid PROMETHEUS_SD_REFRESH: %q", v)
+		}
+	}
+	return d, nil
+}
+
+// discover implements discoverer by reading every source.
+func (d *prometheusSD) discover(ctx context.Context) ([]discoveredSite, error) {
+	var groups []targetGroup
+	for _, src := range d.sources {
+		var found []targetGroup
+		var err error
+		if strings.Contains(src, "://") {
+			if found, err = d.fetch(ctx, src); err != nil {
+				err = fmt.Errorf("%s: %w", src, err)
+			}
+		} else {
+			// Errors of files name the file already
+			found, err = readSDFiles(src)
+		}
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, found...)
+	}
+	var sites []discoveredSite
+	for _, g := range groups {
+		tags := labelTags(g.Labels)
+		for _, target := range g.Targets {
+			if target = strings.TrimSpace(target); target != "" {
+				sites = append(sites, discoveredSite{website: targetSite(target, g.Labels["__scheme__"]), tags: tags})
+			}
+		}
+	}
+	return sites, nil
+}
+
+// targetSite returns the website of a target: a "host:port" target, as
+// Prometheus scrapes them, becomes a URL of the scheme of its group, or of
+// HTTPS for port 443 and HTTP otherwise, so the checks use its port. Other
+// targets are sites as they are.
+func targetSite(target, scheme string) string {
+	host, port, err := net.SplitHostPort(target)
+	if err != nil || strings.Contains(target, "://") {
+		return target
+	}
+	if scheme == "" {
+		scheme = monitor.SchemeHTTP
+		if port == "443" {
+			scheme = monitor.SchemeHTTPS
+		}
+	}
+	return scheme + "://" + net.JoinHostPort(host, port)
+}
+
+// fetch requests the target groups of an HTTP SD endpoint, telling it the
+// refresh interval as Prometheus does.
+func (d *prometheusSD) fetch(ctx context.Context, endpoint string) ([]targetGroup, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Prometheus-Refresh-Interval-Seconds", strconv.Itoa(int(d.refresh.Seconds())))
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var groups []targetGroup
+	if err := json.NewDecoder(io.LimitReader(resp.Body, PrometheusSDMaxBody)).Decode(&groups); err != nil {
+		return nil, fmt.Errorf("decoding target groups: %w", err)
+	}
+	return groups, nil
+}
+
+// readSDFiles reads the target groups of the SD files matching pattern:
+// JSON, or YAML for files ending in .yml or .yaml. A pattern matching no
+// files yields no groups, as the files may not have been written yet.
+func readSDFiles(pattern string) ([]targetGroup, error) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var groups []targetGroup
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(f, PrometheusSDMaxBody))
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		var found []targetGroup
+		switch filepath.Ext(path) {
+		case ".yml", ".yaml":
+			err = yaml.Unmarshal(data, &found)
+		default:
+			err = json.Unmarshal(data, &found)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		groups = append(groups, found...)
+	}
+	return groups, nil
+}
+
+// labelTags converts the labels of a target group to tags such as
+// "env=prod", sorted. Meta labels (starting with "__"), empty labels and
+// labels with values tags cannot hold, such as spaces, are left out.
+func labelTags(labels map[string]string) []string {
+	var tags []string
+	for name, value := range labels {
+		tag := name + "=" + value
+		if strings.HasPrefix(name, "__") || value == "" || !tagPattern.MatchString(tag) {
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags)
+	return tags
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mooship/vivteno/pkg/notify"
+)
+
+// quietHours is a daily period, such as the night, during which the alerts
+// of websites that are not critical are held back, to be sent as a digest
+// once it ends.
+type quietHours struct {
+	// start and end are minutes after midnight in loc; the period wraps
+	// past midnight when end is before start. Equal values disable it.
+	start, end int
+	loc        *time.Location
+	// critical are the tag filters of websites alerted at once all the same
+	// (see notify.MatchTags).
+	critical []string
+}
+
+// quietNotifiers are never held back, as they page according to on-call
+// schedules of their own and open incidents a digest could not.
+var quietNotifiers = []string{"pagerduty", "opsgenie"}
+
+// parseQuietHours parses a period of the day such as "23:00-07:00".
+func parseQuietHours(s string, loc *time.Location) (quietHours, error) {
+	from, to, ok := strings.Cut(strings.ReplaceAll(s, "–", "-"), "-")
+	if !ok {
+		return quietHours{}, fmt.Errorf("expected start-end, e.g. 23:00-07:00, got %q", s)
+	}
+	q := quietHours{loc: loc}
+	for _, part := range []struct {
+		s   string
+		min *int
+	}{{from, &q.start}, {to, &q.end}} {
+		t, err := time.Parse("15:04", strings.TrimSpace(part.s))
+		if err != nil {
+			return quietHours{}, fmt.Errorf("invalid time %q, expected HH:MM", strings.TrimSpace(part.s))
+		}
+		*part.min = t.Hour()*60 + t.Minute()
+	}
+	if q.start == q.end {
+		return quietHours{}, fmt.Errorf("start and end must differ, got %q", s)
+	}
+	return q, nil
+}
+
+// active reports whether now falls within quiet hours.
+func (q quietHours) active(now time.Time) bool {
+	if q.start == q.end {
+		return false
+	}
+	now = now.In(q.loc)
+	minute := now.Hour()*60 + now.Minute()
+	if q.start < q.end {
+		return minute >= q.start && minute < q.end
+	}
+	return minute >= q.start || minute < q.end
+}
+
+// holdBack reports whether e is held back from n during quiet hours: it is
+// neither about a critical website, by its tags or its severity, nor for a
+// notifier of quietNotifiers.
+func (m model) holdBack(n notify.Notifier, e notify.Event, now time.Time) bool {
+	critical := notify.MatchTags(e.Tags, m.quiet.critical) || e.Severity == notify.SeverityCritical
+	return m.quiet.active(now) && !critical && !slices.Contains(quietNotifiers, n.Name())
+}
+
+// hold keeps e to be sent to the notifier of key in the next digest, which
+// lists it at its time in the quiet hours' time zone.
+func (m model) hold(key string, e notify.Event) {
+	e.Time = e.Time.In(m.quiet.loc)
+	m.logger.Info("alert held for quiet hours", "notifier", key, "target", e.Target, "state", e.State)
+	m.held[key] = append(m.held[key], e)
+}
+
+// digestCmd returns a command sending every notifier the alerts held back
+// for it as one digest event once quiet hours are over, or nil.
+func (m model) digestCmd(now time.Time) tea.Cmd {
+	if len(m.held) == 0 || m.quiet.active(now) {
+		return nil
+	}
+	var cmds []tea.Cmd
+	for i, key := range m.alertKeys() {
+		if events := m.held[key]; len(events) > 0 {
+			m.logger.Info("sending quiet hours digest", "notifier", key, "alerts", len(events))
+			cmds = append(cmds, m.send(m.notifiers[i], notify.Event{State: notify.StateDigest, Time: now, Digest: events}))
+		}
+	}
+	// Alerts of notifiers removed by a reload are dropped
+	clear(m.held)
+	return tea.Batch(cmds...)
+}
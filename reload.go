@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/joho/godotenv"
+)
+
+// EnvFile is the dotenv file read at startup and on every reload.
+const EnvFile = ".env"
+
+// ConfigWatchInterval is how often EnvFile is checked for changes when
+// CONFIG_WATCH is enabled.
+const ConfigWatchInterval = 2 * time.Second
+
+// reloadMsg carries a new configuration to the model.
+type reloadMsg struct{ cfg config }
+
+// reloadErrMsg reports a configuration that failed to load; the previous one
+// stays in effect.
+type reloadErrMsg struct{ err error }
+
+// envState tracks where environment variables came from. Variables set in the
+// process environment take precedence over EnvFile, as with godotenv.Load,
+// and are never touched by a reload.
+type envState struct {
+	base   map[string]bool
+	loaded map[string]bool
+}
+
+// loadEnv records the process environment and applies EnvFile on top of it.
+// A missing or unreadable file is ignored at startup.
+func loadEnv() *envState {
+	e := &envState{base: make(map[string]bool), loaded: make(map[string]bool)}
+	for _, kv := range os.Environ() {
+		k, _, _ := strings.Cut(kv, "=")
+		e.base[k] = true
+	}
+	_ = e.reload()
+	return e
+}
+
+// reload re-reads EnvFile, applying changed values and unsetting variables
+// that were removed from it.
+func (e *envState) reload() error {
+	vals, err := godotenv.Read(EnvFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for k := range e.loaded {
+		if _, ok := vals[k]; !ok {
+			_ = os.Unsetenv(k)
+			delete(e.loaded, k)
+		}
+	}
+	for k, v := range vals {
+		if e.base[k] {
+			continue
+		}
+		_ = os.Setenv(k, v)
+		e.loaded[k] = true
+	}
+	return nil
+}
+
+// watchConfig reloads the configuration on SIGHUP and, when poll is set,
+// whenever EnvFile changes, until ctx is cancelled.
+func watchConfig(ctx context.Context, p *tea.Program, env *envState, disc *discovery, poll bool) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	var modified time.Time
+	if poll {
+		ticker := time.NewTicker(ConfigWatchInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+		modified = modTime(EnvFile)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		case <-tick:
+			mt := modTime(EnvFile)
+			if mt.Equal(modified) {
+				continue
+			}
+			modified = mt
+		}
+		p.Send(reloadConfig(env, disc))
+	}
+}
+
+// modTime returns the modification time of path, or the zero time if it
+// cannot be read.
+func modTime(path string) time.Time {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return fi.ModTime()
+}
+
+// reloadConfig re-reads the environment and returns a reloadMsg or
+// reloadErrMsg. The websites last found by disc, if any, are kept.
+func reloadConfig(env *envState, disc *discovery) tea.Msg {
+	if err := env.reload(); err != nil {
+		return reloadErrMsg{err}
+	}
+	cfg, err := loadConfig(disc.current())
+	if err != nil {
+		return reloadErrMsg{err}
+	}
+	return reloadMsg{cfg}
+}
+
+// reload switches the model to cfg. Websites that are still configured keep
+// their state and history; open incidents of removed websites are closed.
+// The monitor is replaced by one for the new targets. WEB_LISTEN,
+// CONTROL_LISTEN, AGENT_TOKEN, HISTORY_FILE, CONFIG_WATCH and the LOG_*,
+// OTEL_*, INFLUX_* and KUBERNETES_* settings only take effect on restart.
+func (m model) reload(cfg config) (tea.Model, tea.Cmd) {
+	cfg.theme.apply()
+	next := initialModel(cfg, m.ctx, m.cancel)
+	for _, t := range next.targets {
+		if old := m.target(t.website); old != nil {
+			t.checkState = old.checkState
+			t.history = t.history.resize(cfg.historySize)
+		}
+	}
+	if j := next.index(m.targets[m.selected].website); j >= 0 {
+		next.selected = j
+		next.focused = m.focused
+	}
+	next.tableView = m.tableView
+	next.historyPane = m.historyPane
+	next.grouped = m.grouped
+	next.collapsed = m.collapsed
+	next.marked = m.marked
+	next.width = m.width
+	next.flashStart = m.flashStart
+	next.incidents = m.incidents
+	next.held = m.held
+	next.pending = m.pending
+	next.store = m.store
+	next.heartbeats = m.heartbeats
+	next.logger = m.logger
+	next.telemetry = m.telemetry
+	next.influx = m.influx
+	next.statsd = m.statsd
+	next.mqtt = m.mqtt
+	if next.telemetry != nil {
+		next.telemetry.retain(cfg.websites)
+	}
+	next.lastNotifyError = m.lastNotifyError
+	next.lastStorageError = m.lastStorageError
+	next.lastExport = m.lastExport
+	next.lastExportError = m.lastExportError
+
+	var cmds []tea.Cmd
+	now := time.Now()
+	var closed bool
+	for _, t := range m.targets {
+		if next.target(t.website) == nil && next.incidents.close(t.website, now) {
+			closed = true
+		}
+	}
+	if closed {
+		cmds = append(cmds, next.pending.track(next.incidents.saveCmd()))
+	}
+
+	m.stopMonitor()
+	next.logger.Info("configuration reloaded", "targets", len(cfg.websites))
+	next.heartbeats.attach(cfg.targets, cfg.heartbeatTokens)
+	mon := cfg.newMonitor()
+	for i, t := range next.targets {
+		if t.paused {
+			mon.Pause(i)
+		}
+	}
+	next = next.startMonitor(mon)
+	if next.store != nil {
+		next.store.setDisplay(cfg.healthOrder, cfg.timezone)
+		next.store.setInterval(cfg.interval)
+		next.store.set(next.snapshot())
+	}
+	cmds = append(cmds, waitForReport(next.mon), next.domainCmd(next.domains(true)))
+	return next, tea.Batch(cmds...)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mooship/vivteno/pkg/notify"
+)
+
+// routeRule is a rule of NOTIFY_ROUTES: the alerts of websites with
+// matching tags and at least a severity go to the notifiers it names.
+type routeRule struct {
+	// Tags are comma-separated tag filters, as in NOTIFY_TAGS; empty
+	// matches every website.
+	Tags string `json:"tags"`
+	// Severity is the lowest severity matched; empty matches any.
+	Severity string   `json:"severity"`
+	Notify   []string `json:"notify"`
+}
+
+// routeByRules dispatches alerts to notifiers by rules. value is a JSON
+// array of rules, e.g. [{"tags": "prod", "severity": "critical", "notify":
+// ["pagerduty"]}, {"tags": "staging", "notify": ["smtp"]}]; a notifier
+// named by rules receives the alerts matching any of them, and notifiers no
+// rule names receive every alert. Rules may only name configured notifiers.
+func routeByRules(notifiers []notify.Notifier, value string) ([]notify.Notifier, error) {
+	if value == "" {
+		return notifiers, nil
+	}
+	var rules []routeRule
+	dec := json.NewDecoder(strings.NewReader(value))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("must be a JSON array of rules, e.g. [{\"tags\": \"prod\", \"severity\": \"critical\", \"notify\": [\"pagerduty\"]}]: %w", err)
+	}
+	configured := make([]string, len(notifiers))
+	for i, n := range notifiers {
+		configured[i] = n.Name()
+	}
+	routes := make(map[string][]notify.Route)
+	for i, rule := range rules {
+		if len(rule.Notify) == 0 {
+			return nil, fmt.Errorf("rule %d: expected notifiers in \"notify\"", i+1)
+		}
+		var r notify.Route
+		var err error
+		if r.Tags, err = parseTags(rule.Tags); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		if rule.Severity != "" {
+			if r.Severity, err = notify.ParseSeverity(rule.Severity); err != nil {
+				return nil, fmt.Errorf("rule %d: %w", i+1, err)
+			}
+		}
+		for _, name := range rule.Notify {
+			if !slices.Contains(notifierNames, name) {
+				return nil, fmt.Errorf("rule %d: unknown notifier %q (expected %s)", i+1, name, strings.Join(notifierNames, ", "))
+			}
+			// A rule for a notifier that is not set up would silently drop
+			// the alerts it matches
+			if !slices.Contains(configured, name) {
+				return nil, fmt.Errorf("rule %d: notifier %q is not configured", i+1, name)
+			}
+			routes[name] = append(routes[name], r)
+		}
+	}
+	out := make([]notify.Notifier, len(notifiers))
+	for i, n := range notifiers {
+		out[i] = n
+		if r, ok := routes[n.Name()]; ok {
+			out[i] = notify.ForRoutes(n, r)
+		}
+	}
+	return out, nil
+}
+
+// parseSeverity is the parse function for per-website severities. An empty
+// value leaves the severity unset, so notifiers apply their own default.
+func parseSeverity(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	return notify.ParseSeverity(s)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// selectionFooterHelp is the footer while websites are selected for bulk
+// actions.
+const selectionFooterHelp = "v end visual selection, Space select/unselect, r re-check, p pause/resume, m mute/unmute the selection, Esc clear, q or Ctrl+C quit."
+
+// rowTargets returns the websites the row of target i stands for: all those
+// of its section when the section is collapsed, or just i.
+func (m model) rowTargets(i int) []int {
+	if g := m.groupOf(i); m.isCollapsed(g) {
+		return g.targets
+	}
+	return []int{i}
+}
+
+// visualRange returns the websites between the row visual selection started
+// on and the cursor, in list order, or nil outside visual selection.
+func (m model) visualRange() []int {
+	if !m.visual {
+		return nil
+	}
+	rows := m.rows()
+	from, to := slices.Index(rows, m.rowOf(m.visualStart)), slices.Index(rows, m.rowOf(m.selected))
+	if from < 0 || to < 0 {
+		return nil
+	}
+	if from > to {
+		from, to = to, from
+	}
+	var out []int
+	for _, r := range rows[from : to+1] {
+		out = append(out, m.rowTargets(r)...)
+	}
+	return out
+}
+
+// rowOf returns the row the website at i is listed on: the header of its
+// section when the section is collapsed.
+func (m model) rowOf(i int) int {
+	if g := m.groupOf(i); m.isCollapsed(g) {
+		return g.targets[0]
+	}
+	return i
+}
+
+// isMarked reports whether the website at i is part of the selection.
+func (m model) isMarked(i int) bool {
+	return m.marked[m.targets[i].website] || slices.Contains(m.visualRange(), i)
+}
+
+// selection returns the websites bulk actions apply to, in list order: the
+// marked ones and those in the visual range, or the website under the
+// cursor when none are selected.
+func (m model) selection() []int {
+	var out []int
+	for _, r := range m.rows() {
+		for _, i := range m.rowTargets(r) {
+			if m.isMarked(i) {
+				out = append(out, i)
+			}
+		}
+	}
+	if len(out) == 0 {
+		return []int{m.selected}
+	}
+	return out
+}
+
+// selecting reports whether websites are selected for bulk actions.
+func (m model) selecting() bool {
+	return m.visual || len(m.marked) > 0
+}
+
+// toggleVisual starts visual selection at the cursor, or ends it and keeps
+// the range selected.
+func (m model) toggleVisual() model {
+	if !m.visual {
+		m.visual, m.visualStart = true, m.selected
+		return m
+	}
+	for _, i := range m.visualRange() {
+		m.marked[m.targets[i].website] = true
+	}
+	m.visual = false
+	return m
+}
+
+// toggleMark selects the website under the cursor, or all those of its
+// collapsed section, or unselects them if they all are.
+func (m model) toggleMark() model {
+	targets := m.rowTargets(m.selected)
+	mark := slices.ContainsFunc(targets, func(i int) bool { return !m.marked[m.targets[i].website] })
+	for _, i := range targets {
+		if mark {
+			m.marked[m.targets[i].website] = true
+		} else {
+			delete(m.marked, m.targets[i].website)
+		}
+	}
+	return m
+}
+
+// clearSelection ends visual selection and unselects every website.
+func (m model) clearSelection() model {
+	m.visual = false
+	clear(m.marked)
+	return m
+}
+
+// marker returns what precedes the website at i in the list: the cursor, a
+// dot when the website is selected, or blanks.
+func (m model) marker(i int) string {
+	if i != m.selected && m.isMarked(i) {
+		return infoStyle.Render("● ")
+	}
+	return cursor(i == m.selected)
+}
+
+// listFooter returns the footer of the overview, which lists the bulk
+// actions while websites are selected.
+func (m model) listFooter() string {
+	if m.selecting() {
+		return m.selectionSummary() + ": " + selectionFooterHelp
+	}
+	return footerHelp
+}
+
+// selectionSummary describes the selection, e.g. "3 websites selected".
+func (m model) selectionSummary() string {
+	n := len(m.selection())
+	if n == 1 {
+		return "1 website selected"
+	}
+	return fmt.Sprintf("%d websites selected", n)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"time"
+)
+
+// SelfStaleCycles is how many check intervals, and at least a minute, may
+// pass without any check completing before vivteno reports itself not
+// ready.
+const SelfStaleCycles = 3
+
+// selfHealth is the state of vivteno itself, served at /healthz and /readyz.
+type selfHealth struct {
+	Status        string    `json:"status"`
+	Uptime        string    `json:"uptime"`
+	UptimeSeconds int64     `json:"uptime_seconds"`
+	Targets       int       `json:"targets"`
+	Paused        int       `json:"paused"`
+	Goroutines    int       `json:"goroutines"`
+	LastCheck     time.Time `json:"last_check,omitzero"`
+	// Reason explains why vivteno is not ready.
+	Reason string `json:"reason,omitempty"`
+}
+
+// handleSelfHealth registers the health endpoints of vivteno itself, for
+// orchestrators and load balancers:
+//
+//	GET /healthz  200 while vivteno serves requests
+//	GET /readyz   200 once checks complete on schedule, 503 otherwise
+//
+// Both answer with a selfHealth.
+func handleSelfHealth(mux *http.ServeMux, store *statusStore) {
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		h, _ := store.selfHealth(time.Now())
+		h.Status, h.Reason = "ok", ""
+		writeControlJSON(w, http.StatusOK, h)
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		h, ready := store.selfHealth(time.Now())
+		code := http.StatusOK
+		if !ready {
+			code = http.StatusServiceUnavailable
+		}
+		writeControlJSON(w, code, h)
+	})
+}
+
+// selfHealth describes vivteno at now and reports whether it is ready: it
+// is once a check has completed, and as long as checks keep completing
+// within SelfStaleCycles intervals. Pausing every website keeps it ready.
+func (s *statusStore) selfHealth(now time.Time) (selfHealth, bool) {
+	targets, _ := s.get()
+	s.mu.RLock()
+	started, interval := s.started, s.interval
+	s.mu.RUnlock()
+	h := selfHealth{
+		Status:        "ready",
+		Uptime:        formatDuration(now.Sub(started)),
+		UptimeSeconds: int64(now.Sub(started).Seconds()),
+		Targets:       len(targets),
+		Goroutines:    runtime.NumGoroutine(),
+	}
+	for _, t := range targets {
+		if t.Paused {
+			h.Paused++
+		}
+		if t.LastChecked.After(h.LastCheck) {
+			h.LastCheck = t.LastChecked
+		}
+	}
+	staleAfter := max(SelfStaleCycles*interval, time.Minute)
+	switch {
+	case h.Paused == h.Targets:
+	case h.LastCheck.IsZero():
+		h.Status, h.Reason = "starting", "no check has completed yet"
+	case now.Sub(h.LastCheck) > staleAfter:
+		h.Status, h.Reason = "stalled", fmt.Sprintf("no check has completed for %s", formatDuration(now.Sub(h.LastCheck)))
+	}
+	return h, h.Reason == ""
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// DefaultShutdownTimeout bounds how long vivteno waits on exit for
+// in-flight checks, notifications and history writes.
+const DefaultShutdownTimeout = 10 * time.Second
+
+// pendingWork tracks commands with side effects outside the program, such
+// as notifications and history writes, so they can be completed before
+// exiting instead of being abandoned with the Bubble Tea program.
+type pendingWork struct {
+	mu   sync.Mutex
+	jobs map[*job]struct{}
+	wg   sync.WaitGroup
+}
+
+// job is a tracked command. It runs once, whether Bubble Tea or finish
+// gets to it first.
+type job struct {
+	once sync.Once
+	cmd  tea.Cmd
+	msg  tea.Msg
+}
+
+func newPendingWork() *pendingWork {
+	return &pendingWork{jobs: make(map[*job]struct{})}
+}
+
+// track returns a command running cmd that is tracked until it has run.
+// It must be called from the program's Update, before finish.
+func (p *pendingWork) track(cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+	j := &job{cmd: cmd}
+	p.mu.Lock()
+	p.jobs[j] = struct{}{}
+	p.mu.Unlock()
+	p.wg.Add(1)
+	return func() tea.Msg { return p.run(j) }
+}
+
+// run runs j, or waits for it to finish if it is already running, and
+// returns its message.
+func (p *pendingWork) run(j *job) tea.Msg {
+	j.once.Do(func() {
+		j.msg = j.cmd()
+		p.mu.Lock()
+		delete(p.jobs, j)
+		p.mu.Unlock()
+		p.wg.Done()
+	})
+	return j.msg
+}
+
+// finish runs the tracked commands the program exited before running, and
+// waits until every tracked command is done or ctx expires. It returns the
+// messages of the commands completed meanwhile and how many are still
+// running.
+func (p *pendingWork) finish(ctx context.Context) (msgs []tea.Msg, running int) {
+	p.mu.Lock()
+	jobs := make([]*job, 0, len(p.jobs))
+	for j := range p.jobs {
+		jobs = append(jobs, j)
+	}
+	p.mu.Unlock()
+
+	results := make(chan tea.Msg, len(jobs))
+	for _, j := range jobs {
+		go func() { results <- p.run(j) }()
+	}
+	for range jobs {
+		select {
+		case msg := <-results:
+			msgs = append(msgs, msg)
+		case <-ctx.Done():
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			return msgs, len(p.jobs)
+		}
+	}
+	return msgs, 0
+}
+
+// shutdown waits, up to the shutdown timeout, for the checks of the
+// stopped monitor to return and for pending notifications and history
+// writes to complete. The model's context must already be cancelled. The
+// reports of interrupted checks are dropped.
+func (m model) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), m.shutdownTimeout)
+	defer cancel()
+	start := time.Now()
+
+	if m.mon != nil {
+		drained := make(chan struct{})
+		go func() {
+			for range m.mon.Reports() {
+			}
+			close(drained)
+		}()
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			m.logger.Warn("shutdown timed out waiting for checks", "timeout", m.shutdownTimeout.String())
+		}
+	}
+
+	msgs, running := m.pending.finish(ctx)
+	for _, msg := range msgs {
+		switch msg := msg.(type) {
+		case notifyErrMsg:
+			fmt.Printf("Notification failed: %v\n", msg.err)
+		case storageErrMsg:
+			m.logger.Error("saving history failed", "error", msg.err.Error())
+			fmt.Printf("Saving history failed: %v\n", msg.err)
+		}
+	}
+	if running > 0 {
+		m.logger.Warn("shutdown timed out, abandoning pending work", "pending", running, "timeout", m.shutdownTimeout.String())
+		fmt.Printf("Shutdown timed out after %v, abandoning %d pending notifications or history writes\n", m.shutdownTimeout, running)
+		return
+	}
+	m.logger.Info("monitoring stopped", "shutdown", time.Since(start).String())
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/mooship/vivteno/pkg/monitor"
+	"github.com/mooship/vivteno/pkg/notify"
+)
+
+// SLO defaults
+const (
+	DefaultSLOWindow = 30 * 24 * time.Hour
+	// DefaultSLOBurnRate spends 2% of a 30-day budget in one hour.
+	DefaultSLOBurnRate = 14.4
+	// SLOBurnWindow is the recent period the burn rate is measured over.
+	SLOBurnWindow = time.Hour
+)
+
+// sloPolicy holds the SLO settings shared by every website.
+type sloPolicy struct {
+	// window is the period availability is measured over.
+	window time.Duration
+	// burnRate alerts when the budget is spent this many times faster
+	// than it lasts over the window; zero disables the alert.
+	burnRate float64
+}
+
+// budgetLevel orders the states of an error budget, from healthy to spent.
+type budgetLevel int
+
+const (
+	budgetOK budgetLevel = iota
+	budgetBurning
+	budgetExhausted
+)
+
+// errorBudget is the state of the SLO of one website: the downtime its
+// objective allows over the window, how much of it outages used, and how
+// fast they used it over the last SLOBurnWindow.
+type errorBudget struct {
+	objective float64
+	window    time.Duration
+	allowed   time.Duration
+	downtime  time.Duration
+	burnRate  float64
+}
+
+// errorBudget computes the budget of t from its incidents, or returns false
+// if t has no SLO. Time vivteno was not running counts as up.
+func (m model) errorBudget(t *target, now time.Time) (errorBudget, bool) {
+	if t.slo == 0 {
+		return errorBudget{}, false
+	}
+	failing := 1 - t.slo/100
+	recent := m.incidents.downtimeBetween(t.website, now.Add(-SLOBurnWindow), now)
+	return errorBudget{
+		objective: t.slo,
+		window:    m.slo.window,
+		allowed:   time.Duration(float64(m.slo.window) * failing),
+		downtime:  m.incidents.downtimeBetween(t.website, now.Add(-m.slo.window), now),
+		burnRate:  float64(recent) / (float64(SLOBurnWindow) * failing),
+	}, true
+}
+
+// remaining returns the fraction of the budget left, negative once outages
+// exceeded it.
+func (b errorBudget) remaining() float64 {
+	return 1 - float64(b.downtime)/float64(b.allowed)
+}
+
+// availability returns the percentage of the window the website was up.
+func (b errorBudget) availability() float64 {
+	return 100 * (1 - float64(b.downtime)/float64(b.window))
+}
+
+// level returns the state of the budget, burning when burnRate is reached.
+func (b errorBudget) level(burnRate float64) budgetLevel {
+	switch {
+	case b.downtime >= b.allowed:
+		return budgetExhausted
+	case burnRate > 0 && b.burnRate >= burnRate:
+		return budgetBurning
+	}
+	return budgetOK
+}
+
+// String summarises the budget for display.
+func (b errorBudget) String() string {
+	return fmt.Sprintf("%s over %s: %s available, %.0f%% of budget left (%s of %s down), burn rate %.1fx",
+		formatPercent(b.objective), formatWindow(b.window), formatPercent(b.availability()),
+		max(b.remaining(), 0)*100, formatDuration(b.downtime), formatDuration(b.allowed), b.burnRate)
+}
+
+// renderBudget renders the error budget of t, or nothing if it has no SLO.
+func renderBudget(m model, t *target) string {
+	b, ok := m.errorBudget(t, time.Now())
+	if !ok {
+		return ""
+	}
+	switch b.level(m.slo.burnRate) {
+	case budgetExhausted:
+		return warningStyle.Render("ERROR BUDGET EXHAUSTED: "+b.String()) + "\n"
+	case budgetBurning:
+		return warningStyle.Render("ERROR BUDGET BURNING: "+b.String()) + "\n"
+	}
+	return renderSection("SLO:", b.String()) + "\n"
+}
+
+// sloSnapshot is the error budget of a website in a targetSnapshot.
+type sloSnapshot struct {
+	Objective    float64 `json:"objective"`
+	Window       string  `json:"window"`
+	Availability float64 `json:"availability"`
+	// BudgetRemaining is the fraction of the error budget left, negative
+	// once it is exceeded.
+	BudgetRemaining float64 `json:"budget_remaining"`
+	BurnRate        float64 `json:"burn_rate"`
+}
+
+// budgetCmd returns a command alerting on the error budget of the website
+// in r when it starts burning too fast or runs out, or nil. Each level is
+// alerted once until the budget recovers below it.
+func (m model) budgetCmd(r monitor.Report) tea.Cmd {
+	t := m.targets[r.Index]
+	b, ok := m.errorBudget(t, r.Time)
+	if !ok {
+		return nil
+	}
+	level := b.level(m.slo.burnRate)
+	prev := t.budgetLevel
+	t.budgetLevel = level
+	if level <= prev || len(m.notifiers) == 0 || t.muted {
+		return nil
+	}
+	msg := "error budget exhausted: " + b.String()
+	if level == budgetBurning {
+		msg = "error budget burning fast: " + b.String()
+	}
+	m.logger.Warn("error budget alert", "target", t.website, "message", msg)
+	return m.deliver(notify.Event{
+		Target:      t.website,
+		State:       notify.StateBudget,
+		Time:        r.Time,
+		URL:         r.Target.URL(),
+		Error:       msg,
+		Tags:        t.tags,
+		Severity:    t.severity,
+		Description: t.description,
+		Owner:       t.owner,
+		Runbook:     t.runbook,
+	})
+}
+
+// parseSLO is the parse function for per-website SLO objectives: a
+// percentage such as "99.9" or "99.9%". An empty value or 0 disables the
+// SLO of that website.
+func parseSLO(s string) (float64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+	if err != nil || v < 0 || v >= 100 {
+		return 0, fmt.Errorf("SLO must be a percentage below 100, e.g. 99.9, got %q", s)
+	}
+	return v, nil
+}
+
+// formatPercent formats a percentage with up to three decimals.
+func formatPercent(p float64) string {
+	return strconv.FormatFloat(math.Round(p*1000)/1000, 'f', -1, 64) + "%"
+}
+
+// formatWindow formats an SLO window in days when it is a whole number of
+// days.
+func formatWindow(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	return d.String()
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mooship/vivteno/pkg/monitor"
+)
+
+// targetSnapshot is a point-in-time copy of one website's state, shared with
+// consumers outside the Bubble Tea program such as the web dashboard.
+type targetSnapshot struct {
+	Website   string `json:"website"`
+	Check     string `json:"check"`
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+	// LatencyLevel is "warning" or "critical" when the latency reached the
+	// website's threshold.
+	LatencyLevel string `json:"latency_level,omitempty"`
+	// PacketLoss is the fraction of failed probes, for websites sending
+	// several probes per check.
+	PacketLoss  *float64  `json:"packet_loss,omitempty"`
+	Uptime      float64   `json:"uptime"`
+	Checks      int       `json:"checks"`
+	LastChecked time.Time `json:"last_checked,omitzero"`
+	LastError   string    `json:"last_error,omitempty"`
+	// PingError and HealthError split LastError into the failures of the
+	// check and of the health endpoint, which are checked independently.
+	PingError   string         `json:"ping_error,omitempty"`
+	HealthError string         `json:"health_error,omitempty"`
+	Health      map[string]any `json:"health,omitempty"`
+	// Families is set for websites checked over IPv4 and IPv6 separately.
+	Families []familySnapshot `json:"families,omitempty"`
+	// Ports is set for websites checking several ports separately.
+	Ports  []portSnapshot `json:"ports,omitempty"`
+	Paused bool           `json:"paused,omitempty"`
+	// Muted is set while the website's alerts are muted, until MutedUntil
+	// if set; Acknowledged while they are muted until it recovers.
+	Muted        bool      `json:"muted,omitempty"`
+	MutedUntil   time.Time `json:"muted_until,omitzero"`
+	Acknowledged bool      `json:"acknowledged,omitempty"`
+	// Flapping is set while the website changes state too often to alert on.
+	Flapping bool `json:"flapping,omitempty"`
+	// Violations are the health assertions the last response did not meet.
+	Violations []string `json:"violations,omitempty"`
+	// Anomaly describes how far the last latency was above the baseline.
+	Anomaly string `json:"anomaly,omitempty"`
+	// Tags label the website, e.g. "prod" or "region=eu".
+	Tags []string `json:"tags,omitempty"`
+	// Headers and HealthHeaders are the captured response headers of the
+	// last check and health endpoint request.
+	Headers       map[string]string `json:"headers,omitempty"`
+	HealthHeaders map[string]string `json:"health_headers,omitempty"`
+	// SLO is set for websites with an availability objective.
+	SLO *sloSnapshot `json:"slo,omitempty"`
+	// Locations are the latest results of the agents checking the website.
+	Locations []locationSnapshot `json:"locations,omitempty"`
+}
+
+// familySnapshot is the latest result of one IP family.
+type familySnapshot struct {
+	Family    string `json:"family"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// portSnapshot is the latest result of one port.
+type portSnapshot struct {
+	Port      string `json:"port"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// locationSnapshot is the latest result an agent reported from a location.
+type locationSnapshot struct {
+	Location  string    `json:"location"`
+	Status    string    `json:"status"`
+	LatencyMs int64     `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+	Checked   time.Time `json:"last_checked"`
+	Received  time.Time `json:"received"`
+}
+
+// statusStore holds the latest snapshot of every website. The model
+// publishes to it after each report; readers may call get concurrently.
+type statusStore struct {
+	mu          sync.RWMutex
+	targets     []targetSnapshot
+	updated     time.Time
+	healthOrder []string
+	timezone    *time.Location
+	// started is when vivteno started and interval is how often websites
+	// are checked, which /readyz judges the last check against.
+	started  time.Time
+	interval time.Duration
+}
+
+func (s *statusStore) set(targets []targetSnapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.targets = targets
+	s.updated = time.Now()
+}
+
+func (s *statusStore) get() ([]targetSnapshot, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.targets, s.updated
+}
+
+// setDisplay stores the settings readers use to present the snapshots.
+func (s *statusStore) setDisplay(healthOrder []string, tz *time.Location) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.healthOrder = healthOrder
+	s.timezone = tz
+}
+
+func (s *statusStore) display() ([]string, *time.Location) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.healthOrder, s.timezone
+}
+
+// setInterval stores the check interval of the configuration.
+func (s *statusStore) setInterval(interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.interval = interval
+}
+
+// snapshot copies the current state of every website. Health data is
+// reduced to the configured fields, if any.
+func (m model) snapshot() []targetSnapshot {
+	out := make([]targetSnapshot, len(m.targets))
+	now := time.Now()
+	for i, t := range m.targets {
+		s := targetSnapshot{
+			Website:       t.website,
+			Check:         t.checkType,
+			Status:        t.state.String(),
+			Checks:        t.checks,
+			Uptime:        t.uptime(),
+			LastChecked:   t.lastChecked,
+			LastError:     t.lastError,
+			PingError:     t.pingError,
+			HealthError:   t.healthError,
+			Health:        t.health,
+			Paused:        t.paused,
+			Muted:         t.muted,
+			MutedUntil:    t.muteExpires,
+			Acknowledged:  t.acknowledged,
+			Flapping:      t.flapping,
+			Violations:    t.violations,
+			Anomaly:       t.anomaly,
+			Tags:          t.tags,
+			Headers:       t.headers,
+			HealthHeaders: t.healthHeaders,
+		}
+		if t.lastPing != "" {
+			s.LatencyMs = t.lastLatency.Milliseconds()
+			s.LatencyLevel = t.latency.level(t.lastLatency)
+		}
+		if t.probes != nil {
+			loss := t.probes.Loss()
+			s.PacketLoss = &loss
+		}
+		if b, ok := m.errorBudget(t, now); ok {
+			s.SLO = &sloSnapshot{
+				Objective:       b.objective,
+				Window:          formatWindow(b.window),
+				Availability:    b.availability(),
+				BudgetRemaining: b.remaining(),
+				BurnRate:        b.burnRate,
+			}
+		}
+		for _, f := range t.families {
+			fs := familySnapshot{Family: monitor.FamilyLabel(f.Family), LatencyMs: f.Latency.Milliseconds()}
+			if f.Err != nil {
+				fs = familySnapshot{Family: fs.Family, Error: resultError(f.Result)}
+			}
+			s.Families = append(s.Families, fs)
+		}
+		for _, p := range t.ports {
+			ps := portSnapshot{Port: p.Port, LatencyMs: p.Latency.Milliseconds()}
+			if p.Err != nil {
+				ps = portSnapshot{Port: p.Port, Error: resultError(p.Result)}
+			}
+			s.Ports = append(s.Ports, ps)
+		}
+		for _, name := range slices.Sorted(maps.Keys(t.locations)) {
+			r := t.locations[name]
+			s.Locations = append(s.Locations, locationSnapshot{
+				Location:  name,
+				Status:    r.status(now).String(),
+				LatencyMs: r.LatencyMs,
+				Error:     r.Error,
+				Checked:   r.Time,
+				Received:  r.received,
+			})
+		}
+		if len(t.healthFields) > 0 && s.Health != nil {
+			fields := make(map[string]any, len(t.healthFields))
+			for _, p := range t.healthFields {
+				if v, err := p.Lookup(s.Health); err == nil {
+					fields[p.Label()] = v
+				}
+			}
+			s.Health = fields
+		}
+		out[i] = s
+	}
+	return out
+}
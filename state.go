@@ -0,0 +1,293 @@
+package main
+
+import (
+	"time"
+
+	"github.com/mooship/vivteno/pkg/monitor"
+)
+
+// status is the health state of a single website.
+type status int
+
+const (
+	statusUnknown status = iota
+	statusUp
+	statusDegraded
+	statusDown
+	statusMaintenance
+	statusSlow
+)
+
+// String returns the lower-case name of the status.
+func (s status) String() string {
+	switch s {
+	case statusUp:
+		return "up"
+	case statusDegraded:
+		return "degraded"
+	case statusDown:
+		return "down"
+	case statusMaintenance:
+		return "maintenance"
+	case statusSlow:
+		return "slow"
+	}
+	return "unknown"
+}
+
+// target is the configuration and check state of one website.
+type target struct {
+	website        string
+	healthEndpoint string
+	checkType      string
+	healthFields   []monitor.Path
+	// healthWatch are the health fields whose changes are logged, and
+	// alerted on with watchAlert.
+	healthWatch []monitor.Path
+	watchAlert  bool
+	// captureHeaders are the response headers shown in the detail view.
+	captureHeaders []string
+	maintenance    []monitor.Window
+	latency        latencyThresholds
+	// domain is the registrable domain whose expiry is monitored, or empty.
+	domain string
+	// expiryDays is how many days before expiry domain is warned about.
+	expiryDays int
+	// tags label the website, e.g. "prod" or "region=eu".
+	tags []string
+	// pushURL receives the outcome of every check, or is empty.
+	pushURL string
+	// slo is the availability objective in percent, or zero for none.
+	slo float64
+	// traceroute is the protocol of the traceroute run when the website
+	// goes down, or empty for none.
+	traceroute string
+	// description, owner and runbook tell whoever handles an alert what the
+	// website is, who is responsible for it and how to fix it.
+	description string
+	owner       string
+	runbook     string
+	// severity is how severe its alerts are, one of the notify.Severity
+	// constants, or empty when unset.
+	severity string
+
+	checkState
+}
+
+// latencyThresholds color latencies and decide when a website is slow. Zero
+// durations are disabled.
+type latencyThresholds struct {
+	warning  time.Duration
+	critical time.Duration
+	// alert counts checks at or above critical as failures, so sustained
+	// slowness degrades the website and alerts like an outage.
+	alert bool
+	// anomaly is the number of standard deviations above the baseline from
+	// which a latency degrades the website; zero disables it.
+	// anomalyAlert counts such checks as failures.
+	anomaly      float64
+	anomalyAlert bool
+}
+
+// slow reports whether a successful check took critically long.
+func (l latencyThresholds) slow(d time.Duration) bool {
+	return l.critical > 0 && d >= l.critical
+}
+
+// level names the highest threshold d reached: "critical", "warning" or "".
+func (l latencyThresholds) level(d time.Duration) string {
+	switch {
+	case l.slow(d):
+		return "critical"
+	case l.warning > 0 && d >= l.warning:
+		return "warning"
+	}
+	return ""
+}
+
+// checkState is what vivteno has learned about a website from its checks.
+// It survives config reloads.
+type checkState struct {
+	// state is only changed by record, which implements the transitions:
+	// a success moves to up, to slow when it took critically long, or to
+	// degraded when the health response violated an assertion; a
+	// failure moves to degraded, and to down once failureThreshold
+	// consecutive checks failed; a failure inside a maintenance window moves
+	// to maintenance without being counted.
+	state status
+	// resume is the state before the current maintenance period, used as
+	// the starting point of the next counted check.
+	resume status
+
+	lastPing  string
+	lastError string
+	// pingError and healthError are the failures of the last check and
+	// health endpoint request, which are checked independently.
+	pingError    string
+	healthError  string
+	health       map[string]any
+	healthTiming *monitor.Timing
+	// lastHealth is the last successful health response, kept through
+	// failures to compare the next one with, and healthChanges are the
+	// fields that recently changed.
+	lastHealth    map[string]any
+	healthChanges healthChanges
+	// headers and healthHeaders are the captured response headers of the
+	// last check and health endpoint request.
+	headers        map[string]string
+	healthHeaders  map[string]string
+	families       []monitor.FamilyResult
+	ports          []monitor.PortResult
+	violations     []string
+	probes         *monitor.ProbeStats
+	tlsInfo        *monitor.TLSInfo
+	lastChecked    time.Time
+	lastLatency    time.Duration
+	latencyHistory []time.Duration
+	// baseline holds the recent latencies anomalies are detected against,
+	// and anomaly describes the last check's anomaly, if any.
+	baseline  []time.Duration
+	anomaly   string
+	failures  int
+	checks    int
+	successes int
+	// alerts are what each notifier was last told about the website.
+	alerts map[string]alertState
+	// notified is the state of the last notification: down, or up for
+	// recoveries.
+	notified status
+	// stateChanges are the times of recent state changes, within the flap
+	// window; flapping is set while there are too many of them.
+	stateChanges []time.Time
+	flapping     bool
+	history      *ring[historyEntry]
+	domainExpiry time.Time
+	domainError  string
+	// paused targets are not checked and keep their last state; a report
+	// that was already in flight when they were paused is dropped.
+	paused bool
+	// muted targets are checked but send no alerts, until muteExpires if
+	// set, or until they recover when they were muted while down
+	// (acknowledged).
+	muted        bool
+	muteExpires  time.Time
+	acknowledged bool
+	// budgetLevel is the state of the error budget last alerted on.
+	budgetLevel budgetLevel
+	// hostKey is the SSH host key fingerprint first seen by the ssh check
+	// of a website without a configured fingerprint.
+	hostKey string
+	// locations are the latest results of the agents checking the website
+	// from elsewhere, by location.
+	locations map[string]locationResult
+	// trace is the last traceroute, run when the website went down.
+	trace *traceResult
+}
+
+// newTargets creates the targets of a configuration with no check state.
+func newTargets(cfg config) []*target {
+	targets := make([]*target, len(cfg.websites))
+	for i, w := range cfg.websites {
+		targets[i] = &target{
+			website:        w,
+			healthEndpoint: cfg.healthEndpoints[i],
+			checkType:      cfg.checkTypes[i],
+			healthFields:   cfg.healthFields[i],
+			healthWatch:    cfg.healthWatch[i],
+			watchAlert:     cfg.watchAlerts[i],
+			captureHeaders: cfg.captureHeaders[i],
+			maintenance:    cfg.maintenance[i],
+			latency:        cfg.latency[i],
+			domain:         cfg.domains[i],
+			expiryDays:     cfg.domainExpiry[i],
+			tags:           cfg.tags[i],
+			pushURL:        cfg.pushURLs[i],
+			slo:            cfg.slos[i],
+			traceroute:     cfg.traceroutes[i],
+			description:    cfg.descriptions[i],
+			owner:          cfg.owners[i],
+			runbook:        cfg.runbooks[i],
+			severity:       cfg.severities[i],
+			checkState:     checkState{history: newRing[historyEntry](cfg.historySize)},
+		}
+	}
+	return targets
+}
+
+// record applies the outcome of a check cycle and returns the state before
+// and after it. The state before a maintenance period stands in for
+// maintenance, so an outage that spans a maintenance window is not
+// reported twice. up is the state a successful check moves to.
+func (t *target) record(ok bool, up status, inMaintenance bool, threshold int) (prev, cur status) {
+	prev = t.state
+	if prev == statusMaintenance {
+		prev = t.resume
+	}
+	if inMaintenance && !ok {
+		if t.state != statusMaintenance {
+			t.resume = t.state
+			t.state = statusMaintenance
+		}
+		return prev, prev
+	}
+	t.checks++
+	if ok {
+		t.successes++
+		t.failures = 0
+		t.state = up
+	} else {
+		t.failures++
+		t.state = statusDegraded
+		if t.failures >= threshold {
+			t.state = statusDown
+		}
+	}
+	return prev, t.state
+}
+
+// pinHostKey trusts the first SSH host key a website presents and turns a
+// later check that sees another key into a failure, like ssh does with
+// known_hosts. The pin survives configuration reloads but not restarts.
+func (t *target) pinHostKey(r *monitor.Result) {
+	fingerprint, _ := r.Data["fingerprint"].(string)
+	if r.Err != nil || t.checkType != monitor.CheckSSH || fingerprint == "" {
+		return
+	}
+	if t.hostKey == "" {
+		t.hostKey = fingerprint
+		return
+	}
+	if fingerprint != t.hostKey {
+		r.Err = &monitor.HostKeyError{Fingerprint: fingerprint, Expected: t.hostKey}
+	}
+}
+
+// lastFailure returns the error of the most recent failed check in the
+// history, or "" if there is none.
+func (t *target) lastFailure() string {
+	history := t.history.all()
+	for j := len(history) - 1; j >= 0; j-- {
+		if !history[j].OK {
+			return history[j].Error
+		}
+	}
+	return ""
+}
+
+// recordLatency keeps the most recent SparklineSamples latencies.
+func (t *target) recordLatency(d time.Duration) {
+	h := append(t.latencyHistory, d)
+	if len(h) > SparklineSamples {
+		h = h[len(h)-SparklineSamples:]
+	}
+	t.latencyHistory = h
+}
+
+// uptime returns the percentage of successful check cycles, or -1 if the
+// website has not been checked yet.
+func (t *target) uptime() float64 {
+	if t.checks == 0 {
+		return -1
+	}
+	return float64(t.successes) / float64(t.checks) * 100
+}
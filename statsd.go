@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mooship/vivteno/pkg/monitor"
+)
+
+// statsd output settings
+const (
+	DefaultStatsdPrefix = "vivteno"
+	// Formats of metrics: DogStatsD tags, or tags folded into the names of
+	// plain statsd metrics.
+	StatsdFormatDatadog = "datadog"
+	StatsdFormatPlain   = "statsd"
+)
+
+// statsdUnsafe matches what plain statsd metric names cannot contain, and
+// statsdTagUnsafe what DogStatsD tags cannot.
+var (
+	statsdUnsafe    = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+	statsdTagUnsafe = strings.NewReplacer(",", "_", "|", "_", "#", "_", "\n", " ")
+)
+
+// statsdClient sends the latency and state of every check cycle as statsd
+// metrics over UDP, one packet per cycle.
+type statsdClient struct {
+	conn   net.Conn
+	prefix string
+	format string
+	// tags are added to the tags of every website.
+	tags   []string
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	failing bool
+}
+
+// loadStatsd reads the STATSD_* settings. It returns nil when STATSD_ADDR is
+// not set.
+func loadStatsd(logger *slog.Logger) (*statsdClient, error) {
+	addr := os.Getenv("STATSD_ADDR")
+	if addr == "" {
+		return nil, nil
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, fmt.Errorf("invalid STATSD_ADDR: %q (expected host:port, e.g. localhost:8125)", addr)
+	}
+	c := &statsdClient{prefix: DefaultStatsdPrefix, format: StatsdFormatDatadog, logger: logger}
+	if v, ok := os.LookupEnv("STATSD_PREFIX"); ok {
+		c.prefix = strings.TrimSuffix(v, ".")
+	}
+	switch v := os.Getenv("STATSD_FORMAT"); v {
+	case "", StatsdFormatDatadog:
+	case StatsdFormatPlain:
+		c.format = StatsdFormatPlain
+	default:
+		return nil, fmt.Errorf("invalid STATSD_FORMAT: %q (expected datadog or statsd)", v)
+	}
+	tags, err := parseTags(os.Getenv("STATSD_TAGS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid STATSD_TAGS: %w", err)
+	}
+	if len(tags) > 0 && c.format == StatsdFormatPlain {
+		return nil, fmt.Errorf("invalid STATSD_TAGS: plain statsd has no tags, set STATSD_FORMAT=datadog")
+	}
+	for _, tag := range tags {
+		c.tags = append(c.tags, statsdTag(tag))
+	}
+	// The address is resolved once, so a missing agent does not fail every
+	// check
+	if c.conn, err = net.Dial("udp", addr); err != nil {
+		return nil, fmt.Errorf("invalid STATSD_ADDR: %w", err)
+	}
+	return c, nil
+}
+
+// record sends the metrics of a check cycle of t: whether it succeeded, its
+// latency if it did and the packet loss of its probes, tagged with the
+// website, its check type and the state it led to, and its own tags.
+func (c *statsdClient) record(t *target, r monitor.Report, ok bool) {
+	up := 0
+	if ok {
+		up = 1
+	}
+	lines := []string{c.metric("check.up", strconv.Itoa(up), "g", t)}
+	if r.Ping.Err == nil {
+		ms := strconv.FormatFloat(float64(r.Ping.Latency)/float64(time.Millisecond), 'f', -1, 64)
+		lines = append(lines, c.metric("check.latency", ms, "ms", t))
+	}
+	if r.Ping.Probes != nil {
+		lines = append(lines, c.metric("check.packet_loss", strconv.FormatFloat(r.Ping.Probes.Loss(), 'f', -1, 64), "g", t))
+	}
+	_, err := c.conn.Write([]byte(strings.Join(lines, "\n")))
+
+	// Only the first of consecutive failures is logged, as they repeat on
+	// every check while the agent is unreachable
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil && !c.failing {
+		c.logger.Error("sending statsd metrics failed", "error", err.Error())
+	} else if err == nil && c.failing {
+		c.logger.Info("sending statsd metrics recovered")
+	}
+	c.failing = err != nil
+}
+
+// metric formats a metric of t: with DogStatsD tags, or with the website
+// and check type in its name for plain statsd, e.g.
+// "vivteno.example_com.tcp.check.latency".
+func (c *statsdClient) metric(name, value, kind string, t *target) string {
+	if c.format == StatsdFormatPlain {
+		parts := []string{statsdUnsafe.ReplaceAllString(t.website, "_"), t.checkType, name}
+		if c.prefix != "" {
+			parts = append([]string{c.prefix}, parts...)
+		}
+		return strings.Join(parts, ".") + ":" + value + "|" + kind
+	}
+	if c.prefix != "" {
+		name = c.prefix + "." + name
+	}
+	tags := append([]string{"target:" + t.website, "check:" + t.checkType, "status:" + t.state.String()}, c.tags...)
+	for _, tag := range t.tags {
+		tags = append(tags, statsdTag(tag))
+	}
+	for i, tag := range tags {
+		tags[i] = statsdTagUnsafe.Replace(tag)
+	}
+	return name + ":" + value + "|" + kind + "|#" + strings.Join(tags, ",")
+}
+
+// statsdTag converts a vivteno tag to a DogStatsD one: "region=eu" becomes
+// "region:eu".
+func statsdTag(tag string) string {
+	return strings.Replace(tag, "=", ":", 1)
+}
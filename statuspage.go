@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Defaults for the statuspage subcommand
+const (
+	StatusPageDir   = "statuspage"
+	StatusPageTitle = "Service Status"
+	// Number of incidents listed on the status page
+	StatusPageIncidents = 10
+	// Timestamps on the status page, in TIMEZONE or UTC
+	StatusPageTimeFormat = "2006-01-02 15:04 MST"
+)
+
+// statusPage is the public view of the current status: no errors, health
+// data or other internals.
+type statusPage struct {
+	Title     string               `json:"title"`
+	Generated time.Time            `json:"generated"`
+	Status    string               `json:"status"`
+	Summary   string               `json:"summary"`
+	Targets   []statusPageTarget   `json:"targets"`
+	Incidents []statusPageIncident `json:"incidents"`
+}
+
+// statusPageTarget is the status of one website with its recent checks,
+// oldest first.
+type statusPageTarget struct {
+	Website     string            `json:"website"`
+	Status      string            `json:"status"`
+	Uptime      *float64          `json:"uptime,omitempty"`
+	LatencyMs   int64             `json:"latency_ms,omitempty"`
+	LastChecked time.Time         `json:"last_checked,omitzero"`
+	History     []statusPageCheck `json:"history"`
+}
+
+type statusPageCheck struct {
+	Time time.Time `json:"time"`
+	OK   bool      `json:"ok"`
+}
+
+// statusPageIncident is an outage without its error message.
+type statusPageIncident struct {
+	Website         string    `json:"website"`
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end,omitzero"`
+	DurationSeconds float64   `json:"duration_seconds"`
+}
+
+var statusPageTemplate = template.Must(template.New("statuspage").Funcs(template.FuncMap{
+	"time": func(t time.Time, tz *time.Location) string {
+		if tz == nil {
+			tz = time.UTC
+		}
+		return t.In(tz).Format(StatusPageTimeFormat)
+	},
+	"uptime": func(u *float64) string {
+		if u == nil {
+			return "-"
+		}
+		return fmt.Sprintf("%.2f%%", *u)
+	},
+	"duration": func(s float64) string { return formatDuration(time.Duration(s) * time.Second) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Page.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; background: #f6f7f9; color: #222; margin: 0 auto; max-width: 48rem; padding: 2rem 1rem; }
+h1 { font-size: 1.6rem; }
+.banner { padding: 1rem; border-radius: .5rem; color: #fff; font-weight: bold; margin-bottom: 1.5rem; }
+.site { background: #fff; border-radius: .5rem; padding: 1rem; margin-bottom: .8rem; }
+.site-head { display: flex; justify-content: space-between; }
+.bars { display: flex; gap: 2px; margin-top: .6rem; }
+.bars span { flex: 1; height: 1.6rem; border-radius: 2px; }
+.meta { color: #777; font-size: .85rem; margin-top: .4rem; }
+.up { background: #2e9e4f; } .slow, .degraded { background: #d9a21b; } .down { background: #d64545; } .maintenance { background: #3b7dd8; } .unknown { background: #999; }
+.label.up { color: #2e9e4f; } .label.slow, .label.degraded { color: #d9a21b; } .label.down { color: #d64545; } .label.maintenance { color: #3b7dd8; } .label.unknown { color: #999; }
+.label { background: none; font-weight: bold; }
+ul { padding-left: 1.2rem; }
+footer { color: #777; font-size: .85rem; margin-top: 2rem; }
+</style>
+</head>
+<body>
+<h1>{{.Page.Title}}</h1>
+<div class="banner {{.Page.Status}}">{{.Page.Summary}}</div>
+{{range .Page.Targets}}<div class="site">
+<div class="site-head"><strong>{{.Website}}</strong><span class="label {{.Status}}">{{.Status}}</span></div>
+<div class="bars">{{range .History}}<span class="{{if .OK}}up{{else}}down{{end}}" title="{{time .Time $.TZ}}"></span>{{end}}</div>
+<div class="meta">Uptime {{uptime .Uptime}}{{if not .LastChecked.IsZero}} &middot; last checked {{time .LastChecked $.TZ}}{{end}}</div>
+</div>
+{{end}}
+<h2>Recent incidents</h2>
+{{if .Page.Incidents}}<ul>
+{{range .Page.Incidents}}<li><strong>{{.Website}}</strong>: {{time .Start $.TZ}} &ndash; {{if .End.IsZero}}ongoing{{else}}{{time .End $.TZ}}{{end}} ({{duration .DurationSeconds}})</li>
+{{end}}</ul>{{else}}<p>No incidents reported.</p>{{end}}
+<footer>Updated {{time .Page.Generated $.TZ}} &middot; <a href="status.json">JSON</a></footer>
+</body>
+</html>
+`))
+
+// statusPage collects the public status of every website.
+func (m model) statusPage(title string, now time.Time) statusPage {
+	overall := m.overall()
+	p := statusPage{Title: title, Generated: now, Status: overall.String(), Summary: statusPageSummary(overall, m.targets)}
+	for _, t := range m.targets {
+		pt := statusPageTarget{Website: t.website, Status: t.state.String(), LastChecked: t.lastChecked, History: []statusPageCheck{}}
+		if u := t.uptime(); u >= 0 {
+			pt.Uptime = &u
+		}
+		if t.lastPing != "" {
+			pt.LatencyMs = t.lastLatency.Milliseconds()
+		}
+		for _, h := range t.history.all() {
+			pt.History = append(pt.History, statusPageCheck{Time: h.Time, OK: h.OK})
+		}
+		p.Targets = append(p.Targets, pt)
+	}
+	p.Incidents = []statusPageIncident{}
+	for _, inc := range m.incidents.recent("", StatusPageIncidents) {
+		if m.target(inc.Target) == nil {
+			continue
+		}
+		p.Incidents = append(p.Incidents, statusPageIncident{
+			Website:         inc.Target,
+			Start:           inc.Start,
+			End:             inc.End,
+			DurationSeconds: inc.duration(now).Round(time.Second).Seconds(),
+		})
+	}
+	return p
+}
+
+// statusPageSummary describes the overall status in the page banner.
+func statusPageSummary(overall status, targets []*target) string {
+	switch overall {
+	case statusUp:
+		return "All systems operational"
+	case statusDegraded, statusSlow:
+		return "Degraded performance"
+	case statusMaintenance:
+		return "Scheduled maintenance"
+	case statusDown:
+		for _, t := range targets {
+			if t.state != statusDown {
+				return "Partial outage"
+			}
+		}
+		return "Major outage"
+	}
+	return "Status unknown"
+}
+
+// writeStatusPage writes index.html and status.json to dir, with times in tz.
+// Each file is replaced atomically so an upload never picks up a partial
+// page.
+func writeStatusPage(p statusPage, dir string, tz *time.Location) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(dir, "status.json"), data, 0o644); err != nil {
+		return err
+	}
+	var html strings.Builder
+	if err := statusPageTemplate.Execute(&html, map[string]any{"Page": p, "TZ": tz}); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, "index.html"), []byte(html.String()), 0o644)
+}
+
+// runCmds runs cmd, and every command it batches, to completion and returns
+// the messages they produced. It lets the statuspage subcommand reuse the
+// model's notifications and history writes without a Bubble Tea program.
+func runCmds(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	switch msg := cmd().(type) {
+	case nil:
+		return nil
+	case tea.BatchMsg:
+		var out []tea.Msg
+		for _, c := range msg {
+			out = append(out, runCmds(c)...)
+		}
+		return out
+	default:
+		return []tea.Msg{msg}
+	}
+}
+
+// runStatusPage implements the statuspage subcommand: it monitors the
+// configured websites without the terminal UI and rewrites the status page
+// after every check until interrupted. It returns the process exit code.
+func runStatusPage(args []string) int {
+	fs := flag.NewFlagSet("statuspage", flag.ContinueOnError)
+	output := fs.String("output", StatusPageDir, "directory the status page is written to")
+	title := fs.String("title", StatusPageTitle, "title of the status page")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	loadEnv()
+	logger, logFile, err := loadLogger()
+	if err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		return 1
+	}
+	defer logFile.Close()
+	tel, err := loadTelemetry(logger)
+	if err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		return 1
+	}
+	influx, err := loadInflux(logger)
+	if err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		return 1
+	}
+	mqtt, err := loadMQTT(logger)
+	if err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		return 1
+	}
+	_, discovered, err := startDiscovery()
+	if err != nil {
+		fmt.Printf("Discovery failed: %v\n", err)
+		return 1
+	}
+	cfg, err := loadConfig(discovered)
+	if err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		return 1
+	}
+	incidents, err := loadIncidents(cfg.historyFile)
+	if err != nil {
+		fmt.Printf("Invalid HISTORY_FILE: %v\n", err)
+		return 1
+	}
+	if err := os.MkdirAll(*output, 0o755); err != nil {
+		fmt.Printf("Invalid --output: %v\n", err)
+		return 1
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	m := initialModel(cfg, ctx, cancel)
+	m.incidents = incidents
+	m.logger = logger
+	m.telemetry = tel
+	m.influx = influx
+	m.mqtt = mqtt
+	wait := runExporters(ctx, m.exporters())
+	defer func() {
+		cancel()
+		wait()
+	}()
+	m.logger.Info("monitoring started", "targets", len(cfg.websites))
+	if err := writeStatusPage(m.statusPage(*title, time.Now()), *output, cfg.timezone); err != nil {
+		fmt.Printf("Writing the status page failed: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Writing the status page to %s\n", filepath.Join(*output, "index.html"))
+
+	m = m.startMonitor(cfg.newMonitor())
+	for r := range m.mon.Reports() {
+		for _, msg := range runCmds(m.applyReport(r)) {
+			switch msg := msg.(type) {
+			case notifyErrMsg:
+				fmt.Printf("Notification failed: %v\n", msg.err)
+			case storageErrMsg:
+				m.logger.Error("saving history failed", "error", msg.err.Error())
+				fmt.Printf("Saving history failed: %v\n", msg.err)
+			}
+		}
+		if err := writeStatusPage(m.statusPage(*title, time.Now()), *output, cfg.timezone); err != nil {
+			m.logger.Error("writing the status page failed", "error", err.Error())
+			fmt.Printf("Writing the status page failed: %v\n", err)
+		}
+	}
+	return 0
+}
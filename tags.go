@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mooship/vivteno/pkg/notify"
+)
+
+// untaggedGroup holds the websites without the grouping tag. The
+// parentheses keep it apart from any valid tag.
+const untaggedGroup = "(untagged)"
+
+// Glyphs in front of expanded and collapsed group headers
+const (
+	glyphExpanded  = "▾"
+	glyphCollapsed = "▸"
+)
+
+var (
+	tagKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_./-]+$`)
+	tagPattern    = regexp.MustCompile(`^[A-Za-z0-9_./-]+(=[A-Za-z0-9_./:-]+)?$`)
+)
+
+// notifierNames are the notifiers NOTIFY_TAGS can restrict.
+var notifierNames = []string{"desktop", "telegram", "matrix", "teams", "ntfy", "gotify", "pagerduty", "opsgenie", "exec", "smtp"}
+
+// parseTags is the parse function for per-website tags: a comma-separated
+// list of names ("prod") and key=value pairs ("region=eu").
+func parseTags(s string) ([]string, error) {
+	tags := parseList(s)
+	for _, tag := range tags {
+		if !tagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag %q, expected name or key=value", tag)
+		}
+	}
+	return tags, nil
+}
+
+// routeByTags restricts notifiers to websites with matching tags. value is
+// a JSON object mapping notifier names to comma-separated tag filters, e.g.
+// {"pagerduty": "prod", "telegram": "prod,staging"}; notifiers it does not
+// name receive every event.
+func routeByTags(notifiers []notify.Notifier, value string) ([]notify.Notifier, error) {
+	if value == "" {
+		return notifiers, nil
+	}
+	var routes map[string]string
+	if err := json.Unmarshal([]byte(value), &routes); err != nil {
+		return nil, fmt.Errorf("must be a JSON object of notifier names and tags, e.g. {\"pagerduty\": \"prod\"}")
+	}
+	filters := make(map[string][]string, len(routes))
+	for name, tags := range routes {
+		if !slices.Contains(notifierNames, name) {
+			return nil, fmt.Errorf("unknown notifier %q (expected %s)", name, strings.Join(notifierNames, ", "))
+		}
+		if filters[name], _ = parseTags(tags); len(filters[name]) == 0 {
+			return nil, fmt.Errorf("%s: expected tags, e.g. prod or region=eu, got %q", name, tags)
+		}
+	}
+	out := make([]notify.Notifier, len(notifiers))
+	for i, n := range notifiers {
+		out[i] = n
+		if f, ok := filters[n.Name()]; ok {
+			out[i] = notify.ForTags(n, f)
+		}
+	}
+	return out, nil
+}
+
+// group is a section of the website list: the websites sharing a tag, in
+// configuration order.
+type group struct {
+	name    string
+	targets []int
+}
+
+// groupName returns the section t is listed in: the value of its groupBy
+// tag, or its first tag when groupBy is empty.
+func (m model) groupName(t *target) string {
+	if m.groupBy == "" {
+		if len(t.tags) > 0 {
+			return t.tags[0]
+		}
+		return untaggedGroup
+	}
+	for _, tag := range t.tags {
+		if tag == m.groupBy || strings.HasPrefix(tag, m.groupBy+"=") {
+			return tag
+		}
+	}
+	return untaggedGroup
+}
+
+// groups returns the sections of the website list in order of their first
+// website. Without grouping, all websites are in one unnamed section.
+func (m model) groups() []group {
+	if !m.grouped {
+		all := make([]int, len(m.targets))
+		for i := range all {
+			all[i] = i
+		}
+		return []group{{targets: all}}
+	}
+	var groups []group
+	for i, t := range m.targets {
+		name := m.groupName(t)
+		j := slices.IndexFunc(groups, func(g group) bool { return g.name == name })
+		if j < 0 {
+			j = len(groups)
+			groups = append(groups, group{name: name})
+		}
+		groups[j].targets = append(groups[j].targets, i)
+	}
+	return groups
+}
+
+// isCollapsed reports whether only the header of g is shown.
+func (m model) isCollapsed(g group) bool {
+	return g.name != "" && m.collapsed[g.name]
+}
+
+// rows returns the websites the cursor can move between, in display order.
+// A collapsed section is represented by its first website.
+func (m model) rows() []int {
+	var rows []int
+	for _, g := range m.groups() {
+		if m.isCollapsed(g) {
+			rows = append(rows, g.targets[0])
+		} else {
+			rows = append(rows, g.targets...)
+		}
+	}
+	return rows
+}
+
+// selectedGroup returns the section of the selected website.
+func (m model) selectedGroup() group {
+	return m.groupOf(m.selected)
+}
+
+// groupOf returns the section of the website at i.
+func (m model) groupOf(i int) group {
+	groups := m.groups()
+	for _, g := range groups {
+		if slices.Contains(g.targets, i) {
+			return g
+		}
+	}
+	return groups[0]
+}
+
+// move moves the cursor by delta rows, stopping at the first and last.
+func (m model) move(delta int) model {
+	rows := m.rows()
+	sel := m.selected
+	if g := m.selectedGroup(); m.isCollapsed(g) {
+		sel = g.targets[0]
+	}
+	pos := slices.Index(rows, sel) + delta
+	m.selected = rows[max(0, min(pos, len(rows)-1))]
+	return m
+}
+
+// setCollapsed collapses or expands the section g. Collapsing it moves the
+// cursor to its header if a website inside was selected.
+func (m model) setCollapsed(g group, collapsed bool) model {
+	if g.name == "" {
+		return m
+	}
+	m.collapsed[g.name] = collapsed
+	if collapsed && slices.Contains(g.targets, m.selected) {
+		m.selected = g.targets[0]
+	}
+	return m
+}
+
+// renderGroupHeader renders the header line of a section: its name, the
+// number of websites and how many are in each state other than up.
+func renderGroupHeader(m model, g group) string {
+	glyph := glyphExpanded
+	if m.isCollapsed(g) {
+		glyph = glyphCollapsed
+	}
+	counts := make(map[status]int)
+	for _, i := range g.targets {
+		counts[m.targets[i].state]++
+	}
+	sites := "sites"
+	if len(g.targets) == 1 {
+		sites = "site"
+	}
+	parts := []string{fmt.Sprintf("%d %s", len(g.targets), sites)}
+	worst := statusUp
+	for _, st := range summaryOrder {
+		if counts[st] == 0 {
+			continue
+		}
+		if worst == statusUp {
+			worst = st
+		}
+		if st != statusUp {
+			parts = append(parts, statusStyle(st).Render(fmt.Sprintf("%d %s", counts[st], st)))
+		}
+	}
+	selected := m.isCollapsed(g) && slices.Contains(g.targets, m.selected)
+	return cursor(selected) + statusGlyph(worst) + " " + sectionTitle.Render(glyph+" "+g.name) + " " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mooship/vivteno/pkg/monitor"
+)
+
+// OpenTelemetry export settings
+const (
+	DefaultTelemetryInterval = time.Minute
+	DefaultServiceName       = "vivteno"
+	// TelemetryMaxSpans bounds the spans buffered between exports; the oldest
+	// are dropped first.
+	TelemetryMaxSpans = 2048
+	telemetryTimeout  = 10 * time.Second
+)
+
+// latencyBounds are the upper bounds, in milliseconds, of the check duration
+// histogram buckets.
+var latencyBounds = []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// telemetry exports check metrics and, optionally, one span per check to an
+// OpenTelemetry collector using OTLP over HTTP with JSON encoding.
+type telemetry struct {
+	endpoint string
+	headers  map[string]string
+	service  string
+	interval time.Duration
+	traces   bool
+	client   *http.Client
+	logger   *slog.Logger
+
+	mu     sync.Mutex
+	series map[string]*targetSeries
+	spans  []map[string]any
+}
+
+// targetSeries accumulates the metrics of one website since start.
+type targetSeries struct {
+	start    time.Time
+	check    string
+	up       bool
+	checks   int64
+	failures int64
+	count    uint64
+	sum      float64
+	min, max float64
+	buckets  []uint64
+}
+
+// loadTelemetry reads the standard OTEL_* settings. It returns nil when
+// OTEL_EXPORTER_OTLP_ENDPOINT is not set.
+func loadTelemetry(logger *slog.Logger) (*telemetry, error) {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	if endpoint == "" {
+		return nil, nil
+	}
+	if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTEL_EXPORTER_OTLP_ENDPOINT: %q", endpoint)
+	}
+	t := &telemetry{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		headers:  make(map[string]string),
+		service:  DefaultServiceName,
+		interval: DefaultTelemetryInterval,
+		client:   &http.Client{Timeout: telemetryTimeout},
+		logger:   logger,
+		series:   make(map[string]*targetSeries),
+	}
+	for _, kv := range parseList(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")) {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("invalid OTEL_EXPORTER_OTLP_HEADERS: %q is not key=value", kv)
+		}
+		// Values may be percent-encoded, as in the specification
+		if dv, err := url.QueryUnescape(v); err == nil {
+			v = dv
+		}
+		t.headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	if v := os.Getenv("OTEL_SERVICE_NAME"); v != "" {
+		t.service = v
+	}
+	if v := os.Getenv("OTEL_METRIC_EXPORT_INTERVAL"); v != "" {
+		ms, err := strconv.Atoi(v)
+		if err != nil || ms <= 0 {
+			return nil, fmt.Errorf("invalid OTEL_METRIC_EXPORT_INTERVAL: %q (milliseconds)", v)
+		}
+		t.interval = time.Duration(ms) * time.Millisecond
+	}
+	switch v := os.Getenv("OTEL_TRACES_EXPORTER"); v {
+	case "", "none":
+	case "otlp":
+		t.traces = true
+	default:
+		return nil, fmt.Errorf("invalid OTEL_TRACES_EXPORTER: %q (expected otlp or none)", v)
+	}
+	return t, nil
+}
+
+// record adds the outcome of a check cycle.
+func (t *telemetry) record(website, check string, r monitor.Report, ok bool) {
+	ms := float64(r.Ping.Latency) / float64(time.Millisecond)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := t.series[website]
+	if s == nil {
+		s = &targetSeries{start: time.Now(), buckets: make([]uint64, len(latencyBounds)+1)}
+		t.series[website] = s
+	}
+	s.check, s.up = check, ok
+	s.checks++
+	if !ok {
+		s.failures++
+	}
+	if r.Ping.Err == nil {
+		if s.count == 0 || ms < s.min {
+			s.min = ms
+		}
+		s.max = max(s.max, ms)
+		s.count++
+		s.sum += ms
+		i := 0
+		for i < len(latencyBounds) && ms > latencyBounds[i] {
+			i++
+		}
+		s.buckets[i]++
+	}
+
+	if !t.traces {
+		return
+	}
+	span := map[string]any{
+		"traceId":           randomHex(16),
+		"spanId":            randomHex(8),
+		"name":              "check " + check,
+		"kind":              3, // client
+		"startTimeUnixNano": unixNano(r.Time),
+		"endTimeUnixNano":   unixNano(r.Time.Add(r.Ping.Latency)),
+		"attributes":        otlpAttributes(website, check, "vivteno.maintenance", r.Maintenance),
+		"status":            map[string]any{"code": 1},
+	}
+	if err := r.Err(); err != nil {
+		span["status"] = map[string]any{"code": 2, "message": err.Error()}
+	}
+	if len(t.spans) >= TelemetryMaxSpans {
+		t.spans = t.spans[1:]
+	}
+	t.spans = append(t.spans, span)
+}
+
+// retain drops the series of websites that are no longer configured. If one
+// is added again, its series restarts with a new start time.
+func (t *telemetry) retain(websites []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	keep := make(map[string]bool, len(websites))
+	for _, w := range websites {
+		keep[w] = true
+	}
+	for w := range t.series {
+		if !keep[w] {
+			delete(t.series, w)
+		}
+	}
+}
+
+// run exports every interval until ctx is cancelled, then once more so the
+// last checks are not lost.
+func (t *telemetry) run(ctx context.Context) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			final, cancel := context.WithTimeout(context.Background(), telemetryTimeout)
+			t.export(final)
+			cancel()
+			return
+		case <-ticker.C:
+			t.export(ctx)
+		}
+	}
+}
+
+// export sends the current metrics and the buffered spans. Failures are
+// logged; metrics are cumulative, so the next export catches up.
+func (t *telemetry) export(ctx context.Context) {
+	metrics, spans := t.payloads(time.Now())
+	if metrics != nil {
+		if err := t.post(ctx, "/v1/metrics", metrics); err != nil {
+			t.logger.Error("exporting metrics failed", "error", err.Error())
+		}
+	}
+	if spans != nil {
+		if err := t.post(ctx, "/v1/traces", spans); err != nil {
+			t.logger.Error("exporting spans failed", "error", err.Error())
+		}
+	}
+}
+
+// payloads builds the OTLP metrics request, if any website was checked, and
+// the traces request, if spans are buffered. Spans are handed over once and
+// dropped if their export fails.
+func (t *telemetry) payloads(now time.Time) (metrics, traces map[string]any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ts := unixNano(now)
+	var durations, up, checks, failures []map[string]any
+	for website, s := range t.series {
+		attrs := otlpAttributes(website, s.check)
+		start := unixNano(s.start)
+		buckets := make([]string, len(s.buckets))
+		for i, n := range s.buckets {
+			buckets[i] = strconv.FormatUint(n, 10)
+		}
+		d := map[string]any{
+			"attributes": attrs, "startTimeUnixNano": start, "timeUnixNano": ts,
+			"count": strconv.FormatUint(s.count, 10), "sum": s.sum,
+			"bucketCounts": buckets, "explicitBounds": latencyBounds,
+		}
+		if s.count > 0 {
+			d["min"], d["max"] = s.min, s.max
+		}
+		durations = append(durations, d)
+		upValue := "0"
+		if s.up {
+			upValue = "1"
+		}
+		up = append(up, map[string]any{"attributes": attrs, "timeUnixNano": ts, "asInt": upValue})
+		checks = append(checks, map[string]any{"attributes": attrs, "startTimeUnixNano": start, "timeUnixNano": ts, "asInt": strconv.FormatInt(s.checks, 10)})
+		failures = append(failures, map[string]any{"attributes": attrs, "startTimeUnixNano": start, "timeUnixNano": ts, "asInt": strconv.FormatInt(s.failures, 10)})
+	}
+	if len(t.series) > 0 {
+		metrics = t.metrics(durations, up, checks, failures)
+	}
+	if len(t.spans) > 0 {
+		traces = t.resource("resourceSpans", "scopeSpans", "spans", t.spans)
+		t.spans = nil
+	}
+	return metrics, traces
+}
+
+// metrics wraps the data points of each metric in an OTLP metrics request.
+func (t *telemetry) metrics(durations, up, checks, failures []map[string]any) map[string]any {
+	const cumulative = 2
+	return t.resource("resourceMetrics", "scopeMetrics", "metrics", []map[string]any{
+		{"name": "vivteno.check.duration", "description": "Latency of successful checks", "unit": "ms",
+			"histogram": map[string]any{"aggregationTemporality": cumulative, "dataPoints": durations}},
+		{"name": "vivteno.target.up", "description": "1 if the last check succeeded, 0 if it failed", "unit": "1",
+			"gauge": map[string]any{"dataPoints": up}},
+		{"name": "vivteno.checks", "description": "Check cycles run", "unit": "{check}",
+			"sum": map[string]any{"aggregationTemporality": cumulative, "isMonotonic": true, "dataPoints": checks}},
+		{"name": "vivteno.check.failures", "description": "Check cycles that failed", "unit": "{check}",
+			"sum": map[string]any{"aggregationTemporality": cumulative, "isMonotonic": true, "dataPoints": failures}},
+	})
+}
+
+// resource wraps items in the resource and instrumentation scope envelope
+// shared by OTLP requests.
+func (t *telemetry) resource(resourceKey, scopeKey, itemsKey string, items []map[string]any) map[string]any {
+	return map[string]any{resourceKey: []map[string]any{{
+		"resource": map[string]any{"attributes": []map[string]any{attribute("service.name", t.service)}},
+		scopeKey: []map[string]any{{
+			"scope":  map[string]any{"name": "github.com/mooship/vivteno"},
+			itemsKey: items,
+		}},
+	}}}
+}
+
+func (t *telemetry) post(ctx context.Context, path string, payload map[string]any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// otlpAttributes returns the attributes identifying a website, followed by
+// extra key/value pairs.
+func otlpAttributes(website, check string, extra ...any) []map[string]any {
+	attrs := []map[string]any{attribute("vivteno.target", website), attribute("vivteno.check", check)}
+	for i := 0; i+1 < len(extra); i += 2 {
+		attrs = append(attrs, attribute(extra[i].(string), extra[i+1]))
+	}
+	return attrs
+}
+
+// attribute encodes a key/value pair as an OTLP attribute.
+func attribute(key string, value any) map[string]any {
+	var v map[string]any
+	switch value := value.(type) {
+	case bool:
+		v = map[string]any{"boolValue": value}
+	default:
+		v = map[string]any{"stringValue": fmt.Sprint(value)}
+	}
+	return map[string]any{"key": key, "value": v}
+}
+
+func unixNano(t time.Time) string { return strconv.FormatInt(t.UnixNano(), 10) }
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
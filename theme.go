@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Built-in themes
+const (
+	ThemeDark      = "dark"
+	ThemeLight     = "light"
+	ThemeSolarized = "solarized"
+	ThemeNoColor   = "no-color"
+	DefaultTheme   = ThemeDark
+)
+
+// theme holds the colors of the terminal UI. Colors are ANSI numbers
+// ("9") or hex codes ("#dc322f").
+type theme struct {
+	Header           lipgloss.TerminalColor
+	HeaderBackground lipgloss.TerminalColor
+	// Title colors section titles and health keys, Text health values.
+	Title lipgloss.TerminalColor
+	Text  lipgloss.TerminalColor
+	// Up, Warning, Down and Notice color states and messages; Muted colors
+	// unknown states and the footer.
+	Up      lipgloss.TerminalColor
+	Warning lipgloss.TerminalColor
+	Down    lipgloss.TerminalColor
+	Notice  lipgloss.TerminalColor
+	Muted   lipgloss.TerminalColor
+	// Badge is the text color of the paused, muted and flapping badges.
+	Badge lipgloss.TerminalColor
+}
+
+var themes = map[string]theme{
+	ThemeDark: {
+		Header: lipgloss.Color("15"), HeaderBackground: lipgloss.Color("0"),
+		Title: lipgloss.Color("7"), Text: lipgloss.Color("15"),
+		Up: lipgloss.Color("10"), Warning: lipgloss.Color("11"), Down: lipgloss.Color("9"),
+		Notice: lipgloss.Color("12"), Muted: lipgloss.Color("8"), Badge: lipgloss.Color("0"),
+	},
+	ThemeLight: {
+		Header: lipgloss.Color("15"), HeaderBackground: lipgloss.Color("4"),
+		Title: lipgloss.Color("8"), Text: lipgloss.Color("0"),
+		Up: lipgloss.Color("2"), Warning: lipgloss.Color("3"), Down: lipgloss.Color("1"),
+		Notice: lipgloss.Color("4"), Muted: lipgloss.Color("8"), Badge: lipgloss.Color("15"),
+	},
+	ThemeSolarized: {
+		Header: lipgloss.Color("#fdf6e3"), HeaderBackground: lipgloss.Color("#073642"),
+		Title: lipgloss.Color("#93a1a1"), Text: lipgloss.Color("#eee8d5"),
+		Up: lipgloss.Color("#859900"), Warning: lipgloss.Color("#b58900"), Down: lipgloss.Color("#dc322f"),
+		Notice: lipgloss.Color("#268bd2"), Muted: lipgloss.Color("#586e75"), Badge: lipgloss.Color("#002b36"),
+	},
+	ThemeNoColor: {
+		Header: lipgloss.NoColor{}, HeaderBackground: lipgloss.NoColor{},
+		Title: lipgloss.NoColor{}, Text: lipgloss.NoColor{},
+		Up: lipgloss.NoColor{}, Warning: lipgloss.NoColor{}, Down: lipgloss.NoColor{},
+		Notice: lipgloss.NoColor{}, Muted: lipgloss.NoColor{}, Badge: lipgloss.NoColor{},
+	},
+}
+
+var colorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// loadTheme reads THEME and THEME_COLORS. NO_COLOR, when set to anything,
+// selects the no-color theme whatever the other settings say.
+func loadTheme() (theme, error) {
+	if os.Getenv("NO_COLOR") != "" {
+		return themes[ThemeNoColor], nil
+	}
+	name := strings.ToLower(os.Getenv("THEME"))
+	if name == "" {
+		name = DefaultTheme
+	}
+	th, ok := themes[name]
+	if !ok {
+		names := make([]string, 0, len(themes))
+		for n := range themes {
+			names = append(names, n)
+		}
+		slices.Sort(names)
+		return th, fmt.Errorf("invalid THEME: %q (expected %s)", name, strings.Join(names, ", "))
+	}
+	for _, kv := range parseList(os.Getenv("THEME_COLORS")) {
+		key, value, found := strings.Cut(kv, "=")
+		if !found {
+			return th, fmt.Errorf("invalid THEME_COLORS: %q is not name=color", kv)
+		}
+		c, err := parseColor(strings.TrimSpace(value))
+		if err != nil {
+			return th, fmt.Errorf("invalid THEME_COLORS: %s: %w", key, err)
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "header":
+			th.Header = c
+		case "header_background":
+			th.HeaderBackground = c
+		case "title":
+			th.Title = c
+		case "text":
+			th.Text = c
+		case "up":
+			th.Up = c
+		case "warning":
+			th.Warning = c
+		case "down":
+			th.Down = c
+		case "notice":
+			th.Notice = c
+		case "muted":
+			th.Muted = c
+		case "badge":
+			th.Badge = c
+		default:
+			return th, fmt.Errorf("invalid THEME_COLORS: unknown color %q", key)
+		}
+	}
+	return th, nil
+}
+
+// parseColor accepts an ANSI color number from 0 to 255 or a hex code.
+func parseColor(s string) (lipgloss.TerminalColor, error) {
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n <= 255 {
+		return lipgloss.Color(s), nil
+	}
+	if colorPattern.MatchString(s) {
+		return lipgloss.Color(s), nil
+	}
+	return nil, fmt.Errorf("%q is not an ANSI color (0-255) or hex code", s)
+}
+
+// --- Styles ---
+var (
+	headerStyle      lipgloss.Style
+	flashStyle       lipgloss.Style
+	sectionTitle     lipgloss.Style
+	infoStyle        lipgloss.Style
+	errorStyle       lipgloss.Style
+	warningStyle     lipgloss.Style
+	noticeStyle      lipgloss.Style
+	healthKeyStyle   lipgloss.Style
+	healthValueStyle lipgloss.Style
+	changedStyle     lipgloss.Style
+	downStyle        lipgloss.Style
+	maintenanceStyle lipgloss.Style
+	degradedStyle    lipgloss.Style
+	unknownStyle     lipgloss.Style
+	pausedStyle      lipgloss.Style
+	flappingStyle    lipgloss.Style
+	mutedStyle       lipgloss.Style
+	footerStyle      lipgloss.Style
+)
+
+func init() {
+	themes[DefaultTheme].apply()
+}
+
+// apply builds the styles of the terminal UI from the theme.
+func (th theme) apply() {
+	headerStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(th.Header).
+		Background(th.HeaderBackground).
+		Padding(0, 2).
+		MarginBottom(1)
+	flashStyle = headerStyle.Reverse(true)
+	sectionTitle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(th.Title).
+		PaddingRight(1)
+	infoStyle = lipgloss.NewStyle().Foreground(th.Up)
+	errorStyle = lipgloss.NewStyle().Foreground(th.Down).Bold(true).Padding(0, 1).MarginTop(1)
+	warningStyle = lipgloss.NewStyle().Foreground(th.Warning).Bold(true).Padding(0, 1).MarginTop(1)
+	noticeStyle = lipgloss.NewStyle().Foreground(th.Notice).Bold(true).Padding(0, 1).MarginTop(1)
+	healthKeyStyle = lipgloss.NewStyle().Foreground(th.Title).Bold(true)
+	healthValueStyle = lipgloss.NewStyle().Foreground(th.Text)
+	changedStyle = lipgloss.NewStyle().Foreground(th.Notice).Bold(true)
+	downStyle = lipgloss.NewStyle().Foreground(th.Down).Bold(true)
+	maintenanceStyle = lipgloss.NewStyle().Foreground(th.Notice).Bold(true)
+	degradedStyle = lipgloss.NewStyle().Foreground(th.Warning).Bold(true)
+	unknownStyle = lipgloss.NewStyle().Foreground(th.Muted)
+	pausedStyle = lipgloss.NewStyle().Foreground(th.Badge).Background(th.Muted).Padding(0, 1)
+	flappingStyle = lipgloss.NewStyle().Foreground(th.Badge).Background(th.Warning).Padding(0, 1)
+	mutedStyle = lipgloss.NewStyle().Foreground(th.Badge).Background(th.Notice).Padding(0, 1)
+	footerStyle = lipgloss.NewStyle().
+		Foreground(th.Muted).
+		Padding(1, 0).
+		MarginTop(1)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mooship/vivteno/pkg/monitor"
+)
+
+// TracerouteTimeout bounds a traceroute, including the lookup of the
+// website's address.
+const TracerouteTimeout = time.Minute
+
+// traceResult is the last traceroute of a website.
+type traceResult struct {
+	time  time.Time
+	trace monitor.Trace
+	err   string
+}
+
+// tracerouteMsg delivers the outcome of a traceroute.
+type tracerouteMsg struct {
+	website string
+	time    time.Time
+	trace   monitor.Trace
+	err     error
+}
+
+// tracerouteCmd traces the path to the website of a report that took it
+// down.
+func (m model) tracerouteCmd(r monitor.Report) tea.Cmd {
+	t := m.targets[r.Index]
+	website, protocol := t.website, t.traceroute
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(m.ctx, TracerouteTimeout)
+		defer cancel()
+		trace, err := monitor.Traceroute(ctx, r.Target, protocol)
+		return tracerouteMsg{website: website, time: r.Time, trace: trace, err: err}
+	}
+}
+
+// applyTraceroute records a traceroute on its website, unless the website
+// was removed by a reload meanwhile.
+func (m model) applyTraceroute(msg tracerouteMsg) {
+	t := m.target(msg.website)
+	if t == nil {
+		return
+	}
+	t.trace = &traceResult{time: msg.time, trace: msg.trace}
+	if msg.err != nil {
+		t.trace.err = msg.err.Error()
+		m.logger.Warn("traceroute failed", "target", t.website, "error", t.trace.err)
+		return
+	}
+	m.logger.Info("traceroute", "target", t.website, "destination", msg.trace.Destination, "hops", len(msg.trace.Hops), "reached", msg.trace.Reached)
+}
+
+// renderTraceroute lists the hops of the last traceroute of a website, one
+// per line, with the routers that did not reply as asterisks.
+func renderTraceroute(m model, t *target) string {
+	var b strings.Builder
+	title := fmt.Sprintf("Traceroute (%s) to %s at %s:", t.trace.trace.Protocol, t.trace.trace.Destination, m.formatTime(t.trace.time))
+	if t.trace.trace.Destination == "" {
+		title = fmt.Sprintf("Traceroute at %s:", m.formatTime(t.trace.time))
+	}
+	b.WriteString(sectionTitle.Render(title))
+	b.WriteString("\n")
+	for _, h := range t.trace.trace.Hops {
+		switch {
+		case h.Addr == "":
+			b.WriteString(unknownStyle.Render(fmt.Sprintf("  %2d  *", h.TTL)))
+		case h.Unreachable:
+			b.WriteString(downStyle.Render(fmt.Sprintf("  %2d  %s  %d ms  destination unreachable", h.TTL, h.Addr, h.RTT.Milliseconds())))
+		default:
+			b.WriteString(infoStyle.Render(fmt.Sprintf("  %2d  %s  %d ms", h.TTL, h.Addr, h.RTT.Milliseconds())))
+		}
+		b.WriteString("\n")
+	}
+	switch {
+	case t.trace.err != "":
+		b.WriteString(downStyle.Render("  " + t.trace.err))
+		b.WriteString("\n")
+	case !t.trace.trace.Reached && len(t.trace.trace.Hops) > 0 && !t.trace.trace.Hops[len(t.trace.trace.Hops)-1].Unreachable:
+		b.WriteString(warningStyle.Render("  destination not reached"))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
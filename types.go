@@ -2,45 +2,161 @@ package main
 
 import (
 	"context"
+	"log/slog"
 	"time"
+
+	"github.com/mooship/vivteno/pkg/monitor"
+	"github.com/mooship/vivteno/pkg/notify"
 )
 
 type model struct {
-	websites          []string
-	schedule          string
-	timezone          *time.Location
-	healthEndpoint    []string
-	lastPing          []string
-	lastError         []string
-	lastHealthGeneric []map[string]any
-	quit              bool
-	ctx               context.Context
-	cancel            context.CancelFunc
-}
-
-func initialModel(websites []string, schedule string, healthEndpoints []string, ctx context.Context, cancel context.CancelFunc) model {
+	targets          []*target
+	schedule         string
+	timezone         *time.Location
+	healthOrder      []string
+	failureThreshold int
+	flap             flapPolicy
+	muteDuration     time.Duration
+	quiet            quietHours
+	held             map[string][]notify.Event // alerts held back during quiet hours, by alert key
+	notifiers        []notify.Notifier
+	lastNotifyError  string
+	alerts           alertPolicy
+	slo              sloPolicy
+	incidents        *incidentLog
+	lastStorageError string
+	lastReloadError  string
+	exportDir        string
+	exportFormat     string
+	rdapServer       string
+	domainRefresh    time.Duration
+	lastExport       string
+	lastExportError  string
+	tableView        bool
+	historyPane      bool
+	grouped          bool
+	groupBy          string
+	collapsed        map[string]bool
+	marked           map[string]bool // websites selected for bulk actions, by name
+	visual           bool            // visual selection from visualStart to the cursor
+	visualStart      int
+	pendingKey       string // first key of a two-key command such as gg
+	focused          bool
+	help             bool // help overlay shown
+	bell             bool
+	bellFlash        bool
+	flashStart       time.Time // when the header started flashing, see flashing
+	quit             bool
+	mon              *monitor.Monitor
+	stopMonitor      context.CancelFunc
+	selected         int
+	width            int // terminal width, 0 until it is reported
+	store            *statusStore
+	heartbeats       *heartbeatStore
+	logger           *slog.Logger
+	telemetry        *telemetry
+	influx           *influxWriter
+	statsd           *statsdClient
+	mqtt             *mqttPublisher
+	pending          *pendingWork
+	shutdownTimeout  time.Duration
+	ctx              context.Context
+	cancel           context.CancelFunc
+}
+
+func initialModel(cfg config, ctx context.Context, cancel context.CancelFunc) model {
 	return model{
-		websites:          websites,
-		schedule:          schedule,
-		timezone:          time.Local,
-		healthEndpoint:    healthEndpoints,
-		lastPing:          make([]string, len(websites)),
-		lastError:         make([]string, len(websites)),
-		lastHealthGeneric: make([]map[string]any, len(websites)),
-		quit:              false,
-		ctx:               ctx,
-		cancel:            cancel,
+		targets:          newTargets(cfg),
+		schedule:         cfg.describeSchedule(),
+		timezone:         cfg.timezone,
+		healthOrder:      cfg.healthOrder,
+		failureThreshold: cfg.threshold,
+		flap:             cfg.flap,
+		muteDuration:     cfg.muteDuration,
+		quiet:            cfg.quiet,
+		held:             make(map[string][]notify.Event),
+		notifiers:        cfg.notifiers,
+		alerts:           cfg.alerts,
+		slo:              cfg.slo,
+		exportDir:        cfg.exportDir,
+		exportFormat:     cfg.exportFormat,
+		rdapServer:       cfg.rdapServer,
+		domainRefresh:    cfg.domainRefresh,
+		incidents:        &incidentLog{},
+		pending:          newPendingWork(),
+		shutdownTimeout:  cfg.shutdownTimeout,
+		tableView:        false,
+		grouped:          cfg.groupBy != "",
+		groupBy:          cfg.groupBy,
+		bell:             cfg.bell,
+		bellFlash:        cfg.bellFlash,
+		collapsed:        make(map[string]bool),
+		marked:           make(map[string]bool),
+		focused:          false,
+		quit:             false,
+		logger:           slog.New(slog.DiscardHandler),
+		ctx:              ctx,
+		cancel:           cancel,
+	}
+}
+
+// startMonitor runs mon until the model's context is cancelled or the
+// monitor is replaced.
+func (m model) startMonitor(mon *monitor.Monitor) model {
+	ctx, stop := context.WithCancel(m.ctx)
+	go mon.Run(ctx)
+	m.mon, m.stopMonitor = mon, stop
+	return m
+}
+
+// setPaused pauses or resumes the checks of the target at i.
+func (m model) setPaused(i int, paused bool) {
+	t := m.targets[i]
+	if t.paused == paused {
+		return
 	}
+	t.paused = paused
+	if paused {
+		m.mon.Pause(i)
+		m.logger.Info("checks paused", "target", t.website)
+	} else {
+		m.mon.Resume(i)
+		m.logger.Info("checks resumed", "target", t.website)
+	}
+}
+
+// pausedCount returns the number of paused targets.
+func (m model) pausedCount() int {
+	var n int
+	for _, t := range m.targets {
+		if t.paused {
+			n++
+		}
+	}
+	return n
 }
 
-type tickMsg time.Time
+// publish updates the web dashboard, if enabled, with the current state.
+func (m model) publish() {
+	if m.store != nil {
+		m.store.set(m.snapshot())
+	}
+}
 
-type pingResult struct {
-	result string
-	err    error
+// index returns the position of website in the target list, or -1.
+func (m model) index(website string) int {
+	for i, t := range m.targets {
+		if t.website == website {
+			return i
+		}
+	}
+	return -1
 }
 
-type healthResultGeneric struct {
-	data map[string]any
-	err  error
+// target returns the target of website, or nil if it is not configured.
+func (m model) target(website string) *target {
+	if i := m.index(website); i >= 0 {
+		return m.targets[i]
+	}
+	return nil
 }
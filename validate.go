@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// runValidate implements the validate subcommand: it loads the configuration
+// the way run does, including Kubernetes discovery and HISTORY_FILE, without
+// starting any checks, and reports the first error or a summary. It returns
+// the process exit code.
+func runValidate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if err := loadEnv().reload(); err != nil {
+		fmt.Printf("Invalid %s: %v\n", EnvFile, err)
+		return 1
+	}
+	logger, logFile, err := loadLogger()
+	if err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		return 1
+	}
+	defer logFile.Close()
+	if _, err := loadTelemetry(logger); err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		return 1
+	}
+	if _, err := loadInflux(logger); err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		return 1
+	}
+	if _, err := loadMQTT(logger); err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		return 1
+	}
+	_, discovered, err := startDiscovery()
+	if err != nil {
+		fmt.Printf("Discovery failed: %v\n", err)
+		return 1
+	}
+	cfg, err := loadConfig(discovered)
+	if err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		return 1
+	}
+	incidents, err := loadIncidents(cfg.historyFile)
+	if err != nil {
+		fmt.Printf("Invalid HISTORY_FILE: %v\n", err)
+		return 1
+	}
+
+	fmt.Println("Configuration is valid")
+	fmt.Printf("  Websites:  %d\n", len(cfg.websites))
+	for i, w := range cfg.websites {
+		fmt.Printf("    %s (%s)\n", w, cfg.checkTypes[i])
+	}
+	fmt.Printf("  Schedule:  %s\n", cfg.describeSchedule())
+	names := make([]string, len(cfg.notifiers))
+	for i, n := range cfg.notifiers {
+		names[i] = n.Name()
+	}
+	if len(names) == 0 {
+		names = append(names, "none")
+	}
+	fmt.Printf("  Notifiers: %s\n", strings.Join(names, ", "))
+	if cfg.historyFile != "" {
+		fmt.Printf("  History:   %s (%d incidents)\n", cfg.historyFile, len(incidents.items))
+	}
+	return 0
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Settings for the web dashboard
+const (
+	WebRefreshSeconds    = 5
+	WebReadHeaderTimeout = 5 * time.Second
+)
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="{{.Refresh}}">
+<title>Vivteno - Website Health Monitor</title>
+<style>
+body { font-family: system-ui, sans-serif; background: #111; color: #eee; margin: 2rem; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: .4rem .8rem; border-bottom: 1px solid #333; vertical-align: top; }
+th { color: #aaa; }
+a { color: #8af; }
+.up { color: #4c4; } .degraded, .slow, .warning { color: #dd4; } .critical { color: #e44; } .down { color: #e44; } .maintenance { color: #48f; } .unknown { color: #888; }
+.health { color: #aaa; font-size: .9em; margin: 0; padding-left: 1rem; }
+footer { color: #777; margin-top: 1rem; font-size: .9em; }
+</style>
+</head>
+<body>
+<h1>Vivteno - Website Health Monitor</h1>
+<table>
+<tr><th>Website</th><th>Status</th><th>Latency</th><th>Uptime</th><th>Last checked</th><th>Details</th></tr>
+{{range .Rows}}<tr>
+<td>{{.Website}} <small>({{.Check}})</small></td>
+<td class="{{.Status}}">{{.Status}}{{if .Paused}} <small class="unknown">(paused)</small>{{end}}{{if .Acknowledged}} <small class="unknown">(acknowledged)</small>{{else if .Muted}} <small class="unknown">(muted)</small>{{end}}{{if .Flapping}} <small class="degraded">(flapping)</small>{{end}}</td>
+<td class="{{.LatencyLevel}}">{{.Latency}}{{range .Families}}<br><small class="{{.Status}}">{{.Text}}</small>{{end}}{{range .Ports}}<br><small class="{{.Status}}">{{.Text}}</small>{{end}}{{range .Locations}}<br><small class="{{.Status}}">{{.Text}}</small>{{end}}</td>
+<td>{{.Uptime}}</td>
+<td>{{.LastChecked}}</td>
+<td>{{if .LastError}}<span class="down">{{.LastError}}</span>{{end}}{{range .Violations}}<span class="degraded">assertion failed: {{.}}</span><br>{{end}}{{if .Health}}
+<ul class="health">{{range .Health}}<li>{{.Key}}: {{.Value}}</li>{{end}}</ul>{{end}}</td>
+</tr>
+{{end}}</table>
+<footer>Updated {{.Updated}} &middot; refreshes every {{.Refresh}}s &middot; <a href="/status.json">JSON</a></footer>
+</body>
+</html>
+`))
+
+// dashboardRow is a targetSnapshot formatted for the HTML dashboard.
+type dashboardRow struct {
+	Website string
+	Check   string
+	Status  string
+	Latency string
+	// LatencyLevel is the CSS class of the latency: warning or critical.
+	LatencyLevel string
+	Uptime       string
+	LastChecked  string
+	LastError    string
+	Health       []healthField
+	Families     []dashboardFamily
+	Ports        []dashboardFamily
+	Locations    []dashboardFamily
+	Paused       bool
+	Muted        bool
+	Acknowledged bool
+	Flapping     bool
+	Violations   []string
+}
+
+// dashboardFamily is the result of one IP family of a dual-stack website, of
+// one port of a website with several ports or of one agent location.
+type dashboardFamily struct {
+	Status string
+	Text   string
+}
+
+type healthField struct {
+	Key   string
+	Value string
+}
+
+// newWebServer returns the dashboard server: an HTML page at / that refreshes
+// itself, the raw snapshots as JSON at /status.json, the health of vivteno
+// itself (see handleSelfHealth), the heartbeat URLs (see handleHeartbeats)
+// and, with agentToken, the URL agents push results to (see handleAgents).
+// Beats and results are sent to the program with send.
+func newWebServer(addr string, store *statusStore, heartbeats *heartbeatStore, agentToken string, send func(tea.Msg)) *http.Server {
+	mux := http.NewServeMux()
+	handleHeartbeats(mux, heartbeats, send)
+	handleAgents(mux, agentToken, send)
+	handleSelfHealth(mux, store)
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		targets, updated := store.get()
+		healthOrder, tz := store.display()
+		rows := make([]dashboardRow, len(targets))
+		for i, t := range targets {
+			rows[i] = newDashboardRow(t, healthOrder, tz)
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = dashboardTemplate.Execute(w, map[string]any{
+			"Rows":    rows,
+			"Updated": formatWebTime(updated, tz),
+			"Refresh": WebRefreshSeconds,
+		})
+	})
+	mux.HandleFunc("GET /status.json", func(w http.ResponseWriter, r *http.Request) {
+		targets, updated := store.get()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"updated": updated,
+			"targets": targets,
+		})
+	})
+	return &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: WebReadHeaderTimeout,
+	}
+}
+
+func newDashboardRow(t targetSnapshot, healthOrder []string, tz *time.Location) dashboardRow {
+	row := dashboardRow{
+		Website:      t.Website,
+		Check:        t.Check,
+		Status:       t.Status,
+		Latency:      "-",
+		Uptime:       "-",
+		LastChecked:  formatWebTime(t.LastChecked, tz),
+		LastError:    t.LastError,
+		LatencyLevel: t.LatencyLevel,
+		Paused:       t.Paused,
+		Muted:        t.Muted,
+		Acknowledged: t.Acknowledged,
+		Flapping:     t.Flapping,
+		Violations:   t.Violations,
+	}
+	if t.LatencyMs > 0 || t.Status == statusUp.String() {
+		row.Latency = fmt.Sprintf("%d ms", t.LatencyMs)
+	}
+	if t.PacketLoss != nil && *t.PacketLoss > 0 {
+		row.Latency += fmt.Sprintf(" (%.0f%% loss)", *t.PacketLoss*100)
+	}
+	if t.Uptime >= 0 {
+		row.Uptime = fmt.Sprintf("%.1f%%", t.Uptime)
+	}
+	for _, f := range t.Families {
+		if f.Error != "" {
+			row.Families = append(row.Families, dashboardFamily{Status: statusDown.String(), Text: f.Family + " down"})
+		} else {
+			row.Families = append(row.Families, dashboardFamily{Status: statusUp.String(), Text: fmt.Sprintf("%s %d ms", f.Family, f.LatencyMs)})
+		}
+	}
+	for _, p := range t.Ports {
+		if p.Error != "" {
+			row.Ports = append(row.Ports, dashboardFamily{Status: statusDown.String(), Text: "port " + p.Port + " down"})
+		} else {
+			row.Ports = append(row.Ports, dashboardFamily{Status: statusUp.String(), Text: fmt.Sprintf("port %s %d ms", p.Port, p.LatencyMs)})
+		}
+	}
+	for _, l := range t.Locations {
+		text := fmt.Sprintf("%s %d ms", l.Location, l.LatencyMs)
+		if l.Status != statusUp.String() {
+			text = l.Location + " " + l.Status
+		}
+		row.Locations = append(row.Locations, dashboardFamily{Status: l.Status, Text: text})
+	}
+	for _, k := range healthKeys(t.Health, healthOrder) {
+		v := t.Health[k]
+		value := fmt.Sprintf("%v", v)
+		switch v.(type) {
+		case map[string]any, []any:
+			if b, err := json.Marshal(v); err == nil {
+				value = string(b)
+			}
+		}
+		row.Health = append(row.Health, healthField{Key: k, Value: value})
+	}
+	return row
+}
+
+func formatWebTime(t time.Time, tz *time.Location) string {
+	if t.IsZero() {
+		return "-"
+	}
+	if tz != nil {
+		t = t.In(tz)
+	}
+	return t.Format(DisplayTimeFormat)
+}